@@ -19,8 +19,16 @@ import (
 // @description Go Clean Arch
 // @contact.name Sefik Can Kanber
 // @contact.url https://github.com/sefikcan
-// @BasePath /api/v1
+// @BasePath /api
 // @host localhost:5000
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Bearer token, e.g. "Bearer eyJhbGciOi..."
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description Api key of a machine client, e.g. "kbr_3f9a..."
 func main()  {
 	log.Println("Starting api server")
 
@@ -28,7 +36,7 @@ func main()  {
 
 	zapLogger := logger.NewLogger(cfg)
 	zapLogger.InitLogger()
-	zapLogger.Infof("AppVersion: %s, LogLevel: %s, Mode: %s, SSL: %v", cfg.Server.AppVersion, cfg.Logger.Level, cfg.Server.Mode, false)
+	zapLogger.Infof("AppVersion: %s, LogLevel: %s, Mode: %s, SSL: %v", cfg.Server.AppVersion, cfg.Logger.Level, cfg.Server.Mode, cfg.Server.SSL)
 
 	psqlDB, err := postgres.NewPsqlDB(cfg)
 	db, err := psqlDB.DB()
@@ -39,9 +47,12 @@ func main()  {
 	}
 	defer db.Close()
 
-	redisClient := redis.NewRedisClient(cfg)
+	redisClient, err := redis.NewRedisClient(cfg)
+	if err != nil {
+		zapLogger.Fatalf("Redis init: %s", err)
+	}
 	defer redisClient.Close()
-	zapLogger.Info("Redis Connected")
+	zapLogger.Infof("Redis Connected, Mode: %s", cfg.Redis.Mode)
 
 	jaegerConfigInstance := jaegerCfg.Configuration{
 		ServiceName: cfg.Metric.ServiceName,
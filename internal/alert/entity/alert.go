@@ -0,0 +1,122 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	ConditionAbove = "above"
+	ConditionBelow = "below"
+	ConditionChangePercent = "change_percent"
+
+	ChannelWebhook = "webhook"
+	ChannelEmail = "email"
+	ChannelLog = "log"
+
+	StatusPending = "pending"
+	StatusSent = "sent"
+	StatusFailed = "failed"
+
+	DefaultWindow = 86400
+
+	AuditEntityType = "alert_rule"
+)
+
+type Rule struct {
+	ID int `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name string `json:"name"`
+	BaseCode string `gorm:"index:idx_alert_rule_pair" json:"base_code"`
+	QuoteCode string `gorm:"index:idx_alert_rule_pair" json:"quote_code"`
+	Condition string `json:"condition"`
+	Threshold float64 `gorm:"type:numeric(24,10)" json:"threshold"`
+	WindowSeconds int `json:"window_seconds"`
+	Channel string `json:"channel"`
+	Target string `json:"target"`
+	CooldownSeconds int `json:"cooldown_seconds"`
+	Active bool `json:"active"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at"`
+}
+
+func (Rule) TableName() string {
+	return "alert_rules"
+}
+
+// Crossed reports whether the rate crossed the threshold between the previous and the current value.
+// Without a previous value, being past the threshold counts as crossing it.
+func (r Rule) Crossed(previous *float64, current float64) bool {
+	switch r.Condition {
+	case ConditionAbove:
+		return current >= r.Threshold && (previous == nil || *previous < r.Threshold)
+	case ConditionBelow:
+		return current <= r.Threshold && (previous == nil || *previous > r.Threshold)
+	default:
+		return false
+	}
+}
+
+// Moved returns the percentage change from the reference value and whether it reaches the threshold
+// in either direction.
+func (r Rule) Moved(reference float64, current float64) (float64, bool) {
+	if r.Condition != ConditionChangePercent || reference == 0 {
+		return 0, false
+	}
+
+	change := (current - reference) / reference * 100
+	return change, math.Abs(change) >= r.Threshold
+}
+
+// InCooldown reports whether the rule fired too recently to fire again at the given time.
+func (r Rule) InCooldown(at time.Time) bool {
+	if r.LastTriggeredAt == nil || r.CooldownSeconds <= 0 {
+		return false
+	}
+
+	return at.Before(r.LastTriggeredAt.Add(time.Second * time.Duration(r.CooldownSeconds)))
+}
+
+func (r Rule) WindowDuration() time.Duration {
+	if r.WindowSeconds <= 0 {
+		return time.Second * DefaultWindow
+	}
+
+	return time.Second * time.Duration(r.WindowSeconds)
+}
+
+func (r Rule) Describe() string {
+	switch r.Condition {
+	case ConditionChangePercent:
+		return fmt.Sprintf("%s/%s moved %g%% within %s", r.BaseCode, r.QuoteCode, r.Threshold, r.WindowDuration())
+	default:
+		return fmt.Sprintf("%s/%s crossed %s %g", r.BaseCode, r.QuoteCode, r.Condition, r.Threshold)
+	}
+}
+
+// Alert is one firing of a rule. A rule fires at most once per rate, which keeps a redelivered
+// rate event from notifying twice.
+type Alert struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	RuleID int `gorm:"index:idx_alert_rule_rate,unique" json:"rule_id"`
+	RateID int64 `gorm:"index:idx_alert_rule_rate,unique" json:"rate_id"`
+	Pair string `json:"pair"`
+	Value float64 `gorm:"type:numeric(24,10)" json:"value"`
+	ReferenceValue *float64 `gorm:"type:numeric(24,10)" json:"reference_value"`
+	Message string `json:"message"`
+	Channel string `json:"channel"`
+	Target string `json:"target"`
+	Status string `json:"status"`
+	Attempts int `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LockedUntil *time.Time `json:"locked_until"`
+	LastError string `json:"last_error"`
+	TriggeredAt time.Time `json:"triggered_at"`
+	SentAt *time.Time `json:"sent_at"`
+}
+
+func (Alert) TableName() string {
+	return "alerts"
+}
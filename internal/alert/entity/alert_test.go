@@ -0,0 +1,75 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRuleCrossed(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name string
+		condition string
+		previous *float64
+		current float64
+		crossed bool
+	}{
+		{name: "above crossing up", condition: ConditionAbove, previous: value(9), current: 10, crossed: true},
+		{name: "above already above", condition: ConditionAbove, previous: value(11), current: 12},
+		{name: "above without history", condition: ConditionAbove, current: 10, crossed: true},
+		{name: "above staying below", condition: ConditionAbove, previous: value(8), current: 9},
+		{name: "below crossing down", condition: ConditionBelow, previous: value(11), current: 10, crossed: true},
+		{name: "below already below", condition: ConditionBelow, previous: value(9), current: 8},
+		{name: "change percent never crosses", condition: ConditionChangePercent, previous: value(1), current: 100},
+	}
+
+	for _, tt := range tests {
+		rule := Rule{Condition: tt.condition, Threshold: 10}
+		if got := rule.Crossed(tt.previous, tt.current); got != tt.crossed {
+			t.Errorf("%s: Crossed = %t, want %t", tt.name, got, tt.crossed)
+		}
+	}
+}
+
+func TestRuleMoved(t *testing.T) {
+	rule := Rule{Condition: ConditionChangePercent, Threshold: 5}
+	tests := []struct {
+		reference float64
+		current float64
+		change float64
+		moved bool
+	}{
+		{reference: 100, current: 105, change: 5, moved: true},
+		{reference: 100, current: 94, change: -6, moved: true},
+		{reference: 100, current: 104, change: 4},
+		{reference: 0, current: 10},
+	}
+
+	for _, tt := range tests {
+		change, moved := rule.Moved(tt.reference, tt.current)
+		if moved != tt.moved || change != tt.change {
+			t.Errorf("Moved(%g, %g) = %g, %t, want %g, %t", tt.reference, tt.current, change, moved, tt.change, tt.moved)
+		}
+	}
+	if _, moved := (Rule{Condition: ConditionAbove, Threshold: 5}).Moved(100, 200); moved {
+		t.Error("a threshold rule reported a move")
+	}
+}
+
+func TestRuleInCooldown(t *testing.T) {
+	lastTriggeredAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rule := Rule{CooldownSeconds: 600, LastTriggeredAt: &lastTriggeredAt}
+
+	if !rule.InCooldown(lastTriggeredAt.Add(time.Minute * 5)) {
+		t.Error("5 minutes after firing the rule is not in its 10 minute cooldown")
+	}
+	if rule.InCooldown(lastTriggeredAt.Add(time.Minute * 10)) {
+		t.Error("the cooldown did not end after 10 minutes")
+	}
+	if (Rule{CooldownSeconds: 600}).InCooldown(lastTriggeredAt) {
+		t.Error("a rule that never fired is in cooldown")
+	}
+	if (Rule{LastTriggeredAt: &lastTriggeredAt}).InCooldown(lastTriggeredAt) {
+		t.Error("a rule without a cooldown is in cooldown")
+	}
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/usecase"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/request/alert"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type AlertHandlers interface {
+	CreateRule() echo.HandlerFunc
+	GetRuleById() echo.HandlerFunc
+	DeleteRule() echo.HandlerFunc
+	GetRules() echo.HandlerFunc
+	GetHistory() echo.HandlerFunc
+}
+
+type alertHandlers struct {
+	cfg *config.Config
+	alertUseCase usecase.AlertUseCase
+	logger logger.Logger
+}
+
+// CreateRule godoc
+// @Summary Create alert rule
+// @Description Creates a rule evaluated on every ingested rate of its pair. above/below fire when the rate crosses the threshold, change_percent fires when it moves by threshold percent within window_seconds (a day by default). cooldown_seconds suppresses repeated firing.
+// @Tags Alert
+// @Accept json
+// @Produce json
+// @Param alertRuleCreateRequest body alert.AlertRuleCreateRequest true "Create Alert Rule"
+// @Success 201 {object} alert.AlertRuleResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/alerts/rules [post]
+func (a alertHandlers) CreateRule() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "alertHandler.CreateRule")
+		defer span.Finish()
+
+		ruleRequest := alert.AlertRuleCreateRequest{}
+		if err := e.Bind(&ruleRequest); err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		createdRule, err := a.alertUseCase.CreateRule(ctx, ruleRequest)
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusCreated, createdRule)
+	}
+}
+
+// GetRuleById godoc
+// @Summary Get by id alert rule
+// @Description Get by id alert rule handler
+// @Tags Alert
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} alert.AlertRuleResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/alerts/rules/{id} [get]
+func (a alertHandlers) GetRuleById() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "alertHandler.GetRuleById")
+		defer span.Finish()
+
+		id, err := strconv.Atoi(e.Param("id"))
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		rule, err := a.alertUseCase.GetRuleById(ctx, id)
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, rule)
+	}
+}
+
+// DeleteRule godoc
+// @Summary Delete alert rule
+// @Description Delete by id alert rule handler, its alert history is dropped
+// @Tags Alert
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Success 204
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/alerts/rules/{id} [delete]
+func (a alertHandlers) DeleteRule() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "alertHandler.DeleteRule")
+		defer span.Finish()
+
+		id, err := strconv.Atoi(e.Param("id"))
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		if err = a.alertUseCase.DeleteRule(ctx, id); err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.NoContent(http.StatusNoContent)
+	}
+}
+
+// GetRules godoc
+// @Summary Get all alert rules
+// @Description Get all alert rules with pagination
+// @Tags Alert
+// @Accept json
+// @Produce json
+// @Param page query int false "page number" Format(page)
+// @Param limit query int false "number of elements per page" Format(limit)
+// @Success 200 {object} alert.AlertRuleListResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/alerts/rules [get]
+func (a alertHandlers) GetRules() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "alertHandler.GetRules")
+		defer span.Finish()
+
+		ruleList, err := a.alertUseCase.GetRules(ctx, getPageableRequest(e))
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, ruleList)
+	}
+}
+
+// GetHistory godoc
+// @Summary Get alert history
+// @Description Get triggered alerts with their notification status, newest first
+// @Tags Alert
+// @Accept json
+// @Produce json
+// @Param rule_id query int false "only alerts of this rule"
+// @Param page query int false "page number" Format(page)
+// @Param limit query int false "number of elements per page" Format(limit)
+// @Success 200 {object} alert.AlertListResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/alerts/history [get]
+func (a alertHandlers) GetHistory() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "alertHandler.GetHistory")
+		defer span.Finish()
+
+		pageableRequest := getPageableRequest(e)
+		if ruleId := e.QueryParam("rule_id"); ruleId != "" {
+			id, err := strconv.Atoi(ruleId)
+			if err != nil {
+				util.PrepareLogging(e, a.logger, err)
+				return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+			}
+			pageableRequest.RuleID = id
+		}
+
+		alertList, err := a.alertUseCase.GetHistory(ctx, pageableRequest)
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, alertList)
+	}
+}
+
+func getPageableRequest(e echo.Context) *alert.AlertPageableRequest {
+	pageableRequest := &alert.AlertPageableRequest{}
+	if page, err := strconv.Atoi(e.QueryParam("page")); err == nil {
+		pageableRequest.Page = page
+	}
+	if limit, err := strconv.Atoi(e.QueryParam("limit")); err == nil {
+		pageableRequest.Size = limit
+	}
+
+	return pageableRequest
+}
+
+func NewAlertHandler(cfg *config.Config, alertUseCase usecase.AlertUseCase, logger logger.Logger) AlertHandlers {
+	return &alertHandlers{
+		cfg: cfg,
+		alertUseCase: alertUseCase,
+		logger: logger,
+	}
+}
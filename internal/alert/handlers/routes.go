@@ -0,0 +1,11 @@
+package handlers
+
+import "github.com/labstack/echo/v4"
+
+func MapAlertRoutes(alertRouteGroup *echo.Group, a AlertHandlers) {
+	alertRouteGroup.POST("/rules", a.CreateRule())
+	alertRouteGroup.GET("/rules", a.GetRules())
+	alertRouteGroup.GET("/rules/:id", a.GetRuleById())
+	alertRouteGroup.DELETE("/rules/:id", a.DeleteRule())
+	alertRouteGroup.GET("/history", a.GetHistory())
+}
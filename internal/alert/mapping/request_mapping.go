@@ -0,0 +1,22 @@
+package mapping
+
+import (
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/request/alert"
+	"strings"
+)
+
+func CreateMapRuleEntity(rule *alert.AlertRuleCreateRequest) entity.Rule {
+	return entity.Rule{
+		Name: rule.Name,
+		BaseCode: strings.ToUpper(rule.BaseCode),
+		QuoteCode: strings.ToUpper(rule.QuoteCode),
+		Condition: rule.Condition,
+		Threshold: rule.Threshold,
+		WindowSeconds: rule.WindowSeconds,
+		Channel: rule.Channel,
+		Target: rule.Target,
+		CooldownSeconds: rule.CooldownSeconds,
+		Active: true,
+	}
+}
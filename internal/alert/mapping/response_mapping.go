@@ -0,0 +1,60 @@
+package mapping
+
+import (
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/alert"
+	rate "github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+)
+
+func MapRuleDto(r entity.Rule) *alert.AlertRuleResponse {
+	return &alert.AlertRuleResponse{
+		ID: r.ID,
+		Name: r.Name,
+		Pair: rate.FormatPair(r.BaseCode, r.QuoteCode),
+		Condition: r.Condition,
+		Threshold: r.Threshold,
+		WindowSeconds: r.WindowSeconds,
+		Channel: r.Channel,
+		Target: r.Target,
+		CooldownSeconds: r.CooldownSeconds,
+		Active: r.Active,
+		LastTriggeredAt: r.LastTriggeredAt,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func MapRuleListDto(rules []entity.Rule) []*alert.AlertRuleResponse {
+	ruleResp := make([]*alert.AlertRuleResponse, 0, len(rules))
+	for _, r := range rules {
+		ruleResp = append(ruleResp, MapRuleDto(r))
+	}
+
+	return ruleResp
+}
+
+func MapAlertDto(a entity.Alert) *alert.AlertResponse {
+	return &alert.AlertResponse{
+		ID: a.ID,
+		RuleID: a.RuleID,
+		RateID: a.RateID,
+		Pair: a.Pair,
+		Value: a.Value,
+		ReferenceValue: a.ReferenceValue,
+		Message: a.Message,
+		Channel: a.Channel,
+		Status: a.Status,
+		Attempts: a.Attempts,
+		LastError: a.LastError,
+		TriggeredAt: a.TriggeredAt,
+		SentAt: a.SentAt,
+	}
+}
+
+func MapAlertListDto(alerts []entity.Alert) []*alert.AlertResponse {
+	alertResp := make([]*alert.AlertResponse, 0, len(alerts))
+	for _, a := range alerts {
+		alertResp = append(alertResp, MapAlertDto(a))
+	}
+
+	return alertResp
+}
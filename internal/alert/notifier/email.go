@@ -0,0 +1,110 @@
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type emailNotifier struct {
+	cfg config.SmtpConfig
+	timeout time.Duration
+}
+
+// Notify mails the alert to its target. STARTTLS is used when the server offers it, a local
+// stand-in like MailHog accepts plain connections.
+func (e emailNotifier) Notify(ctx context.Context, alert entity.Alert) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "emailNotifier.Notify")
+	defer span.Finish()
+
+	if e.cfg.Host == "" {
+		return errors.New("emailNotifier.Notify: smtp host is not configured")
+	}
+
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+	conn, err := (&net.Dialer{Timeout: e.timeout}).DialContext(spanContext, "tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "emailNotifier.Notify.Dial")
+	}
+	if err = conn.SetDeadline(time.Now().Add(e.timeout)); err != nil {
+		conn.Close()
+		return errors.Wrap(err, "emailNotifier.Notify.SetDeadline")
+	}
+
+	client, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "emailNotifier.Notify.NewClient")
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: e.cfg.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return errors.Wrap(err, "emailNotifier.Notify.StartTLS")
+		}
+	}
+	if e.cfg.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)); err != nil {
+			return errors.Wrap(err, "emailNotifier.Notify.Auth")
+		}
+	}
+
+	if err = client.Mail(e.cfg.From); err != nil {
+		return errors.Wrap(err, "emailNotifier.Notify.Mail")
+	}
+	if err = client.Rcpt(alert.Target); err != nil {
+		return errors.Wrap(err, "emailNotifier.Notify.Rcpt")
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return errors.Wrap(err, "emailNotifier.Notify.Data")
+	}
+	if _, err = writer.Write(e.message(alert)); err != nil {
+		return errors.Wrap(err, "emailNotifier.Notify.Write")
+	}
+	if err = writer.Close(); err != nil {
+		return errors.Wrap(err, "emailNotifier.Notify.Close")
+	}
+
+	return client.Quit()
+}
+
+func (e emailNotifier) message(alert entity.Alert) []byte {
+	var builder strings.Builder
+	builder.WriteString("From: " + e.cfg.From + "\r\n")
+	builder.WriteString("To: " + alert.Target + "\r\n")
+	builder.WriteString("Subject: [Kanbersky] " + headerValue(alert.Message) + "\r\n")
+	builder.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(alert.Message + "\r\n\r\n")
+	builder.WriteString(fmt.Sprintf("Pair: %s\r\nValue: %g\r\n", alert.Pair, alert.Value))
+	if alert.ReferenceValue != nil {
+		builder.WriteString(fmt.Sprintf("Reference value: %g\r\n", *alert.ReferenceValue))
+	}
+	builder.WriteString("Triggered at: " + alert.TriggeredAt.UTC().Format(time.RFC3339) + "\r\n")
+
+	return []byte(builder.String())
+}
+
+// headerValue keeps user provided text, like a rule name, from injecting extra headers.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+func NewEmailNotifier(cfg config.SmtpConfig, timeout time.Duration) Notifier {
+	return &emailNotifier{
+		cfg: cfg,
+		timeout: timeout,
+	}
+}
@@ -0,0 +1,23 @@
+package notifier
+
+import (
+	"context"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+)
+
+type logNotifier struct {
+	logger logger.Logger
+}
+
+func (l logNotifier) Notify(ctx context.Context, alert entity.Alert) error {
+	l.logger.Infof("Alert triggered, AlertId: %d, RuleId: %d, Pair: %s, Value: %g, Message: %s", alert.ID, alert.RuleID, alert.Pair, alert.Value, alert.Message)
+
+	return nil
+}
+
+func NewLogNotifier(logger logger.Logger) Notifier {
+	return &logNotifier{
+		logger: logger,
+	}
+}
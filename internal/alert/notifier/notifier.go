@@ -0,0 +1,43 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"net/http"
+	"time"
+)
+
+const defaultTimeout = 10
+
+// Notifier sends a triggered alert over one channel. A returned error makes the alert retried later.
+type Notifier interface {
+	Notify(ctx context.Context, alert entity.Alert) error
+}
+
+type Notifiers map[string]Notifier
+
+func (n Notifiers) Notify(ctx context.Context, alert entity.Alert) error {
+	channelNotifier, ok := n[alert.Channel]
+	if !ok {
+		return fmt.Errorf("notifier.Notify: no notifier for channel %q", alert.Channel)
+	}
+
+	return channelNotifier.Notify(ctx, alert)
+}
+
+// NewNotifiers builds the notifier of every supported channel.
+func NewNotifiers(cfg *config.Config, logger logger.Logger) Notifiers {
+	timeout := cfg.Alert.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return Notifiers{
+		entity.ChannelWebhook: NewWebhookNotifier(cfg, &http.Client{Timeout: time.Second * timeout}),
+		entity.ChannelEmail: NewEmailNotifier(cfg.Smtp, time.Second * timeout),
+		entity.ChannelLog: NewLogNotifier(logger),
+	}
+}
@@ -0,0 +1,68 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/mapping"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	HeaderEvent = "X-Kanbersky-Event"
+	HeaderAlert = "X-Kanbersky-Alert"
+
+	AlertTriggeredEvent = "alert.triggered"
+)
+
+type webhookNotifier struct {
+	cfg *config.Config
+	httpClient *http.Client
+}
+
+// Notify posts the alert as json, anything but a 2xx answer is a failure.
+func (w webhookNotifier) Notify(ctx context.Context, alert entity.Alert) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookNotifier.Notify")
+	defer span.Finish()
+
+	body, err := json.Marshal(mapping.MapAlertDto(alert))
+	if err != nil {
+		return errors.Wrap(err, "webhookNotifier.Notify.Json.Marshal")
+	}
+
+	req, err := http.NewRequestWithContext(spanContext, http.MethodPost, alert.Target, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "webhookNotifier.Notify.NewRequest")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Kanbersky-Alerts/"+w.cfg.Server.AppVersion)
+	req.Header.Set(HeaderEvent, AlertTriggeredEvent)
+	req.Header.Set(HeaderAlert, strconv.FormatInt(alert.ID, 10))
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "webhookNotifier.Notify.Do")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhookNotifier.Notify: unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func NewWebhookNotifier(cfg *config.Config, httpClient *http.Client) Notifier {
+	return &webhookNotifier{
+		cfg: cfg,
+		httpClient: httpClient,
+	}
+}
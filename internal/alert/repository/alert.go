@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	audit "github.com/sefikcan/kanbersky.ca/internal/audit/entity"
+	auditRepository "github.com/sefikcan/kanbersky.ca/internal/audit/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"time"
+)
+
+type AlertRepository interface {
+	CreateRule(ctx context.Context, rule entity.Rule) (entity.Rule, error)
+	GetRuleById(ctx context.Context, id int) (entity.Rule, error)
+	DeleteRule(ctx context.Context, id int) error
+	GetRuleCount(ctx context.Context) (int64, error)
+	GetRules(ctx context.Context, query util.Pagination) ([]entity.Rule, error)
+	GetActiveRules(ctx context.Context, baseCode string, quoteCode string) ([]entity.Rule, error)
+
+	Trigger(ctx context.Context, alert entity.Alert) (bool, error)
+	ClaimAlerts(ctx context.Context, limit int, lease time.Duration) ([]entity.Alert, error)
+	SaveAlert(ctx context.Context, alert entity.Alert) error
+	GetAlertCount(ctx context.Context, ruleId int) (int64, error)
+	GetAlerts(ctx context.Context, ruleId int, query util.Pagination) ([]entity.Alert, error)
+}
+
+type alertRepository struct {
+	db *gorm.DB
+}
+
+func (a alertRepository) CreateRule(ctx context.Context, rule entity.Rule) (entity.Rule, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertRepository.CreateRule")
+	defer span.Finish()
+
+	err := a.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		if result := tx.Create(&rule); result.Error != nil {
+			return errors.Wrap(result.Error, "alertRepository.CreateRule.DbError")
+		}
+
+		return auditRepository.AddAuditLog(tx, entity.AuditEntityType, rule.ID, audit.ActionCreate, nil, rule)
+	})
+	if err != nil {
+		return entity.Rule{}, err
+	}
+
+	return rule, nil
+}
+
+func (a alertRepository) GetRuleById(ctx context.Context, id int) (entity.Rule, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertRepository.GetRuleById")
+	defer span.Finish()
+
+	rule := entity.Rule{}
+	if err := a.db.WithContext(spanContext).Where(`id = ?`, id).First(&rule).Error; err != nil {
+		return entity.Rule{}, errors.Wrap(err, "alertRepository.GetRuleById.DbError")
+	}
+
+	return rule, nil
+}
+
+func (a alertRepository) DeleteRule(ctx context.Context, id int) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertRepository.DeleteRule")
+	defer span.Finish()
+
+	return a.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		before := entity.Rule{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(`id = ?`, id).First(&before).Error; err != nil {
+			return errors.Wrap(err, "alertRepository.DeleteRule.GetForUpdate")
+		}
+		if result := tx.Delete(&entity.Rule{ID: id}); result.Error != nil {
+			return errors.Wrap(result.Error, "alertRepository.DeleteRule.DbError")
+		}
+
+		return auditRepository.AddAuditLog(tx, entity.AuditEntityType, id, audit.ActionDelete, before, nil)
+	})
+}
+
+func (a alertRepository) GetRuleCount(ctx context.Context) (int64, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertRepository.GetRuleCount")
+	defer span.Finish()
+
+	var totalCount int64
+	if err := a.db.WithContext(spanContext).Model(&entity.Rule{}).Count(&totalCount).Error; err != nil {
+		return 0, errors.Wrap(err, "alertRepository.GetRuleCount.DbError")
+	}
+
+	return totalCount, nil
+}
+
+func (a alertRepository) GetRules(ctx context.Context, query util.Pagination) ([]entity.Rule, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertRepository.GetRules")
+	defer span.Finish()
+
+	var rules []entity.Rule
+	if err := a.db.WithContext(spanContext).Offset(query.GetOffset()).Limit(query.GetLimit()).Order("id desc").Find(&rules).Error; err != nil {
+		return nil, errors.Wrap(err, "alertRepository.GetRules.DbError")
+	}
+
+	return rules, nil
+}
+
+func (a alertRepository) GetActiveRules(ctx context.Context, baseCode string, quoteCode string) ([]entity.Rule, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertRepository.GetActiveRules")
+	defer span.Finish()
+
+	var rules []entity.Rule
+	err := a.db.WithContext(spanContext).
+		Where(`active = ? AND base_code = ? AND quote_code = ?`, true, baseCode, quoteCode).
+		Order("id").Find(&rules).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "alertRepository.GetActiveRules.DbError")
+	}
+
+	return rules, nil
+}
+
+// Trigger records the alert unless the rule is still cooling down or already fired for the same rate.
+// The rule row stays locked until the alert is stored, so concurrent evaluations cannot both fire.
+func (a alertRepository) Trigger(ctx context.Context, alert entity.Alert) (bool, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertRepository.Trigger")
+	defer span.Finish()
+
+	triggered := false
+	err := a.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		rule := entity.Rule{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(`id = ?`, alert.RuleID).First(&rule).Error; err != nil {
+			return errors.Wrap(err, "alertRepository.Trigger.LockRule.DbError")
+		}
+		if !rule.Active || rule.InCooldown(alert.TriggeredAt) {
+			return nil
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&alert)
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "alertRepository.Trigger.CreateAlert.DbError")
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&entity.Rule{ID: rule.ID}).Update("last_triggered_at", alert.TriggeredAt).Error; err != nil {
+			return errors.Wrap(err, "alertRepository.Trigger.UpdateRule.DbError")
+		}
+		triggered = true
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return triggered, nil
+}
+
+func (a alertRepository) ClaimAlerts(ctx context.Context, limit int, lease time.Duration) ([]entity.Alert, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertRepository.ClaimAlerts")
+	defer span.Finish()
+
+	var alerts []entity.Alert
+	err := a.db.WithContext(spanContext).Raw(`
+		UPDATE alerts SET locked_until = ?, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM alerts
+			WHERE status = ? AND next_attempt_at <= now() AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, time.Now().UTC().Add(lease), entity.StatusPending, limit).Scan(&alerts).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "alertRepository.ClaimAlerts.DbError")
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].ID < alerts[j].ID
+	})
+
+	return alerts, nil
+}
+
+func (a alertRepository) SaveAlert(ctx context.Context, alert entity.Alert) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertRepository.SaveAlert")
+	defer span.Finish()
+
+	err := a.db.WithContext(spanContext).Model(&entity.Alert{ID: alert.ID}).Updates(map[string]interface{}{
+		"status": alert.Status,
+		"next_attempt_at": alert.NextAttemptAt,
+		"locked_until": nil,
+		"last_error": alert.LastError,
+		"sent_at": alert.SentAt,
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "alertRepository.SaveAlert.DbError")
+	}
+
+	return nil
+}
+
+// GetAlertCount counts the alerts of one rule, or of every rule when ruleId is 0.
+func (a alertRepository) GetAlertCount(ctx context.Context, ruleId int) (int64, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertRepository.GetAlertCount")
+	defer span.Finish()
+
+	var totalCount int64
+	if err := withRule(a.db.WithContext(spanContext).Model(&entity.Alert{}), ruleId).Count(&totalCount).Error; err != nil {
+		return 0, errors.Wrap(err, "alertRepository.GetAlertCount.DbError")
+	}
+
+	return totalCount, nil
+}
+
+func (a alertRepository) GetAlerts(ctx context.Context, ruleId int, query util.Pagination) ([]entity.Alert, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertRepository.GetAlerts")
+	defer span.Finish()
+
+	var alerts []entity.Alert
+	err := withRule(a.db.WithContext(spanContext), ruleId).
+		Offset(query.GetOffset()).Limit(query.GetLimit()).Order("id desc").Find(&alerts).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "alertRepository.GetAlerts.DbError")
+	}
+
+	return alerts, nil
+}
+
+func withRule(db *gorm.DB, ruleId int) *gorm.DB {
+	if ruleId == 0 {
+		return db
+	}
+
+	return db.Where(`rule_id = ?`, ruleId)
+}
+
+func NewAlertRepository(db *gorm.DB) AlertRepository {
+	return &alertRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/mapping"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/repository"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/alert"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/alert"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"net/mail"
+	"net/url"
+)
+
+type AlertUseCase interface {
+	CreateRule(ctx context.Context, request request.AlertRuleCreateRequest) (*response.AlertRuleResponse, error)
+	GetRuleById(ctx context.Context, id int) (*response.AlertRuleResponse, error)
+	DeleteRule(ctx context.Context, id int) error
+	GetRules(ctx context.Context, request *request.AlertPageableRequest) (response.AlertRuleListResponse, error)
+	GetHistory(ctx context.Context, request *request.AlertPageableRequest) (response.AlertListResponse, error)
+}
+
+type alertUseCase struct {
+	cfg *config.Config
+	alertRepository repository.AlertRepository
+	logger logger.Logger
+}
+
+func (a alertUseCase) CreateRule(ctx context.Context, request request.AlertRuleCreateRequest) (*response.AlertRuleResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertUseCase.CreateRule")
+	defer span.Finish()
+
+	if err := util.ValidateStruct(&request); err != nil {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "alertUseCase.CreateRule.ValidateStruct"))
+	}
+	if err := validateTarget(request.Channel, request.Target); err != nil {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "alertUseCase.CreateRule.ValidateTarget"))
+	}
+
+	rule, err := a.alertRepository.CreateRule(spanContext, mapping.CreateMapRuleEntity(&request))
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapRuleDto(rule), nil
+}
+
+func (a alertUseCase) GetRuleById(ctx context.Context, id int) (*response.AlertRuleResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertUseCase.GetRuleById")
+	defer span.Finish()
+
+	rule, err := a.alertRepository.GetRuleById(spanContext, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapRuleDto(rule), nil
+}
+
+func (a alertUseCase) DeleteRule(ctx context.Context, id int) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertUseCase.DeleteRule")
+	defer span.Finish()
+
+	if _, err := a.alertRepository.GetRuleById(spanContext, id); err != nil {
+		return err
+	}
+
+	return a.alertRepository.DeleteRule(spanContext, id)
+}
+
+func (a alertUseCase) GetRules(ctx context.Context, pageableRequest *request.AlertPageableRequest) (response.AlertRuleListResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertUseCase.GetRules")
+	defer span.Finish()
+
+	pagination := util.Pagination{
+		Page: pageableRequest.Page,
+		Limit: pageableRequest.Size,
+	}
+
+	totalCount, err := a.alertRepository.GetRuleCount(spanContext)
+	if err != nil {
+		return response.AlertRuleListResponse{}, err
+	}
+
+	rules, err := a.alertRepository.GetRules(spanContext, pagination)
+	if err != nil {
+		return response.AlertRuleListResponse{}, err
+	}
+
+	return response.AlertRuleListResponse{
+		TotalCount: totalCount,
+		TotalPages: util.GetTotalPages(totalCount, pagination.GetLimit()),
+		Page: pagination.GetPage(),
+		Limit: pagination.GetLimit(),
+		Rules: mapping.MapRuleListDto(rules),
+	}, nil
+}
+
+// GetHistory lists triggered alerts newest first, limited to one rule when RuleID is set.
+func (a alertUseCase) GetHistory(ctx context.Context, pageableRequest *request.AlertPageableRequest) (response.AlertListResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertUseCase.GetHistory")
+	defer span.Finish()
+
+	if pageableRequest.RuleID != 0 {
+		if _, err := a.alertRepository.GetRuleById(spanContext, pageableRequest.RuleID); err != nil {
+			return response.AlertListResponse{}, err
+		}
+	}
+
+	pagination := util.Pagination{
+		Page: pageableRequest.Page,
+		Limit: pageableRequest.Size,
+	}
+
+	totalCount, err := a.alertRepository.GetAlertCount(spanContext, pageableRequest.RuleID)
+	if err != nil {
+		return response.AlertListResponse{}, err
+	}
+
+	alerts, err := a.alertRepository.GetAlerts(spanContext, pageableRequest.RuleID, pagination)
+	if err != nil {
+		return response.AlertListResponse{}, err
+	}
+
+	return response.AlertListResponse{
+		TotalCount: totalCount,
+		TotalPages: util.GetTotalPages(totalCount, pagination.GetLimit()),
+		Page: pagination.GetPage(),
+		Limit: pagination.GetLimit(),
+		Alerts: mapping.MapAlertListDto(alerts),
+	}, nil
+}
+
+func validateTarget(channel string, target string) error {
+	switch channel {
+	case entity.ChannelWebhook:
+		parsed, err := url.ParseRequestURI(target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.New("webhook target must be an http or https url")
+		}
+	case entity.ChannelEmail:
+		address, err := mail.ParseAddress(target)
+		if err != nil || address.Address != target {
+			return errors.New("email target must be a plain email address")
+		}
+	}
+
+	return nil
+}
+
+func NewAlertUseCase(cfg *config.Config, alertRepository repository.AlertRepository, logger logger.Logger) AlertUseCase {
+	return &alertUseCase{
+		cfg: cfg,
+		alertRepository: alertRepository,
+		logger: logger,
+	}
+}
@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/repository"
+	rate "github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+	rateRepository "github.com/sefikcan/kanbersky.ca/internal/rate/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/event"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"gorm.io/gorm"
+	"time"
+)
+
+type alertEvaluator struct {
+	alertRepository repository.AlertRepository
+	rateRepository rateRepository.RateRepository
+	logger logger.Logger
+}
+
+// Publish evaluates the active rules of the pair whenever a rate is ingested. Triggered rules become
+// pending alerts, the notification worker sends them. Rules are not tenant aware, they only watch global rates.
+func (a alertEvaluator) Publish(ctx context.Context, e event.Event) error {
+	if e.Type != rate.RateCreatedEvent {
+		return nil
+	}
+
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertEvaluator.Publish")
+	defer span.Finish()
+
+	current := rate.Rate{}
+	if err := json.Unmarshal(e.Payload, &current); err != nil {
+		return errors.Wrap(err, "alertEvaluator.Publish.Json.Unmarshal")
+	}
+	if current.TenantID != tenant.Global {
+		return nil
+	}
+
+	rules, err := a.alertRepository.GetActiveRules(spanContext, current.BaseCode, current.QuoteCode)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		alert, triggered, err := a.evaluate(spanContext, rule, current)
+		if err != nil {
+			return err
+		}
+		if !triggered || rule.InCooldown(alert.TriggeredAt) {
+			continue
+		}
+
+		if triggered, err = a.alertRepository.Trigger(spanContext, alert); err != nil {
+			return err
+		}
+		if triggered {
+			a.logger.Infof("Alert rule triggered, RuleId: %d, RateId: %d, Message: %s", rule.ID, current.ID, alert.Message)
+		}
+	}
+
+	return nil
+}
+
+func (a alertEvaluator) evaluate(ctx context.Context, rule entity.Rule, current rate.Rate) (entity.Alert, bool, error) {
+	pair := rate.Pair{BaseCode: current.BaseCode, QuoteCode: current.QuoteCode}
+	alert := entity.Alert{
+		RuleID: rule.ID,
+		RateID: current.ID,
+		Pair: current.Pair(),
+		Value: current.Value,
+		Channel: rule.Channel,
+		Target: rule.Target,
+		Status: entity.StatusPending,
+		NextAttemptAt: time.Now().UTC(),
+		TriggeredAt: current.CreatedAt,
+	}
+
+	switch rule.Condition {
+	case entity.ConditionAbove, entity.ConditionBelow:
+		var previousValue *float64
+		previous, err := a.rateRepository.GetPrevious(ctx, pair, current.ID)
+		switch {
+		case err == nil:
+			previousValue = &previous.Value
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return entity.Alert{}, false, err
+		}
+
+		if !rule.Crossed(previousValue, current.Value) {
+			return entity.Alert{}, false, nil
+		}
+		alert.ReferenceValue = previousValue
+		alert.Message = fmt.Sprintf("%s: %s crossed %s %g, now %g", rule.Name, alert.Pair, rule.Condition, rule.Threshold, current.Value)
+	case entity.ConditionChangePercent:
+		// without a rate from before the window there is not enough history to measure the move yet
+		reference, err := a.rateRepository.GetAsOf(ctx, pair, current.CreatedAt.Add(-rule.WindowDuration()))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.Alert{}, false, nil
+		}
+		if err != nil {
+			return entity.Alert{}, false, err
+		}
+
+		change, moved := rule.Moved(reference.Value, current.Value)
+		if !moved {
+			return entity.Alert{}, false, nil
+		}
+		alert.ReferenceValue = &reference.Value
+		alert.Message = fmt.Sprintf("%s: %s moved %+.2f%% within %s, from %g to %g", rule.Name, alert.Pair, change, rule.WindowDuration(), reference.Value, current.Value)
+	default:
+		return entity.Alert{}, false, nil
+	}
+
+	return alert, true, nil
+}
+
+func NewAlertEvaluator(alertRepository repository.AlertRepository, rateRepository rateRepository.RateRepository, logger logger.Logger) event.EventPublisher {
+	return &alertEvaluator{
+		alertRepository: alertRepository,
+		rateRepository: rateRepository,
+		logger: logger,
+	}
+}
@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/repository"
+	rate "github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+	rateRepository "github.com/sefikcan/kanbersky.ca/internal/rate/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/event"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// memoryAlerts keeps rules and alerts in memory, a rule fires at most once per rate like the unique index makes it.
+type memoryAlerts struct {
+	repository.AlertRepository
+	rules []entity.Rule
+	alerts []entity.Alert
+}
+
+func (m *memoryAlerts) GetActiveRules(_ context.Context, baseCode string, quoteCode string) ([]entity.Rule, error) {
+	var rules []entity.Rule
+	for _, rule := range m.rules {
+		if rule.Active && rule.BaseCode == baseCode && rule.QuoteCode == quoteCode {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+func (m *memoryAlerts) Trigger(_ context.Context, alert entity.Alert) (bool, error) {
+	for _, triggered := range m.alerts {
+		if triggered.RuleID == alert.RuleID && triggered.RateID == alert.RateID {
+			return false, nil
+		}
+	}
+	alert.ID = int64(len(m.alerts) + 1)
+	m.alerts = append(m.alerts, alert)
+
+	return true, nil
+}
+
+// stubRates serves the rate history of a single pair, oldest first.
+type stubRates struct {
+	rateRepository.RateRepository
+	history []rate.Rate
+}
+
+func (s *stubRates) GetPrevious(_ context.Context, _ rate.Pair, beforeId int64) (rate.Rate, error) {
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].ID < beforeId {
+			return s.history[i], nil
+		}
+	}
+
+	return rate.Rate{}, errors.Wrap(gorm.ErrRecordNotFound, "stubRates.GetPrevious")
+}
+
+func (s *stubRates) GetAsOf(_ context.Context, _ rate.Pair, at time.Time) (rate.Rate, error) {
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if !s.history[i].CreatedAt.After(at) {
+			return s.history[i], nil
+		}
+	}
+
+	return rate.Rate{}, errors.Wrap(gorm.ErrRecordNotFound, "stubRates.GetAsOf")
+}
+
+func newTestLogger() logger.Logger {
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	return l
+}
+
+func rateCreated(t *testing.T, r rate.Rate) event.Event {
+	t.Helper()
+	payload, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return event.Event{Type: rate.RateCreatedEvent, Payload: payload}
+}
+
+func TestEvaluatorTriggersCrossedRules(t *testing.T) {
+	now := time.Now().UTC()
+	rates := &stubRates{history: []rate.Rate{
+		{ID: 1, BaseCode: "USD", QuoteCode: "TRY", Value: 31, CreatedAt: now.Add(-time.Hour * 2)},
+		{ID: 2, BaseCode: "USD", QuoteCode: "TRY", Value: 32.5, CreatedAt: now},
+	}}
+	alerts := &memoryAlerts{rules: []entity.Rule{
+		{ID: 1, Name: "above 32", BaseCode: "USD", QuoteCode: "TRY", Condition: entity.ConditionAbove, Threshold: 32, Channel: entity.ChannelLog, Active: true},
+		{ID: 2, Name: "below 30", BaseCode: "USD", QuoteCode: "TRY", Condition: entity.ConditionBelow, Threshold: 30, Channel: entity.ChannelLog, Active: true},
+		{ID: 3, Name: "moves 4%", BaseCode: "USD", QuoteCode: "TRY", Condition: entity.ConditionChangePercent, Threshold: 4, WindowSeconds: 3600, Channel: entity.ChannelLog, Active: true},
+		{ID: 4, Name: "inactive", BaseCode: "USD", QuoteCode: "TRY", Condition: entity.ConditionAbove, Threshold: 32, Active: false},
+	}}
+	evaluator := NewAlertEvaluator(alerts, rates, newTestLogger())
+
+	if err := evaluator.Publish(context.Background(), rateCreated(t, rates.history[1])); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if len(alerts.alerts) != 2 || alerts.alerts[0].RuleID != 1 || alerts.alerts[1].RuleID != 3 {
+		t.Fatalf("alerts = %+v, want rules 1 and 3", alerts.alerts)
+	}
+	above := alerts.alerts[0]
+	if above.Status != entity.StatusPending || above.RateID != 2 || above.Pair != "USD/TRY" || above.ReferenceValue == nil || *above.ReferenceValue != 31 {
+		t.Errorf("alert = %+v, want a pending alert of rate 2 with the previous value", above)
+	}
+	if moved := alerts.alerts[1]; moved.ReferenceValue == nil || *moved.ReferenceValue != 31 {
+		t.Errorf("change alert reference = %v, want the rate from before the window", moved.ReferenceValue)
+	}
+
+	// a redelivered event does not fire the rules again
+	if err := evaluator.Publish(context.Background(), rateCreated(t, rates.history[1])); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if len(alerts.alerts) != 2 {
+		t.Errorf("redelivery made %d alerts, want 2", len(alerts.alerts))
+	}
+}
+
+func TestEvaluatorSkips(t *testing.T) {
+	now := time.Now().UTC()
+	lastTriggeredAt := now.Add(-time.Minute)
+	current := rate.Rate{ID: 2, BaseCode: "USD", QuoteCode: "TRY", Value: 33, CreatedAt: now}
+	tests := []struct {
+		name string
+		rule entity.Rule
+		history []rate.Rate
+		event event.Event
+	}{
+		{
+			name: "already above the threshold",
+			rule: entity.Rule{Condition: entity.ConditionAbove, Threshold: 32},
+			history: []rate.Rate{{ID: 1, Value: 32.5, CreatedAt: now.Add(-time.Minute)}, current},
+		},
+		{
+			name: "in cooldown",
+			rule: entity.Rule{Condition: entity.ConditionAbove, Threshold: 32, CooldownSeconds: 3600, LastTriggeredAt: &lastTriggeredAt},
+			history: []rate.Rate{{ID: 1, Value: 31, CreatedAt: now.Add(-time.Minute)}, current},
+		},
+		{
+			name: "change without history before the window",
+			rule: entity.Rule{Condition: entity.ConditionChangePercent, Threshold: 1, WindowSeconds: 3600},
+			history: []rate.Rate{{ID: 1, Value: 20, CreatedAt: now.Add(-time.Minute)}, current},
+		},
+		{
+			name: "tenant rate",
+			rule: entity.Rule{Condition: entity.ConditionAbove, Threshold: 32},
+			event: rateCreated(t, rate.Rate{ID: 2, BaseCode: "USD", QuoteCode: "TRY", Value: 33, TenantID: "acme", CreatedAt: now}),
+		},
+		{
+			name: "other event",
+			rule: entity.Rule{Condition: entity.ConditionAbove, Threshold: 32},
+			event: event.Event{Type: "currency.created", Payload: json.RawMessage(`{}`)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.ID, tt.rule.BaseCode, tt.rule.QuoteCode, tt.rule.Active = 1, "USD", "TRY", true
+			alerts := &memoryAlerts{rules: []entity.Rule{tt.rule}}
+			if tt.event.Type == "" {
+				tt.event = rateCreated(t, current)
+			}
+
+			if err := NewAlertEvaluator(alerts, &stubRates{history: tt.history}, newTestLogger()).Publish(context.Background(), tt.event); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			if len(alerts.alerts) != 0 {
+				t.Errorf("alerts = %+v, want none", alerts.alerts)
+			}
+		})
+	}
+}
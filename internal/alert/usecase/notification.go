@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/notifier"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"time"
+)
+
+const (
+	defaultBatchSize = 50
+	defaultMaxAttempts = 5
+	defaultPollInterval = 1
+	minRetryBackoff = time.Second * 5
+	maxRetryBackoff = time.Minute * 30
+	claimLease = time.Minute * 2
+)
+
+type AlertNotificationUseCase interface {
+	Run(ctx context.Context)
+	NotifyPending(ctx context.Context) (int, error)
+}
+
+type alertNotificationUseCase struct {
+	cfg *config.Config
+	alertRepository repository.AlertRepository
+	notifier notifier.Notifier
+	logger logger.Logger
+}
+
+func (a alertNotificationUseCase) Run(ctx context.Context) {
+	pollInterval := a.cfg.Alert.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	a.logger.Infof("Alert notification worker started, PollInterval: %ds", pollInterval)
+	for {
+		notified, err := a.NotifyPending(ctx)
+		if err != nil {
+			a.logger.Errorf("alertNotificationUseCase.Run: %s", err)
+		}
+		if notified == a.batchSize() {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			a.logger.Info("Alert notification worker stopped")
+			return
+		case <-time.After(time.Second * pollInterval):
+		}
+	}
+}
+
+func (a alertNotificationUseCase) NotifyPending(ctx context.Context) (int, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertNotificationUseCase.NotifyPending")
+	defer span.Finish()
+
+	alerts, err := a.alertRepository.ClaimAlerts(spanContext, a.batchSize(), claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, alert := range alerts {
+		a.notify(spanContext, alert)
+	}
+
+	return len(alerts), nil
+}
+
+func (a alertNotificationUseCase) notify(ctx context.Context, alert entity.Alert) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "alertNotificationUseCase.notify")
+	defer span.Finish()
+
+	err := a.notifier.Notify(spanContext, alert)
+	switch {
+	case err == nil:
+		sentAt := time.Now().UTC()
+		alert.Status = entity.StatusSent
+		alert.SentAt = &sentAt
+		alert.LastError = ""
+	case alert.Attempts >= a.maxAttempts():
+		alert.Status = entity.StatusFailed
+		alert.LastError = err.Error()
+		a.logger.Warnf("Alert notification gave up, AlertId: %d, Channel: %s, Attempts: %d, Error: %s", alert.ID, alert.Channel, alert.Attempts, err)
+	default:
+		alert.LastError = err.Error()
+		alert.NextAttemptAt = time.Now().UTC().Add(retryBackoff(alert.Attempts))
+	}
+
+	if err = a.alertRepository.SaveAlert(spanContext, alert); err != nil {
+		a.logger.Errorf("alertNotificationUseCase.notify.SaveAlert, AlertId: %d, Error: %s", alert.ID, err)
+	}
+}
+
+func (a alertNotificationUseCase) batchSize() int {
+	if a.cfg.Alert.BatchSize <= 0 {
+		return defaultBatchSize
+	}
+
+	return a.cfg.Alert.BatchSize
+}
+
+func (a alertNotificationUseCase) maxAttempts() int {
+	if a.cfg.Alert.MaxAttempts <= 0 {
+		return defaultMaxAttempts
+	}
+
+	return a.cfg.Alert.MaxAttempts
+}
+
+func retryBackoff(attempts int) time.Duration {
+	backoff := minRetryBackoff
+	for i := 1; i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+
+	return backoff
+}
+
+func NewAlertNotificationUseCase(cfg *config.Config, alertRepository repository.AlertRepository, notifier notifier.Notifier, logger logger.Logger) AlertNotificationUseCase {
+	return &alertNotificationUseCase{
+		cfg: cfg,
+		alertRepository: alertRepository,
+		notifier: notifier,
+		logger: logger,
+	}
+}
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/notifier"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"testing"
+	"time"
+)
+
+func (m *memoryAlerts) ClaimAlerts(_ context.Context, limit int, _ time.Duration) ([]entity.Alert, error) {
+	var claimed []entity.Alert
+	for i := range m.alerts {
+		if len(claimed) < limit && m.alerts[i].Status == entity.StatusPending && !m.alerts[i].NextAttemptAt.After(time.Now().UTC()) {
+			m.alerts[i].Attempts++
+			claimed = append(claimed, m.alerts[i])
+		}
+	}
+
+	return claimed, nil
+}
+
+func (m *memoryAlerts) SaveAlert(_ context.Context, alert entity.Alert) error {
+	for i := range m.alerts {
+		if m.alerts[i].ID == alert.ID {
+			m.alerts[i] = alert
+		}
+	}
+
+	return nil
+}
+
+// stubNotifier fails while failures is above zero.
+type stubNotifier struct {
+	failures int
+	sent []int64
+}
+
+func (s *stubNotifier) Notify(_ context.Context, alert entity.Alert) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("smtp is down")
+	}
+	s.sent = append(s.sent, alert.ID)
+
+	return nil
+}
+
+func TestNotificationRetriesThenSends(t *testing.T) {
+	alerts := &memoryAlerts{alerts: []entity.Alert{{ID: 1, Channel: entity.ChannelEmail, Status: entity.StatusPending}}}
+	sender := &stubNotifier{failures: 1}
+	notifications := NewAlertNotificationUseCase(&config.Config{}, alerts, notifier.Notifiers{entity.ChannelEmail: sender}, newTestLogger())
+
+	before := time.Now().UTC()
+	if notified, err := notifications.NotifyPending(context.Background()); err != nil || notified != 1 {
+		t.Fatalf("NotifyPending = %d, %v, want 1", notified, err)
+	}
+	alert := alerts.alerts[0]
+	if alert.Status != entity.StatusPending || alert.LastError != "smtp is down" {
+		t.Fatalf("after a failure: status %s, last error %q", alert.Status, alert.LastError)
+	}
+	if backoff := alert.NextAttemptAt.Sub(before); backoff < minRetryBackoff || backoff > minRetryBackoff+time.Second {
+		t.Errorf("next attempt in %s, want about %s", backoff, minRetryBackoff)
+	}
+	if notified, _ := notifications.NotifyPending(context.Background()); notified != 0 {
+		t.Errorf("notified %d alerts before the backoff passed", notified)
+	}
+
+	alerts.alerts[0].NextAttemptAt = before
+	if _, err := notifications.NotifyPending(context.Background()); err != nil {
+		t.Fatalf("NotifyPending: %v", err)
+	}
+	if alert = alerts.alerts[0]; alert.Status != entity.StatusSent || alert.SentAt == nil || alert.LastError != "" || alert.Attempts != 2 {
+		t.Errorf("after the retry: %+v, want sent on the second attempt", alert)
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("sent %d notifications, want 1", len(sender.sent))
+	}
+}
+
+func TestNotificationGivesUp(t *testing.T) {
+	alerts := &memoryAlerts{alerts: []entity.Alert{
+		{ID: 1, Channel: entity.ChannelEmail, Status: entity.StatusPending, Attempts: 2},
+		{ID: 2, Channel: "sms", Status: entity.StatusPending, Attempts: 2},
+	}}
+	cfg := &config.Config{Alert: config.AlertConfig{MaxAttempts: 3}}
+	notifications := NewAlertNotificationUseCase(cfg, alerts, notifier.Notifiers{entity.ChannelEmail: &stubNotifier{failures: 1}}, newTestLogger())
+
+	if _, err := notifications.NotifyPending(context.Background()); err != nil {
+		t.Fatalf("NotifyPending: %v", err)
+	}
+	for _, alert := range alerts.alerts {
+		if alert.Status != entity.StatusFailed || alert.LastError == "" {
+			t.Errorf("alert %d: status %s, last error %q, want failed after the last attempt", alert.ID, alert.Status, alert.LastError)
+		}
+	}
+}
+
+func TestNotificationRetryBackoff(t *testing.T) {
+	tests := map[int]time.Duration{
+		1: time.Second * 5,
+		2: time.Second * 10,
+		4: time.Second * 40,
+		20: maxRetryBackoff,
+	}
+
+	for attempts, want := range tests {
+		if got := retryBackoff(attempts); got != want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
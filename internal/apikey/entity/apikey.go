@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	// KeyPrefix marks api keys, so they are recognizable in logs and secret scanners
+	KeyPrefix = "kbr_"
+	DisplayPrefixLength = 8
+	scopeSeparator = " "
+
+	AuditEntityType = "api_key"
+)
+
+// ApiKey is a long lived credential of a machine client. Only the hash of the key is stored, the key
+// itself is shown once when it is created.
+type ApiKey struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name string `json:"name"`
+	TenantID string `gorm:"index:idx_api_key_tenant" json:"tenant_id"`
+	Prefix string `json:"prefix"`
+	KeyHash string `gorm:"index:idx_api_key_hash,unique" json:"-"`
+	Scopes string `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+func (ApiKey) TableName() string {
+	return "api_keys"
+}
+
+func (a ApiKey) ScopeList() []string {
+	return strings.Fields(a.Scopes)
+}
+
+// ActiveAt reports whether the key is neither revoked nor expired at t.
+func (a ApiKey) ActiveAt(t time.Time) bool {
+	return a.RevokedAt == nil && (a.ExpiresAt == nil || t.Before(*a.ExpiresAt))
+}
+
+func JoinScopes(scopes []string) string {
+	return strings.Join(scopes, scopeSeparator)
+}
+
+// HashKey hashes the key for lookups. Keys are random 256 bit values, so a fast unsalted hash is enough.
+func HashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
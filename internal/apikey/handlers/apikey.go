@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/apikey/usecase"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/request/apikey"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ApiKeyHandlers interface {
+	Create() echo.HandlerFunc
+	GetById() echo.HandlerFunc
+	GetAll() echo.HandlerFunc
+	Revoke() echo.HandlerFunc
+}
+
+type apiKeyHandlers struct {
+	cfg *config.Config
+	apiKeyUseCase usecase.ApiKeyUseCase
+	logger logger.Logger
+}
+
+// Create godoc
+// @Summary Create api key
+// @Description Creates an api key for a machine client, the key is only returned here
+// @Tags ApiKey
+// @Accept json
+// @Produce json
+// @Param apiKeyCreateRequest body apikey.ApiKeyCreateRequest true "Create Api Key"
+// @Success 201 {object} apikey.ApiKeyResponse
+// @Failure 400 {object} util.HttpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/admin/api-keys [post]
+func (a apiKeyHandlers) Create() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "apiKeyHandler.Create")
+		defer span.Finish()
+
+		apiKeyRequest := apikey.ApiKeyCreateRequest{}
+		if err := e.Bind(&apiKeyRequest); err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			resp := util.ParseBindError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		createdApiKey, err := a.apiKeyUseCase.Create(ctx, apiKeyRequest)
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusCreated, createdApiKey)
+	}
+}
+
+// GetById godoc
+// @Summary Get by id api key
+// @Description Get by id api key handler, the key itself is never returned
+// @Tags ApiKey
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} apikey.ApiKeyResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Failure 404 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/admin/api-keys/{id} [get]
+func (a apiKeyHandlers) GetById() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "apiKeyHandler.GetById")
+		defer span.Finish()
+
+		id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		currentApiKey, err := a.apiKeyUseCase.GetById(ctx, id)
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, currentApiKey)
+	}
+}
+
+// GetAll godoc
+// @Summary Get all api keys
+// @Description Get all api keys with pagination, newest first
+// @Tags ApiKey
+// @Accept json
+// @Produce json
+// @Param page query int false "page number" Format(page)
+// @Param limit query int false "number of elements per page" Format(limit)
+// @Success 200 {object} apikey.ApiKeyListResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/admin/api-keys [get]
+func (a apiKeyHandlers) GetAll() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "apiKeyHandler.GetAll")
+		defer span.Finish()
+
+		apiKeyList, err := a.apiKeyUseCase.GetAll(ctx, getPageableRequest(e))
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, apiKeyList)
+	}
+}
+
+// Revoke godoc
+// @Summary Revoke api key
+// @Description Revokes an api key on every replica at once, revoked keys stay listed
+// @Tags ApiKey
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Success 204
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Failure 404 {object} util.HttpResponse
+// @Failure 503 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/admin/api-keys/{id} [delete]
+func (a apiKeyHandlers) Revoke() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "apiKeyHandler.Revoke")
+		defer span.Finish()
+
+		id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		if err = a.apiKeyUseCase.Revoke(ctx, id); err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.NoContent(http.StatusNoContent)
+	}
+}
+
+func getPageableRequest(e echo.Context) *apikey.ApiKeyPageableRequest {
+	pageableRequest := &apikey.ApiKeyPageableRequest{}
+	if page, err := strconv.Atoi(e.QueryParam("page")); err == nil {
+		pageableRequest.Page = page
+	}
+	if limit, err := strconv.Atoi(e.QueryParam("limit")); err == nil {
+		pageableRequest.Size = limit
+	}
+
+	return pageableRequest
+}
+
+func NewApiKeyHandler(cfg *config.Config, apiKeyUseCase usecase.ApiKeyUseCase, logger logger.Logger) ApiKeyHandlers {
+	return &apiKeyHandlers{
+		cfg: cfg,
+		apiKeyUseCase: apiKeyUseCase,
+		logger: logger,
+	}
+}
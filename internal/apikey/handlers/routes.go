@@ -0,0 +1,10 @@
+package handlers
+
+import "github.com/labstack/echo/v4"
+
+func MapApiKeyRoutes(apiKeyRouteGroup *echo.Group, a ApiKeyHandlers) {
+	apiKeyRouteGroup.POST("", a.Create())
+	apiKeyRouteGroup.DELETE("/:id", a.Revoke())
+	apiKeyRouteGroup.GET("/:id", a.GetById())
+	apiKeyRouteGroup.GET("", a.GetAll())
+}
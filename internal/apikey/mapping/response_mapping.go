@@ -0,0 +1,29 @@
+package mapping
+
+import (
+	"github.com/sefikcan/kanbersky.ca/internal/apikey/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/apikey"
+)
+
+func MapDto(a entity.ApiKey) *apikey.ApiKeyResponse {
+	return &apikey.ApiKeyResponse{
+		ID: a.ID,
+		Name: a.Name,
+		TenantID: a.TenantID,
+		Prefix: a.Prefix,
+		Scopes: a.ScopeList(),
+		ExpiresAt: a.ExpiresAt,
+		LastUsedAt: a.LastUsedAt,
+		RevokedAt: a.RevokedAt,
+		CreatedAt: a.CreatedAt,
+	}
+}
+
+func MapListDto(apiKeys []entity.ApiKey) []*apikey.ApiKeyResponse {
+	apiKeyResp := make([]*apikey.ApiKeyResponse, 0, len(apiKeys))
+	for _, a := range apiKeys {
+		apiKeyResp = append(apiKeyResp, MapDto(a))
+	}
+
+	return apiKeyResp
+}
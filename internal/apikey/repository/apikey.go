@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/apikey/entity"
+	audit "github.com/sefikcan/kanbersky.ca/internal/audit/entity"
+	auditRepository "github.com/sefikcan/kanbersky.ca/internal/audit/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type ApiKeyRepository interface {
+	Create(ctx context.Context, apiKey entity.ApiKey) (entity.ApiKey, error)
+	GetById(ctx context.Context, id int64) (entity.ApiKey, error)
+	GetByHash(ctx context.Context, keyHash string) (entity.ApiKey, error)
+	GetCount(ctx context.Context) (int64, error)
+	GetAll(ctx context.Context, query util.Pagination) ([]entity.ApiKey, error)
+	Revoke(ctx context.Context, id int64) (entity.ApiKey, error)
+	TouchLastUsed(ctx context.Context, id int64, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func (a apiKeyRepository) Create(ctx context.Context, apiKey entity.ApiKey) (entity.ApiKey, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRepository.Create")
+	defer span.Finish()
+
+	err := a.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		if result := tx.Create(&apiKey); result.Error != nil {
+			return errors.Wrap(result.Error, "apiKeyRepository.Create.DbError")
+		}
+
+		return auditRepository.AddAuditLog(tx, entity.AuditEntityType, apiKey.ID, audit.ActionCreate, nil, apiKey)
+	})
+	if err != nil {
+		return entity.ApiKey{}, err
+	}
+
+	return apiKey, nil
+}
+
+func (a apiKeyRepository) GetById(ctx context.Context, id int64) (entity.ApiKey, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRepository.GetById")
+	defer span.Finish()
+
+	apiKey := entity.ApiKey{}
+	if err := withTenant(a.db.WithContext(spanContext)).Where(`id = ?`, id).First(&apiKey).Error; err != nil {
+		return entity.ApiKey{}, errors.Wrap(err, "apiKeyRepository.GetById.DbError")
+	}
+
+	return apiKey, nil
+}
+
+func (a apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (entity.ApiKey, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRepository.GetByHash")
+	defer span.Finish()
+
+	apiKey := entity.ApiKey{}
+	if err := a.db.WithContext(spanContext).Where(`key_hash = ?`, keyHash).First(&apiKey).Error; err != nil {
+		return entity.ApiKey{}, errors.Wrap(err, "apiKeyRepository.GetByHash.DbError")
+	}
+
+	return apiKey, nil
+}
+
+func (a apiKeyRepository) GetCount(ctx context.Context) (int64, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRepository.GetCount")
+	defer span.Finish()
+
+	var totalCount int64
+	if err := withTenant(a.db.WithContext(spanContext)).Model(&entity.ApiKey{}).Count(&totalCount).Error; err != nil {
+		return 0, errors.Wrap(err, "apiKeyRepository.GetCount.DbError")
+	}
+
+	return totalCount, nil
+}
+
+func (a apiKeyRepository) GetAll(ctx context.Context, query util.Pagination) ([]entity.ApiKey, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRepository.GetAll")
+	defer span.Finish()
+
+	var apiKeys []entity.ApiKey
+	if err := withTenant(a.db.WithContext(spanContext)).Offset(query.GetOffset()).Limit(query.GetLimit()).Order("id desc").Find(&apiKeys).Error; err != nil {
+		return nil, errors.Wrap(err, "apiKeyRepository.GetAll.DbError")
+	}
+
+	return apiKeys, nil
+}
+
+// Revoke marks the key revoked, revoking it again keeps the first revocation time and records no change.
+func (a apiKeyRepository) Revoke(ctx context.Context, id int64) (entity.ApiKey, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRepository.Revoke")
+	defer span.Finish()
+
+	apiKey := entity.ApiKey{}
+	err := a.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		before := entity.ApiKey{}
+		if err := withTenant(tx).Clauses(clause.Locking{Strength: "UPDATE"}).Where(`id = ?`, id).First(&before).Error; err != nil {
+			return errors.Wrap(err, "apiKeyRepository.Revoke.GetForUpdate")
+		}
+		if before.RevokedAt != nil {
+			apiKey = before
+			return nil
+		}
+
+		result := tx.Model(&apiKey).Clauses(clause.Returning{}).Where(`id = ?`, id).Update("revoked_at", gorm.Expr("now()"))
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "apiKeyRepository.Revoke.DbError")
+		}
+
+		return auditRepository.AddAuditLog(tx, entity.AuditEntityType, id, audit.ActionUpdate, before, apiKey)
+	})
+	if err != nil {
+		return entity.ApiKey{}, err
+	}
+
+	return apiKey, nil
+}
+
+// TouchLastUsed moves last_used_at forward, an older time never overwrites a newer one.
+func (a apiKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRepository.TouchLastUsed")
+	defer span.Finish()
+
+	err := a.db.WithContext(spanContext).Model(&entity.ApiKey{}).
+		Where(`id = ? AND (last_used_at IS NULL OR last_used_at < ?)`, id, at).
+		UpdateColumn("last_used_at", at).Error
+	if err != nil {
+		return errors.Wrap(err, "apiKeyRepository.TouchLastUsed.DbError")
+	}
+
+	return nil
+}
+
+// withTenant limits the query to the keys of the tenant of the request, keys are never shared between tenants.
+// GetByHash is not limited, authentication finds the tenant of a key through it.
+func withTenant(db *gorm.DB) *gorm.DB {
+	return db.Where(`tenant_id = ?`, tenant.FromContext(db.Statement.Context))
+}
+
+func NewApiKeyRepository(db *gorm.DB) ApiKeyRepository {
+	return &apiKeyRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/apikey/entity"
+	"time"
+)
+
+const (
+	apiKeyCachePrefix = "api_key:"
+	revokedApiKeyCachePrefix = "api_key:revoked:"
+	revokedMarker = "1"
+)
+
+// ErrRevoked is returned for keys with a revocation marker, whatever the cache or the database say.
+var ErrRevoked = errors.New("api key is revoked")
+
+// ApiKeyRedisRepository caches keys by hash. Every replica reads the same cache, so the revocation marker
+// written by Revoke rejects the key on all of them at once.
+type ApiKeyRedisRepository interface {
+	// Get returns redis.Nil on a cache miss and ErrRevoked for revoked keys
+	Get(ctx context.Context, keyHash string) (*entity.ApiKey, error)
+	Set(ctx context.Context, keyHash string, apiKey entity.ApiKey, ttl time.Duration) error
+	Revoke(ctx context.Context, keyHash string, ttl time.Duration) error
+}
+
+type apiKeyRedisRepository struct {
+	redisClient redis.UniversalClient
+}
+
+func (a apiKeyRedisRepository) Get(ctx context.Context, keyHash string) (*entity.ApiKey, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRedisRepository.Get")
+	defer span.Finish()
+
+	values, err := a.redisClient.MGet(spanContext, ApiKeyCacheKey(keyHash), RevokedApiKeyCacheKey(keyHash)).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "apiKeyRedisRepository.Get.RedisClient.MGet")
+	}
+	if values[1] != nil {
+		return nil, ErrRevoked
+	}
+
+	cached, ok := values[0].(string)
+	if !ok {
+		return nil, redis.Nil
+	}
+
+	apiKey := &entity.ApiKey{}
+	if err = json.Unmarshal([]byte(cached), apiKey); err != nil {
+		return nil, errors.Wrap(err, "apiKeyRedisRepository.Get.Json.Unmarshal")
+	}
+
+	return apiKey, nil
+}
+
+func (a apiKeyRedisRepository) Set(ctx context.Context, keyHash string, apiKey entity.ApiKey, ttl time.Duration) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRedisRepository.Set")
+	defer span.Finish()
+
+	apiKeyByte, err := json.Marshal(apiKey)
+	if err != nil {
+		return errors.Wrap(err, "apiKeyRedisRepository.Set.Json.Marshal")
+	}
+	if err = a.redisClient.Set(spanContext, ApiKeyCacheKey(keyHash), apiKeyByte, ttl).Err(); err != nil {
+		return errors.Wrap(err, "apiKeyRedisRepository.Set.RedisClient.Set")
+	}
+
+	return nil
+}
+
+// Revoke drops the cached key and leaves a revocation marker for ttl. The marker also wins over an entry
+// a concurrent lookup writes back after the drop, so ttl has to outlive the cache ttl.
+func (a apiKeyRedisRepository) Revoke(ctx context.Context, keyHash string, ttl time.Duration) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRedisRepository.Revoke")
+	defer span.Finish()
+
+	pipe := a.redisClient.TxPipeline()
+	pipe.Set(spanContext, RevokedApiKeyCacheKey(keyHash), revokedMarker, ttl)
+	pipe.Del(spanContext, ApiKeyCacheKey(keyHash))
+	if _, err := pipe.Exec(spanContext); err != nil {
+		return errors.Wrap(err, "apiKeyRedisRepository.Revoke.Pipeline.Exec")
+	}
+
+	return nil
+}
+
+// ApiKeyCacheKey and RevokedApiKeyCacheKey tag the hash, Get and Revoke need both keys of a hash in one
+// redis cluster slot.
+func ApiKeyCacheKey(keyHash string) string {
+	return apiKeyCachePrefix + "{" + keyHash + "}"
+}
+
+func RevokedApiKeyCacheKey(keyHash string) string {
+	return revokedApiKeyCachePrefix + "{" + keyHash + "}"
+}
+
+func NewApiKeyRedisRepository(redisClient redis.UniversalClient) ApiKeyRedisRepository {
+	return &apiKeyRedisRepository{
+		redisClient: redisClient,
+	}
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/apikey/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sony/gobreaker"
+	"time"
+)
+
+type apiKeyRedisBreakerRepository struct {
+	next ApiKeyRedisRepository
+	breaker *gobreaker.CircuitBreaker
+}
+
+func (a apiKeyRedisBreakerRepository) Get(ctx context.Context, keyHash string) (*entity.ApiKey, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRedisBreakerRepository.Get")
+	defer span.Finish()
+
+	// revoked keys are an answer of redis like misses
+	result, err := breaker.Execute(a.breaker, "apiKeyRedisBreakerRepository.Get", func() (interface{}, error) {
+		return a.next.Get(spanContext, keyHash)
+	}, redis.Nil, ErrRevoked)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*entity.ApiKey), nil
+}
+
+func (a apiKeyRedisBreakerRepository) Set(ctx context.Context, keyHash string, apiKey entity.ApiKey, ttl time.Duration) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRedisBreakerRepository.Set")
+	defer span.Finish()
+
+	_, err := breaker.Execute(a.breaker, "apiKeyRedisBreakerRepository.Set", func() (interface{}, error) {
+		return nil, a.next.Set(spanContext, keyHash, apiKey, ttl)
+	})
+
+	return err
+}
+
+func (a apiKeyRedisBreakerRepository) Revoke(ctx context.Context, keyHash string, ttl time.Duration) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyRedisBreakerRepository.Revoke")
+	defer span.Finish()
+
+	_, err := breaker.Execute(a.breaker, "apiKeyRedisBreakerRepository.Revoke", func() (interface{}, error) {
+		return nil, a.next.Revoke(spanContext, keyHash, ttl)
+	})
+
+	return err
+}
+
+func NewApiKeyRedisBreakerRepository(next ApiKeyRedisRepository, breaker *gobreaker.CircuitBreaker) ApiKeyRedisRepository {
+	return &apiKeyRedisBreakerRepository{
+		next: next,
+		breaker: breaker,
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/apikey/entity"
+	"strings"
+	"testing"
+	"time"
+)
+
+// crossSlotHook refuses commands and transactions with keys of more than one hash tag, as redis cluster does
+// for keys of different slots. miniredis serves the whole cluster from one node and does not check.
+type crossSlotHook struct{}
+
+func hashTag(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
+
+func keys(cmd redis.Cmder) []interface{} {
+	args := cmd.Args()
+	switch cmd.Name() {
+	case "mget", "del", "exists":
+		return args[1:]
+	case "get", "set", "expire", "ttl":
+		return args[1:2]
+	}
+	return nil
+}
+
+func checkSlot(cmds ...redis.Cmder) error {
+	tags := map[string]bool{}
+	for _, cmd := range cmds {
+		for _, key := range keys(cmd) {
+			tags[hashTag(fmt.Sprint(key))] = true
+		}
+	}
+	if len(tags) > 1 {
+		return errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+	}
+	return nil
+}
+
+func (crossSlotHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, checkSlot(cmd)
+}
+
+func (crossSlotHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (crossSlotHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if len(cmds) == 0 || cmds[0].Name() != "multi" {
+		return ctx, nil
+	}
+	return ctx, checkSlot(cmds...)
+}
+
+func (crossSlotHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func TestApiKeyRedisRepositoryOnRedisCluster(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{mr.Addr()}, MaxRedirects: -1})
+	client.AddHook(crossSlotHook{})
+	t.Cleanup(func() { client.Close() })
+
+	var cluster redis.UniversalClient = client
+	apiKeys := NewApiKeyRedisRepository(cluster)
+	ctx := context.Background()
+	keyHash := entity.HashKey("ck_live_secret")
+
+	if _, err := apiKeys.Get(ctx, keyHash); !errors.Is(err, redis.Nil) {
+		t.Fatalf("Get = %v, want a cache miss", err)
+	}
+	if err := apiKeys.Set(ctx, keyHash, entity.ApiKey{ID: 3, Name: "importer"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if apiKey, err := apiKeys.Get(ctx, keyHash); err != nil || apiKey.ID != 3 {
+		t.Fatalf("Get = %+v, %v", apiKey, err)
+	}
+
+	if err := apiKeys.Revoke(ctx, keyHash, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiKeys.Get(ctx, keyHash); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Get = %v after Revoke, want ErrRevoked", err)
+	}
+	if mr.Exists(ApiKeyCacheKey(keyHash)) || !mr.Exists(RevokedApiKeyCacheKey(keyHash)) {
+		t.Error("Revoke kept the cached key or wrote no revocation marker")
+	}
+}
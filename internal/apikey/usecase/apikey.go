@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/apikey/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/apikey/mapping"
+	"github.com/sefikcan/kanbersky.ca/internal/apikey/repository"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/apikey"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/apikey"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheTtl = time.Minute * 5
+	defaultLastUsedInterval = time.Minute
+	lastUsedTimeout = time.Second * 5
+	keyBytes = 32
+	subjectPrefix = "api-key:"
+)
+
+// ApiKeyUseCase manages api keys and authenticates requests made with them.
+type ApiKeyUseCase interface {
+	auth.KeyAuthenticator
+	Create(ctx context.Context, request request.ApiKeyCreateRequest) (*response.ApiKeyResponse, error)
+	GetById(ctx context.Context, id int64) (*response.ApiKeyResponse, error)
+	GetAll(ctx context.Context, request *request.ApiKeyPageableRequest) (response.ApiKeyListResponse, error)
+	Revoke(ctx context.Context, id int64) error
+}
+
+type apiKeyUseCase struct {
+	cfg *config.Config
+	apiKeyRepository repository.ApiKeyRepository
+	apiKeyRedisRepository repository.ApiKeyRedisRepository
+	logger logger.Logger
+	// lastUsed holds when last_used_at of a key id was last written by this instance
+	lastUsed *sync.Map
+}
+
+// Create returns the key once, only its hash is stored. A caller can not grant scopes it does not hold itself.
+func (a apiKeyUseCase) Create(ctx context.Context, request request.ApiKeyCreateRequest) (*response.ApiKeyResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyUseCase.Create")
+	defer span.Finish()
+
+	if err := util.ValidateStruct(&request); err != nil {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "apiKeyUseCase.Create.ValidateStruct"))
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, "expires_at must be in the future", nil)
+	}
+	if principal, ok := auth.FromContext(ctx); ok {
+		for _, scope := range request.Scopes {
+			if !principal.HasScope(scope) {
+				return nil, util.NewHttpResponse(http.StatusForbidden, fmt.Sprintf("%s scope can not be granted by the caller", scope), nil)
+			}
+		}
+	}
+
+	key, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := a.apiKeyRepository.Create(spanContext, entity.ApiKey{
+		Name: request.Name,
+		TenantID: tenant.FromContext(spanContext),
+		Prefix: key[:len(entity.KeyPrefix)+entity.DisplayPrefixLength],
+		KeyHash: entity.HashKey(key),
+		Scopes: entity.JoinScopes(request.Scopes),
+		ExpiresAt: request.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mappedResponse := mapping.MapDto(apiKey)
+	mappedResponse.Key = key
+
+	return mappedResponse, nil
+}
+
+func (a apiKeyUseCase) GetById(ctx context.Context, id int64) (*response.ApiKeyResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyUseCase.GetById")
+	defer span.Finish()
+
+	apiKey, err := a.apiKeyRepository.GetById(spanContext, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapDto(apiKey), nil
+}
+
+func (a apiKeyUseCase) GetAll(ctx context.Context, pageableRequest *request.ApiKeyPageableRequest) (response.ApiKeyListResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyUseCase.GetAll")
+	defer span.Finish()
+
+	pagination := util.Pagination{
+		Page: pageableRequest.Page,
+		Limit: pageableRequest.Size,
+	}
+
+	totalCount, err := a.apiKeyRepository.GetCount(spanContext)
+	if err != nil {
+		return response.ApiKeyListResponse{}, err
+	}
+
+	apiKeys, err := a.apiKeyRepository.GetAll(spanContext, pagination)
+	if err != nil {
+		return response.ApiKeyListResponse{}, err
+	}
+
+	return response.ApiKeyListResponse{
+		TotalCount: totalCount,
+		TotalPages: util.GetTotalPages(totalCount, pagination.GetLimit()),
+		Page: pagination.GetPage(),
+		Limit: pagination.GetLimit(),
+		ApiKeys: mapping.MapListDto(apiKeys),
+	}, nil
+}
+
+// Revoke revokes the key in the database and marks it revoked in the shared cache, which every replica
+// checks on each request. When the cache can not be updated an error is returned so the call is retried,
+// revoking a revoked key is a no-op.
+func (a apiKeyUseCase) Revoke(ctx context.Context, id int64) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyUseCase.Revoke")
+	defer span.Finish()
+
+	apiKey, err := a.apiKeyRepository.Revoke(spanContext, id)
+	if err != nil {
+		return err
+	}
+
+	// the marker outlives every cache entry written before the revocation
+	if err = a.apiKeyRedisRepository.Revoke(spanContext, apiKey.KeyHash, 2*a.cacheTtl()); err != nil {
+		return util.NewHttpResponse(http.StatusServiceUnavailable, "api key is revoked, but the revocation could not be published, retry the request", errors.WithMessage(err, "apiKeyUseCase.Revoke"))
+	}
+
+	return nil
+}
+
+// AuthenticateKey returns the principal of an active key, unknown, revoked and expired keys get
+// auth.ErrInvalidKey. Lookups are served from the cache, the database is only read on a miss or while
+// the cache is down.
+func (a apiKeyUseCase) AuthenticateKey(ctx context.Context, key string) (auth.Principal, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "apiKeyUseCase.AuthenticateKey")
+	defer span.Finish()
+
+	if !strings.HasPrefix(key, entity.KeyPrefix) {
+		return auth.Principal{}, auth.ErrInvalidKey
+	}
+
+	keyHash := entity.HashKey(key)
+	apiKey, err := a.apiKeyRedisRepository.Get(spanContext, keyHash)
+	switch {
+	case errors.Is(err, repository.ErrRevoked):
+		return auth.Principal{}, errors.Wrap(auth.ErrInvalidKey, "key is revoked")
+	case errors.Is(err, redis.Nil):
+		apiKey, err = a.load(spanContext, keyHash, true)
+	case err != nil:
+		breaker.LogError(a.logger, "apiKeyUseCase.AuthenticateKey.Redis", err)
+		apiKey, err = a.load(spanContext, keyHash, false)
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	now := time.Now()
+	if !apiKey.ActiveAt(now) {
+		return auth.Principal{}, errors.Wrap(auth.ErrInvalidKey, "key is revoked or expired")
+	}
+	a.touchLastUsed(apiKey.ID, now)
+
+	return auth.Principal{
+		Subject: fmt.Sprintf("%s%d", subjectPrefix, apiKey.ID),
+		Scopes: apiKey.ScopeList(),
+		Tenant: apiKey.TenantID,
+	}, nil
+}
+
+func (a apiKeyUseCase) load(ctx context.Context, keyHash string, cache bool) (*entity.ApiKey, error) {
+	apiKey, err := a.apiKeyRepository.GetByHash(ctx, keyHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, auth.ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cache {
+		if err = a.apiKeyRedisRepository.Set(ctx, keyHash, apiKey, a.cacheTtl()); err != nil {
+			breaker.LogError(a.logger, "apiKeyUseCase.AuthenticateKey.SetCache", err)
+		}
+	}
+
+	return &apiKey, nil
+}
+
+// touchLastUsed writes last_used_at in the background at most once per interval and key, so busy keys
+// do not turn every request into a database write.
+func (a apiKeyUseCase) touchLastUsed(id int64, now time.Time) {
+	if last, ok := a.lastUsed.Load(id); ok && now.Sub(last.(time.Time)) < a.lastUsedInterval() {
+		return
+	}
+	a.lastUsed.Store(id, now)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), lastUsedTimeout)
+		defer cancel()
+
+		if err := a.apiKeyRepository.TouchLastUsed(ctx, id, now); err != nil {
+			a.logger.Errorf("apiKeyUseCase.touchLastUsed: %s", err)
+		}
+	}()
+}
+
+func (a apiKeyUseCase) cacheTtl() time.Duration {
+	if a.cfg.ApiKey.CacheTtl <= 0 {
+		return defaultCacheTtl
+	}
+
+	return time.Second * a.cfg.ApiKey.CacheTtl
+}
+
+func (a apiKeyUseCase) lastUsedInterval() time.Duration {
+	if a.cfg.ApiKey.LastUsedInterval <= 0 {
+		return defaultLastUsedInterval
+	}
+
+	return time.Second * a.cfg.ApiKey.LastUsedInterval
+}
+
+func generateKey() (string, error) {
+	key := make([]byte, keyBytes)
+	if _, err := rand.Read(key); err != nil {
+		return "", errors.Wrap(err, "apiKeyUseCase.generateKey")
+	}
+
+	return entity.KeyPrefix + hex.EncodeToString(key), nil
+}
+
+func NewApiKeyUseCase(cfg *config.Config, apiKeyRepository repository.ApiKeyRepository, apiKeyRedisRepository repository.ApiKeyRedisRepository, logger logger.Logger) ApiKeyUseCase {
+	return &apiKeyUseCase{
+		cfg: cfg,
+		apiKeyRepository: apiKeyRepository,
+		apiKeyRedisRepository: apiKeyRedisRepository,
+		logger: logger,
+		lastUsed: &sync.Map{},
+	}
+}
@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/apikey/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/apikey/repository"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/apikey"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryApiKeys counts the hash lookups, they are what the cache saves.
+type memoryApiKeys struct {
+	repository.ApiKeyRepository
+	mu sync.Mutex
+	keys map[int64]entity.ApiKey
+	hashLookups int
+	touched chan int64
+}
+
+func (m *memoryApiKeys) Create(_ context.Context, apiKey entity.ApiKey) (entity.ApiKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	apiKey.ID = int64(len(m.keys) + 1)
+	m.keys[apiKey.ID] = apiKey
+	return apiKey, nil
+}
+
+func (m *memoryApiKeys) GetByHash(_ context.Context, keyHash string) (entity.ApiKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hashLookups++
+	for _, apiKey := range m.keys {
+		if apiKey.KeyHash == keyHash {
+			return apiKey, nil
+		}
+	}
+
+	return entity.ApiKey{}, errors.Wrap(gorm.ErrRecordNotFound, "memoryApiKeys.GetByHash")
+}
+
+func (m *memoryApiKeys) Revoke(_ context.Context, id int64) (entity.ApiKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	apiKey := m.keys[id]
+	now := time.Now()
+	apiKey.RevokedAt = &now
+	m.keys[id] = apiKey
+	return apiKey, nil
+}
+
+func (m *memoryApiKeys) TouchLastUsed(_ context.Context, id int64, _ time.Time) error {
+	m.touched <- id
+	return nil
+}
+
+func (m *memoryApiKeys) lookups() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.hashLookups
+}
+
+func newTestUseCase(t *testing.T) (ApiKeyUseCase, *memoryApiKeys, *miniredis.Miniredis) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() {
+		redisClient.Close()
+	})
+	keys := &memoryApiKeys{keys: map[int64]entity.ApiKey{}, touched: make(chan int64, 10)}
+
+	return NewApiKeyUseCase(cfg, keys, repository.NewApiKeyRedisRepository(redisClient), l), keys, mr
+}
+
+// createKey creates a key as a tenant caller holding both currency scopes.
+func createKey(t *testing.T, uc ApiKeyUseCase, scopes ...string) string {
+	t.Helper()
+	ctx := auth.NewContext(tenant.NewContext(context.Background(), "acme"), auth.Principal{Subject: "alice", Scopes: []string{auth.ScopeCurrenciesRead, auth.ScopeCurrenciesWrite}, Tenant: "acme"})
+	created, err := uc.Create(ctx, request.ApiKeyCreateRequest{Name: "importer", Scopes: scopes})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	return created.Key
+}
+
+func TestCreate(t *testing.T) {
+	uc, keys, _ := newTestUseCase(t)
+	key := createKey(t, uc, auth.ScopeCurrenciesRead)
+
+	stored := keys.keys[1]
+	if !strings.HasPrefix(key, entity.KeyPrefix) || len(key) != len(entity.KeyPrefix)+2*keyBytes {
+		t.Errorf("key = %s, want %s and 64 hex characters", key, entity.KeyPrefix)
+	}
+	if stored.KeyHash != entity.HashKey(key) || strings.Contains(stored.KeyHash, key) || stored.Prefix != key[:len(entity.KeyPrefix)+entity.DisplayPrefixLength] {
+		t.Errorf("stored = %+v, want the hash and the display prefix only", stored)
+	}
+	if stored.TenantID != "acme" || stored.Scopes != auth.ScopeCurrenciesRead {
+		t.Errorf("stored tenant %q, scopes %q", stored.TenantID, stored.Scopes)
+	}
+
+	ctx := auth.NewContext(context.Background(), auth.Principal{Subject: "alice", Scopes: []string{auth.ScopeCurrenciesRead}})
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name string
+		request request.ApiKeyCreateRequest
+		status int
+	}{
+		{name: "scope the caller lacks", request: request.ApiKeyCreateRequest{Name: "importer", Scopes: []string{auth.ScopeCurrenciesWrite}}, status: http.StatusForbidden},
+		{name: "expiry in the past", request: request.ApiKeyCreateRequest{Name: "importer", Scopes: []string{auth.ScopeCurrenciesRead}, ExpiresAt: &past}, status: http.StatusBadRequest},
+		{name: "without scopes", request: request.ApiKeyCreateRequest{Name: "importer"}, status: http.StatusBadRequest},
+		{name: "scope with a space", request: request.ApiKeyCreateRequest{Name: "importer", Scopes: []string{"currencies:read currencies:write"}}, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := uc.Create(ctx, tt.request); util.ParseError(err).Status() != tt.status {
+				t.Errorf("Create = %v, want %d", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestAuthenticateKey(t *testing.T) {
+	uc, keys, _ := newTestUseCase(t)
+	key := createKey(t, uc, auth.ScopeCurrenciesRead, auth.ScopeCurrenciesWrite)
+
+	for i := 0; i < 3; i++ {
+		principal, err := uc.AuthenticateKey(context.Background(), key)
+		if err != nil {
+			t.Fatalf("AuthenticateKey: %v", err)
+		}
+		if principal.Subject != "api-key:1" || principal.Tenant != "acme" || !principal.HasScope(auth.ScopeCurrenciesWrite) {
+			t.Fatalf("principal = %+v", principal)
+		}
+	}
+	if keys.lookups() != 1 {
+		t.Errorf("database lookups = %d, want 1, the cache serves the rest", keys.lookups())
+	}
+
+	// last_used_at is written once per interval, not once per request
+	select {
+	case id := <-keys.touched:
+		if id != 1 {
+			t.Errorf("touched key %d, want 1", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("last_used_at was not written")
+	}
+	select {
+	case <-keys.touched:
+		t.Error("last_used_at was written again within the interval")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for name, key := range map[string]string{"without the prefix": "secret", "unknown": entity.KeyPrefix + "unknown"} {
+		if _, err := uc.AuthenticateKey(context.Background(), key); !errors.Is(err, auth.ErrInvalidKey) {
+			t.Errorf("%s: AuthenticateKey = %v, want %v", name, err, auth.ErrInvalidKey)
+		}
+	}
+}
+
+func TestRevokedKeysAreRejectedDespiteTheCache(t *testing.T) {
+	uc, _, mr := newTestUseCase(t)
+	key := createKey(t, uc, auth.ScopeCurrenciesRead)
+	if _, err := uc.AuthenticateKey(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+	// a cached copy written back by another replica must not bring the key back
+	cached, err := mr.Get(repository.ApiKeyCacheKey(entity.HashKey(key)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = uc.Revoke(context.Background(), 1); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if err = mr.Set(repository.ApiKeyCacheKey(entity.HashKey(key)), cached); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = uc.AuthenticateKey(context.Background(), key); !errors.Is(err, auth.ErrInvalidKey) {
+		t.Errorf("AuthenticateKey = %v, want %v", err, auth.ErrInvalidKey)
+	}
+	if ttl := mr.TTL(repository.RevokedApiKeyCacheKey(entity.HashKey(key))); ttl != 2*defaultCacheTtl {
+		t.Errorf("revocation marker ttl = %s, want %s", ttl, 2*defaultCacheTtl)
+	}
+
+	// without the cache the database still knows
+	mr.Close()
+	if _, err = uc.AuthenticateKey(context.Background(), key); !errors.Is(err, auth.ErrInvalidKey) {
+		t.Errorf("AuthenticateKey without the cache = %v, want %v", err, auth.ErrInvalidKey)
+	}
+	if err = uc.Revoke(context.Background(), 1); util.ParseError(err).Status() != http.StatusServiceUnavailable {
+		t.Errorf("Revoke without the cache = %v, want 503 so the call is retried", err)
+	}
+}
+
+func TestExpiredKeysAreRejected(t *testing.T) {
+	uc, keys, mr := newTestUseCase(t)
+	key := createKey(t, uc, auth.ScopeCurrenciesRead)
+	expired := time.Now().Add(-time.Minute)
+	apiKey := keys.keys[1]
+	apiKey.ExpiresAt = &expired
+	keys.keys[1] = apiKey
+
+	mr.Close()
+	if _, err := uc.AuthenticateKey(context.Background(), key); !errors.Is(err, auth.ErrInvalidKey) {
+		t.Errorf("AuthenticateKey = %v, want %v", err, auth.ErrInvalidKey)
+	}
+}
@@ -0,0 +1,92 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const (
+	StatusPending = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusExpired = "expired"
+	StatusFailed = "failed"
+
+	AuditEntityType = "change_request"
+)
+
+// ChangeRequest is a change a maker proposed, it applies once another principal, the checker, approves it.
+// Operation is the permission the change needs, e.g. currency.delete, and Payload the request that applies it.
+type ChangeRequest struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Operation string `json:"operation"`
+	TenantID string `gorm:"index:idx_change_request_tenant" json:"tenant_id"`
+	EntityID string `json:"entity_id"`
+	Payload *string `gorm:"type:jsonb" json:"payload"`
+	Status string `gorm:"index:idx_change_request_status" json:"status"`
+	Maker string `json:"maker"`
+	Checker string `json:"checker"`
+	Reason string `json:"reason"`
+	Result *string `gorm:"type:jsonb" json:"result"`
+	Error string `json:"error"`
+	ExpiresAt time.Time `gorm:"index:idx_change_request_status" json:"expires_at"`
+	DecidedAt *time.Time `json:"decided_at"`
+}
+
+func (ChangeRequest) TableName() string {
+	return "change_requests"
+}
+
+// NewChangeRequest proposes the change for ttl, a nil payload is stored as null.
+func NewChangeRequest(operation string, entityId interface{}, payload any, maker string, ttl time.Duration) (ChangeRequest, error) {
+	changeRequest := ChangeRequest{
+		Operation: operation,
+		Status: StatusPending,
+		Maker: maker,
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	}
+	if entityId != nil {
+		changeRequest.EntityID = fmt.Sprint(entityId)
+	}
+	if payload != nil {
+		var err error
+		if changeRequest.Payload, err = marshal(payload); err != nil {
+			return ChangeRequest{}, errors.Wrap(err, "entity.NewChangeRequest.Json.Marshal")
+		}
+	}
+
+	return changeRequest, nil
+}
+
+// MarshalResult stores what applying the change returned, e.g. the created currency.
+func MarshalResult(result any) (*string, error) {
+	value, err := marshal(result)
+	if err != nil {
+		return nil, errors.Wrap(err, "entity.MarshalResult.Json.Marshal")
+	}
+
+	return value, nil
+}
+
+func marshal(v any) (*string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	value := string(data)
+
+	return &value, nil
+}
+
+// Decode reads the payload into v.
+func (c ChangeRequest) Decode(v any) error {
+	if c.Payload == nil {
+		return errors.New("entity.ChangeRequest.Decode: change request has no payload")
+	}
+
+	return errors.Wrap(json.Unmarshal([]byte(*c.Payload), v), "entity.ChangeRequest.Decode.Json.Unmarshal")
+}
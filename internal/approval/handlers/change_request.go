@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/approval/usecase"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/request/approval"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/approval"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Binder reads what a request changes, the id of the changed entity, nil for creates, and the request that
+// applies the change once it is approved.
+type Binder func(e echo.Context) (entityId interface{}, payload any, err error)
+
+type ChangeRequestHandlers interface {
+	GetById() echo.HandlerFunc
+	GetAll() echo.HandlerFunc
+	Approve() echo.HandlerFunc
+	Reject() echo.HandlerFunc
+	Propose(permission string, bind Binder) echo.MiddlewareFunc
+}
+
+type changeRequestHandlers struct {
+	cfg *config.Config
+	changeRequestUseCase usecase.ChangeRequestUseCase
+	logger logger.Logger
+}
+
+// GetById godoc
+// @Summary Get by id change request
+// @Description Get by id change request handler, visible to its maker and to principals with the change.approve permission
+// @Tags ChangeRequest
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} approval.ChangeRequestResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Failure 404 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/change-requests/{id} [get]
+func (c changeRequestHandlers) GetById() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "changeRequestHandler.GetById")
+		defer span.Finish()
+
+		id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		changeRequest, err := c.changeRequestUseCase.GetById(ctx, id)
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, changeRequest)
+	}
+}
+
+// GetAll godoc
+// @Summary Get all change requests
+// @Description Get change requests with pagination, newest first, needs the change.approve permission
+// @Tags ChangeRequest
+// @Accept json
+// @Produce json
+// @Param status query string false "pending, approved, rejected, expired or failed"
+// @Param page query int false "page number" Format(page)
+// @Param limit query int false "number of elements per page" Format(limit)
+// @Success 200 {object} approval.ChangeRequestListResponse
+// @Failure 400 {object} util.HttpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/change-requests [get]
+func (c changeRequestHandlers) GetAll() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "changeRequestHandler.GetAll")
+		defer span.Finish()
+
+		pageableRequest := approval.ChangeRequestPageableRequest{
+			Status: e.QueryParam("status"),
+		}
+		if page, err := strconv.Atoi(e.QueryParam("page")); err == nil {
+			pageableRequest.Page = page
+		}
+		if limit, err := strconv.Atoi(e.QueryParam("limit")); err == nil {
+			pageableRequest.Size = limit
+		}
+
+		changeRequestList, err := c.changeRequestUseCase.GetAll(ctx, &pageableRequest)
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, changeRequestList)
+	}
+}
+
+// Approve godoc
+// @Summary Approve change request
+// @Description Approves a pending change request of another principal and applies it, a change that fails to apply leaves the request failed. Needs the change.approve permission
+// @Tags ChangeRequest
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param decisionRequest body approval.ChangeRequestDecisionRequest false "Decision"
+// @Success 200 {object} approval.ChangeRequestResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Failure 404 {object} util.HttpResponse
+// @Failure 409 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/change-requests/{id}/approve [post]
+func (c changeRequestHandlers) Approve() echo.HandlerFunc {
+	return c.decide("changeRequestHandler.Approve", c.changeRequestUseCase.Approve)
+}
+
+// Reject godoc
+// @Summary Reject change request
+// @Description Rejects a pending change request of another principal, needs the change.approve permission
+// @Tags ChangeRequest
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param decisionRequest body approval.ChangeRequestDecisionRequest false "Decision"
+// @Success 200 {object} approval.ChangeRequestResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Failure 404 {object} util.HttpResponse
+// @Failure 409 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/change-requests/{id}/reject [post]
+func (c changeRequestHandlers) Reject() echo.HandlerFunc {
+	return c.decide("changeRequestHandler.Reject", c.changeRequestUseCase.Reject)
+}
+
+func (c changeRequestHandlers) decide(operationName string, decide func(ctx context.Context, id int64, request approval.ChangeRequestDecisionRequest) (*response.ChangeRequestResponse, error)) echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), operationName)
+		defer span.Finish()
+
+		id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		decisionRequest := approval.ChangeRequestDecisionRequest{}
+		if e.Request().ContentLength != 0 {
+			if err = e.Bind(&decisionRequest); err != nil {
+				util.PrepareLogging(e, c.logger, err)
+				resp := util.ParseBindError(err)
+				return e.JSON(resp.Status(), resp)
+			}
+		}
+
+		changeRequest, err := decide(ctx, id, decisionRequest)
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, changeRequest)
+	}
+}
+
+// Propose answers the requests of callers whose changes with the permission need approval with a pending
+// change request and 202, instead of calling the handler. Other callers go straight to the handler. It has
+// to run after the permission is checked.
+func (c changeRequestHandlers) Propose(permission string, bind Binder) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(e echo.Context) error {
+			span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "changeRequestHandler.Propose")
+			defer span.Finish()
+
+			requiresApproval, err := c.changeRequestUseCase.RequiresApproval(ctx, permission)
+			if err != nil {
+				util.PrepareLogging(e, c.logger, err)
+				resp := util.ParseError(err)
+				return e.JSON(resp.Status(), resp)
+			}
+			if !requiresApproval {
+				return next(e)
+			}
+
+			entityId, payload, err := bind(e)
+			if err != nil {
+				util.PrepareLogging(e, c.logger, err)
+				resp := util.ParseError(err)
+				return e.JSON(resp.Status(), resp)
+			}
+
+			changeRequest, err := c.changeRequestUseCase.Propose(ctx, permission, entityId, payload)
+			if err != nil {
+				util.PrepareLogging(e, c.logger, err)
+				resp := util.ParseError(err)
+				return e.JSON(resp.Status(), resp)
+			}
+
+			e.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/api/v1/change-requests/%d", changeRequest.ID))
+			return e.JSON(http.StatusAccepted, changeRequest)
+		}
+	}
+}
+
+func NewChangeRequestHandler(cfg *config.Config, changeRequestUseCase usecase.ChangeRequestUseCase, logger logger.Logger) ChangeRequestHandlers {
+	return &changeRequestHandlers{
+		cfg: cfg,
+		changeRequestUseCase: changeRequestUseCase,
+		logger: logger,
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+)
+
+// changeRequestAuthorizationHandlers leaves GetById to the use case, makers may read their own change requests.
+type changeRequestAuthorizationHandlers struct {
+	ChangeRequestHandlers
+	authorize func(permission string) echo.MiddlewareFunc
+}
+
+func (c changeRequestAuthorizationHandlers) GetAll() echo.HandlerFunc {
+	return c.authorize(auth.PermissionChangeApprove)(c.ChangeRequestHandlers.GetAll())
+}
+
+func (c changeRequestAuthorizationHandlers) Approve() echo.HandlerFunc {
+	return c.authorize(auth.PermissionChangeApprove)(c.ChangeRequestHandlers.Approve())
+}
+
+func (c changeRequestAuthorizationHandlers) Reject() echo.HandlerFunc {
+	return c.authorize(auth.PermissionChangeApprove)(c.ChangeRequestHandlers.Reject())
+}
+
+// NewChangeRequestAuthorizationHandler requires the change.approve permission to list and decide change
+// requests, authorize returns the middleware checking a permission, e.g. MiddlewareManager.PermissionMiddleware.
+func NewChangeRequestAuthorizationHandler(next ChangeRequestHandlers, authorize func(permission string) echo.MiddlewareFunc) ChangeRequestHandlers {
+	return &changeRequestAuthorizationHandlers{
+		ChangeRequestHandlers: next,
+		authorize: authorize,
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/internal/approval/usecase"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/approval"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubChangeRequests struct {
+	usecase.ChangeRequestUseCase
+	requiresApproval bool
+	proposed []interface{}
+}
+
+func (s *stubChangeRequests) RequiresApproval(_ context.Context, _ string) (bool, error) {
+	return s.requiresApproval, nil
+}
+
+func (s *stubChangeRequests) Propose(_ context.Context, operation string, entityId interface{}, _ any) (*response.ChangeRequestResponse, error) {
+	s.proposed = append(s.proposed, entityId)
+	return &response.ChangeRequestResponse{ID: 12, Operation: operation, Status: "pending"}, nil
+}
+
+func TestPropose(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	bindId := func(e echo.Context) (interface{}, any, error) {
+		if e.Param("id") == "x" {
+			return nil, nil, util.NewHttpResponse(http.StatusBadRequest, "invalid id", nil)
+		}
+		return e.Param("id"), nil, nil
+	}
+	applied := func(e echo.Context) error {
+		return e.NoContent(http.StatusNoContent)
+	}
+
+	tests := []struct {
+		name string
+		requiresApproval bool
+		id string
+		status int
+		proposed int
+	}{
+		{name: "applies directly", id: "9", status: http.StatusNoContent},
+		{name: "proposes", requiresApproval: true, id: "9", status: http.StatusAccepted, proposed: 1},
+		{name: "invalid requests are not proposed", requiresApproval: true, id: "x", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changeRequests := &stubChangeRequests{requiresApproval: tt.requiresApproval}
+			h := NewChangeRequestHandler(cfg, changeRequests, l)
+
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodDelete, "/", nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+			if err := h.Propose("currency.delete", bindId)(applied)(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec.Code != tt.status || len(changeRequests.proposed) != tt.proposed {
+				t.Fatalf("status %d, proposed %v, want %d and %d proposals", rec.Code, changeRequests.proposed, tt.status, tt.proposed)
+			}
+			if tt.proposed == 0 {
+				return
+			}
+			proposed := response.ChangeRequestResponse{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &proposed); err != nil || proposed.ID != 12 || changeRequests.proposed[0] != "9" {
+				t.Errorf("body %s, %v, proposed %v", rec.Body, err, changeRequests.proposed)
+			}
+			if location := rec.Header().Get(echo.HeaderLocation); location != "/api/v1/change-requests/12" {
+				t.Errorf("location = %s", location)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package handlers
+
+import "github.com/labstack/echo/v4"
+
+func MapChangeRequestRoutes(changeRequestRouteGroup *echo.Group, c ChangeRequestHandlers) {
+	changeRequestRouteGroup.GET("/:id", c.GetById())
+	changeRequestRouteGroup.GET("", c.GetAll())
+	changeRequestRouteGroup.POST("/:id/approve", c.Approve())
+	changeRequestRouteGroup.POST("/:id/reject", c.Reject())
+}
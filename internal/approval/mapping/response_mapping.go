@@ -0,0 +1,43 @@
+package mapping
+
+import (
+	"encoding/json"
+	"github.com/sefikcan/kanbersky.ca/internal/approval/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/approval"
+)
+
+func MapDto(c entity.ChangeRequest) *approval.ChangeRequestResponse {
+	return &approval.ChangeRequestResponse{
+		ID: c.ID,
+		Operation: c.Operation,
+		TenantID: c.TenantID,
+		EntityID: c.EntityID,
+		Payload: rawJson(c.Payload),
+		Status: c.Status,
+		Maker: c.Maker,
+		Checker: c.Checker,
+		Reason: c.Reason,
+		Result: rawJson(c.Result),
+		Error: c.Error,
+		ExpiresAt: c.ExpiresAt,
+		DecidedAt: c.DecidedAt,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+func MapListDto(changeRequests []entity.ChangeRequest) []*approval.ChangeRequestResponse {
+	changeRequestResp := make([]*approval.ChangeRequestResponse, 0, len(changeRequests))
+	for _, c := range changeRequests {
+		changeRequestResp = append(changeRequestResp, MapDto(c))
+	}
+
+	return changeRequestResp
+}
+
+func rawJson(value *string) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+
+	return json.RawMessage(*value)
+}
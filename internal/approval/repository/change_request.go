@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/approval/entity"
+	audit "github.com/sefikcan/kanbersky.ca/internal/audit/entity"
+	auditRepository "github.com/sefikcan/kanbersky.ca/internal/audit/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrNotPending = errors.New("change request is not pending")
+	ErrExpired = errors.New("change request is expired")
+)
+
+type ChangeRequestRepository interface {
+	Create(ctx context.Context, changeRequest entity.ChangeRequest) (entity.ChangeRequest, error)
+	GetById(ctx context.Context, id int64) (entity.ChangeRequest, error)
+	GetCount(ctx context.Context, status string) (int64, error)
+	GetAll(ctx context.Context, status string, query util.Pagination) ([]entity.ChangeRequest, error)
+	Decide(ctx context.Context, id int64, status string, checker string, reason string) (entity.ChangeRequest, error)
+	Complete(ctx context.Context, id int64, status string, outcome any, lastError string) (entity.ChangeRequest, error)
+	Expire(ctx context.Context, limit int) ([]entity.ChangeRequest, error)
+}
+
+type changeRequestRepository struct {
+	db *gorm.DB
+}
+
+func (c changeRequestRepository) Create(ctx context.Context, changeRequest entity.ChangeRequest) (entity.ChangeRequest, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestRepository.Create")
+	defer span.Finish()
+
+	err := c.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		if result := tx.Create(&changeRequest); result.Error != nil {
+			return errors.Wrap(result.Error, "changeRequestRepository.Create.DbError")
+		}
+
+		return auditRepository.AddAuditLog(tx, entity.AuditEntityType, changeRequest.ID, audit.ActionCreate, nil, changeRequest)
+	})
+	if err != nil {
+		return entity.ChangeRequest{}, err
+	}
+
+	return changeRequest, nil
+}
+
+func (c changeRequestRepository) GetById(ctx context.Context, id int64) (entity.ChangeRequest, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestRepository.GetById")
+	defer span.Finish()
+
+	changeRequest := entity.ChangeRequest{}
+	if err := withTenant(c.db.WithContext(spanContext)).Where(`id = ?`, id).First(&changeRequest).Error; err != nil {
+		return entity.ChangeRequest{}, errors.Wrap(err, "changeRequestRepository.GetById.DbError")
+	}
+
+	return changeRequest, nil
+}
+
+func (c changeRequestRepository) GetCount(ctx context.Context, status string) (int64, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestRepository.GetCount")
+	defer span.Finish()
+
+	var totalCount int64
+	if err := withStatus(withTenant(c.db.WithContext(spanContext)).Model(&entity.ChangeRequest{}), status).Count(&totalCount).Error; err != nil {
+		return 0, errors.Wrap(err, "changeRequestRepository.GetCount.DbError")
+	}
+
+	return totalCount, nil
+}
+
+func (c changeRequestRepository) GetAll(ctx context.Context, status string, query util.Pagination) ([]entity.ChangeRequest, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestRepository.GetAll")
+	defer span.Finish()
+
+	var changeRequests []entity.ChangeRequest
+	err := withStatus(withTenant(c.db.WithContext(spanContext)), status).
+		Offset(query.GetOffset()).Limit(query.GetLimit()).Order("id desc").Find(&changeRequests).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "changeRequestRepository.GetAll.DbError")
+	}
+
+	return changeRequests, nil
+}
+
+// Decide moves a pending change request that is not expired to status, concurrent decisions on the same
+// request are serialized and only the first one wins.
+func (c changeRequestRepository) Decide(ctx context.Context, id int64, status string, checker string, reason string) (entity.ChangeRequest, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestRepository.Decide")
+	defer span.Finish()
+
+	changeRequest := entity.ChangeRequest{}
+	err := c.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		before, err := getForUpdate(tx, id)
+		if err != nil {
+			return errors.Wrap(err, "changeRequestRepository.Decide.GetForUpdate")
+		}
+		switch {
+		case before.Status != entity.StatusPending:
+			return ErrNotPending
+		case !before.ExpiresAt.After(time.Now()):
+			return ErrExpired
+		}
+
+		decidedAt := time.Now().UTC()
+		changeRequest = before
+		changeRequest.Status = status
+		changeRequest.Checker = checker
+		changeRequest.Reason = reason
+		changeRequest.DecidedAt = &decidedAt
+		if result := tx.Save(&changeRequest); result.Error != nil {
+			return errors.Wrap(result.Error, "changeRequestRepository.Decide.DbError")
+		}
+
+		return auditRepository.AddAuditLog(tx, entity.AuditEntityType, id, audit.ActionUpdate, before, changeRequest)
+	})
+	if err != nil {
+		return entity.ChangeRequest{}, err
+	}
+
+	return changeRequest, nil
+}
+
+// Complete records the outcome of applying an approved change request, the result of the change or the
+// error it failed with.
+func (c changeRequestRepository) Complete(ctx context.Context, id int64, status string, outcome any, lastError string) (entity.ChangeRequest, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestRepository.Complete")
+	defer span.Finish()
+
+	changeRequest := entity.ChangeRequest{}
+	err := c.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		before, err := getForUpdate(tx, id)
+		if err != nil {
+			return errors.Wrap(err, "changeRequestRepository.Complete.GetForUpdate")
+		}
+
+		changeRequest = before
+		changeRequest.Status = status
+		changeRequest.Error = lastError
+		if outcome != nil {
+			if changeRequest.Result, err = entity.MarshalResult(outcome); err != nil {
+				return err
+			}
+		}
+		if result := tx.Save(&changeRequest); result.Error != nil {
+			return errors.Wrap(result.Error, "changeRequestRepository.Complete.DbError")
+		}
+
+		return auditRepository.AddAuditLog(tx, entity.AuditEntityType, id, audit.ActionUpdate, before, changeRequest)
+	})
+	if err != nil {
+		return entity.ChangeRequest{}, err
+	}
+
+	return changeRequest, nil
+}
+
+// Expire marks up to limit pending change requests past their expiry as expired. Rows another replica is
+// expiring or deciding at the same time are skipped.
+func (c changeRequestRepository) Expire(ctx context.Context, limit int) ([]entity.ChangeRequest, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestRepository.Expire")
+	defer span.Finish()
+
+	var changeRequests []entity.ChangeRequest
+	err := c.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		var stale []entity.ChangeRequest
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where(`status = ? AND expires_at <= ?`, entity.StatusPending, time.Now().UTC()).
+			Order("id asc").Limit(limit).Find(&stale).Error
+		if err != nil {
+			return errors.Wrap(err, "changeRequestRepository.Expire.DbError")
+		}
+
+		for _, before := range stale {
+			changeRequest := before
+			changeRequest.Status = entity.StatusExpired
+			if result := tx.Save(&changeRequest); result.Error != nil {
+				return errors.Wrap(result.Error, "changeRequestRepository.Expire.Save.DbError")
+			}
+			if err = auditRepository.AddAuditLog(tx, entity.AuditEntityType, changeRequest.ID, audit.ActionUpdate, before, changeRequest); err != nil {
+				return err
+			}
+			changeRequests = append(changeRequests, changeRequest)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changeRequests, nil
+}
+
+func getForUpdate(tx *gorm.DB, id int64) (entity.ChangeRequest, error) {
+	changeRequest := entity.ChangeRequest{}
+	if err := withTenant(tx).Clauses(clause.Locking{Strength: "UPDATE"}).Where(`id = ?`, id).First(&changeRequest).Error; err != nil {
+		return entity.ChangeRequest{}, err
+	}
+
+	return changeRequest, nil
+}
+
+// withTenant limits the query to the change requests of the tenant of the request, checkers only decide the
+// changes of their own tenant. Expire is not limited, it runs for every tenant.
+func withTenant(db *gorm.DB) *gorm.DB {
+	return db.Where(`tenant_id = ?`, tenant.FromContext(db.Statement.Context))
+}
+
+func withStatus(query *gorm.DB, status string) *gorm.DB {
+	if status == "" {
+		return query
+	}
+
+	return query.Where(`status = ?`, status)
+}
+
+func NewChangeRequestRepository(db *gorm.DB) ChangeRequestRepository {
+	return &changeRequestRepository{
+		db: db,
+	}
+}
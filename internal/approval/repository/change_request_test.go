@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/approval/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/testutil"
+	"testing"
+	"time"
+)
+
+var changeRequestColumns = []string{"id", "operation", "tenant_id", "entity_id", "status", "maker", "expires_at"}
+
+func TestDecide(t *testing.T) {
+	ctx := tenant.NewContext(context.Background(), "acme")
+	tests := []struct {
+		name string
+		status string
+		expiresAt time.Time
+		err error
+	}{
+		{name: "expired", status: entity.StatusPending, expiresAt: time.Now().Add(-time.Second), err: ErrExpired},
+		{name: "decided", status: entity.StatusRejected, expiresAt: time.Now().Add(time.Hour), err: ErrNotPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := testutil.NewMockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT \* FROM "change_requests" WHERE tenant_id = \$1 AND id = \$2 ORDER BY "change_requests"."id" LIMIT 1 FOR UPDATE`).
+				WithArgs("acme", 4).
+				WillReturnRows(sqlmock.NewRows(changeRequestColumns).AddRow(4, "currency.delete", "acme", "9", tt.status, "alice", tt.expiresAt))
+			mock.ExpectRollback()
+
+			if _, err := NewChangeRequestRepository(db).Decide(ctx, 4, entity.StatusApproved, "bob", ""); !errors.Is(err, tt.err) {
+				t.Errorf("Decide = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	db, mock := testutil.NewMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "change_requests" WHERE tenant_id = \$1 AND id = \$2 .* FOR UPDATE`).
+		WithArgs("acme", 4).
+		WillReturnRows(sqlmock.NewRows(changeRequestColumns).AddRow(4, "currency.delete", "acme", "9", entity.StatusPending, "alice", time.Now().Add(time.Hour)))
+	mock.ExpectExec(`UPDATE "change_requests" SET .*"status"=\$\d+,"maker"=\$\d+,"checker"=\$\d+,"reason"=\$\d+`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(sqlmock.AnyArg(), entity.AuditEntityType, "4", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	decided, err := NewChangeRequestRepository(db).Decide(ctx, 4, entity.StatusApproved, "bob", "looks right")
+	if err != nil || decided.Status != entity.StatusApproved || decided.Checker != "bob" || decided.DecidedAt == nil {
+		t.Errorf("Decide = %+v, %v", decided, err)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	db, mock := testutil.NewMockDB(t)
+	before := time.Now().UTC()
+
+	// every tenant, rows other replicas hold are skipped
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "change_requests" WHERE status = \$1 AND expires_at <= \$2 ORDER BY id asc LIMIT 2 FOR UPDATE SKIP LOCKED`).
+		WithArgs(entity.StatusPending, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(changeRequestColumns).
+			AddRow(4, "currency.delete", "acme", "9", entity.StatusPending, "alice", before.Add(-time.Hour)).
+			AddRow(5, "rate.publish", "globex", "", entity.StatusPending, "dave", before.Add(-time.Minute)))
+	for _, id := range []string{"4", "5"} {
+		mock.ExpectExec(`UPDATE "change_requests" SET .*"status"=\$\d+`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+			WithArgs(sqlmock.AnyArg(), entity.AuditEntityType, id, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	}
+	mock.ExpectCommit()
+
+	expired, err := NewChangeRequestRepository(db).Expire(context.Background(), 2)
+	if err != nil || len(expired) != 2 {
+		t.Fatalf("Expire = %+v, %v", expired, err)
+	}
+	for _, changeRequest := range expired {
+		if changeRequest.Status != entity.StatusExpired {
+			t.Errorf("change request %d is %s", changeRequest.ID, changeRequest.Status)
+		}
+	}
+
+	// a failed save expires none of the batch
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "change_requests"`).
+		WillReturnRows(sqlmock.NewRows(changeRequestColumns).AddRow(4, "currency.delete", "acme", "9", entity.StatusPending, "alice", before.Add(-time.Hour)))
+	mock.ExpectExec(`UPDATE "change_requests"`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	if expired, err = NewChangeRequestRepository(db).Expire(context.Background(), 2); err == nil || expired != nil {
+		t.Errorf("Expire = %+v, %v, want the error", expired, err)
+	}
+}
@@ -0,0 +1,313 @@
+package usecase
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/approval/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/approval/mapping"
+	"github.com/sefikcan/kanbersky.ca/internal/approval/repository"
+	currencyUseCase "github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/approval"
+	currencyRequest "github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	rateRequest "github.com/sefikcan/kanbersky.ca/internal/dto/request/rate"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/approval"
+	rateUseCase "github.com/sefikcan/kanbersky.ca/internal/rate/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTtl = time.Hour * 24
+	defaultExpireInterval = time.Minute
+	expireBatchSize = 100
+)
+
+// ChangeRequestUseCase holds the changes of makers whose roles require approval until a checker approves
+// them. Approved changes apply through the currency and rate use cases, as if the checker made them.
+type ChangeRequestUseCase interface {
+	RequiresApproval(ctx context.Context, permission string) (bool, error)
+	Propose(ctx context.Context, operation string, entityId interface{}, payload any) (*response.ChangeRequestResponse, error)
+	GetById(ctx context.Context, id int64) (*response.ChangeRequestResponse, error)
+	GetAll(ctx context.Context, request *request.ChangeRequestPageableRequest) (response.ChangeRequestListResponse, error)
+	Approve(ctx context.Context, id int64, request request.ChangeRequestDecisionRequest) (*response.ChangeRequestResponse, error)
+	Reject(ctx context.Context, id int64, request request.ChangeRequestDecisionRequest) (*response.ChangeRequestResponse, error)
+	Run(ctx context.Context)
+	ExpireStale(ctx context.Context) (int, error)
+}
+
+type changeRequestUseCase struct {
+	cfg *config.Config
+	changeRequestRepository repository.ChangeRequestRepository
+	currencyUseCase currencyUseCase.CurrencyUseCase
+	rateUseCase rateUseCase.RateUseCase
+	authorizer auth.Authorizer
+	policy auth.ApprovalPolicy
+	logger logger.Logger
+}
+
+// RequiresApproval tells whether the caller has to propose changes needing the permission, callers of
+// unauthenticated requests never have to.
+func (c changeRequestUseCase) RequiresApproval(ctx context.Context, permission string) (bool, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestUseCase.RequiresApproval")
+	defer span.Finish()
+
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return false, nil
+	}
+
+	return c.policy.RequiresApproval(spanContext, principal, permission)
+}
+
+// Propose stores the change as a pending change request of the caller, payload is the request that applies it.
+func (c changeRequestUseCase) Propose(ctx context.Context, operation string, entityId interface{}, payload any) (*response.ChangeRequestResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestUseCase.Propose")
+	defer span.Finish()
+
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, util.NewHttpResponse(http.StatusUnauthorized, "the request is not authenticated", nil)
+	}
+
+	changeRequest, err := entity.NewChangeRequest(operation, entityId, payload, principal.Subject, c.ttl())
+	if err != nil {
+		return nil, err
+	}
+	// approving applies the change in the tenant of the checker, which only sees the requests of its tenant
+	changeRequest.TenantID = tenant.FromContext(spanContext)
+
+	createdChangeRequest, err := c.changeRequestRepository.Create(spanContext, changeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Infof("Change request proposed, Id: %d, Operation: %s, Maker: %s", createdChangeRequest.ID, operation, principal.Subject)
+
+	return mapping.MapDto(createdChangeRequest), nil
+}
+
+// GetById returns the change request to its maker and to checkers.
+func (c changeRequestUseCase) GetById(ctx context.Context, id int64) (*response.ChangeRequestResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestUseCase.GetById")
+	defer span.Finish()
+
+	changeRequest, err := c.changeRequestRepository.GetById(spanContext, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if principal, ok := auth.FromContext(ctx); ok && principal.Subject != changeRequest.Maker {
+		allowed, err := c.authorizer.HasPermission(spanContext, principal, auth.PermissionChangeApprove)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, util.NewHttpResponse(http.StatusForbidden, "change requests are visible to their maker and to checkers", nil)
+		}
+	}
+
+	return mapping.MapDto(changeRequest), nil
+}
+
+func (c changeRequestUseCase) GetAll(ctx context.Context, pageableRequest *request.ChangeRequestPageableRequest) (response.ChangeRequestListResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestUseCase.GetAll")
+	defer span.Finish()
+
+	if err := util.ValidateStruct(pageableRequest); err != nil {
+		return response.ChangeRequestListResponse{}, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "changeRequestUseCase.GetAll.ValidateStruct"))
+	}
+
+	pagination := util.Pagination{
+		Page: pageableRequest.Page,
+		Limit: pageableRequest.Size,
+	}
+
+	totalCount, err := c.changeRequestRepository.GetCount(spanContext, pageableRequest.Status)
+	if err != nil {
+		return response.ChangeRequestListResponse{}, err
+	}
+
+	changeRequests, err := c.changeRequestRepository.GetAll(spanContext, pageableRequest.Status, pagination)
+	if err != nil {
+		return response.ChangeRequestListResponse{}, err
+	}
+
+	return response.ChangeRequestListResponse{
+		TotalCount: totalCount,
+		TotalPages: util.GetTotalPages(totalCount, pagination.GetLimit()),
+		Page: pagination.GetPage(),
+		Limit: pagination.GetLimit(),
+		ChangeRequests: mapping.MapListDto(changeRequests),
+	}, nil
+}
+
+// Approve applies the change request and records its outcome. A change that fails to apply, e.g. because
+// the currency was deleted in the meantime, leaves the change request failed with the error.
+func (c changeRequestUseCase) Approve(ctx context.Context, id int64, decisionRequest request.ChangeRequestDecisionRequest) (*response.ChangeRequestResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestUseCase.Approve")
+	defer span.Finish()
+
+	approvedChangeRequest, err := c.decide(spanContext, id, entity.StatusApproved, decisionRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	status, lastError := entity.StatusApproved, ""
+	result, err := c.apply(spanContext, approvedChangeRequest)
+	if err != nil {
+		c.logger.Errorf("changeRequestUseCase.Approve.Apply, Id: %d, Operation: %s, Error: %s", id, approvedChangeRequest.Operation, err)
+		status, lastError = entity.StatusFailed, util.ResponseMessage(util.ParseError(err))
+		result = nil
+	}
+
+	completedChangeRequest, err := c.changeRequestRepository.Complete(spanContext, id, status, result, lastError)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapDto(completedChangeRequest), nil
+}
+
+func (c changeRequestUseCase) Reject(ctx context.Context, id int64, decisionRequest request.ChangeRequestDecisionRequest) (*response.ChangeRequestResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestUseCase.Reject")
+	defer span.Finish()
+
+	rejectedChangeRequest, err := c.decide(spanContext, id, entity.StatusRejected, decisionRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapDto(rejectedChangeRequest), nil
+}
+
+// decide checks that the caller is not the maker, four eyes need two principals.
+func (c changeRequestUseCase) decide(ctx context.Context, id int64, status string, decisionRequest request.ChangeRequestDecisionRequest) (entity.ChangeRequest, error) {
+	if err := util.ValidateStruct(&decisionRequest); err != nil {
+		return entity.ChangeRequest{}, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "changeRequestUseCase.decide.ValidateStruct"))
+	}
+
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return entity.ChangeRequest{}, util.NewHttpResponse(http.StatusUnauthorized, "the request is not authenticated", nil)
+	}
+
+	changeRequest, err := c.changeRequestRepository.GetById(ctx, id)
+	if err != nil {
+		return entity.ChangeRequest{}, err
+	}
+	if changeRequest.Maker == principal.Subject {
+		return entity.ChangeRequest{}, util.NewHttpResponse(http.StatusForbidden, "a change request can not be decided by its maker", nil)
+	}
+
+	decidedChangeRequest, err := c.changeRequestRepository.Decide(ctx, id, status, principal.Subject, decisionRequest.Reason)
+	switch {
+	case errors.Is(err, repository.ErrNotPending), errors.Is(err, repository.ErrExpired):
+		return entity.ChangeRequest{}, util.NewHttpResponse(http.StatusConflict, err.Error(), nil)
+	case err != nil:
+		return entity.ChangeRequest{}, err
+	}
+
+	c.logger.Infof("Change request %s, Id: %d, Operation: %s, Maker: %s, Checker: %s", status, id, changeRequest.Operation, changeRequest.Maker, principal.Subject)
+
+	return decidedChangeRequest, nil
+}
+
+// apply runs the proposed change, the result is what the use case returned, nil for deletes.
+func (c changeRequestUseCase) apply(ctx context.Context, changeRequest entity.ChangeRequest) (any, error) {
+	switch changeRequest.Operation {
+	case auth.PermissionCurrencyCreate:
+		createRequest := currencyRequest.CurrencyCreateRequest{}
+		if err := changeRequest.Decode(&createRequest); err != nil {
+			return nil, err
+		}
+		return c.currencyUseCase.Create(ctx, createRequest)
+	case auth.PermissionCurrencyUpdate:
+		updateRequest := currencyRequest.CurrencyUpdateRequest{}
+		if err := changeRequest.Decode(&updateRequest); err != nil {
+			return nil, err
+		}
+		return c.currencyUseCase.Update(ctx, updateRequest)
+	case auth.PermissionCurrencyDelete:
+		id, err := strconv.Atoi(changeRequest.EntityID)
+		if err != nil {
+			return nil, errors.Wrap(err, "changeRequestUseCase.apply.EntityID")
+		}
+		return nil, c.currencyUseCase.Delete(ctx, id)
+	case auth.PermissionRatePublish:
+		createRequest := rateRequest.RateCreateRequest{}
+		if err := changeRequest.Decode(&createRequest); err != nil {
+			return nil, err
+		}
+		return c.rateUseCase.Create(ctx, createRequest)
+	default:
+		return nil, errors.Errorf("changeRequestUseCase.apply: unknown operation %q", changeRequest.Operation)
+	}
+}
+
+// Run expires stale change requests until ctx is done.
+func (c changeRequestUseCase) Run(ctx context.Context) {
+	interval := defaultExpireInterval
+	if c.cfg.Approval.ExpireInterval > 0 {
+		interval = time.Second * c.cfg.Approval.ExpireInterval
+	}
+
+	c.logger.Infof("Change request expiry started, Interval: %s", interval)
+	for {
+		expired, err := c.ExpireStale(ctx)
+		if err != nil {
+			c.logger.Errorf("changeRequestUseCase.Run: %s", err)
+		}
+		if expired == expireBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			c.logger.Info("Change request expiry stopped")
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (c changeRequestUseCase) ExpireStale(ctx context.Context) (int, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "changeRequestUseCase.ExpireStale")
+	defer span.Finish()
+
+	expired, err := c.changeRequestRepository.Expire(spanContext, expireBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, changeRequest := range expired {
+		c.logger.Infof("Change request expired, Id: %d, Operation: %s, Maker: %s", changeRequest.ID, changeRequest.Operation, changeRequest.Maker)
+	}
+
+	return len(expired), nil
+}
+
+func (c changeRequestUseCase) ttl() time.Duration {
+	if c.cfg.Approval.Ttl <= 0 {
+		return defaultTtl
+	}
+
+	return time.Second * c.cfg.Approval.Ttl
+}
+
+func NewChangeRequestUseCase(cfg *config.Config, changeRequestRepository repository.ChangeRequestRepository, currencyUseCase currencyUseCase.CurrencyUseCase, rateUseCase rateUseCase.RateUseCase, authorizer auth.Authorizer, policy auth.ApprovalPolicy, logger logger.Logger) ChangeRequestUseCase {
+	return &changeRequestUseCase{
+		cfg: cfg,
+		changeRequestRepository: changeRequestRepository,
+		currencyUseCase: currencyUseCase,
+		rateUseCase: rateUseCase,
+		authorizer: authorizer,
+		policy: policy,
+		logger: logger,
+	}
+}
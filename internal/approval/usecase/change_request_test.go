@@ -0,0 +1,330 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/approval/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/approval/repository"
+	currencyUseCase "github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/approval"
+	currencyRequest "github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	currencyResponse "github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"gorm.io/gorm"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryChangeRequests decides and expires like the postgres repository, the expiry is checked against now.
+type memoryChangeRequests struct {
+	repository.ChangeRequestRepository
+	mu sync.Mutex
+	changeRequests map[int64]entity.ChangeRequest
+	now time.Time
+}
+
+func (m *memoryChangeRequests) Create(_ context.Context, changeRequest entity.ChangeRequest) (entity.ChangeRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changeRequest.ID = int64(len(m.changeRequests) + 1)
+	m.changeRequests[changeRequest.ID] = changeRequest
+	return changeRequest, nil
+}
+
+func (m *memoryChangeRequests) GetById(ctx context.Context, id int64) (entity.ChangeRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changeRequest, ok := m.changeRequests[id]
+	if !ok || changeRequest.TenantID != tenant.FromContext(ctx) {
+		return entity.ChangeRequest{}, errors.Wrap(gorm.ErrRecordNotFound, "memoryChangeRequests.GetById")
+	}
+	return changeRequest, nil
+}
+
+func (m *memoryChangeRequests) Decide(_ context.Context, id int64, status string, checker string, reason string) (entity.ChangeRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changeRequest := m.changeRequests[id]
+	switch {
+	case changeRequest.Status != entity.StatusPending:
+		return entity.ChangeRequest{}, repository.ErrNotPending
+	case !changeRequest.ExpiresAt.After(m.now):
+		return entity.ChangeRequest{}, repository.ErrExpired
+	}
+	decidedAt := m.now
+	changeRequest.Status, changeRequest.Checker, changeRequest.Reason, changeRequest.DecidedAt = status, checker, reason, &decidedAt
+	m.changeRequests[id] = changeRequest
+	return changeRequest, nil
+}
+
+func (m *memoryChangeRequests) Complete(_ context.Context, id int64, status string, outcome any, lastError string) (entity.ChangeRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changeRequest := m.changeRequests[id]
+	changeRequest.Status, changeRequest.Error = status, lastError
+	if outcome != nil {
+		var err error
+		if changeRequest.Result, err = entity.MarshalResult(outcome); err != nil {
+			return entity.ChangeRequest{}, err
+		}
+	}
+	m.changeRequests[id] = changeRequest
+	return changeRequest, nil
+}
+
+func (m *memoryChangeRequests) Expire(_ context.Context, limit int) ([]entity.ChangeRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []int64
+	for id, changeRequest := range m.changeRequests {
+		if changeRequest.Status == entity.StatusPending && !changeRequest.ExpiresAt.After(m.now) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var expired []entity.ChangeRequest
+	for _, id := range ids {
+		if len(expired) == limit {
+			break
+		}
+		changeRequest := m.changeRequests[id]
+		changeRequest.Status = entity.StatusExpired
+		m.changeRequests[id] = changeRequest
+		expired = append(expired, changeRequest)
+	}
+	return expired, nil
+}
+
+func (m *memoryChangeRequests) pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending := 0
+	for _, changeRequest := range m.changeRequests {
+		if changeRequest.Status == entity.StatusPending {
+			pending++
+		}
+	}
+	return pending
+}
+
+func (m *memoryChangeRequests) advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+}
+
+type stubCurrencies struct {
+	currencyUseCase.CurrencyUseCase
+	err error
+	deleted []int
+}
+
+func (s *stubCurrencies) Create(ctx context.Context, createRequest currencyRequest.CurrencyCreateRequest) (*currencyResponse.CurrencyResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &currencyResponse.CurrencyResponse{ID: 3, Title: createRequest.Title, IsoCode: createRequest.IsoCode, TenantID: tenant.FromContext(ctx)}, nil
+}
+
+func (s *stubCurrencies) Delete(_ context.Context, id int) error {
+	s.deleted = append(s.deleted, id)
+	return s.err
+}
+
+// checkers holds the subjects allowed to approve, every other principal is a maker.
+type checkers map[string]bool
+
+func (c checkers) HasPermission(_ context.Context, principal auth.Principal, _ string) (bool, error) {
+	return c[principal.Subject], nil
+}
+
+func (c checkers) RequiresApproval(_ context.Context, principal auth.Principal, _ string) (bool, error) {
+	return !c[principal.Subject], nil
+}
+
+func newTestUseCase(t *testing.T) (ChangeRequestUseCase, *memoryChangeRequests, *stubCurrencies) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	cfg.Approval.Ttl = 60
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	changeRequests := &memoryChangeRequests{changeRequests: map[int64]entity.ChangeRequest{}, now: time.Now()}
+	currencies := &stubCurrencies{}
+	policy := checkers{"bob": true, "carol": true}
+
+	return NewChangeRequestUseCase(cfg, changeRequests, currencies, nil, policy, policy, l), changeRequests, currencies
+}
+
+func as(subject string) context.Context {
+	return auth.NewContext(tenant.NewContext(context.Background(), "acme"), auth.Principal{Subject: subject, Tenant: "acme"})
+}
+
+func TestPropose(t *testing.T) {
+	uc, changeRequests, _ := newTestUseCase(t)
+
+	for subject, want := range map[string]bool{"alice": true, "bob": false} {
+		if required, err := uc.RequiresApproval(as(subject), auth.PermissionCurrencyCreate); err != nil || required != want {
+			t.Errorf("RequiresApproval(%s) = %v, %v, want %v", subject, required, err, want)
+		}
+	}
+	if required, err := uc.RequiresApproval(context.Background(), auth.PermissionCurrencyCreate); err != nil || required {
+		t.Errorf("RequiresApproval without a principal = %v, %v", required, err)
+	}
+
+	proposed, err := uc.Propose(as("alice"), auth.PermissionCurrencyCreate, nil, currencyRequest.CurrencyCreateRequest{Title: "Lira", IsoCode: "TRY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := changeRequests.changeRequests[proposed.ID]
+	if proposed.Status != entity.StatusPending || proposed.Maker != "alice" || stored.TenantID != "acme" || string(proposed.Payload) != `{"title":"Lira","iso_code":"TRY"}` {
+		t.Errorf("proposed = %+v, tenant %q", proposed, stored.TenantID)
+	}
+	if ttl := proposed.ExpiresAt.Sub(time.Now()); ttl <= 59*time.Second || ttl > time.Minute {
+		t.Errorf("expires in %s, want the configured minute", ttl)
+	}
+
+	if _, err = uc.Propose(context.Background(), auth.PermissionCurrencyCreate, nil, nil); util.ParseError(err).Status() != http.StatusUnauthorized {
+		t.Errorf("Propose without a principal = %v, want 401", err)
+	}
+}
+
+func TestApprove(t *testing.T) {
+	uc, _, currencies := newTestUseCase(t)
+	proposed, err := uc.Propose(as("alice"), auth.PermissionCurrencyCreate, nil, currencyRequest.CurrencyCreateRequest{Title: "Lira", IsoCode: "TRY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// four eyes, the maker can not approve its own change
+	if _, err = uc.Approve(as("alice"), proposed.ID, request.ChangeRequestDecisionRequest{}); util.ParseError(err).Status() != http.StatusForbidden {
+		t.Errorf("Approve by the maker = %v, want 403", err)
+	}
+	if _, err = uc.Approve(auth.NewContext(tenant.NewContext(context.Background(), "globex"), auth.Principal{Subject: "bob"}), proposed.ID, request.ChangeRequestDecisionRequest{}); util.ParseError(err).Status() != http.StatusNotFound {
+		t.Errorf("Approve from another tenant = %v, want 404", err)
+	}
+
+	approved, err := uc.Approve(as("bob"), proposed.ID, request.ChangeRequestDecisionRequest{Reason: "looks right"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := currencyResponse.CurrencyResponse{}
+	if err = json.Unmarshal(approved.Result, &created); err != nil || created.Title != "Lira" || created.TenantID != "acme" {
+		t.Errorf("result = %s, %v, want the currency created in the tenant of the request", approved.Result, err)
+	}
+	if approved.Status != entity.StatusApproved || approved.Checker != "bob" || approved.Reason != "looks right" || approved.DecidedAt == nil {
+		t.Errorf("approved = %+v", approved)
+	}
+
+	// only the first decision wins
+	if _, err = uc.Reject(as("carol"), proposed.ID, request.ChangeRequestDecisionRequest{}); util.ParseError(err).Status() != http.StatusConflict {
+		t.Errorf("Reject after approval = %v, want 409", err)
+	}
+
+	currencies.err = util.NewHttpResponse(http.StatusNotFound, util.NotFound.Error(), nil)
+	deleteProposal, err := uc.Propose(as("alice"), auth.PermissionCurrencyDelete, 9, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := uc.Approve(as("bob"), deleteProposal.ID, request.ChangeRequestDecisionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status != entity.StatusFailed || failed.Error != util.NotFound.Error() || failed.Result != nil || len(currencies.deleted) != 1 || currencies.deleted[0] != 9 {
+		t.Errorf("failed = %+v, deleted %v, want the delete of 9 failed with not found", failed, currencies.deleted)
+	}
+}
+
+func TestReject(t *testing.T) {
+	uc, _, currencies := newTestUseCase(t)
+	proposed, err := uc.Propose(as("alice"), auth.PermissionCurrencyDelete, 9, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = uc.Reject(as("bob"), proposed.ID, request.ChangeRequestDecisionRequest{Reason: strings.Repeat("x", 501)}); util.ParseError(err).Status() != http.StatusBadRequest {
+		t.Errorf("Reject with a long reason = %v, want 400", err)
+	}
+	rejected, err := uc.Reject(as("bob"), proposed.ID, request.ChangeRequestDecisionRequest{Reason: "not now"})
+	if err != nil || rejected.Status != entity.StatusRejected || rejected.Checker != "bob" {
+		t.Errorf("Reject = %+v, %v", rejected, err)
+	}
+	if len(currencies.deleted) != 0 {
+		t.Errorf("deleted %v, a rejected change applied", currencies.deleted)
+	}
+}
+
+func TestExpiredChangeRequestsCanNotBeDecided(t *testing.T) {
+	uc, changeRequests, currencies := newTestUseCase(t)
+	proposed, err := uc.Propose(as("alice"), auth.PermissionCurrencyDelete, 9, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// past its expiry but before the expiry loop marked it
+	changeRequests.advance(2 * time.Minute)
+	if _, err = uc.Approve(as("bob"), proposed.ID, request.ChangeRequestDecisionRequest{}); util.ParseError(err).Status() != http.StatusConflict {
+		t.Errorf("Approve after expiry = %v, want 409", err)
+	}
+
+	expired, err := uc.ExpireStale(context.Background())
+	if err != nil || expired != 1 || changeRequests.changeRequests[proposed.ID].Status != entity.StatusExpired {
+		t.Errorf("ExpireStale = %d, %v, status %s", expired, err, changeRequests.changeRequests[proposed.ID].Status)
+	}
+	if _, err = uc.Approve(as("bob"), proposed.ID, request.ChangeRequestDecisionRequest{}); util.ParseError(err).Status() != http.StatusConflict {
+		t.Errorf("Approve of an expired change request = %v, want 409", err)
+	}
+	if len(currencies.deleted) != 0 {
+		t.Errorf("deleted %v, an expired change applied", currencies.deleted)
+	}
+}
+
+func TestRunExpiresFullBatchesWithoutWaiting(t *testing.T) {
+	uc, changeRequests, _ := newTestUseCase(t)
+	for i := 0; i < expireBatchSize+5; i++ {
+		if _, err := uc.Propose(as("alice"), auth.PermissionCurrencyDelete, i, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	changeRequests.advance(2 * time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		uc.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		pending := changeRequests.pending()
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d change requests still pending, the second batch waited for the interval", pending)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
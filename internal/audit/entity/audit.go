@@ -0,0 +1,156 @@
+package entity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/audit"
+	"sort"
+	"time"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// AuditLog records one change of an entity. Rows are only ever inserted, the table rejects updates and deletes.
+type AuditLog struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	EntityType string `gorm:"index:idx_audit_entity" json:"entity_type"`
+	EntityID string `gorm:"index:idx_audit_entity" json:"entity_id"`
+	Action string `json:"action"`
+	Actor string `gorm:"index:idx_audit_actor" json:"actor"`
+	RequestID string `json:"request_id"`
+	IPAddress string `json:"ip_address"`
+	Before *string `gorm:"type:jsonb" json:"before"`
+	After *string `gorm:"type:jsonb" json:"after"`
+	Changes string `gorm:"type:jsonb" json:"changes"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// Change is the old and new json value of a changed field, null when the field did not exist.
+type Change struct {
+	Field string `json:"field"`
+	From json.RawMessage `json:"from"`
+	To json.RawMessage `json:"to"`
+}
+
+// NewAuditLog records the change of an entity from before to after, before is nil for a create and after
+// is nil for a delete. Both are compared by their json fields, fields hidden from json are never recorded.
+func NewAuditLog(ctx context.Context, entityType string, entityId interface{}, action string, before any, after any) (AuditLog, error) {
+	beforeJson, err := marshal(before)
+	if err != nil {
+		return AuditLog{}, errors.Wrap(err, "entity.NewAuditLog.Before")
+	}
+	afterJson, err := marshal(after)
+	if err != nil {
+		return AuditLog{}, errors.Wrap(err, "entity.NewAuditLog.After")
+	}
+
+	changes, err := diff(beforeJson, afterJson)
+	if err != nil {
+		return AuditLog{}, err
+	}
+	changesJson, err := json.Marshal(changes)
+	if err != nil {
+		return AuditLog{}, errors.Wrap(err, "entity.NewAuditLog.Json.Marshal")
+	}
+
+	actor := audit.FromContext(ctx)
+	return AuditLog{
+		EntityType: entityType,
+		EntityID: fmt.Sprint(entityId),
+		Action: action,
+		Actor: actor.Subject,
+		RequestID: actor.RequestId,
+		IPAddress: actor.IPAddress,
+		Before: beforeJson,
+		After: afterJson,
+		Changes: string(changesJson),
+	}, nil
+}
+
+func marshal(v any) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	value := string(data)
+
+	return &value, nil
+}
+
+// diff compares the top level fields of two json objects, a nil object has no fields.
+func diff(before *string, after *string) ([]Change, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, errors.Wrap(err, "entity.diff.Before")
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, errors.Wrap(err, "entity.diff.After")
+	}
+
+	names := make([]string, 0, len(beforeFields)+len(afterFields))
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]Change, 0, len(names))
+	for _, name := range names {
+		from, to := orNull(beforeFields[name]), orNull(afterFields[name])
+		if !bytes.Equal(from, to) {
+			changes = append(changes, Change{Field: name, From: from, To: to})
+		}
+	}
+
+	return changes, nil
+}
+
+func fields(object *string) (map[string]json.RawMessage, error) {
+	values := map[string]json.RawMessage{}
+	if object == nil {
+		return values, nil
+	}
+	if err := json.Unmarshal([]byte(*object), &values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func orNull(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+
+	return value
+}
+
+// Filter narrows audit logs down, empty fields and zero times match everything.
+type Filter struct {
+	EntityType string
+	EntityID string
+	Actor string
+	Action string
+	From time.Time
+	To time.Time
+}
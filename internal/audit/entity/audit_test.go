@@ -0,0 +1,82 @@
+package entity
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sefikcan/kanbersky.ca/pkg/audit"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"testing"
+)
+
+type key struct {
+	ID int `json:"id"`
+	Name string `json:"name"`
+	Scopes string `json:"scopes,omitempty"`
+	Hash string `json:"-"`
+}
+
+func TestNewAuditLogChanges(t *testing.T) {
+	before := key{ID: 7, Name: "importer", Hash: "old"}
+	after := key{ID: 7, Name: "exporter", Scopes: "currencies:read", Hash: "new"}
+	tests := []struct {
+		name string
+		action string
+		before any
+		after any
+		changes string
+	}{
+		{name: "create", action: ActionCreate, after: after, changes: `[{"field":"id","from":null,"to":7},{"field":"name","from":null,"to":"exporter"},{"field":"scopes","from":null,"to":"currencies:read"}]`},
+		{name: "update", action: ActionUpdate, before: before, after: after, changes: `[{"field":"name","from":"importer","to":"exporter"},{"field":"scopes","from":null,"to":"currencies:read"}]`},
+		{name: "update without changes", action: ActionUpdate, before: before, after: before, changes: `[]`},
+		{name: "delete", action: ActionDelete, before: before, changes: `[{"field":"id","from":7,"to":null},{"field":"name","from":"importer","to":null}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditLog, err := NewAuditLog(context.Background(), "api_key", 7, tt.action, tt.before, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if auditLog.Changes != tt.changes {
+				t.Errorf("changes = %s, want %s", auditLog.Changes, tt.changes)
+			}
+			if auditLog.EntityType != "api_key" || auditLog.EntityID != "7" || auditLog.Action != tt.action {
+				t.Errorf("audit log = %+v", auditLog)
+			}
+			// fields hidden from json never reach the trail
+			if (auditLog.Before == nil) != (tt.before == nil) || (auditLog.After == nil) != (tt.after == nil) {
+				t.Fatalf("before %v, after %v", auditLog.Before, auditLog.After)
+			}
+			for _, snapshot := range []*string{auditLog.Before, auditLog.After} {
+				if snapshot == nil {
+					continue
+				}
+				fields := map[string]json.RawMessage{}
+				if err = json.Unmarshal([]byte(*snapshot), &fields); err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := fields["Hash"]; ok {
+					t.Errorf("snapshot %s has the hidden field", *snapshot)
+				}
+			}
+		})
+	}
+}
+
+func TestNewAuditLogActor(t *testing.T) {
+	ctx := audit.NewContext(context.Background(), audit.Source{RequestId: "req-1", IPAddress: "10.0.0.1:4321"})
+	ctx = auth.NewContext(ctx, auth.Principal{Subject: "alice"})
+
+	auditLog, err := NewAuditLog(ctx, "currency", 7, ActionDelete, key{ID: 7}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auditLog.Actor != "alice" || auditLog.RequestID != "req-1" || auditLog.IPAddress != "10.0.0.1:4321" {
+		t.Errorf("actor %q, request id %q, ip %q", auditLog.Actor, auditLog.RequestID, auditLog.IPAddress)
+	}
+
+	// changes made outside http, e.g. over gRPC, have no source
+	if auditLog, err = NewAuditLog(context.Background(), "currency", 7, ActionDelete, key{ID: 7}, nil); err != nil || auditLog.Actor != "" || auditLog.RequestID != "" {
+		t.Errorf("audit log = %+v, %v, want no actor", auditLog, err)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/audit/usecase"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/request/audit"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+type AuditHandlers interface {
+	GetAll() echo.HandlerFunc
+}
+
+type auditHandlers struct {
+	cfg *config.Config
+	auditUseCase usecase.AuditUseCase
+	logger logger.Logger
+}
+
+// GetAll godoc
+// @Summary Get audit logs
+// @Description Get the audit trail of creates, updates and deletes with pagination, newest first, needs the audit.read permission
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Param entity query string false "entity type, e.g. currency, rate, alert_rule, webhook or api_key"
+// @Param id query string false "entity id, needs entity"
+// @Param actor query string false "subject of the caller that made the change"
+// @Param action query string false "create, update or delete"
+// @Param from query string false "changed at or after, 2006-01-02 or RFC3339"
+// @Param to query string false "changed before, 2006-01-02 or RFC3339"
+// @Param page query int false "page number" Format(page)
+// @Param limit query int false "number of elements per page" Format(limit)
+// @Success 200 {object} audit.AuditLogListResponse
+// @Failure 400 {object} util.HttpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/audit [get]
+func (a auditHandlers) GetAll() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "auditHandler.GetAll")
+		defer span.Finish()
+
+		auditPageableRequest := audit.AuditPageableRequest{
+			Entity: e.QueryParam("entity"),
+			ID: e.QueryParam("id"),
+			Actor: e.QueryParam("actor"),
+			Action: e.QueryParam("action"),
+		}
+		if page, err := strconv.Atoi(e.QueryParam("page")); err == nil {
+			auditPageableRequest.Page = page
+		}
+		if limit, err := strconv.Atoi(e.QueryParam("limit")); err == nil {
+			auditPageableRequest.Size = limit
+		}
+
+		var err error
+		if auditPageableRequest.From, err = parseDate("from", e.QueryParam("from")); err == nil {
+			auditPageableRequest.To, err = parseDate("to", e.QueryParam("to"))
+		}
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, err.Error(), nil))
+		}
+
+		auditLogList, err := a.auditUseCase.GetAll(ctx, &auditPageableRequest)
+		if err != nil {
+			util.PrepareLogging(e, a.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, auditLogList)
+	}
+}
+
+// parseDate accepts a day or an RFC3339 timestamp, an empty value is the zero time.
+func parseDate(name string, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.Parse(dateLayout, value); err == nil {
+		return date, nil
+	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a %s date or an RFC3339 timestamp", name, dateLayout)
+	}
+
+	return date, nil
+}
+
+func NewAuditHandler(cfg *config.Config, auditUseCase usecase.AuditUseCase, logger logger.Logger) AuditHandlers {
+	return &auditHandlers{
+		cfg: cfg,
+		auditUseCase: auditUseCase,
+		logger: logger,
+	}
+}
@@ -0,0 +1,7 @@
+package handlers
+
+import "github.com/labstack/echo/v4"
+
+func MapAuditRoutes(auditRouteGroup *echo.Group, a AuditHandlers) {
+	auditRouteGroup.GET("", a.GetAll())
+}
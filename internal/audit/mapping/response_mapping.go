@@ -0,0 +1,52 @@
+package mapping
+
+import (
+	"encoding/json"
+	"github.com/sefikcan/kanbersky.ca/internal/audit/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/audit"
+)
+
+func MapDto(a entity.AuditLog) *audit.AuditLogResponse {
+	var changes []entity.Change
+	_ = json.Unmarshal([]byte(a.Changes), &changes)
+
+	changeResp := make([]*audit.AuditChangeResponse, 0, len(changes))
+	for _, c := range changes {
+		changeResp = append(changeResp, &audit.AuditChangeResponse{
+			Field: c.Field,
+			From: c.From,
+			To: c.To,
+		})
+	}
+
+	return &audit.AuditLogResponse{
+		ID: a.ID,
+		EntityType: a.EntityType,
+		EntityID: a.EntityID,
+		Action: a.Action,
+		Actor: a.Actor,
+		RequestID: a.RequestID,
+		IPAddress: a.IPAddress,
+		Before: rawJson(a.Before),
+		After: rawJson(a.After),
+		Changes: changeResp,
+		CreatedAt: a.CreatedAt,
+	}
+}
+
+func MapListDto(auditLogs []entity.AuditLog) []*audit.AuditLogResponse {
+	auditLogResp := make([]*audit.AuditLogResponse, 0, len(auditLogs))
+	for _, a := range auditLogs {
+		auditLogResp = append(auditLogResp, MapDto(a))
+	}
+
+	return auditLogResp
+}
+
+func rawJson(value *string) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+
+	return json.RawMessage(*value)
+}
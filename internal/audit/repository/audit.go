@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/audit/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	GetCount(ctx context.Context, filter entity.Filter) (int64, error)
+	GetAll(ctx context.Context, filter entity.Filter, query util.Pagination) ([]entity.AuditLog, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func (a auditRepository) GetCount(ctx context.Context, filter entity.Filter) (int64, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "auditRepository.GetCount")
+	defer span.Finish()
+
+	var totalCount int64
+	if err := withFilter(a.db.WithContext(spanContext).Model(&entity.AuditLog{}), filter).Count(&totalCount).Error; err != nil {
+		return 0, errors.Wrap(err, "auditRepository.GetCount.DbError")
+	}
+
+	return totalCount, nil
+}
+
+func (a auditRepository) GetAll(ctx context.Context, filter entity.Filter, query util.Pagination) ([]entity.AuditLog, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "auditRepository.GetAll")
+	defer span.Finish()
+
+	var auditLogs []entity.AuditLog
+	err := withFilter(a.db.WithContext(spanContext), filter).
+		Offset(query.GetOffset()).Limit(query.GetLimit()).Order("id desc").Find(&auditLogs).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "auditRepository.GetAll.DbError")
+	}
+
+	return auditLogs, nil
+}
+
+func withFilter(query *gorm.DB, filter entity.Filter) *gorm.DB {
+	if filter.EntityType != "" {
+		query = query.Where(`entity_type = ?`, filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where(`entity_id = ?`, filter.EntityID)
+	}
+	if filter.Actor != "" {
+		query = query.Where(`actor = ?`, filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where(`action = ?`, filter.Action)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where(`created_at >= ?`, filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where(`created_at < ?`, filter.To)
+	}
+
+	return query
+}
+
+// AddAuditLog records the change in the transaction of the change itself, so no change is committed without
+// its audit log. The actor comes from the context of the transaction.
+func AddAuditLog(tx *gorm.DB, entityType string, entityId interface{}, action string, before any, after any) error {
+	auditLog, err := entity.NewAuditLog(tx.Statement.Context, entityType, entityId, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	if result := tx.Create(&auditLog); result.Error != nil {
+		return errors.Wrap(result.Error, "auditRepository.AddAuditLog.DbError")
+	}
+
+	return nil
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/audit/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/audit"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/testutil"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+type title struct {
+	Title string `json:"title"`
+}
+
+func TestAddAuditLogWritesInTheTransactionOfTheChange(t *testing.T) {
+	db, mock := testutil.NewMockDB(t)
+	ctx := auth.NewContext(audit.NewContext(context.Background(), audit.Source{RequestId: "req-1", IPAddress: "10.0.0.1"}), auth.Principal{Subject: "alice"})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "audit_logs" \("created_at","entity_type","entity_id","action","actor","request_id","ip_address","before","after","changes"\)`).
+		WithArgs(sqlmock.AnyArg(), "currency", "7", entity.ActionUpdate, "alice", "req-1", "10.0.0.1", `{"title":"Euro"}`, `{"title":"Euro Area"}`, `[{"field":"title","from":"Euro","to":"Euro Area"}]`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return AddAuditLog(tx, "currency", 7, entity.ActionUpdate, title{Title: "Euro"}, title{Title: "Euro Area"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed audit log fails the change with it
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).WillReturnError(errors.New("permission denied"))
+	mock.ExpectRollback()
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return AddAuditLog(tx, "currency", 7, entity.ActionDelete, title{Title: "Euro"}, nil)
+	})
+	if err == nil {
+		t.Error("the change was committed without its audit log")
+	}
+}
+
+func TestGetAllFilters(t *testing.T) {
+	db, mock := testutil.NewMockDB(t)
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	mock.ExpectQuery(`SELECT \* FROM "audit_logs" WHERE entity_type = \$1 AND entity_id = \$2 AND actor = \$3 AND action = \$4 AND created_at >= \$5 AND created_at < \$6 ORDER BY id desc LIMIT 10 OFFSET 10`).
+		WithArgs("currency", "7", "alice", entity.ActionUpdate, from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "entity_type", "entity_id"}).AddRow(12, "currency", "7"))
+	mock.ExpectQuery(`SELECT \* FROM "audit_logs" ORDER BY id desc LIMIT 10`).
+		WithArgs().
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	audits := NewAuditRepository(db)
+	auditLogs, err := audits.GetAll(context.Background(), entity.Filter{EntityType: "currency", EntityID: "7", Actor: "alice", Action: entity.ActionUpdate, From: from, To: to}, util.Pagination{Page: 2, Limit: 10})
+	if err != nil || len(auditLogs) != 1 || auditLogs[0].ID != 12 {
+		t.Fatalf("GetAll = %+v, %v", auditLogs, err)
+	}
+	if _, err = audits.GetAll(context.Background(), entity.Filter{}, util.Pagination{Limit: 10}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/audit/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/audit/mapping"
+	"github.com/sefikcan/kanbersky.ca/internal/audit/repository"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/audit"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/audit"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+)
+
+// AuditUseCase reads the audit trail, it is written by the repositories in the transaction of each change.
+type AuditUseCase interface {
+	GetAll(ctx context.Context, request *request.AuditPageableRequest) (response.AuditLogListResponse, error)
+}
+
+type auditUseCase struct {
+	cfg *config.Config
+	auditRepository repository.AuditRepository
+	logger logger.Logger
+}
+
+// GetAll returns the matching audit logs newest first.
+func (a auditUseCase) GetAll(ctx context.Context, pageableRequest *request.AuditPageableRequest) (response.AuditLogListResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "auditUseCase.GetAll")
+	defer span.Finish()
+
+	if err := util.ValidateStruct(pageableRequest); err != nil {
+		return response.AuditLogListResponse{}, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "auditUseCase.GetAll.ValidateStruct"))
+	}
+
+	filter := entity.Filter{
+		EntityType: pageableRequest.Entity,
+		EntityID: pageableRequest.ID,
+		Actor: pageableRequest.Actor,
+		Action: pageableRequest.Action,
+		From: pageableRequest.From,
+		To: pageableRequest.To,
+	}
+	pagination := util.Pagination{
+		Page: pageableRequest.Page,
+		Limit: pageableRequest.Size,
+	}
+
+	totalCount, err := a.auditRepository.GetCount(spanContext, filter)
+	if err != nil {
+		return response.AuditLogListResponse{}, err
+	}
+
+	auditLogs, err := a.auditRepository.GetAll(spanContext, filter, pagination)
+	if err != nil {
+		return response.AuditLogListResponse{}, err
+	}
+
+	return response.AuditLogListResponse{
+		TotalCount: totalCount,
+		TotalPages: util.GetTotalPages(totalCount, pagination.GetLimit()),
+		Page: pagination.GetPage(),
+		Limit: pagination.GetLimit(),
+		AuditLogs: mapping.MapListDto(auditLogs),
+	}, nil
+}
+
+func NewAuditUseCase(cfg *config.Config, auditRepository repository.AuditRepository, logger logger.Logger) AuditUseCase {
+	return &auditUseCase{
+		cfg: cfg,
+		auditRepository: auditRepository,
+		logger: logger,
+	}
+}
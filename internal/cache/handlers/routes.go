@@ -0,0 +1,8 @@
+package handlers
+
+import "github.com/labstack/echo/v4"
+
+func MapCacheRoutes(cacheRouteGroup *echo.Group, c CacheHandlers) {
+	cacheRouteGroup.POST("/warmup", c.StartWarmUp())
+	cacheRouteGroup.GET("/warmup", c.GetWarmUpProgress())
+}
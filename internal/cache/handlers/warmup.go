@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/cache/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+)
+
+type CacheHandlers interface {
+	StartWarmUp() echo.HandlerFunc
+	GetWarmUpProgress() echo.HandlerFunc
+}
+
+type cacheHandlers struct {
+	cfg *config.Config
+	cacheWarmUpUseCase usecase.CacheWarmUpUseCase
+	logger logger.Logger
+}
+
+// StartWarmUp godoc
+// @Summary Start cache warm-up
+// @Description Loads all currencies and the latest rates into the cache in the background
+// @Tags Cache
+// @Accept json
+// @Produce json
+// @Success 202 {object} cache.WarmUpResponse
+// @Failure 409 {object} cache.WarmUpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/admin/cache/warmup [post]
+func (c cacheHandlers) StartWarmUp() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, _ := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "cacheHandler.StartWarmUp")
+		defer span.Finish()
+
+		// the warm-up outlives the request, so it must not inherit its cancellation
+		progress, err := c.cacheWarmUpUseCase.StartWarmUp(context.Background())
+		if errors.Is(err, usecase.ErrWarmUpInProgress) {
+			return e.JSON(http.StatusConflict, progress)
+		}
+
+		return e.JSON(http.StatusAccepted, progress)
+	}
+}
+
+// GetWarmUpProgress godoc
+// @Summary Get cache warm-up progress
+// @Description Reports the progress of the last cache warm-up
+// @Tags Cache
+// @Accept json
+// @Produce json
+// @Success 200 {object} cache.WarmUpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/admin/cache/warmup [get]
+func (c cacheHandlers) GetWarmUpProgress() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "cacheHandler.GetWarmUpProgress")
+		defer span.Finish()
+
+		return e.JSON(http.StatusOK, c.cacheWarmUpUseCase.GetWarmUpProgress(ctx))
+	}
+}
+
+func NewCacheHandler(cfg *config.Config, cacheWarmUpUseCase usecase.CacheWarmUpUseCase, logger logger.Logger) CacheHandlers {
+	return &cacheHandlers{
+		cfg: cfg,
+		cacheWarmUpUseCase: cacheWarmUpUseCase,
+		logger: logger,
+	}
+}
@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/mapping"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+const CurrencyChangeChannel = "currency_changes"
+
+type currencyChange struct {
+	Op string `json:"op"`
+	ID int `json:"id"`
+	TenantID string `json:"tenant_id"`
+}
+
+type CacheSyncUseCase interface {
+	HandleCurrencyChange(ctx context.Context, payload string) error
+}
+
+type cacheSyncUseCase struct {
+	cfg *config.Config
+	currencyRepository repository.CurrencyRepository
+	currencyRedisRepository repository.CurrencyRedisRepository
+	logger logger.Logger
+}
+
+// HandleCurrencyChange applies a notification of the currencies table trigger to the cache,
+// so rows changed outside of the api do not stay stale until their ttl expires.
+func (c cacheSyncUseCase) HandleCurrencyChange(ctx context.Context, payload string) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "cacheSyncUseCase.HandleCurrencyChange")
+	defer span.Finish()
+
+	change := currencyChange{}
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		return errors.Wrap(err, "cacheSyncUseCase.HandleCurrencyChange.Json.Unmarshal")
+	}
+
+	key := repository.CurrencyCacheKey(change.TenantID, change.ID)
+	if change.Op == "DELETE" {
+		return c.currencyRedisRepository.Delete(spanContext, key)
+	}
+
+	currentCurrency, err := c.currencyRepository.GetById(tenant.NewContext(spanContext, change.TenantID), change.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.currencyRedisRepository.Delete(spanContext, key)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.currencyRedisRepository.Set(spanContext, key, repository.CurrencyCacheTtl, mapping.MapDto(currentCurrency))
+}
+
+func NewCacheSyncUseCase(cfg *config.Config, currencyRepository repository.CurrencyRepository, currencyRedisRepository repository.CurrencyRedisRepository, logger logger.Logger) CacheSyncUseCase {
+	return &cacheSyncUseCase{
+		cfg: cfg,
+		currencyRepository: currencyRepository,
+		currencyRedisRepository: currencyRedisRepository,
+		logger: logger,
+	}
+}
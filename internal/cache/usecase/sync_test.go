@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/repository"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func (s *stubCurrencyRepository) GetById(ctx context.Context, id int) (entity.Currency, error) {
+	for _, currency := range s.currencies {
+		if currency.ID == id && currency.TenantID == tenant.FromContext(ctx) {
+			return currency, nil
+		}
+	}
+
+	return entity.Currency{}, errors.Wrap(gorm.ErrRecordNotFound, "stubCurrencyRepository.GetById")
+}
+
+func (s *stubCurrencyCache) Set(_ context.Context, key string, _ int, param any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.values[key] = param
+
+	return nil
+}
+
+func (s *stubCurrencyCache) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+
+	return nil
+}
+
+func TestHandleCurrencyChange(t *testing.T) {
+	stale := &response.CurrencyResponse{ID: 1, Title: "Stale", TenantID: "acme"}
+	tests := []struct {
+		name string
+		payload string
+		id int
+		// cached is the title cached for the currency of acme afterwards, empty when it is evicted
+		cached string
+		fails bool
+	}{
+		{name: "update refreshes the cache", payload: `{"op":"UPDATE","id":1,"tenant_id":"acme"}`, id: 1, cached: "Currency"},
+		{name: "insert caches the row", payload: `{"op":"INSERT","id":1,"tenant_id":"acme"}`, id: 1, cached: "Currency"},
+		{name: "delete evicts", payload: `{"op":"DELETE","id":1,"tenant_id":"acme"}`, id: 1},
+		{name: "row of another tenant is not cached", payload: `{"op":"UPDATE","id":1,"tenant_id":"globex"}`, id: 1, cached: "Stale"},
+		{name: "missing row evicts", payload: `{"op":"UPDATE","id":7,"tenant_id":"acme"}`, id: 7},
+		{name: "malformed payload", payload: `{"op":`, id: 1, cached: "Stale", fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			currencyCache := &stubCurrencyCache{values: map[string]any{
+				repository.CurrencyCacheKey("acme", 1): stale,
+				repository.CurrencyCacheKey("acme", 7): stale,
+			}}
+			sync := NewCacheSyncUseCase(&config.Config{}, &stubCurrencyRepository{currencies: newCurrencies(1)}, currencyCache, newTestLogger())
+
+			err := sync.HandleCurrencyChange(context.Background(), tt.payload)
+			if (err != nil) != tt.fails {
+				t.Fatalf("err = %v, want error %t", err, tt.fails)
+			}
+
+			cached, ok := currencyCache.values[repository.CurrencyCacheKey("acme", tt.id)].(*response.CurrencyResponse)
+			switch {
+			case tt.cached == "" && ok:
+				t.Errorf("currency is still cached as %q", cached.Title)
+			case tt.cached != "" && (!ok || cached.Title != tt.cached):
+				t.Errorf("cached = %v, want %q", cached, tt.cached)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package usecase
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/mapping"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/repository"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/cache"
+	rateMapping "github.com/sefikcan/kanbersky.ca/internal/rate/mapping"
+	rateRepository "github.com/sefikcan/kanbersky.ca/internal/rate/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"sync"
+	"time"
+)
+
+const (
+	WarmUpStatusIdle = "idle"
+	WarmUpStatusRunning = "running"
+	WarmUpStatusCompleted = "completed"
+	WarmUpStatusFailed = "failed"
+
+	defaultWarmUpBatchSize = 500
+)
+
+var ErrWarmUpInProgress = errors.New("cache warm-up is already in progress")
+
+type CacheWarmUpUseCase interface {
+	StartWarmUp(ctx context.Context) (cache.WarmUpResponse, error)
+	GetWarmUpProgress(ctx context.Context) cache.WarmUpResponse
+}
+
+type cacheWarmUpUseCase struct {
+	cfg *config.Config
+	currencyRepository repository.CurrencyRepository
+	currencyRedisRepository repository.CurrencyRedisRepository
+	rateRepository rateRepository.RateRepository
+	rateRedisRepository rateRepository.RateRedisRepository
+	logger logger.Logger
+
+	mu sync.RWMutex
+	progress cache.WarmUpResponse
+}
+
+// StartWarmUp marks the warm-up as running and loads the cache in the background,
+// so callers get the initial progress back immediately.
+func (c *cacheWarmUpUseCase) StartWarmUp(ctx context.Context) (cache.WarmUpResponse, error) {
+	c.mu.Lock()
+	if c.progress.Status == WarmUpStatusRunning {
+		progress := c.progress
+		c.mu.Unlock()
+		return progress, ErrWarmUpInProgress
+	}
+
+	startedAt := time.Now().UTC()
+	c.progress = cache.WarmUpResponse{
+		Status: WarmUpStatusRunning,
+		StartedAt: &startedAt,
+	}
+	progress := c.progress
+	c.mu.Unlock()
+
+	go c.warmUp(ctx)
+
+	return progress, nil
+}
+
+func (c *cacheWarmUpUseCase) GetWarmUpProgress(ctx context.Context) cache.WarmUpResponse {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.progress
+}
+
+func (c *cacheWarmUpUseCase) warmUp(ctx context.Context) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "cacheWarmUpUseCase.warmUp")
+	defer span.Finish()
+
+	c.updateProgress(func(p *cache.WarmUpResponse) {
+		p.Total = c.currencyRepository.GetCount(spanContext)
+	})
+
+	err := c.warmUpCurrencies(spanContext)
+	if err == nil {
+		err = c.warmUpLatestRates(spanContext)
+	}
+
+	finishedAt := time.Now().UTC()
+	c.updateProgress(func(p *cache.WarmUpResponse) {
+		p.FinishedAt = &finishedAt
+		if err != nil {
+			p.Status = WarmUpStatusFailed
+			p.Error = err.Error()
+			return
+		}
+		p.Status = WarmUpStatusCompleted
+	})
+
+	if err != nil {
+		c.logger.Errorf("cacheWarmUpUseCase.warmUp: %s", err)
+		return
+	}
+
+	progress := c.GetWarmUpProgress(spanContext)
+	c.logger.Infof("Cache warm-up completed, Loaded: %d, Batches: %d, Time: %s", progress.Loaded, progress.Batches, finishedAt.Sub(*progress.StartedAt))
+}
+
+func (c *cacheWarmUpUseCase) warmUpCurrencies(ctx context.Context) error {
+	batchSize := c.batchSize()
+	lastId := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "cacheWarmUpUseCase.warmUpCurrencies.Context")
+		}
+
+		currencies, err := c.currencyRepository.GetBatch(ctx, lastId, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(currencies) == 0 {
+			return nil
+		}
+
+		params := make(map[string]any, len(currencies))
+		for _, currency := range currencies {
+			params[repository.CurrencyCacheKey(currency.TenantID, currency.ID)] = mapping.MapDto(currency)
+		}
+
+		if err = c.currencyRedisRepository.SetMany(ctx, repository.CurrencyCacheTtl, params); err != nil {
+			return err
+		}
+
+		lastId = currencies[len(currencies)-1].ID
+		c.updateProgress(func(p *cache.WarmUpResponse) {
+			p.Loaded += int64(len(currencies))
+			p.Batches++
+		})
+	}
+}
+
+func (c *cacheWarmUpUseCase) warmUpLatestRates(ctx context.Context) error {
+	rates, err := c.rateRepository.GetLatest(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	c.updateProgress(func(p *cache.WarmUpResponse) {
+		p.Total += int64(len(rates))
+	})
+
+	batchSize := c.batchSize()
+	for start := 0; start < len(rates); start += batchSize {
+		end := start + batchSize
+		if end > len(rates) {
+			end = len(rates)
+		}
+
+		if err = c.rateRedisRepository.SetLatest(ctx, rateMapping.MapListDto(rates[start:end])); err != nil {
+			return err
+		}
+
+		c.updateProgress(func(p *cache.WarmUpResponse) {
+			p.Loaded += int64(end - start)
+			p.Batches++
+		})
+	}
+
+	return nil
+}
+
+func (c *cacheWarmUpUseCase) batchSize() int {
+	if c.cfg.Cache.WarmUpBatchSize <= 0 {
+		return defaultWarmUpBatchSize
+	}
+
+	return c.cfg.Cache.WarmUpBatchSize
+}
+
+func (c *cacheWarmUpUseCase) updateProgress(update func(p *cache.WarmUpResponse)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	update(&c.progress)
+}
+
+func NewCacheWarmUpUseCase(cfg *config.Config, currencyRepository repository.CurrencyRepository, currencyRedisRepository repository.CurrencyRedisRepository, rateRepository rateRepository.RateRepository, rateRedisRepository rateRepository.RateRedisRepository, logger logger.Logger) CacheWarmUpUseCase {
+	return &cacheWarmUpUseCase{
+		cfg: cfg,
+		currencyRepository: currencyRepository,
+		currencyRedisRepository: currencyRedisRepository,
+		rateRepository: rateRepository,
+		rateRedisRepository: rateRedisRepository,
+		logger: logger,
+		progress: cache.WarmUpResponse{
+			Status: WarmUpStatusIdle,
+		},
+	}
+}
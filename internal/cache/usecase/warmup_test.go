@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/repository"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/cache"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	rateEntity "github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+	rateRepository "github.com/sefikcan/kanbersky.ca/internal/rate/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubCurrencyRepository serves currencies in id order, the methods it does not override panic.
+type stubCurrencyRepository struct {
+	repository.CurrencyRepository
+	currencies []entity.Currency
+	// release blocks GetBatch until it is closed, when set
+	release chan struct{}
+}
+
+func (s *stubCurrencyRepository) GetCount(_ context.Context) int64 {
+	return int64(len(s.currencies))
+}
+
+func (s *stubCurrencyRepository) GetBatch(_ context.Context, afterId int, size int) ([]entity.Currency, error) {
+	if s.release != nil {
+		<-s.release
+	}
+
+	var batch []entity.Currency
+	for _, currency := range s.currencies {
+		if currency.ID > afterId && len(batch) < size {
+			batch = append(batch, currency)
+		}
+	}
+
+	return batch, nil
+}
+
+// stubCurrencyCache keeps the cached currencies by key and counts the batches written to it.
+type stubCurrencyCache struct {
+	repository.CurrencyRedisRepository
+	mu sync.Mutex
+	values map[string]any
+	batches int
+	err error
+}
+
+func (s *stubCurrencyCache) SetMany(_ context.Context, _ int, params map[string]any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	for key, param := range params {
+		s.values[key] = param
+	}
+	s.batches++
+
+	return nil
+}
+
+func (s *stubCurrencyCache) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.values)
+}
+
+type stubRateRepository struct {
+	rateRepository.RateRepository
+	rates []rateEntity.Rate
+}
+
+func (s *stubRateRepository) GetLatest(_ context.Context, _ []rateEntity.Pair) ([]rateEntity.Rate, error) {
+	return s.rates, nil
+}
+
+type stubRateCache struct {
+	rateRepository.RateRedisRepository
+	mu sync.Mutex
+	rates []*rate.RateResponse
+}
+
+func (s *stubRateCache) SetLatest(_ context.Context, rates []*rate.RateResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rates = append(s.rates, rates...)
+
+	return nil
+}
+
+func newTestLogger() logger.Logger {
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	return l
+}
+
+func newCurrencies(count int) []entity.Currency {
+	currencies := make([]entity.Currency, 0, count)
+	for id := 1; id <= count; id++ {
+		currencies = append(currencies, entity.Currency{ID: id, Title: "Currency", IsoCode: "C", TenantID: "acme"})
+	}
+
+	return currencies
+}
+
+// waitForWarmUp polls the progress until the warm-up is no longer running.
+func waitForWarmUp(t *testing.T, warmUp CacheWarmUpUseCase) cache.WarmUpResponse {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		if progress := warmUp.GetWarmUpProgress(context.Background()); progress.Status != WarmUpStatusRunning {
+			return progress
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+	t.Fatal("warm-up did not finish")
+
+	return cache.WarmUpResponse{}
+}
+
+func TestWarmUpLoadsEveryBatch(t *testing.T) {
+	cfg := &config.Config{Cache: config.CacheConfig{WarmUpBatchSize: 2}}
+	currencies := &stubCurrencyRepository{currencies: newCurrencies(5)}
+	currencyCache := &stubCurrencyCache{values: map[string]any{}}
+	rates := &stubRateRepository{rates: []rateEntity.Rate{
+		{BaseCode: "USD", QuoteCode: "TRY", Value: 32},
+		{BaseCode: "EUR", QuoteCode: "TRY", Value: 35},
+		{BaseCode: "GBP", QuoteCode: "TRY", Value: 41},
+	}}
+	rateCache := &stubRateCache{}
+	warmUp := NewCacheWarmUpUseCase(cfg, currencies, currencyCache, rates, rateCache, newTestLogger())
+
+	if progress := warmUp.GetWarmUpProgress(context.Background()); progress.Status != WarmUpStatusIdle {
+		t.Fatalf("status before start = %s, want %s", progress.Status, WarmUpStatusIdle)
+	}
+	if _, err := warmUp.StartWarmUp(context.Background()); err != nil {
+		t.Fatalf("StartWarmUp: %v", err)
+	}
+
+	progress := waitForWarmUp(t, warmUp)
+	if progress.Status != WarmUpStatusCompleted {
+		t.Fatalf("status = %s (%s), want %s", progress.Status, progress.Error, WarmUpStatusCompleted)
+	}
+	// 5 currencies in batches of 2 and 3 rates in batches of 2
+	if progress.Total != 8 || progress.Loaded != 8 || progress.Batches != 5 {
+		t.Errorf("total %d, loaded %d, batches %d, want 8, 8 and 5", progress.Total, progress.Loaded, progress.Batches)
+	}
+	if progress.StartedAt == nil || progress.FinishedAt == nil {
+		t.Errorf("started at %v, finished at %v, want both set", progress.StartedAt, progress.FinishedAt)
+	}
+	if currencyCache.len() != 5 || currencyCache.batches != 3 {
+		t.Errorf("cached %d currencies in %d batches, want 5 in 3", currencyCache.len(), currencyCache.batches)
+	}
+	if _, ok := currencyCache.values[repository.CurrencyCacheKey("acme", 5)]; !ok {
+		t.Error("the last currency is not cached under its tenant key")
+	}
+	if len(rateCache.rates) != 3 {
+		t.Errorf("cached %d rates, want 3", len(rateCache.rates))
+	}
+}
+
+func TestWarmUpRunsOnce(t *testing.T) {
+	currencies := &stubCurrencyRepository{currencies: newCurrencies(1), release: make(chan struct{})}
+	warmUp := NewCacheWarmUpUseCase(&config.Config{}, currencies, &stubCurrencyCache{values: map[string]any{}}, &stubRateRepository{}, &stubRateCache{}, newTestLogger())
+
+	if _, err := warmUp.StartWarmUp(context.Background()); err != nil {
+		t.Fatalf("StartWarmUp: %v", err)
+	}
+	progress, err := warmUp.StartWarmUp(context.Background())
+	if !errors.Is(err, ErrWarmUpInProgress) || progress.Status != WarmUpStatusRunning {
+		t.Errorf("second start = %s, %v, want running and %v", progress.Status, err, ErrWarmUpInProgress)
+	}
+
+	close(currencies.release)
+	if progress = waitForWarmUp(t, warmUp); progress.Status != WarmUpStatusCompleted {
+		t.Fatalf("status = %s, want %s", progress.Status, WarmUpStatusCompleted)
+	}
+	// a finished warm-up can be started again
+	if _, err = warmUp.StartWarmUp(context.Background()); err != nil {
+		t.Errorf("restart: %v", err)
+	}
+	waitForWarmUp(t, warmUp)
+}
+
+func TestWarmUpReportsFailures(t *testing.T) {
+	currencyCache := &stubCurrencyCache{values: map[string]any{}, err: errors.New("redis is down")}
+	warmUp := NewCacheWarmUpUseCase(&config.Config{}, &stubCurrencyRepository{currencies: newCurrencies(3)}, currencyCache, &stubRateRepository{}, &stubRateCache{}, newTestLogger())
+
+	if _, err := warmUp.StartWarmUp(context.Background()); err != nil {
+		t.Fatalf("StartWarmUp: %v", err)
+	}
+
+	progress := waitForWarmUp(t, warmUp)
+	if progress.Status != WarmUpStatusFailed || progress.Error != "redis is down" {
+		t.Errorf("status = %s (%q), want %s with the cache error", progress.Status, progress.Error, WarmUpStatusFailed)
+	}
+	if progress.Loaded != 0 || progress.FinishedAt == nil {
+		t.Errorf("loaded %d, finished at %v, want nothing loaded and a finish time", progress.Loaded, progress.FinishedAt)
+	}
+}
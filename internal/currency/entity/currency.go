@@ -8,6 +8,7 @@ type Currency struct {
 	ID int `gorm:"primary_key" json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-	Title string `gorm:"index:idx_title,unique" json:"title"`
-	IsoCode string `gorm:"index:idx_iso_code,unique" json:"iso_code"`
+	TenantID string `gorm:"index:idx_currency_tenant_title,unique,priority:1;index:idx_currency_tenant_iso_code,unique,priority:1" json:"tenant_id"`
+	Title string `gorm:"index:idx_currency_tenant_title,unique,priority:2" json:"title"`
+	IsoCode string `gorm:"index:idx_currency_tenant_iso_code,unique,priority:2" json:"iso_code"`
 }
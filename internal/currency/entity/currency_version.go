@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// CurrencyVersion is the state of a currency during [ValidFrom, ValidTo), ValidTo is nil for the current
+// version. Updates close the current version and open the next one, deletes only close it.
+type CurrencyVersion struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	CurrencyID int `gorm:"index:idx_currency_version,unique" json:"currency_id"`
+	Version int `gorm:"index:idx_currency_version,unique" json:"version"`
+	TenantID string `json:"tenant_id"`
+	Title string `json:"title"`
+	IsoCode string `json:"iso_code"`
+	ValidFrom time.Time `json:"valid_from"`
+	ValidTo *time.Time `json:"valid_to"`
+}
+
+func (CurrencyVersion) TableName() string {
+	return "currency_versions"
+}
+
+// NewCurrencyVersion opens version of currency, valid from at.
+func NewCurrencyVersion(currency Currency, version int, at time.Time) CurrencyVersion {
+	return CurrencyVersion{
+		CurrencyID: currency.ID,
+		Version: version,
+		TenantID: currency.TenantID,
+		Title: currency.Title,
+		IsoCode: currency.IsoCode,
+		ValidFrom: at,
+	}
+}
@@ -0,0 +1,9 @@
+package entity
+
+const (
+	AggregateType = "currency"
+
+	CurrencyCreatedEvent = "currency.created"
+	CurrencyUpdatedEvent = "currency.updated"
+	CurrencyDeletedEvent = "currency.deleted"
+)
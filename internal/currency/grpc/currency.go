@@ -0,0 +1,225 @@
+package grpc
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	approvalResponse "github.com/sefikcan/kanbersky.ca/internal/dto/response/approval"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"github.com/sefikcan/kanbersky.ca/internal/interceptor"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	currencyv1 "github.com/sefikcan/kanbersky.ca/pkg/pb/currency/v1"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	googleGrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strconv"
+)
+
+const (
+	// MetadataChangeRequestId is the trailer naming the change request a mutation was turned into.
+	MetadataChangeRequestId = "change-request-id"
+	defaultPage = 1
+	defaultSize = 10
+)
+
+// MethodPermissions are the scopes and permissions of the methods, the same ones the currency routes check.
+var MethodPermissions = map[string]interceptor.MethodPermission{
+	currencyv1.CurrencyService_GetCurrency_FullMethodName: {Scope: auth.ScopeCurrenciesRead, Permission: auth.PermissionCurrencyRead},
+	currencyv1.CurrencyService_ListCurrencies_FullMethodName: {Scope: auth.ScopeCurrenciesRead, Permission: auth.PermissionCurrencyRead},
+	currencyv1.CurrencyService_CreateCurrency_FullMethodName: {Scope: auth.ScopeCurrenciesWrite, Permission: auth.PermissionCurrencyCreate},
+	currencyv1.CurrencyService_UpdateCurrency_FullMethodName: {Scope: auth.ScopeCurrenciesWrite, Permission: auth.PermissionCurrencyUpdate},
+	currencyv1.CurrencyService_DeleteCurrency_FullMethodName: {Scope: auth.ScopeCurrenciesWrite, Permission: auth.PermissionCurrencyDelete},
+}
+
+// ChangeProposer holds the changes of callers whose roles require approval, e.g. ChangeRequestUseCase.
+type ChangeProposer interface {
+	RequiresApproval(ctx context.Context, permission string) (bool, error)
+	Propose(ctx context.Context, operation string, entityId interface{}, payload any) (*approvalResponse.ChangeRequestResponse, error)
+}
+
+// currencyServer exposes CurrencyUseCase over gRPC, it is the gRPC counterpart of the currency handlers.
+// Creates, updates and deletes are refused unless grpc.mutations is set.
+type currencyServer struct {
+	currencyv1.UnimplementedCurrencyServiceServer
+	cfg *config.Config
+	currencyUseCase usecase.CurrencyUseCase
+	changeProposer ChangeProposer
+	logger logger.Logger
+}
+
+func (c currencyServer) CreateCurrency(ctx context.Context, req *currencyv1.CreateCurrencyRequest) (*currencyv1.Currency, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyServer.CreateCurrency")
+	defer span.Finish()
+
+	if err := c.checkMutations(); err != nil {
+		return nil, err
+	}
+
+	createRequest := request.CurrencyCreateRequest{
+		Title: req.GetTitle(),
+		IsoCode: req.GetIsoCode(),
+	}
+	// validated up front, a change request that can never apply is not proposed
+	if err := util.ValidateStruct(&createRequest); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := c.propose(spanContext, auth.PermissionCurrencyCreate, nil, createRequest); err != nil {
+		return nil, err
+	}
+
+	createdCurrency, err := c.currencyUseCase.Create(spanContext, createRequest)
+	if err != nil {
+		return nil, util.ParseGrpcError(err)
+	}
+
+	return mapCurrency(createdCurrency), nil
+}
+
+func (c currencyServer) UpdateCurrency(ctx context.Context, req *currencyv1.UpdateCurrencyRequest) (*currencyv1.Currency, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyServer.UpdateCurrency")
+	defer span.Finish()
+
+	if err := c.checkMutations(); err != nil {
+		return nil, err
+	}
+
+	updateRequest := request.CurrencyUpdateRequest{
+		ID: int(req.GetId()),
+		Title: req.GetTitle(),
+		IsoCode: req.GetIsoCode(),
+	}
+	if err := c.propose(spanContext, auth.PermissionCurrencyUpdate, updateRequest.ID, updateRequest); err != nil {
+		return nil, err
+	}
+
+	updatedCurrency, err := c.currencyUseCase.Update(spanContext, updateRequest)
+	if err != nil {
+		return nil, util.ParseGrpcError(err)
+	}
+
+	return mapCurrency(updatedCurrency), nil
+}
+
+func (c currencyServer) GetCurrency(ctx context.Context, req *currencyv1.GetCurrencyRequest) (*currencyv1.Currency, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyServer.GetCurrency")
+	defer span.Finish()
+
+	currentCurrency, err := c.currencyUseCase.GetById(spanContext, int(req.GetId()))
+	if err != nil {
+		return nil, util.ParseGrpcError(err)
+	}
+
+	return mapCurrency(currentCurrency), nil
+}
+
+func (c currencyServer) DeleteCurrency(ctx context.Context, req *currencyv1.DeleteCurrencyRequest) (*currencyv1.DeleteCurrencyResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyServer.DeleteCurrency")
+	defer span.Finish()
+
+	if err := c.checkMutations(); err != nil {
+		return nil, err
+	}
+	if err := c.propose(spanContext, auth.PermissionCurrencyDelete, int(req.GetId()), nil); err != nil {
+		return nil, err
+	}
+
+	if err := c.currencyUseCase.Delete(spanContext, int(req.GetId())); err != nil {
+		return nil, util.ParseGrpcError(err)
+	}
+
+	return &currencyv1.DeleteCurrencyResponse{}, nil
+}
+
+func (c currencyServer) ListCurrencies(ctx context.Context, req *currencyv1.ListCurrenciesRequest) (*currencyv1.ListCurrenciesResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyServer.ListCurrencies")
+	defer span.Finish()
+
+	if req.GetPage() < 0 || req.GetSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page and size must not be negative")
+	}
+	// unset page and size get the defaults of the http handler
+	pageableRequest := request.CurrencyPageableRequest{
+		Page: int(req.GetPage()),
+		Size: int(req.GetSize()),
+		OrderBy: req.GetOrderBy(),
+	}
+	if pageableRequest.Page == 0 {
+		pageableRequest.Page = defaultPage
+	}
+	if pageableRequest.Size == 0 {
+		pageableRequest.Size = defaultSize
+	}
+
+	currencyList, err := c.currencyUseCase.GetAll(spanContext, &pageableRequest)
+	if err != nil {
+		return nil, util.ParseGrpcError(err)
+	}
+
+	currencies := make([]*currencyv1.Currency, 0, len(currencyList.Currencies))
+	for _, currency := range currencyList.Currencies {
+		currencies = append(currencies, mapCurrency(currency))
+	}
+
+	return &currencyv1.ListCurrenciesResponse{
+		TotalCount: currencyList.TotalCount,
+		TotalPages: int32(currencyList.TotalPages),
+		Page: int32(currencyList.Page),
+		Limit: int32(currencyList.Limit),
+		Currencies: currencies,
+	}, nil
+}
+
+func (c currencyServer) checkMutations() error {
+	if !c.cfg.Grpc.Mutations {
+		return status.Error(codes.PermissionDenied, "changes are disabled on grpc, use the http api")
+	}
+
+	return nil
+}
+
+// propose turns the change of a caller whose role requires approval into a change request, like the approval
+// handlers do for http. The change does not apply, the caller gets FailedPrecondition with the id of the change
+// request in the change-request-id trailer. A nil error lets the change apply right away.
+func (c currencyServer) propose(ctx context.Context, permission string, entityId interface{}, payload any) error {
+	requiresApproval, err := c.changeProposer.RequiresApproval(ctx, permission)
+	if err != nil {
+		return util.ParseGrpcError(err)
+	}
+	if !requiresApproval {
+		return nil
+	}
+
+	changeRequest, err := c.changeProposer.Propose(ctx, permission, entityId, payload)
+	if err != nil {
+		return util.ParseGrpcError(err)
+	}
+
+	changeRequestId := strconv.FormatInt(changeRequest.ID, 10)
+	if err = googleGrpc.SetTrailer(ctx, metadata.Pairs(MetadataChangeRequestId, changeRequestId)); err != nil {
+		c.logger.Warnf("currencyServer.propose.SetTrailer: %s", err)
+	}
+
+	return status.Errorf(codes.FailedPrecondition, "change request %s is pending approval", changeRequestId)
+}
+
+func mapCurrency(currency *response.CurrencyResponse) *currencyv1.Currency {
+	return &currencyv1.Currency{
+		Id: int64(currency.ID),
+		Title: currency.Title,
+		IsoCode: currency.IsoCode,
+	}
+}
+
+func NewCurrencyServer(cfg *config.Config, currencyUseCase usecase.CurrencyUseCase, changeProposer ChangeProposer, logger logger.Logger) currencyv1.CurrencyServiceServer {
+	return &currencyServer{
+		cfg: cfg,
+		currencyUseCase: currencyUseCase,
+		changeProposer: changeProposer,
+		logger: logger,
+	}
+}
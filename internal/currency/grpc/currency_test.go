@@ -0,0 +1,167 @@
+package grpc
+
+import (
+	"context"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	approvalResponse "github.com/sefikcan/kanbersky.ca/internal/dto/response/approval"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	currencyv1 "github.com/sefikcan/kanbersky.ca/pkg/pb/currency/v1"
+	googleGrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+// stubCurrencyUseCase records the changes that reached the use case, the methods it does not override panic.
+type stubCurrencyUseCase struct {
+	usecase.CurrencyUseCase
+	created []request.CurrencyCreateRequest
+	deleted []int
+	pages []request.CurrencyPageableRequest
+}
+
+func (s *stubCurrencyUseCase) Create(_ context.Context, createRequest request.CurrencyCreateRequest) (*response.CurrencyResponse, error) {
+	s.created = append(s.created, createRequest)
+	return &response.CurrencyResponse{ID: 1, Title: createRequest.Title, IsoCode: createRequest.IsoCode}, nil
+}
+
+func (s *stubCurrencyUseCase) Delete(_ context.Context, id int) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func (s *stubCurrencyUseCase) GetAll(_ context.Context, pageableRequest *request.CurrencyPageableRequest) (response.CurrencyListResponse, error) {
+	s.pages = append(s.pages, *pageableRequest)
+	return response.CurrencyListResponse{TotalCount: 25, TotalPages: 3, Page: pageableRequest.Page, Limit: pageableRequest.Size}, nil
+}
+
+type stubProposer struct {
+	requiresApproval bool
+	proposed []string
+}
+
+func (s *stubProposer) RequiresApproval(context.Context, string) (bool, error) {
+	return s.requiresApproval, nil
+}
+
+func (s *stubProposer) Propose(_ context.Context, operation string, _ interface{}, _ any) (*approvalResponse.ChangeRequestResponse, error) {
+	s.proposed = append(s.proposed, operation)
+	return &approvalResponse.ChangeRequestResponse{ID: 42, Operation: operation}, nil
+}
+
+func newTestClient(t *testing.T, cfg *config.Config, currencyUseCase usecase.CurrencyUseCase, proposer ChangeProposer) currencyv1.CurrencyServiceClient {
+	t.Helper()
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	listener := bufconn.Listen(1 << 20)
+	server := googleGrpc.NewServer()
+	currencyv1.RegisterCurrencyServiceServer(server, NewCurrencyServer(cfg, currencyUseCase, proposer, l))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := googleGrpc.Dial("bufnet",
+		googleGrpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		googleGrpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return currencyv1.NewCurrencyServiceClient(conn)
+}
+
+func TestListCurrenciesPaging(t *testing.T) {
+	currencies := &stubCurrencyUseCase{}
+	client := newTestClient(t, &config.Config{}, currencies, &stubProposer{})
+
+	list, err := client.ListCurrencies(context.Background(), &currencyv1.ListCurrenciesRequest{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if got := currencies.pages[0]; got.Page != 1 || got.Size != 10 {
+		t.Errorf("use case got page %d size %d, want page 1 size 10", got.Page, got.Size)
+	}
+	if list.GetPage() != 1 || list.GetLimit() != 10 || list.GetTotalPages() != 3 {
+		t.Errorf("list = %v", list)
+	}
+
+	if _, err = client.ListCurrencies(context.Background(), &currencyv1.ListCurrenciesRequest{Page: 2, Size: 5}); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if got := currencies.pages[1]; got.Page != 2 || got.Size != 5 {
+		t.Errorf("use case got page %d size %d, want page 2 size 5", got.Page, got.Size)
+	}
+
+	for _, req := range []*currencyv1.ListCurrenciesRequest{{Page: -1}, {Size: -1}} {
+		_, err = client.ListCurrencies(context.Background(), req)
+		if code := status.Code(err); code != codes.InvalidArgument {
+			t.Errorf("%v: code = %s, want %s", req, code, codes.InvalidArgument)
+		}
+	}
+	if len(currencies.pages) != 2 {
+		t.Errorf("negative paging reached the use case")
+	}
+}
+
+func TestMutationsAreDisabledByDefault(t *testing.T) {
+	currencies := &stubCurrencyUseCase{}
+	client := newTestClient(t, &config.Config{}, currencies, &stubProposer{})
+
+	_, err := client.CreateCurrency(context.Background(), &currencyv1.CreateCurrencyRequest{Title: "Euro", IsoCode: "EUR"})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Fatalf("create: code = %s, want %s", code, codes.PermissionDenied)
+	}
+	_, err = client.DeleteCurrency(context.Background(), &currencyv1.DeleteCurrencyRequest{Id: 1})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Fatalf("delete: code = %s, want %s", code, codes.PermissionDenied)
+	}
+	if len(currencies.created) > 0 || len(currencies.deleted) > 0 {
+		t.Error("a change reached the use case")
+	}
+}
+
+func TestMutationsApplyWithoutApproval(t *testing.T) {
+	currencies := &stubCurrencyUseCase{}
+	client := newTestClient(t, &config.Config{Grpc: config.GrpcConfig{Mutations: true}}, currencies, &stubProposer{})
+
+	created, err := client.CreateCurrency(context.Background(), &currencyv1.CreateCurrencyRequest{Title: "Euro", IsoCode: "EUR"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.GetIsoCode() != "EUR" || len(currencies.created) != 1 {
+		t.Errorf("created = %v, use case got %d creates", created, len(currencies.created))
+	}
+
+	_, err = client.CreateCurrency(context.Background(), &currencyv1.CreateCurrencyRequest{Title: "E", IsoCode: "EUR"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("invalid create: code = %s, want %s", code, codes.InvalidArgument)
+	}
+}
+
+func TestMutationsOfMakersBecomeChangeRequests(t *testing.T) {
+	currencies := &stubCurrencyUseCase{}
+	proposer := &stubProposer{requiresApproval: true}
+	client := newTestClient(t, &config.Config{Grpc: config.GrpcConfig{Mutations: true}}, currencies, proposer)
+
+	var trailer metadata.MD
+	_, err := client.DeleteCurrency(context.Background(), &currencyv1.DeleteCurrencyRequest{Id: 7}, googleGrpc.Trailer(&trailer))
+	if code := status.Code(err); code != codes.FailedPrecondition {
+		t.Fatalf("code = %s, want %s", code, codes.FailedPrecondition)
+	}
+	if got := trailer.Get(MetadataChangeRequestId); len(got) != 1 || got[0] != "42" {
+		t.Errorf("%s trailer = %v, want [42]", MetadataChangeRequestId, got)
+	}
+	if len(proposer.proposed) != 1 || len(currencies.deleted) != 0 {
+		t.Errorf("proposed %d changes, use case got %d deletes", len(proposer.proposed), len(currencies.deleted))
+	}
+}
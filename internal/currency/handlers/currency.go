@@ -1,24 +1,31 @@
 package handlers
 
 import (
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/mapping"
 	"github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
 	"github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	"github.com/sefikcan/kanbersky.ca/pkg/codec"
 	"github.com/sefikcan/kanbersky.ca/pkg/config"
 	"github.com/sefikcan/kanbersky.ca/pkg/logger"
 	"github.com/sefikcan/kanbersky.ca/pkg/util"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
+const dateLayout = "2006-01-02"
+
 type CurrencyHandlers interface {
 	Create() echo.HandlerFunc
 	Update() echo.HandlerFunc
 	GetById() echo.HandlerFunc
 	Delete() echo.HandlerFunc
 	GetAll() echo.HandlerFunc
+	Versions() echo.HandlerFunc
 }
 
 type currencyHandlers struct {
@@ -29,13 +36,21 @@ type currencyHandlers struct {
 
 // Create godoc
 // @Summary Create currency
-// @Description Create currency handler
+// @Description Create currency handler, needs the currency.create permission
 // @Tags Currency
-// @Accept json
-// @Produce json
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
 // @Param currencyCreateRequest body currency.CurrencyCreateRequest true "Create Currency"
-// @Success 201 {object} currency.CurrencyResponse
-// @Router /currencies [post]
+// @Param Idempotency-Key header string false "retries with the same key replay the first response"
+// @Success 201 {object} currency.CurrencyV1Response
+// @Success 202 {object} approval.ChangeRequestResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Failure 409 {object} util.HttpResponse
+// @Failure 422 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/currencies [post]
 func (c currencyHandlers) Create() echo.HandlerFunc {
 	return func(e echo.Context) error {
 		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "currencyHandler.Create")
@@ -44,29 +59,35 @@ func (c currencyHandlers) Create() echo.HandlerFunc {
 		currencyRequest := currency.CurrencyCreateRequest{}
 		if err := e.Bind(&currencyRequest); err != nil {
 			util.PrepareLogging(e, c.logger, err)
-			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()),nil))
+			resp := util.ParseBindError(err)
+			return codec.Render(e, resp.Status(), resp)
 		}
 
 		createdCurrency, err := c.currencyUseCase.Create(ctx, currencyRequest)
 		if err != nil {
 			util.PrepareLogging(e, c.logger, err)
-			return e.JSON(http.StatusInternalServerError, util.NewHttpResponse(http.StatusInternalServerError, strings.ToLower(err.Error()),nil))
+			return codec.Render(e, http.StatusInternalServerError, util.NewHttpResponse(http.StatusInternalServerError, strings.ToLower(err.Error()),nil))
 		}
 
-		return e.JSON(http.StatusCreated, createdCurrency)
+		return codec.Render(e, http.StatusCreated, mapping.MapV1Dto(createdCurrency))
 	}
 }
 
 // Update godoc
 // @Summary Update currencies
-// @Description Update currency handler
+// @Description Update currency handler, needs the currency.update permission
 // @Tags Currency
-// @Accept json
-// @Produce json
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
 // @Param id path int true "id"
 // @Param currencyUpdateRequest body currency.CurrencyUpdateRequest true "Update Currency"
-// @Success 200 {object} currency.CurrencyResponse
-// @Router /currencies/{id} [put]
+// @Success 200 {object} currency.CurrencyV1Response
+// @Success 202 {object} approval.ChangeRequestResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/currencies/{id} [put]
 func (c currencyHandlers) Update() echo.HandlerFunc {
 	return func(e echo.Context) error {
 		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "currencyHandler.Update")
@@ -75,35 +96,43 @@ func (c currencyHandlers) Update() echo.HandlerFunc {
 		id, err := strconv.Atoi(e.Param("id"))
 		if err != nil {
 			util.PrepareLogging(e, c.logger, err)
-			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()),nil))
+			return codec.Render(e, http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()),nil))
 		}
 
 		currency := currency.CurrencyUpdateRequest{}
 		if err = e.Bind(&currency); err != nil {
 			util.PrepareLogging(e, c.logger, err)
-			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()),nil))
+			resp := util.ParseBindError(err)
+			return codec.Render(e, resp.Status(), resp)
 		}
 
 		currency.ID = id
 		updatedCurrency, err := c.currencyUseCase.Update(ctx, currency)
 		if err != nil {
 			util.PrepareLogging(e, c.logger, err)
-			return e.JSON(http.StatusInternalServerError, util.NewHttpResponse(http.StatusInternalServerError, strings.ToLower(err.Error()),nil))
+			return codec.Render(e, http.StatusInternalServerError, util.NewHttpResponse(http.StatusInternalServerError, strings.ToLower(err.Error()),nil))
 		}
 
-		return e.JSON(http.StatusOK, updatedCurrency)
+		return codec.Render(e, http.StatusOK, mapping.MapV1Dto(updatedCurrency))
 	}
 }
 
 // GetById godoc
 // @Summary Get by id currency
-// @Description Get by id currency handler
+// @Description Get by id currency handler, needs the currency.read permission. With as_of the currency is returned as it was at that time
 // @Tags Currencies
-// @Accept json
-// @Produce json
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
 // @Param id path int true "id"
-// @Success 200 {object} currency.CurrencyResponse
-// @Router /currencies/{id} [get]
+// @Param as_of query string false "a 2006-01-02 date (00:00 UTC) or an RFC3339 timestamp"
+// @Success 200 {object} currency.CurrencyV1Response
+// @Failure 400 {object} util.HttpResponse
+// @Failure 404 {object} util.HttpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/currencies/{id} [get]
 func (c currencyHandlers) GetById() echo.HandlerFunc {
 	return func(e echo.Context) error {
 		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "currencyHandler.GetById")
@@ -112,28 +141,50 @@ func (c currencyHandlers) GetById() echo.HandlerFunc {
 		id, err := strconv.Atoi(e.Param("id"))
 		if err != nil {
 			util.PrepareLogging(e, c.logger, err)
-			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()),nil))
+			return codec.Render(e, http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()),nil))
+		}
+
+		if e.QueryParam("as_of") != "" {
+			asOf, err := parseAsOf(e.QueryParam("as_of"))
+			if err != nil {
+				util.PrepareLogging(e, c.logger, err)
+				return codec.Render(e, http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, err.Error(), nil))
+			}
+
+			version, err := c.currencyUseCase.GetAsOf(ctx, id, asOf)
+			if err != nil {
+				util.PrepareLogging(e, c.logger, err)
+				resp := util.ParseError(err)
+				return codec.Render(e, resp.Status(), resp)
+			}
+
+			return codec.Render(e, http.StatusOK, mapping.MapVersionV1Dto(version))
 		}
 
 		currencyCurrency, err := c.currencyUseCase.GetById(ctx, id)
 		if err != nil {
 			util.PrepareLogging(e, c.logger, err)
-			return e.JSON(http.StatusInternalServerError, util.NewHttpResponse(http.StatusInternalServerError, strings.ToLower(err.Error()),nil))
+			return codec.Render(e, http.StatusInternalServerError, util.NewHttpResponse(http.StatusInternalServerError, strings.ToLower(err.Error()),nil))
 		}
 
-		return e.JSON(http.StatusOK, currencyCurrency)
+		return codec.Render(e, http.StatusOK, mapping.MapV1Dto(currencyCurrency))
 	}
 }
 
 // Delete godoc
 // @Summary Delete currency
-// @Description Delete by id currency handler
+// @Description Delete by id currency handler, needs the currency.delete permission
 // @Tags Currency
-// @Accept json
-// @Produce json
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
 // @Param id path int true "id"
 // @Success 204
-// @Router /currencies/{id} [delete]
+// @Success 202 {object} approval.ChangeRequestResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/currencies/{id} [delete]
 func (c currencyHandlers) Delete() echo.HandlerFunc {
 	return func(e echo.Context) error {
 		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "currencyHandler.Delete")
@@ -142,12 +193,12 @@ func (c currencyHandlers) Delete() echo.HandlerFunc {
 		id, err := strconv.Atoi(e.Param("id"))
 		if err != nil {
 			util.PrepareLogging(e, c.logger, err)
-			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()),nil))
+			return codec.Render(e, http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()),nil))
 		}
 
 		if err = c.currencyUseCase.Delete(ctx, id); err != nil {
 			util.PrepareLogging(e, c.logger, err)
-			return e.JSON(http.StatusInternalServerError, util.NewHttpResponse(http.StatusInternalServerError, strings.ToLower(err.Error()),nil))
+			return codec.Render(e, http.StatusInternalServerError, util.NewHttpResponse(http.StatusInternalServerError, strings.ToLower(err.Error()),nil))
 		}
 
 		return e.NoContent(http.StatusNoContent)
@@ -156,15 +207,19 @@ func (c currencyHandlers) Delete() echo.HandlerFunc {
 
 // GetAll godoc
 // @Summary Get all currencies
-// @Description Get all currencies with pagination
+// @Description Get all currencies with pagination, needs the currency.read permission
 // @Tags Currencies
-// @Accept json
-// @Produce json
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,text/csv,application/msgpack
 // @Param page query int false "page number" Format(page)
 // @Param size query int false "number of elements per page" Format(size)
 // @Param orderBy query int false "filter name" Format(orderBy)
-// @Success 200 {object} currency.CurrencyListResponse
-// @Router /currencies [get]
+// @Success 200 {object} currency.CurrencyV1ListResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/currencies [get]
 func (c currencyHandlers) GetAll() echo.HandlerFunc {
 	return func(e echo.Context) error {
 		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "currencyHandler.GetAll")
@@ -196,11 +251,62 @@ func (c currencyHandlers) GetAll() echo.HandlerFunc {
 		currencyList, err := c.currencyUseCase.GetAll(ctx, &currencyPageableRequest)
 		if err != nil {
 			util.PrepareLogging(e, c.logger, err)
-			return e.JSON(http.StatusInternalServerError, util.NewHttpResponse(http.StatusInternalServerError, strings.ToLower(err.Error()),nil))
+			return codec.Render(e, http.StatusInternalServerError, util.NewHttpResponse(http.StatusInternalServerError, strings.ToLower(err.Error()),nil))
+		}
+
+		return codec.Render(e, http.StatusOK, mapping.MapV1ListDto(currencyList))
+	}
+}
+
+// Versions godoc
+// @Summary Get currency versions
+// @Description Get every version of a currency with its validity period, oldest first, needs the currency.read permission
+// @Tags Currencies
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
+// @Param id path int true "id"
+// @Success 200 {object} currency.CurrencyVersionListResponse
+// @Failure 400 {object} util.HttpResponse
+// @Failure 404 {object} util.HttpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/currencies/{id}/versions [get]
+func (c currencyHandlers) Versions() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "currencyHandler.Versions")
+		defer span.Finish()
+
+		id, err := strconv.Atoi(e.Param("id"))
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			return codec.Render(e, http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()),nil))
 		}
 
-		return e.JSON(http.StatusOK, currencyList)
+		versionList, err := c.currencyUseCase.GetVersions(ctx, id)
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			resp := util.ParseError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		return codec.Render(e, http.StatusOK, versionList)
+	}
+}
+
+// parseAsOf accepts a day, meaning its start in UTC, or an RFC3339 timestamp.
+func parseAsOf(value string) (time.Time, error) {
+	if date, err := time.Parse(dateLayout, value); err == nil {
+		return date, nil
 	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("as_of must be a %s date or an RFC3339 timestamp", dateLayout)
+	}
+
+	return date, nil
 }
 
 func NewCurrencyHandler(cfg *config.Config, currencyUseCase usecase.CurrencyUseCase, logger logger.Logger) CurrencyHandlers {
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	approvalHandlers "github.com/sefikcan/kanbersky.ca/internal/approval/handlers"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type currencyApprovalHandlers struct {
+	CurrencyHandlers
+	propose func(permission string, bind approvalHandlers.Binder) echo.MiddlewareFunc
+}
+
+func (c currencyApprovalHandlers) Create() echo.HandlerFunc {
+	return c.propose(auth.PermissionCurrencyCreate, bindCreateProposal)(c.CurrencyHandlers.Create())
+}
+
+func (c currencyApprovalHandlers) Update() echo.HandlerFunc {
+	return c.propose(auth.PermissionCurrencyUpdate, bindUpdateProposal)(c.CurrencyHandlers.Update())
+}
+
+func (c currencyApprovalHandlers) Delete() echo.HandlerFunc {
+	return c.propose(auth.PermissionCurrencyDelete, bindDeleteProposal)(c.CurrencyHandlers.Delete())
+}
+
+// NewCurrencyApprovalHandler turns the creates, updates and deletes of callers whose roles require approval
+// into change requests, propose returns the middleware doing it, e.g. ChangeRequestHandlers.Propose.
+func NewCurrencyApprovalHandler(next CurrencyHandlers, propose func(permission string, bind approvalHandlers.Binder) echo.MiddlewareFunc) CurrencyHandlers {
+	return &currencyApprovalHandlers{
+		CurrencyHandlers: next,
+		propose: propose,
+	}
+}
+
+type currencyV2ApprovalHandlers struct {
+	CurrencyV2Handlers
+	propose func(permission string, bind approvalHandlers.Binder) echo.MiddlewareFunc
+}
+
+func (c currencyV2ApprovalHandlers) Create() echo.HandlerFunc {
+	return c.propose(auth.PermissionCurrencyCreate, bindCreateProposal)(c.CurrencyV2Handlers.Create())
+}
+
+func (c currencyV2ApprovalHandlers) Update() echo.HandlerFunc {
+	return c.propose(auth.PermissionCurrencyUpdate, bindUpdateProposal)(c.CurrencyV2Handlers.Update())
+}
+
+func (c currencyV2ApprovalHandlers) Delete() echo.HandlerFunc {
+	return c.propose(auth.PermissionCurrencyDelete, bindDeleteProposal)(c.CurrencyV2Handlers.Delete())
+}
+
+// NewCurrencyV2ApprovalHandler turns the changes of callers whose roles require approval into change requests.
+func NewCurrencyV2ApprovalHandler(next CurrencyV2Handlers, propose func(permission string, bind approvalHandlers.Binder) echo.MiddlewareFunc) CurrencyV2Handlers {
+	return &currencyV2ApprovalHandlers{
+		CurrencyV2Handlers: next,
+		propose: propose,
+	}
+}
+
+// bindCreateProposal validates the request up front, a change request that can never apply is not proposed.
+func bindCreateProposal(e echo.Context) (interface{}, any, error) {
+	createRequest := currency.CurrencyCreateRequest{}
+	if err := e.Bind(&createRequest); err != nil {
+		return nil, nil, util.ParseBindError(err)
+	}
+	if err := util.ValidateStruct(&createRequest); err != nil {
+		return nil, nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "currencyHandler.bindCreateProposal.ValidateStruct"))
+	}
+
+	return nil, createRequest, nil
+}
+
+func bindUpdateProposal(e echo.Context) (interface{}, any, error) {
+	id, err := strconv.Atoi(e.Param("id"))
+	if err != nil {
+		return nil, nil, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil)
+	}
+
+	updateRequest := currency.CurrencyUpdateRequest{}
+	if err = e.Bind(&updateRequest); err != nil {
+		return nil, nil, util.ParseBindError(err)
+	}
+	updateRequest.ID = id
+
+	return id, updateRequest, nil
+}
+
+func bindDeleteProposal(e echo.Context) (interface{}, any, error) {
+	id, err := strconv.Atoi(e.Param("id"))
+	if err != nil {
+		return nil, nil, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil)
+	}
+
+	return id, nil, nil
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+)
+
+type currencyAuthorizationHandlers struct {
+	next CurrencyHandlers
+	authorize func(permission string) echo.MiddlewareFunc
+}
+
+func (c currencyAuthorizationHandlers) Create() echo.HandlerFunc {
+	return c.authorize(auth.PermissionCurrencyCreate)(c.next.Create())
+}
+
+func (c currencyAuthorizationHandlers) Update() echo.HandlerFunc {
+	return c.authorize(auth.PermissionCurrencyUpdate)(c.next.Update())
+}
+
+func (c currencyAuthorizationHandlers) GetById() echo.HandlerFunc {
+	return c.authorize(auth.PermissionCurrencyRead)(c.next.GetById())
+}
+
+func (c currencyAuthorizationHandlers) Delete() echo.HandlerFunc {
+	return c.authorize(auth.PermissionCurrencyDelete)(c.next.Delete())
+}
+
+func (c currencyAuthorizationHandlers) GetAll() echo.HandlerFunc {
+	return c.authorize(auth.PermissionCurrencyRead)(c.next.GetAll())
+}
+
+func (c currencyAuthorizationHandlers) Versions() echo.HandlerFunc {
+	return c.authorize(auth.PermissionCurrencyRead)(c.next.Versions())
+}
+
+// NewCurrencyAuthorizationHandler checks the permission of each handler before calling next, authorize
+// returns the middleware checking a permission, e.g. MiddlewareManager.PermissionMiddleware.
+func NewCurrencyAuthorizationHandler(next CurrencyHandlers, authorize func(permission string) echo.MiddlewareFunc) CurrencyHandlers {
+	return &currencyAuthorizationHandlers{
+		next: next,
+		authorize: authorize,
+	}
+}
+
+type currencyV2AuthorizationHandlers struct {
+	next CurrencyV2Handlers
+	authorize func(permission string) echo.MiddlewareFunc
+}
+
+func (c currencyV2AuthorizationHandlers) Create() echo.HandlerFunc {
+	return c.authorize(auth.PermissionCurrencyCreate)(c.next.Create())
+}
+
+func (c currencyV2AuthorizationHandlers) Update() echo.HandlerFunc {
+	return c.authorize(auth.PermissionCurrencyUpdate)(c.next.Update())
+}
+
+func (c currencyV2AuthorizationHandlers) GetById() echo.HandlerFunc {
+	return c.authorize(auth.PermissionCurrencyRead)(c.next.GetById())
+}
+
+func (c currencyV2AuthorizationHandlers) Delete() echo.HandlerFunc {
+	return c.authorize(auth.PermissionCurrencyDelete)(c.next.Delete())
+}
+
+func (c currencyV2AuthorizationHandlers) GetAll() echo.HandlerFunc {
+	return c.authorize(auth.PermissionCurrencyRead)(c.next.GetAll())
+}
+
+// NewCurrencyV2AuthorizationHandler checks the permission of each handler before calling next.
+func NewCurrencyV2AuthorizationHandler(next CurrencyV2Handlers, authorize func(permission string) echo.MiddlewareFunc) CurrencyV2Handlers {
+	return &currencyV2AuthorizationHandlers{
+		next: next,
+		authorize: authorize,
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubHandlers answers every route with 200.
+type stubHandlers struct{}
+
+func (stubHandlers) ok() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+}
+
+func (s stubHandlers) Create() echo.HandlerFunc {
+	return s.ok()
+}
+
+func (s stubHandlers) Update() echo.HandlerFunc {
+	return s.ok()
+}
+
+func (s stubHandlers) GetById() echo.HandlerFunc {
+	return s.ok()
+}
+
+func (s stubHandlers) Delete() echo.HandlerFunc {
+	return s.ok()
+}
+
+func (s stubHandlers) GetAll() echo.HandlerFunc {
+	return s.ok()
+}
+
+func (s stubHandlers) Versions() echo.HandlerFunc {
+	return s.ok()
+}
+
+// grantOnly is an authorize func letting through the handlers of one permission, the rest get 403.
+func grantOnly(granted string) func(permission string) echo.MiddlewareFunc {
+	return func(permission string) echo.MiddlewareFunc {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				if permission != granted {
+					return c.NoContent(http.StatusForbidden)
+				}
+				return next(c)
+			}
+		}
+	}
+}
+
+func TestAuthorizationHandlersCheckThePermissionOfEachRoute(t *testing.T) {
+	tests := []struct {
+		route string
+		permission string
+		v1 func(CurrencyHandlers) echo.HandlerFunc
+		v2 func(CurrencyV2Handlers) echo.HandlerFunc
+	}{
+		{route: "create", permission: auth.PermissionCurrencyCreate, v1: CurrencyHandlers.Create, v2: CurrencyV2Handlers.Create},
+		{route: "update", permission: auth.PermissionCurrencyUpdate, v1: CurrencyHandlers.Update, v2: CurrencyV2Handlers.Update},
+		{route: "delete", permission: auth.PermissionCurrencyDelete, v1: CurrencyHandlers.Delete, v2: CurrencyV2Handlers.Delete},
+		{route: "get by id", permission: auth.PermissionCurrencyRead, v1: CurrencyHandlers.GetById, v2: CurrencyV2Handlers.GetById},
+		{route: "get all", permission: auth.PermissionCurrencyRead, v1: CurrencyHandlers.GetAll, v2: CurrencyV2Handlers.GetAll},
+		{route: "versions", permission: auth.PermissionCurrencyRead, v1: CurrencyHandlers.Versions},
+	}
+	status := func(h echo.HandlerFunc) int {
+		rec := httptest.NewRecorder()
+		_ = h(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec))
+		return rec.Code
+	}
+
+	for _, granted := range []string{auth.PermissionCurrencyRead, auth.PermissionCurrencyCreate, auth.PermissionCurrencyUpdate, auth.PermissionCurrencyDelete} {
+		v1 := NewCurrencyAuthorizationHandler(stubHandlers{}, grantOnly(granted))
+		v2 := NewCurrencyV2AuthorizationHandler(stubHandlers{}, grantOnly(granted))
+
+		for _, tt := range tests {
+			want := http.StatusForbidden
+			if tt.permission == granted {
+				want = http.StatusOK
+			}
+			if got := status(tt.v1(v1)); got != want {
+				t.Errorf("v1 %s with %s granted = %d, want %d", tt.route, granted, got, want)
+			}
+			if tt.v2 != nil {
+				if got := status(tt.v2(v2)); got != want {
+					t.Errorf("v2 %s with %s granted = %d, want %d", tt.route, granted, got, want)
+				}
+			}
+		}
+	}
+}
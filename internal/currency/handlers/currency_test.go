@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAsOf(t *testing.T) {
+	tests := map[string]time.Time{
+		"2024-03-01": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"2024-03-01T09:30:00Z": time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+		"2024-03-01T12:30:00+03:00": time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+	}
+	for value, want := range tests {
+		if asOf, err := parseAsOf(value); err != nil || !asOf.Equal(want) {
+			t.Errorf("parseAsOf(%s) = %s, %v, want %s", value, asOf, err, want)
+		}
+	}
+
+	for _, value := range []string{"01.03.2024", "2024-03-01 09:30", "yesterday"} {
+		if _, err := parseAsOf(value); err == nil {
+			t.Errorf("parseAsOf(%s) accepted", value)
+		}
+	}
+}
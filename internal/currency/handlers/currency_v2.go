@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/mapping"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	"github.com/sefikcan/kanbersky.ca/pkg/codec"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	currencyV2CollectionPath = "/api/v2/currencies"
+	defaultPage = 1
+	defaultSize = 10
+)
+
+// CurrencyV2Handlers serve the /api/v2 currency contract, which adds timestamps and links to v1.
+type CurrencyV2Handlers interface {
+	Create() echo.HandlerFunc
+	Update() echo.HandlerFunc
+	GetById() echo.HandlerFunc
+	Delete() echo.HandlerFunc
+	GetAll() echo.HandlerFunc
+}
+
+type currencyV2Handlers struct {
+	cfg *config.Config
+	currencyUseCase usecase.CurrencyUseCase
+	logger logger.Logger
+}
+
+// Create godoc
+// @Summary Create currency
+// @Description Create currency, v2 contract, needs the currency.create permission
+// @Tags Currency v2
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
+// @Param currencyCreateRequest body currency.CurrencyCreateRequest true "Create Currency"
+// @Param Idempotency-Key header string false "retries with the same key replay the first response"
+// @Success 201 {object} currency.CurrencyV2Response
+// @Success 202 {object} approval.ChangeRequestResponse
+// @Failure 400 {object} util.HttpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Failure 409 {object} util.HttpResponse
+// @Failure 422 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/currencies [post]
+func (c currencyV2Handlers) Create() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "currencyV2Handler.Create")
+		defer span.Finish()
+
+		currencyRequest := currency.CurrencyCreateRequest{}
+		if err := e.Bind(&currencyRequest); err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			resp := util.ParseBindError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		createdCurrency, err := c.currencyUseCase.Create(ctx, currencyRequest)
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			resp := util.ParseError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		mappedResponse := mapping.MapV2Dto(createdCurrency, currencyV2CollectionPath)
+		e.Response().Header().Set(echo.HeaderLocation, mappedResponse.Links.Self)
+
+		return codec.Render(e, http.StatusCreated, mappedResponse)
+	}
+}
+
+// Update godoc
+// @Summary Update currency
+// @Description Update currency, v2 contract, needs the currency.update permission
+// @Tags Currency v2
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
+// @Param id path int true "id"
+// @Param currencyUpdateRequest body currency.CurrencyUpdateRequest true "Update Currency"
+// @Success 200 {object} currency.CurrencyV2Response
+// @Success 202 {object} approval.ChangeRequestResponse
+// @Failure 400 {object} util.HttpResponse
+// @Failure 404 {object} util.HttpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/currencies/{id} [put]
+func (c currencyV2Handlers) Update() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "currencyV2Handler.Update")
+		defer span.Finish()
+
+		id, err := strconv.Atoi(e.Param("id"))
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			return codec.Render(e, http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		updateRequest := currency.CurrencyUpdateRequest{}
+		if err = e.Bind(&updateRequest); err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			resp := util.ParseBindError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		updateRequest.ID = id
+		updatedCurrency, err := c.currencyUseCase.Update(ctx, updateRequest)
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			resp := util.ParseError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		return codec.Render(e, http.StatusOK, mapping.MapV2Dto(updatedCurrency, currencyV2CollectionPath))
+	}
+}
+
+// GetById godoc
+// @Summary Get by id currency
+// @Description Get currency by id, v2 contract, needs the currency.read permission
+// @Tags Currency v2
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
+// @Param id path int true "id"
+// @Success 200 {object} currency.CurrencyV2Response
+// @Failure 404 {object} util.HttpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/currencies/{id} [get]
+func (c currencyV2Handlers) GetById() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "currencyV2Handler.GetById")
+		defer span.Finish()
+
+		id, err := strconv.Atoi(e.Param("id"))
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			return codec.Render(e, http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		currentCurrency, err := c.currencyUseCase.GetById(ctx, id)
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			resp := util.ParseError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		return codec.Render(e, http.StatusOK, mapping.MapV2Dto(currentCurrency, currencyV2CollectionPath))
+	}
+}
+
+// Delete godoc
+// @Summary Delete currency
+// @Description Delete currency by id, v2 contract, needs the currency.delete permission
+// @Tags Currency v2
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
+// @Param id path int true "id"
+// @Success 204
+// @Success 202 {object} approval.ChangeRequestResponse
+// @Failure 404 {object} util.HttpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/currencies/{id} [delete]
+func (c currencyV2Handlers) Delete() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "currencyV2Handler.Delete")
+		defer span.Finish()
+
+		id, err := strconv.Atoi(e.Param("id"))
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			return codec.Render(e, http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		if err = c.currencyUseCase.Delete(ctx, id); err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			resp := util.ParseError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		return e.NoContent(http.StatusNoContent)
+	}
+}
+
+// GetAll godoc
+// @Summary Get all currencies
+// @Description Get currencies with pagination and page links, v2 contract, needs the currency.read permission
+// @Tags Currency v2
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,text/csv,application/msgpack
+// @Param page query int false "page number" Format(page)
+// @Param limit query int false "number of elements per page" Format(limit)
+// @Param sort query string false "sort field"
+// @Success 200 {object} currency.CurrencyV2ListResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/currencies [get]
+func (c currencyV2Handlers) GetAll() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "currencyV2Handler.GetAll")
+		defer span.Finish()
+
+		currencyList, err := c.currencyUseCase.GetAll(ctx, getPageableRequest(e))
+		if err != nil {
+			util.PrepareLogging(e, c.logger, err)
+			resp := util.ParseError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		return codec.Render(e, http.StatusOK, mapping.MapV2ListDto(currencyList, currencyV2CollectionPath))
+	}
+}
+
+func getPageableRequest(e echo.Context) *currency.CurrencyPageableRequest {
+	pageableRequest := &currency.CurrencyPageableRequest{
+		Page: defaultPage,
+		Size: defaultSize,
+		OrderBy: e.QueryParam("sort"),
+	}
+	if page, err := strconv.Atoi(e.QueryParam("page")); err == nil && page > 0 {
+		pageableRequest.Page = page
+	}
+	if limit, err := strconv.Atoi(e.QueryParam("limit")); err == nil && limit > 0 {
+		pageableRequest.Size = limit
+	}
+
+	return pageableRequest
+}
+
+func NewCurrencyV2Handler(cfg *config.Config, currencyUseCase usecase.CurrencyUseCase, logger logger.Logger) CurrencyV2Handlers {
+	return &currencyV2Handlers{
+		cfg: cfg,
+		currencyUseCase: currencyUseCase,
+		logger: logger,
+	}
+}
@@ -3,6 +3,15 @@ package handlers
 import "github.com/labstack/echo/v4"
 
 func MapCurrencyRoutes(currencyRouteGroup *echo.Group, c CurrencyHandlers) {
+	currencyRouteGroup.POST("", c.Create())
+	currencyRouteGroup.PUT("/:id", c.Update())
+	currencyRouteGroup.DELETE("/:id", c.Delete())
+	currencyRouteGroup.GET("/:id", c.GetById())
+	currencyRouteGroup.GET("/:id/versions", c.Versions())
+	currencyRouteGroup.GET("", c.GetAll())
+}
+
+func MapCurrencyV2Routes(currencyRouteGroup *echo.Group, c CurrencyV2Handlers) {
 	currencyRouteGroup.POST("", c.Create())
 	currencyRouteGroup.PUT("/:id", c.Update())
 	currencyRouteGroup.DELETE("/:id", c.Delete())
@@ -1,6 +1,7 @@
 package mapping
 
 import (
+	"fmt"
 	"github.com/sefikcan/kanbersky.ca/internal/currency/entity"
 	"github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
 )
@@ -12,6 +13,9 @@ func MapDto(c entity.Currency) *currency.CurrencyResponse {
 		ID: c.ID,
 		Title: c.Title,
 		IsoCode: c.IsoCode,
+		TenantID: c.TenantID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
 	}
 }
 
@@ -24,3 +28,110 @@ func MapListDto(currencies []entity.Currency) CurrencyResponses {
 
 	return currencyResp
 }
+
+func MapV1Dto(c *currency.CurrencyResponse) *currency.CurrencyV1Response {
+	return &currency.CurrencyV1Response{
+		ID: c.ID,
+		Title: c.Title,
+		IsoCode: c.IsoCode,
+	}
+}
+
+func MapVersionDto(v entity.CurrencyVersion) *currency.CurrencyVersionResponse {
+	return &currency.CurrencyVersionResponse{
+		ID: v.CurrencyID,
+		Version: v.Version,
+		Title: v.Title,
+		IsoCode: v.IsoCode,
+		ValidFrom: v.ValidFrom,
+		ValidTo: v.ValidTo,
+	}
+}
+
+func MapVersionListDto(id int, versions []entity.CurrencyVersion) currency.CurrencyVersionListResponse {
+	versionResp := make([]*currency.CurrencyVersionResponse, 0, len(versions))
+	for _, v := range versions {
+		versionResp = append(versionResp, MapVersionDto(v))
+	}
+
+	return currency.CurrencyVersionListResponse{
+		ID: id,
+		Versions: versionResp,
+	}
+}
+
+// MapVersionV1Dto keeps the frozen v1 contract for the state of a currency in the past.
+func MapVersionV1Dto(v *currency.CurrencyVersionResponse) *currency.CurrencyV1Response {
+	return &currency.CurrencyV1Response{
+		ID: v.ID,
+		Title: v.Title,
+		IsoCode: v.IsoCode,
+	}
+}
+
+func MapV1ListDto(list currency.CurrencyListResponse) currency.CurrencyV1ListResponse {
+	currencies := make([]*currency.CurrencyV1Response, 0, len(list.Currencies))
+	for _, c := range list.Currencies {
+		currencies = append(currencies, MapV1Dto(c))
+	}
+
+	return currency.CurrencyV1ListResponse{
+		TotalCount: list.TotalCount,
+		TotalPages: list.TotalPages,
+		Page: list.Page,
+		Limit: list.Limit,
+		Currencies: currencies,
+	}
+}
+
+// MapV2Dto builds the links from collectionPath, e.g. /api/v2/currencies.
+func MapV2Dto(c *currency.CurrencyResponse, collectionPath string) *currency.CurrencyV2Response {
+	return &currency.CurrencyV2Response{
+		ID: c.ID,
+		Title: c.Title,
+		IsoCode: c.IsoCode,
+		TenantID: c.TenantID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Links: currency.CurrencyLinks{
+			Self: fmt.Sprintf("%s/%d", collectionPath, c.ID),
+			Collection: collectionPath,
+		},
+	}
+}
+
+func MapV2ListDto(list currency.CurrencyListResponse, collectionPath string) currency.CurrencyV2ListResponse {
+	currencies := make([]*currency.CurrencyV2Response, 0, len(list.Currencies))
+	for _, c := range list.Currencies {
+		currencies = append(currencies, MapV2Dto(c, collectionPath))
+	}
+
+	lastPage := list.TotalPages
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	pageLink := func(page int) string {
+		return fmt.Sprintf("%s?page=%d&limit=%d", collectionPath, page, list.Limit)
+	}
+
+	links := currency.PageLinks{
+		Self: pageLink(list.Page),
+		First: pageLink(1),
+		Last: pageLink(lastPage),
+	}
+	if list.Page > 1 {
+		links.Prev = pageLink(list.Page - 1)
+	}
+	if list.Page < lastPage {
+		links.Next = pageLink(list.Page + 1)
+	}
+
+	return currency.CurrencyV2ListResponse{
+		TotalCount: list.TotalCount,
+		TotalPages: list.TotalPages,
+		Page: list.Page,
+		Limit: list.Limit,
+		Currencies: currencies,
+		Links: links,
+	}
+}
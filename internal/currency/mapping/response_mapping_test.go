@@ -0,0 +1,72 @@
+package mapping
+
+import (
+	"encoding/json"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"testing"
+	"time"
+)
+
+func TestMapV1DtoKeepsTheFrozenContract(t *testing.T) {
+	c := &currency.CurrencyResponse{ID: 7, Title: "Euro", IsoCode: "EUR", TenantID: "acme", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+
+	body, err := json.Marshal(MapV1Dto(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"id":7,"title":"Euro","iso_code":"EUR"}` {
+		t.Errorf("v1 body = %s, want only id, title and iso_code", body)
+	}
+}
+
+func TestMapV2ListDtoLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		page int
+		totalPages int
+		links currency.PageLinks
+	}{
+		{
+			name: "first page",
+			page: 1,
+			totalPages: 3,
+			links: currency.PageLinks{Self: "/api/v2/currencies?page=1&limit=2", First: "/api/v2/currencies?page=1&limit=2", Last: "/api/v2/currencies?page=3&limit=2", Next: "/api/v2/currencies?page=2&limit=2"},
+		},
+		{
+			name: "middle page",
+			page: 2,
+			totalPages: 3,
+			links: currency.PageLinks{Self: "/api/v2/currencies?page=2&limit=2", First: "/api/v2/currencies?page=1&limit=2", Last: "/api/v2/currencies?page=3&limit=2", Prev: "/api/v2/currencies?page=1&limit=2", Next: "/api/v2/currencies?page=3&limit=2"},
+		},
+		{
+			name: "last page",
+			page: 3,
+			totalPages: 3,
+			links: currency.PageLinks{Self: "/api/v2/currencies?page=3&limit=2", First: "/api/v2/currencies?page=1&limit=2", Last: "/api/v2/currencies?page=3&limit=2", Prev: "/api/v2/currencies?page=2&limit=2"},
+		},
+		{
+			name: "empty list",
+			page: 1,
+			links: currency.PageLinks{Self: "/api/v2/currencies?page=1&limit=2", First: "/api/v2/currencies?page=1&limit=2", Last: "/api/v2/currencies?page=1&limit=2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := currency.CurrencyListResponse{
+				TotalPages: tt.totalPages,
+				Page: tt.page,
+				Limit: 2,
+				Currencies: []*currency.CurrencyResponse{{ID: 7, Title: "Euro", IsoCode: "EUR"}},
+			}
+
+			mapped := MapV2ListDto(list, "/api/v2/currencies")
+			if mapped.Links != tt.links {
+				t.Errorf("links = %+v, want %+v", mapped.Links, tt.links)
+			}
+			if links := mapped.Currencies[0].Links; links.Self != "/api/v2/currencies/7" || links.Collection != "/api/v2/currencies" {
+				t.Errorf("currency links = %+v", links)
+			}
+		})
+	}
+}
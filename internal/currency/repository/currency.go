@@ -4,9 +4,16 @@ import (
 	"context"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
+	audit "github.com/sefikcan/kanbersky.ca/internal/audit/entity"
+	auditRepository "github.com/sefikcan/kanbersky.ca/internal/audit/repository"
 	"github.com/sefikcan/kanbersky.ca/internal/currency/entity"
+	outbox "github.com/sefikcan/kanbersky.ca/internal/outbox/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
 	"github.com/sefikcan/kanbersky.ca/pkg/util"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"time"
 )
 
 type CurrencyRepository interface {
@@ -16,6 +23,10 @@ type CurrencyRepository interface {
 	Delete(ctx context.Context, id int) error
 	GetCount(ctx context.Context) int64
 	GetAll(ctx context.Context, query util.Pagination) []entity.Currency
+	GetBatch(ctx context.Context, afterId int, size int) ([]entity.Currency, error)
+	Export(ctx context.Context, from time.Time, to time.Time, fn func(entity.Currency) error) error
+	GetAsOf(ctx context.Context, id int, at time.Time) (entity.CurrencyVersion, error)
+	GetVersions(ctx context.Context, id int) ([]entity.CurrencyVersion, error)
 }
 
 type currencyRepository struct {
@@ -26,8 +37,24 @@ func (c currencyRepository) Create(ctx context.Context, currency entity.Currency
 	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRepository.Create")
 	defer span.Finish()
 
-	if result := c.db.WithContext(spanContext).Create(&currency); result.Error != nil {
-		return entity.Currency{}, errors.Wrap(result.Error, "currencyRepository.Create.DbError")
+	err := c.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		if err := checkGlobalConflict(tx, currency); err != nil {
+			return err
+		}
+		if result := tx.Create(&currency); result.Error != nil {
+			return errors.Wrap(result.Error, "currencyRepository.Create.DbError")
+		}
+		if err := openVersion(tx, currency, 1, currency.CreatedAt); err != nil {
+			return err
+		}
+		if err := auditRepository.AddAuditLog(tx, entity.AggregateType, currency.ID, audit.ActionCreate, nil, currency); err != nil {
+			return err
+		}
+
+		return addOutboxEvent(tx, currency.ID, entity.CurrencyCreatedEvent, currency)
+	})
+	if err != nil {
+		return entity.Currency{}, err
 	}
 
 	return currency, nil
@@ -37,8 +64,34 @@ func (c currencyRepository) Update(ctx context.Context, currency entity.Currency
 	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRepository.Update")
 	defer span.Finish()
 
-	if result := c.db.WithContext(spanContext).Save(&currency); result.Error != nil {
-		return entity.Currency{}, errors.Wrap(result.Error, "currencyRepository.Update.DbError")
+	err := c.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		before, err := getForUpdate(tx, currency.ID)
+		if err != nil {
+			return errors.Wrap(err, "currencyRepository.Update.GetForUpdate")
+		}
+		if err = checkGlobalConflict(tx, currency); err != nil {
+			return err
+		}
+		if result := tx.Save(&currency); result.Error != nil {
+			return errors.Wrap(result.Error, "currencyRepository.Update.DbError")
+		}
+		if before.Title != currency.Title || before.IsoCode != currency.IsoCode {
+			var version int
+			if version, err = closeVersion(tx, currency.ID, currency.UpdatedAt); err != nil {
+				return err
+			}
+			if err = openVersion(tx, currency, version+1, currency.UpdatedAt); err != nil {
+				return err
+			}
+		}
+		if err = auditRepository.AddAuditLog(tx, entity.AggregateType, currency.ID, audit.ActionUpdate, before, currency); err != nil {
+			return err
+		}
+
+		return addOutboxEvent(tx, currency.ID, entity.CurrencyUpdatedEvent, currency)
+	})
+	if err != nil {
+		return entity.Currency{}, err
 	}
 
 	return currency, nil
@@ -49,7 +102,7 @@ func (c currencyRepository) GetById(ctx context.Context, id int) (entity.Currenc
 	defer span.Finish()
 
 	currentCurrency := entity.Currency{}
-	err := c.db.WithContext(spanContext).Where(`id = ?`, id).First(&currentCurrency).Error
+	err := withVisibleTenants(c.db.WithContext(spanContext)).Where(`id = ?`, id).First(&currentCurrency).Error
 	if err != nil {
 		return entity.Currency{}, errors.Wrap(err,"currencyRepository.GetById.DbError")
 	}
@@ -61,11 +114,24 @@ func (c currencyRepository) Delete(ctx context.Context, id int) error {
 	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRepository.Delete")
 	defer span.Finish()
 
-	if result := c.db.WithContext(spanContext).Delete(&entity.Currency{ID: id}); result.Error != nil {
-		return errors.Wrap(result.Error, "currencyRepository.Delete.DbError")
-	}
+	return c.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		before, err := getForUpdate(tx, id)
+		if err != nil {
+			return errors.Wrap(err, "currencyRepository.Delete.GetForUpdate")
+		}
+		if result := tx.Delete(&entity.Currency{ID: id}); result.Error != nil {
+			return errors.Wrap(result.Error, "currencyRepository.Delete.DbError")
+		}
+		if _, err = closeVersion(tx, id, time.Now()); err != nil {
+			return err
+		}
+		if err = auditRepository.AddAuditLog(tx, entity.AggregateType, id, audit.ActionDelete, before, nil); err != nil {
+			return err
+		}
 
-	return nil
+		// the deleted row names the tenant, as the payloads of the other events do
+		return addOutboxEvent(tx, id, entity.CurrencyDeletedEvent, before)
+	})
 }
 
 func (c currencyRepository) GetCount(ctx context.Context) int64 {
@@ -75,7 +141,7 @@ func (c currencyRepository) GetCount(ctx context.Context) int64 {
 	var currencies []*entity.Currency
 
 	var totalCount int64
-	c.db.WithContext(spanContext).Model(currencies).Count(&totalCount)
+	withVisibleTenants(c.db.WithContext(spanContext)).Model(currencies).Count(&totalCount)
 
 	return totalCount
 }
@@ -85,11 +151,161 @@ func (c currencyRepository) GetAll(ctx context.Context, query util.Pagination) [
 	defer span.Finish()
 
 	var currencies []entity.Currency
-	c.db.WithContext(spanContext).Offset(query.GetOffset()).Limit(query.GetLimit()).Order(query.GetLimit()).Find(&currencies)
+	withVisibleTenants(c.db.WithContext(spanContext)).Offset(query.GetOffset()).Limit(query.GetLimit()).Order(query.GetLimit()).Find(&currencies)
 
 	return currencies
 }
 
+// GetBatch walks the currencies of every tenant, it is meant for background jobs like the cache warm-up.
+func (c currencyRepository) GetBatch(ctx context.Context, afterId int, size int) ([]entity.Currency, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRepository.GetBatch")
+	defer span.Finish()
+
+	var currencies []entity.Currency
+	if err := c.db.WithContext(spanContext).Where(`id > ?`, afterId).Order("id asc").Limit(size).Find(&currencies).Error; err != nil {
+		return nil, errors.Wrap(err, "currencyRepository.GetBatch.DbError")
+	}
+
+	return currencies, nil
+}
+
+// Export walks the currencies created in [from, to) in id order with a database cursor, so the result is
+// never held in memory. Zero times leave the range open, an error of fn stops the walk.
+func (c currencyRepository) Export(ctx context.Context, from time.Time, to time.Time, fn func(entity.Currency) error) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRepository.Export")
+	defer span.Finish()
+
+	query := withVisibleTenants(c.db.WithContext(spanContext)).Model(&entity.Currency{})
+	if !from.IsZero() {
+		query = query.Where(`created_at >= ?`, from)
+	}
+	if !to.IsZero() {
+		query = query.Where(`created_at < ?`, to)
+	}
+
+	rows, err := query.Order("id asc").Rows()
+	if err != nil {
+		return errors.Wrap(err, "currencyRepository.Export.DbError")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		currency := entity.Currency{}
+		if err = c.db.ScanRows(rows, &currency); err != nil {
+			return errors.Wrap(err, "currencyRepository.Export.ScanRows")
+		}
+		if err = fn(currency); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "currencyRepository.Export.Rows")
+}
+
+// GetAsOf returns the version of the currency valid at the given time, deleted currencies keep their history.
+func (c currencyRepository) GetAsOf(ctx context.Context, id int, at time.Time) (entity.CurrencyVersion, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRepository.GetAsOf")
+	defer span.Finish()
+
+	version := entity.CurrencyVersion{}
+	err := withVisibleTenants(c.db.WithContext(spanContext)).
+		Where(`currency_id = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)`, id, at, at).
+		First(&version).Error
+	if err != nil {
+		return entity.CurrencyVersion{}, errors.Wrap(err, "currencyRepository.GetAsOf.DbError")
+	}
+
+	return version, nil
+}
+
+func (c currencyRepository) GetVersions(ctx context.Context, id int) ([]entity.CurrencyVersion, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRepository.GetVersions")
+	defer span.Finish()
+
+	var versions []entity.CurrencyVersion
+	if err := withVisibleTenants(c.db.WithContext(spanContext)).Where(`currency_id = ?`, id).Order("version asc").Find(&versions).Error; err != nil {
+		return nil, errors.Wrap(err, "currencyRepository.GetVersions.DbError")
+	}
+
+	return versions, nil
+}
+
+// getForUpdate locks the row until the transaction ends, so the audit log has the state the change replaced.
+// Only currencies of the tenant of the request are found, tenants can not change the global ones.
+func getForUpdate(tx *gorm.DB, id int) (entity.Currency, error) {
+	currency := entity.Currency{}
+	if err := tx.Where(`tenant_id = ?`, tenant.FromContext(tx.Statement.Context)).Clauses(clause.Locking{Strength: "UPDATE"}).Where(`id = ?`, id).First(&currency).Error; err != nil {
+		return entity.Currency{}, err
+	}
+
+	return currency, nil
+}
+
+// withVisibleTenants limits the query to the currencies of the tenant of the request and the global ones.
+func withVisibleTenants(db *gorm.DB) *gorm.DB {
+	return db.Where(`tenant_id IN ?`, tenant.Visible(tenant.FromContext(db.Statement.Context)))
+}
+
+// checkGlobalConflict keeps tenants from taking the title or iso code of a global currency, which would
+// make the code mean two currencies for them. Codes of other tenants do not conflict.
+func checkGlobalConflict(tx *gorm.DB, currency entity.Currency) error {
+	if currency.TenantID == tenant.Global {
+		return nil
+	}
+
+	var count int64
+	err := tx.Model(&entity.Currency{}).
+		Where(`tenant_id = ? AND (title = ? OR iso_code = ?)`, tenant.Global, currency.Title, currency.IsoCode).
+		Count(&count).Error
+	if err != nil {
+		return errors.Wrap(err, "currencyRepository.checkGlobalConflict.DbError")
+	}
+	if count > 0 {
+		return util.NewHttpResponse(http.StatusConflict, "a global currency has the same title or iso code", nil)
+	}
+
+	return nil
+}
+
+func openVersion(tx *gorm.DB, currency entity.Currency, version int, at time.Time) error {
+	currencyVersion := entity.NewCurrencyVersion(currency, version, at)
+	if result := tx.Create(&currencyVersion); result.Error != nil {
+		return errors.Wrap(result.Error, "currencyRepository.openVersion.DbError")
+	}
+
+	return nil
+}
+
+// closeVersion ends the open version of the currency at the given time and returns the latest version number.
+// Callers hold the lock of the currency row, so no other transaction changes its versions meanwhile.
+func closeVersion(tx *gorm.DB, id int, at time.Time) (int, error) {
+	err := tx.Model(&entity.CurrencyVersion{}).Where(`currency_id = ? AND valid_to IS NULL`, id).Update("valid_to", at).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "currencyRepository.closeVersion.DbError")
+	}
+
+	var version int
+	err = tx.Model(&entity.CurrencyVersion{}).Where(`currency_id = ?`, id).Select(`COALESCE(MAX(version), 0)`).Scan(&version).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "currencyRepository.closeVersion.LatestVersion")
+	}
+
+	return version, nil
+}
+
+func addOutboxEvent(tx *gorm.DB, id int, eventType string, payload any) error {
+	outboxEvent, err := outbox.NewOutboxEvent(entity.AggregateType, id, eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	if result := tx.Create(&outboxEvent); result.Error != nil {
+		return errors.Wrap(result.Error, "currencyRepository.addOutboxEvent.DbError")
+	}
+
+	return nil
+}
+
 func NewCurrencyRepository(db *gorm.DB) CurrencyRepository {
 	return &currencyRepository{
 		db: db,
@@ -3,21 +3,26 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/go-redis/redis/v8"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
 	"time"
 )
 
+const CurrencyCacheTtl = 3600
+
 type CurrencyRedisRepository interface {
 	GetByKey(ctx context.Context, key string) (*currency.CurrencyResponse, error)
 	Set(ctx context.Context, key string, seconds int, param any) error
+	SetMany(ctx context.Context, seconds int, params map[string]any) error
 	Delete(ctx context.Context, key string) error
 }
 
 type currencyRedisRepository struct {
-	redisClient *redis.Client
+	redisClient redis.UniversalClient
 }
 
 func (c currencyRedisRepository) GetByKey(ctx context.Context, key string) (*currency.CurrencyResponse, error) {
@@ -52,6 +57,26 @@ func (c currencyRedisRepository) Set(ctx context.Context, key string, seconds in
 	return nil
 }
 
+func (c currencyRedisRepository) SetMany(ctx context.Context, seconds int, params map[string]any) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRedisRepository.SetMany")
+	defer span.Finish()
+
+	pipe := c.redisClient.Pipeline()
+	for key, param := range params {
+		currencyByte, err := json.Marshal(param)
+		if err != nil {
+			return errors.Wrap(err, "currencyRedisRepository.SetMany.Json.Marshal")
+		}
+		pipe.Set(spanContext, key, currencyByte, time.Second * time.Duration(seconds))
+	}
+
+	if _, err := pipe.Exec(spanContext); err != nil {
+		return errors.Wrap(err, "currencyRedisRepository.SetMany.Pipeline.Exec")
+	}
+
+	return nil
+}
+
 func (c currencyRedisRepository) Delete(ctx context.Context, key string) error {
 	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRedisRepository.Delete")
 	defer span.Finish()
@@ -63,7 +88,16 @@ func (c currencyRedisRepository) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
-func NewCurrencyRedisRepository(redisClient *redis.Client) CurrencyRedisRepository {
+// CurrencyCacheKey is the key of a currency of the tenant, the keys of tenants are namespaced by their id.
+func CurrencyCacheKey(tenantId string, id int) string {
+	if tenantId == tenant.Global {
+		return fmt.Sprintf("%s: %v", "currency", id)
+	}
+
+	return fmt.Sprintf("tenant:%s:%s: %v", tenantId, "currency", id)
+}
+
+func NewCurrencyRedisRepository(redisClient redis.UniversalClient) CurrencyRedisRepository {
 	return &currencyRedisRepository{
 		redisClient: redisClient,
 	}
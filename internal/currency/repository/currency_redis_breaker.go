@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sony/gobreaker"
+)
+
+type currencyRedisBreakerRepository struct {
+	next CurrencyRedisRepository
+	breaker *gobreaker.CircuitBreaker
+}
+
+func (c currencyRedisBreakerRepository) GetByKey(ctx context.Context, key string) (*currency.CurrencyResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRedisBreakerRepository.GetByKey")
+	defer span.Finish()
+
+	result, err := breaker.Execute(c.breaker, "currencyRedisBreakerRepository.GetByKey", func() (interface{}, error) {
+		return c.next.GetByKey(spanContext, key)
+	}, redis.Nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*currency.CurrencyResponse), nil
+}
+
+func (c currencyRedisBreakerRepository) Set(ctx context.Context, key string, seconds int, param any) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRedisBreakerRepository.Set")
+	defer span.Finish()
+
+	_, err := breaker.Execute(c.breaker, "currencyRedisBreakerRepository.Set", func() (interface{}, error) {
+		return nil, c.next.Set(spanContext, key, seconds, param)
+	})
+
+	return err
+}
+
+func (c currencyRedisBreakerRepository) SetMany(ctx context.Context, seconds int, params map[string]any) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRedisBreakerRepository.SetMany")
+	defer span.Finish()
+
+	_, err := breaker.Execute(c.breaker, "currencyRedisBreakerRepository.SetMany", func() (interface{}, error) {
+		return nil, c.next.SetMany(spanContext, seconds, params)
+	})
+
+	return err
+}
+
+func (c currencyRedisBreakerRepository) Delete(ctx context.Context, key string) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyRedisBreakerRepository.Delete")
+	defer span.Finish()
+
+	_, err := breaker.Execute(c.breaker, "currencyRedisBreakerRepository.Delete", func() (interface{}, error) {
+		return nil, c.next.Delete(spanContext, key)
+	})
+
+	return err
+}
+
+func NewCurrencyRedisBreakerRepository(next CurrencyRedisRepository, breaker *gobreaker.CircuitBreaker) CurrencyRedisRepository {
+	return &currencyRedisBreakerRepository{
+		next: next,
+		breaker: breaker,
+	}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sony/gobreaker"
+	"testing"
+	"time"
+)
+
+func newTestBreakerRepository(t *testing.T, maxFailures uint32) (*miniredis.Miniredis, *gobreaker.CircuitBreaker, CurrencyRedisRepository) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1, DialTimeout: time.Millisecond * 100})
+	t.Cleanup(func() { client.Close() })
+	cb := breaker.NewCircuitBreaker("redis", config.CircuitBreakerConfig{MaxFailures: maxFailures, Timeout: 60}, nil, l)
+
+	return mr, cb, NewCurrencyRedisBreakerRepository(NewCurrencyRedisRepository(client), cb)
+}
+
+func TestBreakerRepositoryIgnoresCacheMisses(t *testing.T) {
+	_, cb, repo := newTestBreakerRepository(t, 2)
+
+	for i := 0; i < 5; i++ {
+		if _, err := repo.GetByKey(context.Background(), CurrencyCacheKey("", 1)); !errors.Is(err, redis.Nil) {
+			t.Fatalf("err = %v, want a cache miss", err)
+		}
+	}
+	if cb.State() != gobreaker.StateClosed {
+		t.Errorf("state = %s after cache misses, want closed", cb.State())
+	}
+
+	cached := &currency.CurrencyResponse{ID: 1, Title: "Euro", IsoCode: "EUR"}
+	if err := repo.Set(context.Background(), CurrencyCacheKey("", 1), CurrencyCacheTtl, cached); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if got, err := repo.GetByKey(context.Background(), CurrencyCacheKey("", 1)); err != nil || got.IsoCode != "EUR" {
+		t.Errorf("get = %+v, %v", got, err)
+	}
+}
+
+func TestBreakerRepositoryOpensWhenRedisIsDown(t *testing.T) {
+	mr, cb, repo := newTestBreakerRepository(t, 2)
+	mr.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.GetByKey(context.Background(), CurrencyCacheKey("", 1)); err == nil || errors.Is(err, breaker.ErrUnavailable) {
+			t.Fatalf("err = %v, want the redis error while the breaker is closed", err)
+		}
+	}
+	if cb.State() != gobreaker.StateOpen {
+		t.Fatalf("state = %s, want open", cb.State())
+	}
+
+	// an open breaker answers without waiting for redis
+	started := time.Now()
+	_, err := repo.GetByKey(context.Background(), CurrencyCacheKey("", 1))
+	if !errors.Is(err, breaker.ErrUnavailable) {
+		t.Errorf("get err = %v, want ErrUnavailable", err)
+	}
+	if err = repo.Set(context.Background(), CurrencyCacheKey("", 1), CurrencyCacheTtl, nil); !errors.Is(err, breaker.ErrUnavailable) {
+		t.Errorf("set err = %v, want ErrUnavailable", err)
+	}
+	if err = repo.Delete(context.Background(), CurrencyCacheKey("", 1)); !errors.Is(err, breaker.ErrUnavailable) {
+		t.Errorf("delete err = %v, want ErrUnavailable", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Millisecond*50 {
+		t.Errorf("open breaker took %s", elapsed)
+	}
+}
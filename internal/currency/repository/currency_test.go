@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/testutil"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var currencyColumns = []string{"id", "tenant_id", "title", "iso_code"}
+
+// expectLocked expects the currency row to be locked and returns it as it was before the change.
+func expectLocked(mock sqlmock.Sqlmock, title string, isoCode string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "currencies" WHERE tenant_id = \$1 AND id = \$2 .* FOR UPDATE`).
+		WithArgs("acme", 7).
+		WillReturnRows(sqlmock.NewRows(currencyColumns).AddRow(7, "acme", title, isoCode))
+}
+
+func expectAuditAndOutbox(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO "outbox_events"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+}
+
+func TestUpdateClosesTheVersionAndOpensTheNext(t *testing.T) {
+	db, mock := testutil.NewMockDB(t)
+	ctx := tenant.NewContext(context.Background(), "acme")
+
+	expectLocked(mock, "Lira", "TRY")
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "currencies" WHERE tenant_id = \$1 AND \(title = \$2 OR iso_code = \$3\)`).
+		WithArgs(tenant.Global, "Turkish Lira", "TRY").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`UPDATE "currencies" SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "currency_versions" SET "valid_to"=\$1 WHERE currency_id = \$2 AND valid_to IS NULL`).
+		WithArgs(sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT COALESCE\(MAX\(version\), 0\) FROM "currency_versions" WHERE currency_id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(2))
+	mock.ExpectQuery(`INSERT INTO "currency_versions" \("currency_id","version","tenant_id","title","iso_code","valid_from","valid_to"\)`).
+		WithArgs(7, 3, "acme", "Turkish Lira", "TRY", sqlmock.AnyArg(), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	expectAuditAndOutbox(mock)
+
+	if _, err := NewCurrencyRepository(db).Update(ctx, entity.Currency{ID: 7, TenantID: "acme", Title: "Turkish Lira", IsoCode: "TRY"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateWithoutChangesKeepsTheVersion(t *testing.T) {
+	db, mock := testutil.NewMockDB(t)
+	ctx := tenant.NewContext(context.Background(), "acme")
+
+	// no statement touches currency_versions, the mock fails on any the test does not expect
+	expectLocked(mock, "Lira", "TRY")
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "currencies"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`UPDATE "currencies" SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAuditAndOutbox(mock)
+
+	if _, err := NewCurrencyRepository(db).Update(ctx, entity.Currency{ID: 7, TenantID: "acme", Title: "Lira", IsoCode: "TRY"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteOnlyClosesTheVersion(t *testing.T) {
+	db, mock := testutil.NewMockDB(t)
+	ctx := tenant.NewContext(context.Background(), "acme")
+	before := time.Now()
+
+	expectLocked(mock, "Lira", "TRY")
+	mock.ExpectExec(`DELETE FROM "currencies" WHERE "currencies"."id" = \$1`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "currency_versions" SET "valid_to"=\$1 WHERE currency_id = \$2 AND valid_to IS NULL`).
+		WithArgs(closedAfter(before), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT COALESCE\(MAX\(version\), 0\) FROM "currency_versions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	// subscribers tell the tenant of a deletion from the payload
+	mock.ExpectQuery(`INSERT INTO "outbox_events"`).
+		WithArgs(sqlmock.AnyArg(), entity.AggregateType, "7", entity.CurrencyDeletedEvent, deletedPayload{ID: 7, TenantID: "acme", Title: "Lira"}, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	if err := NewCurrencyRepository(db).Delete(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetAsOf(t *testing.T) {
+	db, mock := testutil.NewMockDB(t)
+	ctx := tenant.NewContext(context.Background(), "acme")
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// the version valid at the time, of the tenant or a global currency
+	mock.ExpectQuery(`SELECT \* FROM "currency_versions" WHERE tenant_id IN \(\$1,\$2\) AND \(currency_id = \$3 AND valid_from <= \$4 AND \(valid_to IS NULL OR valid_to > \$5\)\)`).
+		WithArgs(tenant.Global, "acme", 7, at, at).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency_id", "version", "title", "valid_from", "valid_to"}).
+			AddRow(10, 7, 2, "Lira", at.AddDate(0, -1, 0), at.AddDate(0, 1, 0)))
+	mock.ExpectQuery(`SELECT \* FROM "currency_versions" WHERE tenant_id IN \(\$1,\$2\) AND currency_id = \$3 ORDER BY version asc`).
+		WithArgs(tenant.Global, "acme", 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency_id", "version"}).AddRow(9, 7, 1).AddRow(10, 7, 2))
+
+	currencies := NewCurrencyRepository(db)
+	version, err := currencies.GetAsOf(ctx, 7, at)
+	if err != nil || version.Version != 2 || version.Title != "Lira" || version.ValidTo == nil {
+		t.Errorf("GetAsOf = %+v, %v", version, err)
+	}
+	versions, err := currencies.GetVersions(ctx, 7)
+	if err != nil || len(versions) != 2 || versions[0].Version != 1 {
+		t.Errorf("GetVersions = %+v, %v", versions, err)
+	}
+}
+
+// deletedPayload matches the outbox payload of the deleted currency.
+type deletedPayload entity.Currency
+
+func (d deletedPayload) Match(v driver.Value) bool {
+	payload, ok := v.(string)
+	deleted := entity.Currency{}
+	return ok && json.Unmarshal([]byte(payload), &deleted) == nil && deleted.ID == d.ID && deleted.TenantID == d.TenantID && deleted.Title == d.Title
+}
+
+// closedAfter matches the time a version is closed at.
+type closedAfter time.Time
+
+func (c closedAfter) Match(v driver.Value) bool {
+	at, ok := v.(time.Time)
+	return ok && !at.Before(time.Time(c)) && !at.After(time.Now())
+}
+
+func TestCreateKeepsTenantsOffTheGlobalCodes(t *testing.T) {
+	db, mock := testutil.NewMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "currencies" WHERE tenant_id = \$1 AND \(title = \$2 OR iso_code = \$3\)`).
+		WithArgs(tenant.Global, "Dollar", "USD").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	_, err := NewCurrencyRepository(db).Create(tenant.NewContext(context.Background(), "retail"), entity.Currency{TenantID: "retail", Title: "Dollar", IsoCode: "USD"})
+	if util.ParseError(err).Status() != http.StatusConflict {
+		t.Errorf("Create = %v, want 409", err)
+	}
+}
+
+func TestCurrencyCacheKey(t *testing.T) {
+	// the global keys predate tenants, cached currencies keep their keys
+	if key := CurrencyCacheKey(tenant.Global, 7); key != "currency: 7" {
+		t.Errorf("global key = %s", key)
+	}
+	if key := CurrencyCacheKey("retail", 7); key != "tenant:retail:currency: 7" {
+		t.Errorf("tenant key = %s", key)
+	}
+}
@@ -2,17 +2,20 @@ package usecase
 
 import (
 	"context"
-	"fmt"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/entity"
 	"github.com/sefikcan/kanbersky.ca/internal/currency/mapping"
 	"github.com/sefikcan/kanbersky.ca/internal/currency/repository"
 	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
 	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
 	"github.com/sefikcan/kanbersky.ca/pkg/config"
 	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
 	"github.com/sefikcan/kanbersky.ca/pkg/util"
 	"net/http"
+	"time"
 )
 
 type CurrencyUseCase interface {
@@ -21,6 +24,9 @@ type CurrencyUseCase interface {
 	GetById(ctx context.Context, id int) (*response.CurrencyResponse, error)
 	Delete(ctx context.Context, id int) error
 	GetAll(ctx context.Context, request *request.CurrencyPageableRequest) (response.CurrencyListResponse, error)
+	Export(ctx context.Context, request request.CurrencyExportRequest, fn func(*response.CurrencyResponse) error) error
+	GetAsOf(ctx context.Context, id int, at time.Time) (*response.CurrencyVersionResponse, error)
+	GetVersions(ctx context.Context, id int) (response.CurrencyVersionListResponse, error)
 }
 
 type currencyUseCase struct {
@@ -39,6 +45,7 @@ func (c currencyUseCase) Create(ctx context.Context, request request.CurrencyCre
 	}
 
 	currency := mapping.CreateMapEntity(&request)
+	currency.TenantID = tenant.FromContext(spanContext)
 
 	resp, err := c.currencyRepository.Create(spanContext, currency)
 	if err != nil {
@@ -47,8 +54,8 @@ func (c currencyUseCase) Create(ctx context.Context, request request.CurrencyCre
 
 	mappedResponse := mapping.MapDto(resp)
 
-	if err := c.currencyRedisRepository.Set(spanContext, fmt.Sprintf("%s: %v", "currency", mappedResponse.ID), 3600, mappedResponse); err != nil {
-		c.logger.Errorf("currencyUseCase.Create.SetCache: %s", err)
+	if err := c.currencyRedisRepository.Set(spanContext, repository.CurrencyCacheKey(resp.TenantID, mappedResponse.ID), repository.CurrencyCacheTtl, mappedResponse); err != nil {
+		breaker.LogError(c.logger, "currencyUseCase.Create.SetCache", err)
 	}
 
 	return mappedResponse, nil
@@ -66,6 +73,9 @@ func (c currencyUseCase) Update(ctx context.Context, request request.CurrencyUpd
 	if err != nil {
 		return nil, err
 	}
+	if err = checkOwner(spanContext, currentCurrency); err != nil {
+		return nil, err
+	}
 
 	if request.Title != "" {
 		currentCurrency.Title = request.Title
@@ -82,22 +92,27 @@ func (c currencyUseCase) Update(ctx context.Context, request request.CurrencyUpd
 
 	mappedResponse := mapping.MapDto(updatedCurrency)
 
-	if err := c.currencyRedisRepository.Set(spanContext, fmt.Sprintf("%s: %v", "currency", mappedResponse.ID),  3600, mappedResponse); err != nil {
-		c.logger.Errorf("currencyUseCase.Update.SetCache: %s", err)
+	if err := c.currencyRedisRepository.Set(spanContext, repository.CurrencyCacheKey(updatedCurrency.TenantID, mappedResponse.ID), repository.CurrencyCacheTtl, mappedResponse); err != nil {
+		breaker.LogError(c.logger, "currencyUseCase.Update.SetCache", err)
 	}
 
 	return mappedResponse, nil
 }
 
+// GetById reads the cache first, the currencies are cached under the key of their own tenant, so the keys of
+// the tenant of the request and the global ones are tried.
 func (c currencyUseCase) GetById(ctx context.Context, id int) (*response.CurrencyResponse, error) {
 	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyUseCase.GetById")
 	defer span.Finish()
 
-	currency, err := c.currencyRedisRepository.GetByKey(spanContext, fmt.Sprintf("%s: %v", "currency", id))
-	if err != nil {
-		c.logger.Errorf("currencyUseCase.GetById.Redis: %v", err)
-	} else {
-		return currency, nil
+	visibleTenants := tenant.Visible(tenant.FromContext(spanContext))
+	for i := len(visibleTenants) - 1; i >= 0; i-- {
+		currency, err := c.currencyRedisRepository.GetByKey(spanContext, repository.CurrencyCacheKey(visibleTenants[i], id))
+		if err != nil {
+			breaker.LogError(c.logger, "currencyUseCase.GetById.Redis", err)
+		} else {
+			return currency, nil
+		}
 	}
 
 	currentCurrency, err := c.currencyRepository.GetById(spanContext, id)
@@ -107,8 +122,8 @@ func (c currencyUseCase) GetById(ctx context.Context, id int) (*response.Currenc
 
 	mappedResponse := mapping.MapDto(currentCurrency)
 
-	if err := c.currencyRedisRepository.Set(spanContext, fmt.Sprintf("%s: %v", "currency", mappedResponse.ID), 3600, mappedResponse); err != nil {
-		c.logger.Errorf("currencyUseCase.GetById.SetCache: %s", err)
+	if err := c.currencyRedisRepository.Set(spanContext, repository.CurrencyCacheKey(currentCurrency.TenantID, mappedResponse.ID), repository.CurrencyCacheTtl, mappedResponse); err != nil {
+		breaker.LogError(c.logger, "currencyUseCase.GetById.SetCache", err)
 	}
 
 	return mappedResponse, nil
@@ -118,17 +133,20 @@ func (c currencyUseCase) Delete(ctx context.Context, id int) error {
 	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyUseCase.Delete")
 	defer span.Finish()
 
-	_, err := c.currencyRepository.GetById(spanContext, id)
+	currentCurrency, err := c.currencyRepository.GetById(spanContext, id)
 	if err != nil {
 		return err
 	}
+	if err = checkOwner(spanContext, currentCurrency); err != nil {
+		return err
+	}
 
 	if err = c.currencyRepository.Delete(spanContext, id); err != nil {
 		return err
 	}
 
-	if err = c.currencyRedisRepository.Delete(spanContext, fmt.Sprintf("%s: %v", "currency", id)); err != nil {
-		c.logger.Errorf("currencyUseCase.Delete.DeleteCache: %s", err)
+	if err = c.currencyRedisRepository.Delete(spanContext, repository.CurrencyCacheKey(currentCurrency.TenantID, id)); err != nil {
+		breaker.LogError(c.logger, "currencyUseCase.Delete.DeleteCache", err)
 	}
 
 	return nil
@@ -165,6 +183,58 @@ func (c currencyUseCase) GetAll(ctx context.Context, pageableRequest *request.Cu
 	}, nil
 }
 
+// Export passes the currencies of the request to fn one by one in id order, straight from the database cursor.
+func (c currencyUseCase) Export(ctx context.Context, exportRequest request.CurrencyExportRequest, fn func(*response.CurrencyResponse) error) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyUseCase.Export")
+	defer span.Finish()
+
+	if err := util.ValidateStruct(&exportRequest); err != nil {
+		return util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "currencyUseCase.Export.ValidateStruct"))
+	}
+
+	return c.currencyRepository.Export(spanContext, exportRequest.From, exportRequest.To, func(currency entity.Currency) error {
+		return fn(mapping.MapDto(currency))
+	})
+}
+
+// GetAsOf returns the currency as it was at the given time, bypassing the cache which only holds the current state.
+func (c currencyUseCase) GetAsOf(ctx context.Context, id int, at time.Time) (*response.CurrencyVersionResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyUseCase.GetAsOf")
+	defer span.Finish()
+
+	version, err := c.currencyRepository.GetAsOf(spanContext, id, at)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapVersionDto(version), nil
+}
+
+func (c currencyUseCase) GetVersions(ctx context.Context, id int) (response.CurrencyVersionListResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "currencyUseCase.GetVersions")
+	defer span.Finish()
+
+	versions, err := c.currencyRepository.GetVersions(spanContext, id)
+	if err != nil {
+		return response.CurrencyVersionListResponse{}, err
+	}
+	if len(versions) == 0 {
+		return response.CurrencyVersionListResponse{}, util.NewHttpResponse(http.StatusNotFound, util.NotFound.Error(), errors.Errorf("currencyUseCase.GetVersions: no versions of currency %d", id))
+	}
+
+	return mapping.MapVersionListDto(id, versions), nil
+}
+
+// checkOwner lets only the tenant of a currency change it, tenants see the global currencies but can not
+// change them.
+func checkOwner(ctx context.Context, currency entity.Currency) error {
+	if currency.TenantID != tenant.FromContext(ctx) {
+		return util.NewHttpResponse(http.StatusForbidden, "global currencies can not be changed by tenants", errors.Errorf("currencyUseCase.checkOwner: currency %d belongs to another tenant", currency.ID))
+	}
+
+	return nil
+}
+
 func NewCurrencyUseCase(cfg *config.Config, currencyRepository repository.CurrencyRepository, currencyRedisRepository repository.CurrencyRedisRepository, logger logger.Logger) CurrencyUseCase {
 	return &currencyUseCase{
 		cfg: cfg,
@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/repository"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"github.com/sony/gobreaker"
+	"gorm.io/gorm"
+	"net/http"
+	"testing"
+)
+
+// stubCurrencyRepository serves currencies from a map, the methods it does not override panic.
+type stubCurrencyRepository struct {
+	repository.CurrencyRepository
+	currencies map[int]entity.Currency
+	reads int
+}
+
+func (s *stubCurrencyRepository) GetById(_ context.Context, id int) (entity.Currency, error) {
+	s.reads++
+	currency, ok := s.currencies[id]
+	if !ok {
+		return entity.Currency{}, errors.Wrap(gorm.ErrRecordNotFound, "stubCurrencyRepository.GetById")
+	}
+
+	return currency, nil
+}
+
+func (s *stubCurrencyRepository) Create(_ context.Context, currency entity.Currency) (entity.Currency, error) {
+	currency.ID = len(s.currencies) + 1
+	s.currencies[currency.ID] = currency
+
+	return currency, nil
+}
+
+func (s *stubCurrencyRepository) Update(_ context.Context, currency entity.Currency) (entity.Currency, error) {
+	s.currencies[currency.ID] = currency
+
+	return currency, nil
+}
+
+func (s *stubCurrencyRepository) Delete(_ context.Context, id int) error {
+	delete(s.currencies, id)
+
+	return nil
+}
+
+// memoryCache keeps the cached currencies by key.
+type memoryCache struct {
+	currencies map[string]*response.CurrencyResponse
+}
+
+func (m *memoryCache) GetByKey(_ context.Context, key string) (*response.CurrencyResponse, error) {
+	currency, ok := m.currencies[key]
+	if !ok {
+		return nil, errors.New("redis: nil")
+	}
+
+	return currency, nil
+}
+
+func (m *memoryCache) Set(_ context.Context, key string, _ int, param any) error {
+	m.currencies[key] = param.(*response.CurrencyResponse)
+	return nil
+}
+
+func (m *memoryCache) SetMany(context.Context, int, map[string]any) error {
+	return nil
+}
+
+func (m *memoryCache) Delete(_ context.Context, key string) error {
+	delete(m.currencies, key)
+	return nil
+}
+
+// downCache fails like redis does when it is unreachable.
+type downCache struct {
+	calls int
+}
+
+func (d *downCache) GetByKey(context.Context, string) (*response.CurrencyResponse, error) {
+	d.calls++
+	return nil, errors.New("dial tcp: connection refused")
+}
+
+func (d *downCache) Set(context.Context, string, int, any) error {
+	d.calls++
+	return errors.New("dial tcp: connection refused")
+}
+
+func (d *downCache) SetMany(context.Context, int, map[string]any) error {
+	d.calls++
+	return errors.New("dial tcp: connection refused")
+}
+
+func (d *downCache) Delete(context.Context, string) error {
+	d.calls++
+	return errors.New("dial tcp: connection refused")
+}
+
+func newTestLogger() logger.Logger {
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	return l
+}
+
+func TestCurrenciesAreServedWhileRedisIsDown(t *testing.T) {
+	l := newTestLogger()
+	db := &stubCurrencyRepository{currencies: map[int]entity.Currency{1: {ID: 1, Title: "Euro", IsoCode: "EUR"}}}
+	cache := &downCache{}
+	cb := breaker.NewCircuitBreaker("redis", config.CircuitBreakerConfig{MaxFailures: 3, Timeout: 60}, nil, l)
+	currencies := NewCurrencyUseCase(&config.Config{}, db, repository.NewCurrencyRedisBreakerRepository(cache, cb), l)
+
+	for i := 0; i < 5; i++ {
+		currency, err := currencies.GetById(context.Background(), 1)
+		if err != nil || currency.IsoCode != "EUR" {
+			t.Fatalf("get %d = %+v, %v, want the currency from the database", i, currency, err)
+		}
+	}
+	if db.reads != 5 {
+		t.Errorf("database served %d reads, want 5", db.reads)
+	}
+	if cb.State() != gobreaker.StateOpen {
+		t.Errorf("state = %s, want open", cb.State())
+	}
+	// the breaker opened on the third failure, the later calls did not reach redis
+	if cache.calls != 3 {
+		t.Errorf("redis got %d calls, want 3", cache.calls)
+	}
+
+	created, err := currencies.Create(context.Background(), request.CurrencyCreateRequest{Title: "Dollar", IsoCode: "USD"})
+	if err != nil || created.IsoCode != "USD" {
+		t.Errorf("create = %+v, %v, a failed cache write must not fail the create", created, err)
+	}
+}
+
+func TestTenantsOnlyChangeTheirOwnCurrencies(t *testing.T) {
+	l := newTestLogger()
+	db := &stubCurrencyRepository{currencies: map[int]entity.Currency{
+		1: {ID: 1, Title: "Euro", IsoCode: "EUR"},
+		2: {ID: 2, TenantID: "retail", Title: "Points", IsoCode: "PTS"},
+	}}
+	cache := &memoryCache{currencies: map[string]*response.CurrencyResponse{}}
+	currencies := NewCurrencyUseCase(&config.Config{}, db, cache, l)
+	retail := tenant.NewContext(context.Background(), "retail")
+
+	if _, err := currencies.Update(retail, request.CurrencyUpdateRequest{ID: 1, Title: "Euros"}); util.ParseError(err).Status() != http.StatusForbidden {
+		t.Errorf("tenant update of a global currency = %v, want 403", err)
+	}
+	if err := currencies.Delete(retail, 1); util.ParseError(err).Status() != http.StatusForbidden {
+		t.Errorf("tenant delete of a global currency = %v, want 403", err)
+	}
+	if db.currencies[1].Title != "Euro" {
+		t.Errorf("global currency = %+v, a tenant changed it", db.currencies[1])
+	}
+
+	updated, err := currencies.Update(retail, request.CurrencyUpdateRequest{ID: 2, Title: "Stars"})
+	if err != nil || updated.Title != "Stars" {
+		t.Fatalf("Update = %+v, %v", updated, err)
+	}
+	if cached := cache.currencies[repository.CurrencyCacheKey("retail", 2)]; cached == nil || cached.Title != "Stars" {
+		t.Errorf("cache = %v, want the update under the key of the tenant", cache.currencies)
+	}
+	if _, err = currencies.Update(context.Background(), request.CurrencyUpdateRequest{ID: 2, Title: "Stars"}); util.ParseError(err).Status() != http.StatusForbidden {
+		t.Errorf("platform update of a tenant currency = %v, want 403", err)
+	}
+}
+
+func TestGetByIdReadsTheCacheOfTheTenantFirst(t *testing.T) {
+	l := newTestLogger()
+	db := &stubCurrencyRepository{currencies: map[int]entity.Currency{3: {ID: 3, TenantID: "retail", Title: "Points", IsoCode: "PTS"}}}
+	cache := &memoryCache{currencies: map[string]*response.CurrencyResponse{
+		repository.CurrencyCacheKey(tenant.Global, 1): {ID: 1, Title: "Euro"},
+		repository.CurrencyCacheKey("retail", 2): {ID: 2, TenantID: "retail", Title: "Stars"},
+		repository.CurrencyCacheKey("wholesale", 2): {ID: 2, TenantID: "wholesale", Title: "Credits"},
+	}}
+	currencies := NewCurrencyUseCase(&config.Config{}, db, cache, l)
+	retail := tenant.NewContext(context.Background(), "retail")
+
+	for id, title := range map[int]string{1: "Euro", 2: "Stars"} {
+		if currency, err := currencies.GetById(retail, id); err != nil || currency.Title != title {
+			t.Errorf("GetById(%d) = %+v, %v, want %s", id, currency, err, title)
+		}
+	}
+	if db.reads != 0 {
+		t.Errorf("database served %d reads, the cache had every currency", db.reads)
+	}
+
+	// a miss is cached under the key of the tenant the currency belongs to
+	if currency, err := currencies.GetById(retail, 3); err != nil || currency.Title != "Points" || db.reads != 1 {
+		t.Fatalf("GetById(3) = %+v, %v after %d reads", currency, err, db.reads)
+	}
+	if cache.currencies[repository.CurrencyCacheKey("retail", 3)] == nil || cache.currencies[repository.CurrencyCacheKey(tenant.Global, 3)] != nil {
+		t.Errorf("cache = %v, want currency 3 under the key of retail only", cache.currencies)
+	}
+}
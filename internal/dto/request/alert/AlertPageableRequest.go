@@ -0,0 +1,7 @@
+package alert
+
+type AlertPageableRequest struct {
+	Size int `json:"size,omitempty"`
+	Page int `json:"page,omitempty"`
+	RuleID int `json:"rule_id,omitempty"`
+}
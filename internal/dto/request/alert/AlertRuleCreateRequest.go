@@ -0,0 +1,13 @@
+package alert
+
+type AlertRuleCreateRequest struct {
+	Name string `json:"name" validate:"required,max=128"`
+	BaseCode string `json:"base_code" validate:"required,len=3,alpha"`
+	QuoteCode string `json:"quote_code" validate:"required,len=3,alpha,nefield=BaseCode"`
+	Condition string `json:"condition" validate:"required,oneof=above below change_percent"`
+	Threshold float64 `json:"threshold" validate:"required,gt=0"`
+	WindowSeconds int `json:"window_seconds" validate:"gte=0"`
+	Channel string `json:"channel" validate:"required,oneof=webhook email log"`
+	Target string `json:"target" validate:"required_if=Channel webhook,required_if=Channel email,max=512"`
+	CooldownSeconds int `json:"cooldown_seconds" validate:"gte=0"`
+}
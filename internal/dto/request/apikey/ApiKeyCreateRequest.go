@@ -0,0 +1,9 @@
+package apikey
+
+import "time"
+
+type ApiKeyCreateRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,required,excludesall= "`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
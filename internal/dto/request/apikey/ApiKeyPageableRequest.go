@@ -0,0 +1,6 @@
+package apikey
+
+type ApiKeyPageableRequest struct {
+	Size int `json:"size,omitempty"`
+	Page int `json:"page,omitempty"`
+}
@@ -0,0 +1,5 @@
+package approval
+
+type ChangeRequestDecisionRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
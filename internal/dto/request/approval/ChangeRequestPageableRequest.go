@@ -0,0 +1,7 @@
+package approval
+
+type ChangeRequestPageableRequest struct {
+	Size int `json:"size,omitempty"`
+	Page int `json:"page,omitempty"`
+	Status string `json:"status,omitempty" validate:"omitempty,oneof=pending approved rejected expired failed"`
+}
@@ -0,0 +1,15 @@
+package audit
+
+import "time"
+
+// AuditPageableRequest filters audit logs, empty fields and zero times match everything.
+type AuditPageableRequest struct {
+	Size int `json:"size,omitempty"`
+	Page int `json:"page,omitempty"`
+	Entity string `json:"entity,omitempty"`
+	ID string `json:"id,omitempty" validate:"omitempty,excluded_without=Entity"`
+	Actor string `json:"actor,omitempty"`
+	Action string `json:"action,omitempty" validate:"omitempty,oneof=create update delete"`
+	From time.Time `json:"from,omitempty"`
+	To time.Time `json:"to,omitempty" validate:"omitempty,gtfield=From"`
+}
@@ -1,6 +1,9 @@
 package currency
 
+import "encoding/xml"
+
 type CurrencyCreateRequest struct {
-	Title string `json:"title" validate:"required,min=3,max=12"`
-	IsoCode string `json:"iso_code" validate:"required,min=3"`
+	XMLName xml.Name `json:"-" xml:"currency"`
+	Title string `json:"title" xml:"title" validate:"required,min=3,max=12"`
+	IsoCode string `json:"iso_code" xml:"iso_code" validate:"required,min=3"`
 }
@@ -0,0 +1,9 @@
+package currency
+
+import "time"
+
+// CurrencyExportRequest filters exported currencies by creation time, zero times leave the range open.
+type CurrencyExportRequest struct {
+	From time.Time `json:"from,omitempty"`
+	To time.Time `json:"to,omitempty" validate:"omitempty,gtfield=From"`
+}
@@ -1,7 +1,10 @@
 package currency
 
+import "encoding/xml"
+
 type CurrencyUpdateRequest struct {
-	ID int `json:"id"`
-	Title string `json:"title"`
-	IsoCode string `json:"iso_code"`
+	XMLName xml.Name `json:"-" xml:"currency"`
+	ID int `json:"id" xml:"id"`
+	Title string `json:"title" xml:"title"`
+	IsoCode string `json:"iso_code" xml:"iso_code"`
 }
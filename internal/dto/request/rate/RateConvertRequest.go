@@ -0,0 +1,7 @@
+package rate
+
+type RateConvertRequest struct {
+	From string `json:"from" validate:"required,len=3,alpha"`
+	To string `json:"to" validate:"required,len=3,alpha"`
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+}
@@ -0,0 +1,11 @@
+package rate
+
+import "encoding/xml"
+
+type RateCreateRequest struct {
+	XMLName xml.Name `json:"-" xml:"rate"`
+	BaseCode string `json:"base_code" xml:"base_code" validate:"required,len=3,alpha"`
+	QuoteCode string `json:"quote_code" xml:"quote_code" validate:"required,len=3,alpha,nefield=BaseCode"`
+	Value float64 `json:"value" xml:"value" validate:"required,gt=0"`
+	Source string `json:"source" xml:"source" validate:"max=64"`
+}
@@ -0,0 +1,10 @@
+package rate
+
+import "time"
+
+// RateExportRequest filters exported rates by pair and creation time, zero times leave the range open.
+type RateExportRequest struct {
+	Pairs string `json:"pairs,omitempty"`
+	From time.Time `json:"from,omitempty"`
+	To time.Time `json:"to,omitempty" validate:"omitempty,gtfield=From"`
+}
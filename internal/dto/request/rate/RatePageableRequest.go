@@ -0,0 +1,7 @@
+package rate
+
+type RatePageableRequest struct {
+	Size int `json:"size,omitempty"`
+	Page int `json:"page,omitempty"`
+	Pair string `json:"pair,omitempty"`
+}
@@ -0,0 +1,6 @@
+package rate
+
+type RateStreamMessage struct {
+	Type string `json:"type"`
+	Pairs []string `json:"pairs"`
+}
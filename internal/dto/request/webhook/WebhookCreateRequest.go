@@ -0,0 +1,7 @@
+package webhook
+
+type WebhookCreateRequest struct {
+	URL string `json:"url" validate:"required,url"`
+	Events []string `json:"events" validate:"required,min=1,dive,required"`
+	Secret string `json:"secret" validate:"omitempty,min=16"`
+}
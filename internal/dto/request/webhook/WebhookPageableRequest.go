@@ -0,0 +1,6 @@
+package webhook
+
+type WebhookPageableRequest struct {
+	Size int `json:"size,omitempty"`
+	Page int `json:"page,omitempty"`
+}
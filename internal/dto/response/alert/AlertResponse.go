@@ -0,0 +1,19 @@
+package alert
+
+import "time"
+
+type AlertResponse struct {
+	ID int64 `json:"id"`
+	RuleID int `json:"rule_id"`
+	RateID int64 `json:"rate_id"`
+	Pair string `json:"pair"`
+	Value float64 `json:"value"`
+	ReferenceValue *float64 `json:"reference_value,omitempty"`
+	Message string `json:"message"`
+	Channel string `json:"channel"`
+	Status string `json:"status"`
+	Attempts int `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+	TriggeredAt time.Time `json:"triggered_at"`
+	SentAt *time.Time `json:"sent_at,omitempty"`
+}
@@ -0,0 +1,9 @@
+package alert
+
+type AlertRuleListResponse struct {
+	TotalCount int64 `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+	Page int `json:"page"`
+	Limit int `json:"limit"`
+	Rules []*AlertRuleResponse `json:"rules"`
+}
@@ -0,0 +1,18 @@
+package alert
+
+import "time"
+
+type AlertRuleResponse struct {
+	ID int `json:"id"`
+	Name string `json:"name"`
+	Pair string `json:"pair"`
+	Condition string `json:"condition"`
+	Threshold float64 `json:"threshold"`
+	WindowSeconds int `json:"window_seconds,omitempty"`
+	Channel string `json:"channel"`
+	Target string `json:"target,omitempty"`
+	CooldownSeconds int `json:"cooldown_seconds"`
+	Active bool `json:"active"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
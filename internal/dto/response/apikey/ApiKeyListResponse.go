@@ -0,0 +1,9 @@
+package apikey
+
+type ApiKeyListResponse struct {
+	TotalCount int64 `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+	Page int `json:"page"`
+	Limit int `json:"limit"`
+	ApiKeys []*ApiKeyResponse `json:"api_keys"`
+}
@@ -0,0 +1,16 @@
+package apikey
+
+import "time"
+
+type ApiKeyResponse struct {
+	ID int64 `json:"id"`
+	Name string `json:"name"`
+	TenantID string `json:"tenant_id,omitempty"`
+	Prefix string `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	Key string `json:"key,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,9 @@
+package approval
+
+type ChangeRequestListResponse struct {
+	TotalCount int64 `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+	Page int `json:"page"`
+	Limit int `json:"limit"`
+	ChangeRequests []*ChangeRequestResponse `json:"change_requests"`
+}
@@ -0,0 +1,23 @@
+package approval
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ChangeRequestResponse struct {
+	ID int64 `json:"id"`
+	Operation string `json:"operation"`
+	TenantID string `json:"tenant_id,omitempty"`
+	EntityID string `json:"entity_id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+	Status string `json:"status"`
+	Maker string `json:"maker"`
+	Checker string `json:"checker,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Result json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	Error string `json:"error,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,9 @@
+package audit
+
+type AuditLogListResponse struct {
+	TotalCount int64 `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+	Page int `json:"page"`
+	Limit int `json:"limit"`
+	AuditLogs []*AuditLogResponse `json:"audit_logs"`
+}
@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditLogResponse struct {
+	ID int64 `json:"id"`
+	EntityType string `json:"entity_type"`
+	EntityID string `json:"entity_id"`
+	Action string `json:"action"`
+	Actor string `json:"actor"`
+	RequestID string `json:"request_id"`
+	IPAddress string `json:"ip_address"`
+	Before json.RawMessage `json:"before" swaggertype:"object"`
+	After json.RawMessage `json:"after" swaggertype:"object"`
+	Changes []*AuditChangeResponse `json:"changes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuditChangeResponse struct {
+	Field string `json:"field"`
+	From json.RawMessage `json:"from" swaggertype:"object"`
+	To json.RawMessage `json:"to" swaggertype:"object"`
+}
@@ -0,0 +1,13 @@
+package cache
+
+import "time"
+
+type WarmUpResponse struct {
+	Status string `json:"status"`
+	Total int64 `json:"total"`
+	Loaded int64 `json:"loaded"`
+	Batches int `json:"batches"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error string `json:"error,omitempty"`
+}
@@ -1,7 +1,12 @@
 package currency
 
+import "time"
+
 type CurrencyResponse struct {
 	ID int `json:"id"`
 	Title string `json:"title"`
 	IsoCode string `json:"iso_code"`
+	TenantID string `json:"tenant_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,12 @@
+package currency
+
+import "encoding/xml"
+
+type CurrencyV1ListResponse struct {
+	XMLName xml.Name `json:"-" xml:"currency_list"`
+	TotalCount int64 `json:"total_count" xml:"total_count"`
+	TotalPages int `json:"total_pages" xml:"total_pages"`
+	Page int `json:"page" xml:"page"`
+	Limit int `json:"limit" xml:"limit"`
+	Currencies []*CurrencyV1Response `json:"currencies" xml:"currencies>currency"`
+}
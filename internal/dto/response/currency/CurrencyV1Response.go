@@ -0,0 +1,11 @@
+package currency
+
+import "encoding/xml"
+
+// CurrencyV1Response is the frozen /api/v1 contract, new fields only go to CurrencyV2Response.
+type CurrencyV1Response struct {
+	XMLName xml.Name `json:"-" xml:"currency"`
+	ID int `json:"id" xml:"id"`
+	Title string `json:"title" xml:"title"`
+	IsoCode string `json:"iso_code" xml:"iso_code"`
+}
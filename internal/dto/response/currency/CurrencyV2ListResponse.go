@@ -0,0 +1,21 @@
+package currency
+
+import "encoding/xml"
+
+type CurrencyV2ListResponse struct {
+	XMLName xml.Name `json:"-" xml:"currency_list"`
+	TotalCount int64 `json:"total_count" xml:"total_count"`
+	TotalPages int `json:"total_pages" xml:"total_pages"`
+	Page int `json:"page" xml:"page"`
+	Limit int `json:"limit" xml:"limit"`
+	Currencies []*CurrencyV2Response `json:"currencies" xml:"currencies>currency"`
+	Links PageLinks `json:"_links" xml:"_links"`
+}
+
+type PageLinks struct {
+	Self string `json:"self" xml:"self"`
+	First string `json:"first" xml:"first"`
+	Last string `json:"last" xml:"last"`
+	Prev string `json:"prev,omitempty" xml:"prev,omitempty"`
+	Next string `json:"next,omitempty" xml:"next,omitempty"`
+}
@@ -0,0 +1,22 @@
+package currency
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+type CurrencyV2Response struct {
+	XMLName xml.Name `json:"-" xml:"currency"`
+	ID int `json:"id" xml:"id"`
+	Title string `json:"title" xml:"title"`
+	IsoCode string `json:"iso_code" xml:"iso_code"`
+	TenantID string `json:"tenant_id,omitempty" xml:"tenant_id,omitempty"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+	Links CurrencyLinks `json:"_links" xml:"_links"`
+}
+
+type CurrencyLinks struct {
+	Self string `json:"self" xml:"self"`
+	Collection string `json:"collection" xml:"collection"`
+}
@@ -0,0 +1,9 @@
+package currency
+
+import "encoding/xml"
+
+type CurrencyVersionListResponse struct {
+	XMLName xml.Name `json:"-" xml:"currency_version_list"`
+	ID int `json:"id" xml:"id"`
+	Versions []*CurrencyVersionResponse `json:"versions" xml:"versions>currency_version"`
+}
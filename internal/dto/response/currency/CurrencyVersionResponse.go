@@ -0,0 +1,16 @@
+package currency
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+type CurrencyVersionResponse struct {
+	XMLName xml.Name `json:"-" xml:"currency_version"`
+	ID int `json:"id" xml:"id"`
+	Version int `json:"version" xml:"version"`
+	Title string `json:"title" xml:"title"`
+	IsoCode string `json:"iso_code" xml:"iso_code"`
+	ValidFrom time.Time `json:"valid_from" xml:"valid_from"`
+	ValidTo *time.Time `json:"valid_to" xml:"valid_to,omitempty"`
+}
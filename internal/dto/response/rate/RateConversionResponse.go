@@ -0,0 +1,18 @@
+package rate
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+type RateConversionResponse struct {
+	XMLName xml.Name `json:"-" xml:"conversion"`
+	From string `json:"from" xml:"from"`
+	To string `json:"to" xml:"to"`
+	Amount float64 `json:"amount" xml:"amount"`
+	Rate float64 `json:"rate" xml:"rate"`
+	Result float64 `json:"result" xml:"result"`
+	Inverted bool `json:"inverted" xml:"inverted"`
+	RateID int64 `json:"rate_id" xml:"rate_id"`
+	AsOf time.Time `json:"as_of" xml:"as_of"`
+}
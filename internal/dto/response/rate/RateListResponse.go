@@ -0,0 +1,12 @@
+package rate
+
+import "encoding/xml"
+
+type RateListResponse struct {
+	XMLName xml.Name `json:"-" xml:"rate_list"`
+	TotalCount int64 `json:"total_count" xml:"total_count"`
+	TotalPages int `json:"total_pages" xml:"total_pages"`
+	Page int `json:"page" xml:"page"`
+	Limit int `json:"limit" xml:"limit"`
+	Rates []*RateResponse `json:"rates" xml:"rates>rate"`
+}
@@ -0,0 +1,18 @@
+package rate
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+type RateResponse struct {
+	XMLName xml.Name `json:"-" xml:"rate"`
+	ID int64 `json:"id" xml:"id"`
+	Pair string `json:"pair" xml:"pair"`
+	BaseCode string `json:"base_code" xml:"base_code"`
+	QuoteCode string `json:"quote_code" xml:"quote_code"`
+	Value float64 `json:"value" xml:"value"`
+	Source string `json:"source" xml:"source"`
+	TenantID string `json:"tenant_id,omitempty" xml:"tenant_id,omitempty"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+}
@@ -0,0 +1,9 @@
+package rate
+
+type RateStreamMessage struct {
+	Type string `json:"type"`
+	Pairs []string `json:"pairs,omitempty"`
+	Rates []*RateResponse `json:"rates,omitempty"`
+	Rate *RateResponse `json:"rate,omitempty"`
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,8 @@
+package rbac
+
+type PermissionsResponse struct {
+	Subject string `json:"subject"`
+	Roles []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	ApprovalPermissions []string `json:"approval_permissions"`
+}
@@ -0,0 +1,9 @@
+package webhook
+
+type WebhookDeliveryListResponse struct {
+	TotalCount int64 `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+	Page int `json:"page"`
+	Limit int `json:"limit"`
+	Deliveries []*WebhookDeliveryResponse `json:"deliveries"`
+}
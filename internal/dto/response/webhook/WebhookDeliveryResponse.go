@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookDeliveryResponse struct {
+	ID int64 `json:"id"`
+	SubscriptionID int `json:"subscription_id"`
+	EventID string `json:"event_id"`
+	EventType string `json:"event_type"`
+	Payload json.RawMessage `json:"payload" swaggertype:"object"`
+	Status string `json:"status"`
+	Attempts int `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastStatusCode int `json:"last_status_code"`
+	LastError string `json:"last_error,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	AttemptHistory []*WebhookDeliveryAttemptResponse `json:"attempt_history,omitempty"`
+}
+
+type WebhookDeliveryAttemptResponse struct {
+	StatusCode int `json:"status_code"`
+	ResponseBody string `json:"response_body,omitempty"`
+	Error string `json:"error,omitempty"`
+	DurationMs int64 `json:"duration_ms"`
+	CreatedAt time.Time `json:"created_at"`
+}
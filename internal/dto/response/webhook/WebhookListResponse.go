@@ -0,0 +1,9 @@
+package webhook
+
+type WebhookListResponse struct {
+	TotalCount int64 `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+	Page int `json:"page"`
+	Limit int `json:"limit"`
+	Webhooks []*WebhookResponse `json:"webhooks"`
+}
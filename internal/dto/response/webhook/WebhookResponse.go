@@ -0,0 +1,12 @@
+package webhook
+
+import "time"
+
+type WebhookResponse struct {
+	ID int `json:"id"`
+	URL string `json:"url"`
+	Events []string `json:"events"`
+	Active bool `json:"active"`
+	Secret string `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
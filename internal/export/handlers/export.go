@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	currencyUseCase "github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	currencyRequest "github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	rateRequest "github.com/sefikcan/kanbersky.ca/internal/dto/request/rate"
+	currencyResponse "github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	rateResponse "github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	rateUseCase "github.com/sefikcan/kanbersky.ca/internal/rate/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/codec"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFlushRows = 1000
+	dateLayout = "2006-01-02"
+	fileTimeLayout = "20060102T150405Z"
+)
+
+var fileExtensions = map[string]string{
+	codec.MediaTypeCSV: "csv",
+	codec.MediaTypeNDJSON: "ndjson",
+}
+
+// ExportHandlers stream whole tables as CSV or NDJSON downloads, row by row from a database cursor.
+type ExportHandlers interface {
+	Currencies() echo.HandlerFunc
+	Rates() echo.HandlerFunc
+}
+
+type exportHandlers struct {
+	cfg *config.Config
+	currencyUseCase currencyUseCase.CurrencyUseCase
+	rateUseCase rateUseCase.RateUseCase
+	logger logger.Logger
+}
+
+// Currencies godoc
+// @Summary Export currencies
+// @Description Streams every currency created in [from, to) as a CSV or NDJSON download, gzipped when the client accepts it
+// @Tags Export
+// @Produce text/csv,application/x-ndjson
+// @Param format query string false "csv or ndjson, overrides the Accept header"
+// @Param from query string false "created at or after, 2006-01-02 or RFC3339"
+// @Param to query string false "created before, 2006-01-02 or RFC3339"
+// @Success 200 {array} currency.CurrencyResponse
+// @Failure 400 {object} util.HttpResponse
+// @Failure 406 {object} util.HttpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/export/currencies [get]
+func (x exportHandlers) Currencies() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "exportHandler.Currencies")
+		defer span.Finish()
+
+		exportRequest := currencyRequest.CurrencyExportRequest{}
+		if err := getDateRange(e, &exportRequest.From, &exportRequest.To); err != nil {
+			util.PrepareLogging(e, x.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		return x.export(e, "currencies", currencyResponse.CurrencyResponse{}, func(write func(interface{}) error) error {
+			return x.currencyUseCase.Export(ctx, exportRequest, func(currency *currencyResponse.CurrencyResponse) error {
+				return write(currency)
+			})
+		})
+	}
+}
+
+// Rates godoc
+// @Summary Export rates
+// @Description Streams the rate history created in [from, to) as a CSV or NDJSON download, gzipped when the client accepts it
+// @Tags Export
+// @Produce text/csv,application/x-ndjson
+// @Param format query string false "csv or ndjson, overrides the Accept header"
+// @Param pairs query string false "comma separated pairs, e.g. USD/TRY,EUR/TRY"
+// @Param from query string false "created at or after, 2006-01-02 or RFC3339"
+// @Param to query string false "created before, 2006-01-02 or RFC3339"
+// @Success 200 {array} rate.RateResponse
+// @Failure 400 {object} util.HttpResponse
+// @Failure 406 {object} util.HttpResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/export/rates [get]
+func (x exportHandlers) Rates() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "exportHandler.Rates")
+		defer span.Finish()
+
+		exportRequest := rateRequest.RateExportRequest{
+			Pairs: e.QueryParam("pairs"),
+		}
+		if err := getDateRange(e, &exportRequest.From, &exportRequest.To); err != nil {
+			util.PrepareLogging(e, x.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		return x.export(e, "rates", rateResponse.RateResponse{}, func(write func(interface{}) error) error {
+			return x.rateUseCase.Export(ctx, exportRequest, func(rate *rateResponse.RateResponse) error {
+				return write(rate)
+			})
+		})
+	}
+}
+
+// export runs the export with a write func that streams each row to the client. The response starts with
+// the first row, so a failure before it, like a validation error, still gets a regular error response.
+func (x exportHandlers) export(e echo.Context, name string, row interface{}, run func(write func(interface{}) error) error) error {
+	mediaType, err := getMediaType(e)
+	if err != nil {
+		resp := util.ParseError(err)
+		return e.JSON(resp.Status(), resp)
+	}
+
+	res := e.Response()
+	var rows codec.RowWriter
+	var compressor *gzip.Writer
+	written := 0
+
+	start := func() error {
+		// the server write timeout is meant for regular requests, an export takes as long as the table needs
+		if err := http.NewResponseController(res.Writer).SetWriteDeadline(time.Time{}); err != nil {
+			x.logger.Warnf("exportHandler.SetWriteDeadline: %s", err)
+		}
+
+		header := res.Header()
+		header.Set(echo.HeaderContentType, mediaType+"; charset=UTF-8")
+		header.Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s-%s.%s"`, name, time.Now().UTC().Format(fileTimeLayout), fileExtensions[mediaType]))
+		header.Set("X-Accel-Buffering", "no")
+		header.Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+
+		var w io.Writer = res
+		if acceptsGzip(e.Request()) {
+			compressor = x.newGzipWriter(res)
+			header.Set(echo.HeaderContentEncoding, "gzip")
+			w = compressor
+		}
+		res.WriteHeader(http.StatusOK)
+
+		rowWriter, err := codec.NewRowWriter(mediaType, w, row)
+		rows = rowWriter
+		return err
+	}
+
+	err = run(func(v interface{}) error {
+		if rows == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := rows.Write(v); err != nil {
+			return err
+		}
+
+		if written++; written%x.flushRows() == 0 {
+			return flush(res, rows, compressor)
+		}
+		return nil
+	})
+	if err == nil && rows == nil {
+		err = start()
+	}
+	if err != nil {
+		util.PrepareLogging(e, x.logger, err)
+		if !res.Committed {
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+		// the status is already sent, aborting the connection is the only way left to tell the client the
+		// download is incomplete
+		panic(http.ErrAbortHandler)
+	}
+
+	if err = flush(res, rows, compressor); err != nil {
+		util.PrepareLogging(e, x.logger, err)
+		return nil
+	}
+	if compressor != nil {
+		if err = compressor.Close(); err != nil {
+			util.PrepareLogging(e, x.logger, err)
+		}
+	}
+
+	return nil
+}
+
+// newGzipWriter uses the configured compression level, an unset or invalid one means the gzip default.
+func (x exportHandlers) newGzipWriter(w io.Writer) *gzip.Writer {
+	if x.cfg.Export.CompressionLevel == 0 {
+		return gzip.NewWriter(w)
+	}
+
+	compressor, err := gzip.NewWriterLevel(w, x.cfg.Export.CompressionLevel)
+	if err != nil {
+		x.logger.Warnf("Export compression level %d is invalid, the default is used: %s", x.cfg.Export.CompressionLevel, err)
+		return gzip.NewWriter(w)
+	}
+
+	return compressor
+}
+
+func (x exportHandlers) flushRows() int {
+	if x.cfg.Export.FlushRows <= 0 {
+		return defaultFlushRows
+	}
+
+	return x.cfg.Export.FlushRows
+}
+
+func flush(res *echo.Response, rows codec.RowWriter, compressor *gzip.Writer) error {
+	if err := rows.Flush(); err != nil {
+		return err
+	}
+	if compressor != nil {
+		if err := compressor.Flush(); err != nil {
+			return err
+		}
+	}
+	res.Flush()
+
+	return nil
+}
+
+// getMediaType takes the format query parameter over the Accept header.
+func getMediaType(e echo.Context) (string, error) {
+	switch strings.ToLower(e.QueryParam("format")) {
+	case "":
+		mediaType, err := codec.NegotiateRows(e.Request().Header.Get(echo.HeaderAccept))
+		if err != nil {
+			return "", util.NewHttpResponse(http.StatusNotAcceptable, strings.ToLower(err.Error()), nil)
+		}
+		return mediaType, nil
+	case "csv":
+		return codec.MediaTypeCSV, nil
+	case "ndjson":
+		return codec.MediaTypeNDJSON, nil
+	default:
+		return "", util.NewHttpResponse(http.StatusBadRequest, fmt.Sprintf("format %q is not supported, expected csv or ndjson", e.QueryParam("format")), nil)
+	}
+}
+
+func getDateRange(e echo.Context, from *time.Time, to *time.Time) error {
+	var err error
+	if *from, err = parseDate("from", e.QueryParam("from")); err != nil {
+		return err
+	}
+	*to, err = parseDate("to", e.QueryParam("to"))
+
+	return err
+}
+
+// parseDate accepts a day or an RFC3339 timestamp, an empty value is the zero time.
+func parseDate(name string, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.Parse(dateLayout, value); err == nil {
+		return date, nil
+	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a %s date or an RFC3339 timestamp", name, dateLayout)
+	}
+
+	return date, nil
+}
+
+func acceptsGzip(req *http.Request) bool {
+	for _, encoding := range strings.Split(req.Header.Get(echo.HeaderAcceptEncoding), ",") {
+		name, params, _ := strings.Cut(encoding, ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewExportHandler(cfg *config.Config, currencyUseCase currencyUseCase.CurrencyUseCase, rateUseCase rateUseCase.RateUseCase, logger logger.Logger) ExportHandlers {
+	return &exportHandlers{
+		cfg: cfg,
+		currencyUseCase: currencyUseCase,
+		rateUseCase: rateUseCase,
+		logger: logger,
+	}
+}
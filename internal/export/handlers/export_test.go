@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	currencyUseCase "github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	currencyRequest "github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	rateRequest "github.com/sefikcan/kanbersky.ca/internal/dto/request/rate"
+	currencyResponse "github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	rateResponse "github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	rateUseCase "github.com/sefikcan/kanbersky.ca/internal/rate/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var createdAt = time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+// stubCurrencies streams its currencies, err is returned after failAfter rows.
+type stubCurrencies struct {
+	currencyUseCase.CurrencyUseCase
+	currencies []*currencyResponse.CurrencyResponse
+	failAfter int
+	err error
+	request currencyRequest.CurrencyExportRequest
+}
+
+func (s *stubCurrencies) Export(_ context.Context, request currencyRequest.CurrencyExportRequest, fn func(*currencyResponse.CurrencyResponse) error) error {
+	s.request = request
+	for i, currency := range s.currencies {
+		if s.err != nil && i == s.failAfter {
+			return s.err
+		}
+		if err := fn(currency); err != nil {
+			return err
+		}
+	}
+
+	return s.err
+}
+
+type stubRates struct {
+	rateUseCase.RateUseCase
+	rates []*rateResponse.RateResponse
+	request rateRequest.RateExportRequest
+}
+
+func (s *stubRates) Export(_ context.Context, request rateRequest.RateExportRequest, fn func(*rateResponse.RateResponse) error) error {
+	s.request = request
+	for _, rate := range s.rates {
+		if err := fn(rate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newCurrencies(n int) []*currencyResponse.CurrencyResponse {
+	currencies := make([]*currencyResponse.CurrencyResponse, 0, n)
+	for id := 1; id <= n; id++ {
+		currencies = append(currencies, &currencyResponse.CurrencyResponse{ID: id, Title: "Euro", IsoCode: "EUR", CreatedAt: createdAt, UpdatedAt: createdAt})
+	}
+
+	return currencies
+}
+
+func newTestServer(t *testing.T, cfg *config.Config, currencies *stubCurrencies, rates *stubRates) *httptest.Server {
+	t.Helper()
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	e := echo.New()
+	MapExportRoutes(e.Group("/export"), NewExportHandler(cfg, currencies, rates, l))
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// get leaves the body compressed, the default client would decompress it and drop the header.
+func get(t *testing.T, url string, header http.Header) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = header
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		res.Body.Close()
+	})
+
+	return res
+}
+
+func TestExportCurrencies(t *testing.T) {
+	header := "id,title,iso_code,tenant_id,created_at,updated_at\n"
+	row := func(id string) string {
+		return id + ",Euro,EUR,,2024-03-01T09:30:00Z,2024-03-01T09:30:00Z\n"
+	}
+	tests := []struct {
+		name string
+		query string
+		header http.Header
+		currencies int
+		contentType string
+		filename string
+		body string
+	}{
+		{name: "csv by default", currencies: 2, contentType: "text/csv; charset=UTF-8", filename: "csv", body: header + row("1") + row("2")},
+		{name: "empty export keeps the csv header", contentType: "text/csv; charset=UTF-8", filename: "csv", body: header},
+		{name: "ndjson by accept", header: http.Header{echo.HeaderAccept: {"application/x-ndjson"}}, currencies: 2, contentType: "application/x-ndjson; charset=UTF-8", filename: "ndjson",
+			body: `{"id":1,"title":"Euro","iso_code":"EUR","created_at":"2024-03-01T09:30:00Z","updated_at":"2024-03-01T09:30:00Z"}` + "\n" + `{"id":2,"title":"Euro","iso_code":"EUR","created_at":"2024-03-01T09:30:00Z","updated_at":"2024-03-01T09:30:00Z"}` + "\n"},
+		{name: "format overrides accept", query: "?format=CSV", header: http.Header{echo.HeaderAccept: {"application/x-ndjson"}}, currencies: 1, contentType: "text/csv; charset=UTF-8", filename: "csv", body: header + row("1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, &config.Config{Export: config.ExportConfig{FlushRows: 1}}, &stubCurrencies{currencies: newCurrencies(tt.currencies)}, &stubRates{})
+			res := get(t, server.URL+"/export/currencies"+tt.query, tt.header)
+
+			if res.StatusCode != http.StatusOK || res.Header.Get(echo.HeaderContentType) != tt.contentType {
+				t.Fatalf("status %d, content type %s, want 200 and %s", res.StatusCode, res.Header.Get(echo.HeaderContentType), tt.contentType)
+			}
+			disposition := regexp.MustCompile(`^attachment; filename="currencies-\d{8}T\d{6}Z\.` + tt.filename + `"$`)
+			if !disposition.MatchString(res.Header.Get(echo.HeaderContentDisposition)) {
+				t.Errorf("content disposition = %s", res.Header.Get(echo.HeaderContentDisposition))
+			}
+			body, err := io.ReadAll(res.Body)
+			if err != nil || string(body) != tt.body {
+				t.Errorf("body = %q, %v, want %q", body, err, tt.body)
+			}
+		})
+	}
+}
+
+func TestExportCompressesForGzipClients(t *testing.T) {
+	server := newTestServer(t, &config.Config{Export: config.ExportConfig{CompressionLevel: gzip.BestSpeed}}, &stubCurrencies{currencies: newCurrencies(3)}, &stubRates{})
+
+	res := get(t, server.URL+"/export/currencies?format=ndjson", http.Header{echo.HeaderAcceptEncoding: {"br, gzip;q=0.8"}})
+	if res.Header.Get(echo.HeaderContentEncoding) != "gzip" || res.Header.Get(echo.HeaderVary) != echo.HeaderAcceptEncoding {
+		t.Fatalf("content encoding %q, vary %q, want gzip", res.Header.Get(echo.HeaderContentEncoding), res.Header.Get(echo.HeaderVary))
+	}
+	reader, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := json.NewDecoder(reader)
+	for id := 1; id <= 3; id++ {
+		currency := currencyResponse.CurrencyResponse{}
+		if err = decoder.Decode(&currency); err != nil || currency.ID != id {
+			t.Fatalf("row %d = %+v, %v", id, currency, err)
+		}
+	}
+
+	// q=0 turns gzip off
+	res = get(t, server.URL+"/export/currencies", http.Header{echo.HeaderAcceptEncoding: {"gzip;q=0"}})
+	if res.Header.Get(echo.HeaderContentEncoding) != "" {
+		t.Errorf("content encoding = %q, want none", res.Header.Get(echo.HeaderContentEncoding))
+	}
+}
+
+func TestExportRates(t *testing.T) {
+	rates := &stubRates{rates: []*rateResponse.RateResponse{{ID: 1, Pair: "USD/TRY", BaseCode: "USD", QuoteCode: "TRY", Value: 32.25, Source: "manual", CreatedAt: createdAt}}}
+	server := newTestServer(t, &config.Config{}, &stubCurrencies{}, rates)
+
+	res := get(t, server.URL+"/export/rates?pairs=USD/TRY&from=2024-03-01&to=2024-03-02T12:00:00Z", nil)
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,pair,base_code,quote_code,value,source,tenant_id,created_at\n1,USD/TRY,USD,TRY,32.25,manual,,2024-03-01T09:30:00Z\n"; string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+	if !strings.HasPrefix(res.Header.Get(echo.HeaderContentDisposition), `attachment; filename="rates-`) {
+		t.Errorf("content disposition = %s", res.Header.Get(echo.HeaderContentDisposition))
+	}
+
+	want := rateRequest.RateExportRequest{Pairs: "USD/TRY", From: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)}
+	if rates.request != want {
+		t.Errorf("request = %+v, want %+v", rates.request, want)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		query string
+		accept string
+		err error
+		status int
+	}{
+		{name: "malformed date", query: "?from=01.03.2024", status: http.StatusBadRequest},
+		{name: "unknown format", query: "?format=xlsx", status: http.StatusBadRequest},
+		{name: "not acceptable", accept: "image/png", status: http.StatusNotAcceptable},
+		{name: "validation error", err: util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), nil), status: http.StatusBadRequest},
+		{name: "error before the first row", err: errors.New("connection refused"), status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, &config.Config{}, &stubCurrencies{currencies: newCurrencies(2), err: tt.err}, &stubRates{})
+			res := get(t, server.URL+"/export/currencies"+tt.query, http.Header{echo.HeaderAccept: {tt.accept}})
+
+			if res.StatusCode != tt.status || !strings.HasPrefix(res.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+				t.Errorf("status %d, content type %s, want %d and json", res.StatusCode, res.Header.Get(echo.HeaderContentType), tt.status)
+			}
+		})
+	}
+}
+
+func TestExportAbortsAfterTheFirstRow(t *testing.T) {
+	currencies := &stubCurrencies{currencies: newCurrencies(3), failAfter: 2, err: errors.New("connection reset")}
+	server := newTestServer(t, &config.Config{Export: config.ExportConfig{FlushRows: 1}}, currencies, &stubRates{})
+
+	res := get(t, server.URL+"/export/currencies", nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", res.StatusCode)
+	}
+
+	// the rows sent so far arrive, the download itself must not look complete
+	body, err := io.ReadAll(res.Body)
+	if err == nil {
+		t.Errorf("body = %q, want a broken download", body)
+	}
+	if lines := strings.Count(string(body), "\n"); lines != 3 {
+		t.Errorf("received %d lines, want the header and 2 rows: %q", lines, body)
+	}
+}
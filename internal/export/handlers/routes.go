@@ -0,0 +1,8 @@
+package handlers
+
+import "github.com/labstack/echo/v4"
+
+func MapExportRoutes(exportRouteGroup *echo.Group, x ExportHandlers) {
+	exportRouteGroup.GET("/currencies", x.Currencies())
+	exportRouteGroup.GET("/rates", x.Rates())
+}
@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	currencyUseCase "github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	rateUseCase "github.com/sefikcan/kanbersky.ca/internal/rate/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+)
+
+const (
+	defaultMaxDepth = 8
+	defaultMaxComplexity = 500
+	queryCacheSize = 1000
+)
+
+type GraphQLHandlers interface {
+	Query() echo.HandlerFunc
+}
+
+type graphQLHandlers struct {
+	cfg *config.Config
+	rateUseCase rateUseCase.RateUseCase
+	server *handler.Server
+	logger logger.Logger
+}
+
+// Query godoc
+// @Summary GraphQL endpoint
+// @Description Executes a GraphQL query over currencies, rates and conversions. Queries can be sent as a json body {"query": "...", "variables": {}} or with GET query parameters.
+// @Tags GraphQL
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/graphql [post]
+func (g graphQLHandlers) Query() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "graphQLHandler.Query")
+		defer span.Finish()
+
+		ctx = WithLoaders(ctx, NewLoaders(g.rateUseCase, g.cfg.GraphQL.MaxBatch))
+		g.server.ServeHTTP(e.Response(), e.Request().WithContext(ctx))
+
+		return nil
+	}
+}
+
+func (g graphQLHandlers) maxDepth() int {
+	if g.cfg.GraphQL.MaxDepth <= 0 {
+		return defaultMaxDepth
+	}
+
+	return g.cfg.GraphQL.MaxDepth
+}
+
+func (g graphQLHandlers) maxComplexity() int {
+	if g.cfg.GraphQL.MaxComplexity <= 0 {
+		return defaultMaxComplexity
+	}
+
+	return g.cfg.GraphQL.MaxComplexity
+}
+
+func NewGraphQLHandler(cfg *config.Config, currencyUseCase currencyUseCase.CurrencyUseCase, rateUseCase rateUseCase.RateUseCase, logger logger.Logger) GraphQLHandlers {
+	resolver := &Resolver{
+		cfg: cfg,
+		currencyUseCase: currencyUseCase,
+		rateUseCase: rateUseCase,
+		logger: logger,
+	}
+	g := graphQLHandlers{
+		cfg: cfg,
+		rateUseCase: rateUseCase,
+		logger: logger,
+	}
+
+	server := handler.New(NewExecutableSchema(Config{Resolvers: resolver, Complexity: complexity()}))
+	server.AddTransport(transport.GET{})
+	server.AddTransport(transport.POST{})
+	server.SetQueryCache(lru.New(queryCacheSize))
+	if cfg.GraphQL.Introspection {
+		server.Use(extension.Introspection{})
+	}
+	server.Use(DepthLimit{MaxDepth: g.maxDepth()})
+	server.Use(extension.FixedComplexityLimit(g.maxComplexity()))
+	server.SetErrorPresenter(resolver.presentError)
+	g.server = server
+
+	return g
+}
@@ -0,0 +1,216 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	currencyUseCase "github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	currencyRequest "github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	rateRequest "github.com/sefikcan/kanbersky.ca/internal/dto/request/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	rateUseCase "github.com/sefikcan/kanbersky.ca/internal/rate/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type stubCurrencies struct {
+	currencyUseCase.CurrencyUseCase
+	currencies []*currency.CurrencyResponse
+}
+
+func (s *stubCurrencies) GetById(_ context.Context, id int) (*currency.CurrencyResponse, error) {
+	for _, c := range s.currencies {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	if id < 0 {
+		return nil, errors.New("connection refused")
+	}
+
+	return nil, errors.Wrap(gorm.ErrRecordNotFound, "stubCurrencies.GetById")
+}
+
+func (s *stubCurrencies) GetAll(_ context.Context, request *currencyRequest.CurrencyPageableRequest) (currency.CurrencyListResponse, error) {
+	return currency.CurrencyListResponse{TotalCount: int64(len(s.currencies)), TotalPages: 1, Page: request.Page, Limit: request.Size, Currencies: s.currencies}, nil
+}
+
+// stubRates counts the use case calls, the resolvers of one query run concurrently.
+type stubRates struct {
+	rateUseCase.RateUseCase
+	mu sync.Mutex
+	latestCalls int
+	historyCalls [][]string
+}
+
+func (s *stubRates) GetLatest(_ context.Context, pairs string) ([]*rate.RateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latestCalls++
+	return []*rate.RateResponse{
+		{ID: 1, Pair: "USD/TRY", BaseCode: "USD", QuoteCode: "TRY", Value: 32},
+		{ID: 2, Pair: "EUR/TRY", BaseCode: "EUR", QuoteCode: "TRY", Value: 35},
+		{ID: 3, Pair: "EUR/USD", BaseCode: "EUR", QuoteCode: "USD", Value: 1.1},
+	}, nil
+}
+
+func (s *stubRates) GetHistory(_ context.Context, pairs []string, limit int) (map[string][]*rate.RateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.historyCalls = append(s.historyCalls, pairs)
+	history := make(map[string][]*rate.RateResponse, len(pairs))
+	for _, pair := range pairs {
+		for id := 1; id <= limit; id++ {
+			history[pair] = append(history[pair], &rate.RateResponse{ID: int64(id), Pair: pair})
+		}
+	}
+
+	return history, nil
+}
+
+func (s *stubRates) GetAll(_ context.Context, request *rateRequest.RatePageableRequest) (rate.RateListResponse, error) {
+	return rate.RateListResponse{}, errors.New("connection refused")
+}
+
+type graphQLResponse struct {
+	Data map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func newTestServer(t *testing.T, cfg *config.Config, rates *stubRates) *httptest.Server {
+	t.Helper()
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	currencies := &stubCurrencies{currencies: []*currency.CurrencyResponse{
+		{ID: 1, Title: "Dollar", IsoCode: "USD"},
+		{ID: 2, Title: "Euro", IsoCode: "EUR"},
+		{ID: 3, Title: "Lira", IsoCode: "TRY"},
+	}}
+	e := echo.New()
+	MapGraphQLRoutes(e.Group("/graphql"), NewGraphQLHandler(cfg, currencies, rates, l))
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func query(t *testing.T, server *httptest.Server, q string) graphQLResponse {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{"query": q})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Post(server.URL+"/graphql", echo.MIMEApplicationJSON, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	resp := graphQLResponse{}
+	if err = json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	return resp
+}
+
+func TestNestedRatesAreBatched(t *testing.T) {
+	rates := &stubRates{}
+	server := newTestServer(t, &config.Config{}, rates)
+
+	resp := query(t, server, `{ currencies { totalCount currencies { isoCode latestRates { pair } history(quote: "TRY", limit: 2) { id } } } }`)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("errors = %+v", resp.Errors)
+	}
+
+	var list struct {
+		TotalCount int `json:"totalCount"`
+		Currencies []struct {
+			IsoCode string `json:"isoCode"`
+			LatestRates []struct {
+				Pair string `json:"pair"`
+			} `json:"latestRates"`
+			History []struct {
+				ID string `json:"id"`
+			} `json:"history"`
+		} `json:"currencies"`
+	}
+	if err := json.Unmarshal(resp.Data["currencies"], &list); err != nil {
+		t.Fatal(err)
+	}
+	if list.TotalCount != 3 || len(list.Currencies) != 3 {
+		t.Fatalf("currencies = %+v", list)
+	}
+	// latest rates are grouped by base currency, the lira quotes nothing
+	for currencyIndex, want := range []int{1, 2, 0} {
+		if got := len(list.Currencies[currencyIndex].LatestRates); got != want {
+			t.Errorf("%s has %d latest rates, want %d", list.Currencies[currencyIndex].IsoCode, got, want)
+		}
+	}
+	if len(list.Currencies[0].History) != 2 || list.Currencies[0].History[0].ID != "1" {
+		t.Errorf("USD/TRY history = %+v, want 2 rates with string ids", list.Currencies[0].History)
+	}
+
+	// one call per loader instead of one per currency
+	if rates.latestCalls != 1 || len(rates.historyCalls) != 1 || len(rates.historyCalls[0]) != 3 {
+		t.Errorf("latest calls %d, history calls %v, want one of each", rates.latestCalls, rates.historyCalls)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	server := newTestServer(t, &config.Config{GraphQL: config.GraphQLConfig{MaxDepth: 3, MaxComplexity: 200}}, &stubRates{})
+
+	resp := query(t, server, `{ currency(id: 404) { title } }`)
+	if len(resp.Errors) != 0 || string(resp.Data["currency"]) != "null" {
+		t.Errorf("missing currency = %s, %+v, want null without errors", resp.Data["currency"], resp.Errors)
+	}
+
+	tests := []struct {
+		name string
+		query string
+		code string
+		message string
+	}{
+		{name: "depth limit", query: `{ currencies { currencies { history(quote: "TRY") { pair } } } }`, code: errDepthLimit},
+		{name: "complexity limit", query: `{ currencies(size: 100) { currencies { title } } }`, code: "COMPLEXITY_LIMIT_EXCEEDED"},
+		{name: "negative size", query: `{ currencies(size: -1) { currencies { title } } }`, code: "BAD_REQUEST"},
+		{name: "negative size of rates", query: `{ rates(size: -1) { totalCount } }`, code: "BAD_REQUEST"},
+		{name: "size zero", query: `{ currencies(size: 0) { totalCount } }`, code: "BAD_REQUEST"},
+		{name: "size over the maximum", query: `{ rates(size: 101) { totalCount } }`, code: "BAD_REQUEST"},
+		{name: "page zero", query: `{ currencies(page: 0) { totalCount } }`, code: "BAD_REQUEST"},
+		{name: "negative size does not offset the complexity", query: `{ a: currencies(size: -1000) { currencies { title } } b: currencies(size: 100) { currencies { title } } }`, code: "COMPLEXITY_LIMIT_EXCEEDED"},
+		{name: "internal error", query: `{ rates { totalCount } }`, code: "INTERNAL_SERVER_ERROR", message: "internal server error"},
+		{name: "internal error of a nullable field", query: `{ currency(id: -1) { title } }`, code: "INTERNAL_SERVER_ERROR", message: "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := query(t, server, tt.query)
+			if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != tt.code {
+				t.Fatalf("errors = %+v, want one %s error", resp.Errors, tt.code)
+			}
+			if tt.message != "" && resp.Errors[0].Message != tt.message {
+				t.Errorf("message = %q, want %q without details", resp.Errors[0].Message, tt.message)
+			}
+			if strings.Contains(resp.Errors[0].Message, "connection refused") {
+				t.Errorf("message leaks the cause: %q", resp.Errors[0].Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"context"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const errDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+
+// DepthLimit rejects operations whose selections nest deeper than the limit, before any resolver runs.
+type DepthLimit struct {
+	MaxDepth int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = DepthLimit{}
+
+func (d DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (d DepthLimit) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (d DepthLimit) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if d.MaxDepth <= 0 || rc.Operation == nil {
+		return nil
+	}
+
+	depth := selectionDepth(rc.Operation.SelectionSet, map[string]bool{})
+	if depth > d.MaxDepth {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.MaxDepth)
+		err.Extensions = map[string]interface{}{"code": errDepthLimit}
+		return err
+	}
+
+	return nil
+}
+
+// selectionDepth follows fragments too, visiting tracks the fragments on the current path so a
+// fragment cycle cannot recurse forever.
+func selectionDepth(selections ast.SelectionSet, visiting map[string]bool) int {
+	depth := 0
+	for _, selection := range selections {
+		current := 0
+		switch s := selection.(type) {
+		case *ast.Field:
+			if len(s.SelectionSet) > 0 {
+				current = 1 + selectionDepth(s.SelectionSet, visiting)
+			} else {
+				current = 1
+			}
+		case *ast.InlineFragment:
+			current = selectionDepth(s.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			if s.Definition == nil || visiting[s.Name] {
+				continue
+			}
+			visiting[s.Name] = true
+			current = selectionDepth(s.Definition.SelectionSet, visiting)
+			delete(visiting, s.Name)
+		}
+		if current > depth {
+			depth = current
+		}
+	}
+
+	return depth
+}
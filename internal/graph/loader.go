@@ -0,0 +1,77 @@
+package graph
+
+import (
+	"context"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/dataloader"
+)
+
+type loadersCtxKey struct{}
+
+type historyKey struct {
+	Pair string
+	Limit int
+}
+
+// Loaders batch the per currency rate lookups of one request, so a currency list costs one rate query
+// instead of one per currency.
+type Loaders struct {
+	latestRates *dataloader.Loader[string, []*response.RateResponse]
+	history *dataloader.Loader[historyKey, []*response.RateResponse]
+}
+
+func NewLoaders(rateUseCase usecase.RateUseCase, maxBatch int) *Loaders {
+	return &Loaders{
+		latestRates: dataloader.New(latestRatesByBase(rateUseCase), 0, maxBatch),
+		history: dataloader.New(historyByPair(rateUseCase), 0, maxBatch),
+	}
+}
+
+func WithLoaders(ctx context.Context, loaders *Loaders) context.Context {
+	return context.WithValue(ctx, loadersCtxKey{}, loaders)
+}
+
+func loadersFor(ctx context.Context) *Loaders {
+	return ctx.Value(loadersCtxKey{}).(*Loaders)
+}
+
+// latestRatesByBase reads the latest rate of every pair once and groups it by base currency.
+func latestRatesByBase(rateUseCase usecase.RateUseCase) dataloader.BatchFunc[string, []*response.RateResponse] {
+	return func(ctx context.Context, baseCodes []string) (map[string][]*response.RateResponse, error) {
+		rates, err := rateUseCase.GetLatest(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+
+		byBase := make(map[string][]*response.RateResponse, len(baseCodes))
+		for _, rate := range rates {
+			byBase[rate.BaseCode] = append(byBase[rate.BaseCode], rate)
+		}
+
+		return byBase, nil
+	}
+}
+
+// historyByPair loads the history of all pairs sharing a limit with one use case call.
+func historyByPair(rateUseCase usecase.RateUseCase) dataloader.BatchFunc[historyKey, []*response.RateResponse] {
+	return func(ctx context.Context, keys []historyKey) (map[historyKey][]*response.RateResponse, error) {
+		pairsByLimit := make(map[int][]string)
+		for _, key := range keys {
+			pairsByLimit[key.Limit] = append(pairsByLimit[key.Limit], key.Pair)
+		}
+
+		history := make(map[historyKey][]*response.RateResponse, len(keys))
+		for limit, pairs := range pairsByLimit {
+			rates, err := rateUseCase.GetHistory(ctx, pairs, limit)
+			if err != nil {
+				return nil, err
+			}
+			for pair, pairRates := range rates {
+				history[historyKey{Pair: pair, Limit: limit}] = pairRates
+			}
+		}
+
+		return history, nil
+	}
+}
@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"context"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/pkg/errors"
+	currencyUseCase "github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	rateUseCase "github.com/sefikcan/kanbersky.ca/internal/rate/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultPage = 1
+	defaultSize = 10
+	maxSize = 100
+	defaultHistoryLimit = 30
+	// latestRatesComplexity estimates the pairs quoted against one currency
+	latestRatesComplexity = 10
+)
+
+// Resolver answers GraphQL queries through the same use cases as the REST handlers.
+type Resolver struct {
+	cfg *config.Config
+	currencyUseCase currencyUseCase.CurrencyUseCase
+	rateUseCase rateUseCase.RateUseCase
+	logger logger.Logger
+}
+
+// complexity weighs list fields by the number of items they can return, so the complexity limit
+// also bounds nested lists like currencies { history }.
+func complexity() ComplexityRoot {
+	c := ComplexityRoot{}
+	c.Query.Currencies = func(childComplexity int, page *int, size *int) int {
+		return 1 + clamp(intValue(size, defaultSize), maxSize)*childComplexity
+	}
+	c.Query.Rates = func(childComplexity int, pair *string, page *int, size *int) int {
+		return 1 + clamp(intValue(size, defaultSize), maxSize)*childComplexity
+	}
+	c.Currency.LatestRates = func(childComplexity int) int {
+		return 1 + latestRatesComplexity*childComplexity
+	}
+	c.Currency.History = func(childComplexity int, quote string, limit *int) int {
+		return 1 + clamp(intValue(limit, defaultHistoryLimit), rateUseCase.MaxHistoryLimit)*childComplexity
+	}
+
+	return c
+}
+
+// clamp keeps a list size of complexity() between 1 and max, a negative size would make the complexity
+// negative and let any query pass the limit. The resolvers reject such sizes anyway.
+func clamp(value int, max int) int {
+	if value < 1 {
+		return 1
+	}
+	if value > max {
+		return max
+	}
+
+	return value
+}
+
+// pageable checks the paging arguments of a list field, a size out of range would drop the limit of the
+// query and read the whole table.
+func pageable(page *int, size *int) (int, int, error) {
+	pageValue, sizeValue := intValue(page, defaultPage), intValue(size, defaultSize)
+	if pageValue < 1 || sizeValue < 1 || sizeValue > maxSize {
+		return 0, 0, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.Errorf("page must be positive and size between 1 and %d", maxSize))
+	}
+
+	return pageValue, sizeValue, nil
+}
+
+// presentError classifies resolver errors like the REST handlers do. Internal errors are logged and
+// reported without details.
+func (r *Resolver) presentError(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	var existing *gqlerror.Error
+	if errors.As(err, &existing) && existing.Extensions != nil {
+		return gqlErr
+	}
+
+	resp := util.ParseError(err)
+	gqlErr.Message = util.ResponseMessage(resp)
+	if resp.Status() >= http.StatusInternalServerError {
+		r.logger.Errorf("GraphQL, Path: %s, Error: %s", gqlErr.Path, err)
+		gqlErr.Message = strings.ToLower(util.InternalServerError.Error())
+	}
+	gqlErr.Extensions = map[string]interface{}{
+		"code": strings.ToUpper(strings.ReplaceAll(http.StatusText(resp.Status()), " ", "_")),
+		"status": resp.Status(),
+	}
+
+	return gqlErr
+}
+
+func intValue(value *int, defaultValue int) int {
+	if value == nil {
+		return defaultValue
+	}
+
+	return *value
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
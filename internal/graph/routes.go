@@ -0,0 +1,8 @@
+package graph
+
+import "github.com/labstack/echo/v4"
+
+func MapGraphQLRoutes(graphQLRouteGroup *echo.Group, g GraphQLHandlers) {
+	graphQLRouteGroup.GET("", g.Query())
+	graphQLRouteGroup.POST("", g.Query())
+}
@@ -0,0 +1,150 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.36
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	currencyRequest "github.com/sefikcan/kanbersky.ca/internal/dto/request/currency"
+	rateRequest "github.com/sefikcan/kanbersky.ca/internal/dto/request/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+)
+
+// RateID is the resolver for the rateId field.
+func (r *conversionResolver) RateID(ctx context.Context, obj *rate.RateConversionResponse) (*int64, error) {
+	if obj.RateID == 0 {
+		return nil, nil
+	}
+
+	return &obj.RateID, nil
+}
+
+// AsOf is the resolver for the asOf field.
+func (r *conversionResolver) AsOf(ctx context.Context, obj *rate.RateConversionResponse) (*time.Time, error) {
+	if obj.AsOf.IsZero() {
+		return nil, nil
+	}
+
+	return &obj.AsOf, nil
+}
+
+// LatestRates is the resolver for the latestRates field.
+func (r *currencyResolver) LatestRates(ctx context.Context, obj *currency.CurrencyResponse) ([]*rate.RateResponse, error) {
+	rates, err := loadersFor(ctx).latestRates.Load(ctx, strings.ToUpper(obj.IsoCode))
+	if rates == nil && err == nil {
+		return []*rate.RateResponse{}, nil
+	}
+
+	return rates, err
+}
+
+// History is the resolver for the history field.
+func (r *currencyResolver) History(ctx context.Context, obj *currency.CurrencyResponse, quote string, limit *int) ([]*rate.RateResponse, error) {
+	pair, err := entity.ParsePair(entity.FormatPair(obj.IsoCode, quote))
+	if err != nil {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), err)
+	}
+
+	rates, err := loadersFor(ctx).history.Load(ctx, historyKey{Pair: pair.String(), Limit: intValue(limit, defaultHistoryLimit)})
+	if rates == nil && err == nil {
+		return []*rate.RateResponse{}, nil
+	}
+
+	return rates, err
+}
+
+// Currency is the resolver for the currency field.
+func (r *queryResolver) Currency(ctx context.Context, id int) (*currency.CurrencyResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "queryResolver.Currency")
+	defer span.Finish()
+
+	currentCurrency, err := r.currencyUseCase.GetById(spanContext, id)
+	if err != nil && util.ParseError(err).Status() == http.StatusNotFound {
+		return nil, nil
+	}
+
+	return currentCurrency, err
+}
+
+// Currencies is the resolver for the currencies field.
+func (r *queryResolver) Currencies(ctx context.Context, page *int, size *int) (*currency.CurrencyListResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "queryResolver.Currencies")
+	defer span.Finish()
+
+	pageValue, sizeValue, err := pageable(page, size)
+	if err != nil {
+		return nil, err
+	}
+	currencies, err := r.currencyUseCase.GetAll(spanContext, &currencyRequest.CurrencyPageableRequest{
+		Page: pageValue,
+		Size: sizeValue,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &currencies, nil
+}
+
+// LatestRates is the resolver for the latestRates field.
+func (r *queryResolver) LatestRates(ctx context.Context, pairs []string) ([]*rate.RateResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "queryResolver.LatestRates")
+	defer span.Finish()
+
+	return r.rateUseCase.GetLatest(spanContext, strings.Join(pairs, ","))
+}
+
+// Rates is the resolver for the rates field.
+func (r *queryResolver) Rates(ctx context.Context, pair *string, page *int, size *int) (*rate.RateListResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "queryResolver.Rates")
+	defer span.Finish()
+
+	pageValue, sizeValue, err := pageable(page, size)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := r.rateUseCase.GetAll(spanContext, &rateRequest.RatePageableRequest{
+		Page: pageValue,
+		Size: sizeValue,
+		Pair: stringValue(pair),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rates, nil
+}
+
+// Convert is the resolver for the convert field.
+func (r *queryResolver) Convert(ctx context.Context, from string, to string, amount float64) (*rate.RateConversionResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "queryResolver.Convert")
+	defer span.Finish()
+
+	return r.rateUseCase.Convert(spanContext, rateRequest.RateConvertRequest{
+		From: from,
+		To: to,
+		Amount: amount,
+	})
+}
+
+// Conversion returns ConversionResolver implementation.
+func (r *Resolver) Conversion() ConversionResolver { return &conversionResolver{r} }
+
+// Currency returns CurrencyResolver implementation.
+func (r *Resolver) Currency() CurrencyResolver { return &currencyResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type conversionResolver struct{ *Resolver }
+type currencyResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
@@ -0,0 +1,142 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// metadata keys are the lower case http headers, grpc-gateway and curl style clients send the same names
+const (
+	metadataApiKey = "x-api-key"
+	metadataTenantId = "x-tenant-id"
+)
+
+var errMissingCredentials = errors.New("missing bearer token")
+
+// MethodPermission is the scope and the role permission a gRPC method needs, the counterpart of the
+// ScopeMiddleware and PermissionMiddleware of a route.
+type MethodPermission struct {
+	Scope string
+	Permission string
+}
+
+// isPublic tells the methods anyone may call, balancers check health without credentials.
+func isPublic(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+// AuthInterceptor authenticates the x-api-key metadata, or the bearer token of authorization when there is
+// none, and puts the principal into the context, like AuthMiddleware does for http. Missing or invalid
+// credentials get Unauthenticated. It does nothing when auth is disabled.
+func (im *InterceptorManager) AuthInterceptor(verifier auth.Verifier, keys auth.KeyAuthenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !im.cfg.Auth.Enabled || isPublic(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		principal, err := im.authenticate(ctx, verifier, keys)
+		switch {
+		case errors.Is(err, errMissingCredentials):
+			return nil, status.Error(codes.Unauthenticated, errMissingCredentials.Error())
+		case errors.Is(err, auth.ErrInvalidKey):
+			return nil, status.Error(codes.Unauthenticated, auth.ErrInvalidKey.Error())
+		case errors.Is(err, auth.ErrTokenExpired):
+			return nil, status.Error(codes.Unauthenticated, auth.ErrTokenExpired.Error())
+		case errors.Is(err, auth.ErrInvalidToken):
+			im.logger.Warnf("Authentication failed, RequestId: %s, Method: %s, Error: %s", GetRequestId(ctx), info.FullMethod, err)
+			return nil, status.Error(codes.Unauthenticated, auth.ErrInvalidToken.Error())
+		case err != nil:
+			return nil, util.ParseGrpcError(err)
+		}
+
+		return handler(auth.NewContext(ctx, principal), req)
+	}
+}
+
+func (im *InterceptorManager) authenticate(ctx context.Context, verifier auth.Verifier, keys auth.KeyAuthenticator) (auth.Principal, error) {
+	if key := metadataValue(ctx, metadataApiKey); key != "" {
+		return keys.AuthenticateKey(ctx, key)
+	}
+
+	token, ok := auth.BearerToken(metadataValue(ctx, strings.ToLower(echo.HeaderAuthorization)))
+	if !ok {
+		return auth.Principal{}, errMissingCredentials
+	}
+
+	return verifier.Verify(ctx, token)
+}
+
+// TenantInterceptor puts the tenant of the call into the context with the rules of TenantMiddleware, the
+// tenant is named with the x-tenant-id metadata. It has to run after AuthInterceptor.
+func (im *InterceptorManager) TenantInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	requested := metadataValue(ctx, metadataTenantId)
+	principal, ok := auth.FromContext(ctx)
+	tenantId, err := auth.ResolveTenant(principal, ok, requested)
+	switch {
+	case errors.Is(err, auth.ErrInvalidTenant):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, auth.ErrAnonymousTenant):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, auth.ErrForeignTenant):
+		im.logger.Warnf("Tenant denied, RequestId: %s, Subject: %s, Tenant: %s", GetRequestId(ctx), principal.Subject, requested)
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return handler(tenant.NewContext(ctx, tenantId), req)
+}
+
+// AuthorizationInterceptor checks the scope and permission of the method, methods missing from permissions
+// are refused so a new method is not open by accident. It does nothing when auth is disabled and has to run
+// after AuthInterceptor.
+func (im *InterceptorManager) AuthorizationInterceptor(authorizer auth.Authorizer, permissions map[string]MethodPermission) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !im.cfg.Auth.Enabled || isPublic(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		required, ok := permissions[info.FullMethod]
+		if !ok {
+			return nil, status.Errorf(codes.PermissionDenied, "%s is not authorized for anyone", info.FullMethod)
+		}
+		principal, ok := auth.FromContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+		if !principal.HasScope(required.Scope) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s scope is required", required.Scope)
+		}
+
+		allowed, err := authorizer.HasPermission(ctx, principal, required.Permission)
+		if err != nil {
+			return nil, util.ParseGrpcError(err)
+		}
+		if !allowed {
+			im.logger.Warnf("Permission denied, RequestId: %s, Subject: %s, Permission: %s", GetRequestId(ctx), principal.Subject, required.Permission)
+			return nil, status.Errorf(codes.PermissionDenied, "%s permission is required", required.Permission)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
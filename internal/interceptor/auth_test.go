@@ -0,0 +1,190 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+const testMethod = "/kanbersky.currency.v1.CurrencyService/GetCurrency"
+
+var errLookup = errors.New("lookup failed")
+
+type stubVerifier map[string]auth.Principal
+
+func (s stubVerifier) Verify(_ context.Context, token string) (auth.Principal, error) {
+	if token == "expired" {
+		return auth.Principal{}, auth.ErrTokenExpired
+	}
+	principal, ok := s[token]
+	if !ok {
+		return auth.Principal{}, errors.Wrap(auth.ErrInvalidToken, "signature is invalid")
+	}
+
+	return principal, nil
+}
+
+type stubKeys struct{}
+
+func (stubKeys) AuthenticateKey(_ context.Context, key string) (auth.Principal, error) {
+	if key == "broken" {
+		return auth.Principal{}, errLookup
+	}
+
+	return auth.Principal{}, auth.ErrInvalidKey
+}
+
+type stubAuthorizer map[string]bool
+
+func (s stubAuthorizer) HasPermission(_ context.Context, principal auth.Principal, permission string) (bool, error) {
+	return s[principal.Subject+" "+permission], nil
+}
+
+func newTestInterceptorManager(authEnabled bool) *InterceptorManager {
+	cfg := &config.Config{Auth: config.AuthConfig{Enabled: authEnabled}}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	return NewInterceptorManager(cfg, l)
+}
+
+func incoming(pairs ...string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(pairs...))
+}
+
+// call runs the interceptor and returns the context the handler got, nil when the handler was not called.
+func call(interceptor grpc.UnaryServerInterceptor, ctx context.Context, method string) (context.Context, error) {
+	var handled context.Context
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		handled = ctx
+		return nil, nil
+	})
+
+	return handled, err
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	im := newTestInterceptorManager(true)
+	interceptor := im.AuthInterceptor(stubVerifier{"good": {Subject: "alice"}}, stubKeys{})
+
+	tests := []struct {
+		name string
+		ctx context.Context
+		method string
+		code codes.Code
+		subject string
+	}{
+		{name: "missing credentials", ctx: context.Background(), method: testMethod, code: codes.Unauthenticated},
+		{name: "not a bearer token", ctx: incoming("authorization", "Basic Zm9v"), method: testMethod, code: codes.Unauthenticated},
+		{name: "invalid token", ctx: incoming("authorization", "Bearer bad"), method: testMethod, code: codes.Unauthenticated},
+		{name: "expired token", ctx: incoming("authorization", "Bearer expired"), method: testMethod, code: codes.Unauthenticated},
+		{name: "invalid api key", ctx: incoming("x-api-key", "kbr_nope"), method: testMethod, code: codes.Unauthenticated},
+		{name: "key lookup failure is not a credential failure", ctx: incoming("x-api-key", "broken"), method: testMethod, code: codes.Internal},
+		{name: "valid token", ctx: incoming("authorization", "Bearer good"), method: testMethod, code: codes.OK, subject: "alice"},
+		{name: "health checks are public", ctx: context.Background(), method: "/" + healthpb.Health_ServiceDesc.ServiceName + "/Check", code: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled, err := call(interceptor, tt.ctx, tt.method)
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("code = %s, want %s (%v)", code, tt.code, err)
+			}
+			if tt.code != codes.OK {
+				if handled != nil {
+					t.Fatal("handler called after a refused call")
+				}
+				return
+			}
+
+			principal, _ := auth.FromContext(handled)
+			if principal.Subject != tt.subject {
+				t.Errorf("subject = %q, want %q", principal.Subject, tt.subject)
+			}
+		})
+	}
+}
+
+func TestAuthInterceptorDisabled(t *testing.T) {
+	im := newTestInterceptorManager(false)
+
+	if _, err := call(im.AuthInterceptor(nil, nil), context.Background(), testMethod); err != nil {
+		t.Fatalf("unexpected error with auth disabled: %v", err)
+	}
+}
+
+func TestTenantInterceptor(t *testing.T) {
+	im := newTestInterceptorManager(true)
+	platform := auth.NewContext(incoming("x-tenant-id", "retail"), auth.Principal{Subject: "ops"})
+	bound := auth.NewContext(incoming("x-tenant-id", "wholesale"), auth.Principal{Subject: "shop", Tenant: "retail"})
+	own := auth.NewContext(context.Background(), auth.Principal{Subject: "shop", Tenant: "retail"})
+
+	tests := []struct {
+		name string
+		ctx context.Context
+		code codes.Code
+		tenant string
+	}{
+		{name: "anonymous gets global records", ctx: context.Background(), code: codes.OK, tenant: tenant.Global},
+		{name: "anonymous can not pick a tenant", ctx: incoming("x-tenant-id", "retail"), code: codes.Unauthenticated},
+		{name: "invalid tenant", ctx: auth.NewContext(incoming("x-tenant-id", "Retail!"), auth.Principal{Subject: "ops"}), code: codes.InvalidArgument},
+		{name: "platform picks a tenant", ctx: platform, code: codes.OK, tenant: "retail"},
+		{name: "tenant is bound to its tenant", ctx: own, code: codes.OK, tenant: "retail"},
+		{name: "tenant names another tenant", ctx: bound, code: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled, err := call(im.TenantInterceptor, tt.ctx, testMethod)
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("code = %s, want %s (%v)", code, tt.code, err)
+			}
+			if tt.code == codes.OK && tenant.FromContext(handled) != tt.tenant {
+				t.Errorf("tenant = %q, want %q", tenant.FromContext(handled), tt.tenant)
+			}
+		})
+	}
+}
+
+func TestAuthorizationInterceptor(t *testing.T) {
+	im := newTestInterceptorManager(true)
+	permissions := map[string]MethodPermission{
+		testMethod: {Scope: auth.ScopeCurrenciesRead, Permission: auth.PermissionCurrencyRead},
+	}
+	interceptor := im.AuthorizationInterceptor(stubAuthorizer{"alice " + auth.PermissionCurrencyRead: true}, permissions)
+	withScope := func(subject string, scopes ...string) context.Context {
+		return auth.NewContext(context.Background(), auth.Principal{Subject: subject, Scopes: scopes})
+	}
+
+	tests := []struct {
+		name string
+		ctx context.Context
+		method string
+		code codes.Code
+	}{
+		{name: "allowed", ctx: withScope("alice", auth.ScopeCurrenciesRead), method: testMethod, code: codes.OK},
+		{name: "unauthenticated", ctx: context.Background(), method: testMethod, code: codes.Unauthenticated},
+		{name: "missing scope", ctx: withScope("alice"), method: testMethod, code: codes.PermissionDenied},
+		{name: "missing permission", ctx: withScope("bob", auth.ScopeCurrenciesRead), method: testMethod, code: codes.PermissionDenied},
+		{name: "unknown methods are refused", ctx: withScope("alice", auth.ScopeCurrenciesRead), method: "/kanbersky.currency.v1.CurrencyService/Purge", code: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := call(interceptor, tt.ctx, tt.method)
+			if code := status.Code(err); code != tt.code {
+				t.Errorf("code = %s, want %s (%v)", code, tt.code, err)
+			}
+		})
+	}
+}
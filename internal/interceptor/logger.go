@@ -0,0 +1,26 @@
+package interceptor
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+func (im *InterceptorManager) LoggerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	address := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		address = p.Addr.String()
+	}
+
+	im.logger.Infof("RequestId: %s, Method: %s, IPAddress: %s, Status: %s, Time: %s", GetRequestId(ctx), info.FullMethod, address, status.Code(err), time.Since(start).String())
+	if err != nil {
+		im.logger.Errorf("Error, RequestId: %s, Method: %s, Error: %s", GetRequestId(ctx), info.FullMethod, err)
+	}
+
+	return resp, err
+}
@@ -0,0 +1,18 @@
+package interceptor
+
+import (
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+)
+
+type InterceptorManager struct {
+	cfg *config.Config
+	logger logger.Logger
+}
+
+func NewInterceptorManager(cfg *config.Config, logger logger.Logger) *InterceptorManager {
+	return &InterceptorManager{
+		cfg: cfg,
+		logger: logger,
+	}
+}
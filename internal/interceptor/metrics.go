@@ -0,0 +1,25 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/sefikcan/kanbersky.ca/pkg/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+const grpcMethod = "GRPC"
+
+// MetricsInterceptor records the same hit and response time series as the http middleware,
+// with the gRPC code as status and the full method as path.
+func (im *InterceptorManager) MetricsInterceptor(metrics metric.Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := int(status.Code(err))
+
+		metrics.ObserveResponseTime(code, grpcMethod, info.FullMethod, time.Since(start).Seconds())
+		metrics.IncreaseHits(code, grpcMethod, info.FullMethod)
+		return resp, err
+	}
+}
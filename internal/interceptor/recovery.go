@@ -0,0 +1,20 @@
+package interceptor
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryInterceptor turns a panic into an Internal error, like the Recover middleware does for http.
+func (im *InterceptorManager) RecoveryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			im.logger.Errorf("Panic recovered, RequestId: %s, Method: %s, Panic: %v", GetRequestId(ctx), info.FullMethod, r)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+
+	return handler(ctx, req)
+}
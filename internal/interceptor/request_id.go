@@ -0,0 +1,35 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/random"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+// RequestIdInterceptor keeps the caller's x-request-id or generates one, echoes it back in the
+// response header and stores it in the context the same way util.GetRequestCtx does for http.
+func (im *InterceptorManager) RequestIdInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	requestId := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(strings.ToLower(echo.HeaderXRequestID)); len(values) > 0 {
+			requestId = values[0]
+		}
+	}
+	if requestId == "" {
+		requestId = random.String(32)
+	}
+
+	if err := grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(echo.HeaderXRequestID), requestId)); err != nil {
+		im.logger.Warnf("RequestIdInterceptor.SetHeader: %s", err)
+	}
+
+	return handler(context.WithValue(ctx, "RequestCtx", requestId), req)
+}
+
+func GetRequestId(ctx context.Context) string {
+	requestId, _ := ctx.Value("RequestCtx").(string)
+	return requestId
+}
@@ -0,0 +1,58 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// TracingInterceptor continues the caller's trace when the metadata carries one, so the spans the
+// usecases start from the context end up under the same trace.
+func (im *InterceptorManager) TracingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	tracer := opentracing.GlobalTracer()
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	}
+	parentContext, err := tracer.Extract(opentracing.HTTPHeaders, metadataCarrier(md))
+	if err != nil && err != opentracing.ErrSpanContextNotFound {
+		im.logger.Warnf("TracingInterceptor.Extract: %s", err)
+	}
+
+	span := tracer.StartSpan(info.FullMethod, ext.RPCServerOption(parentContext), ext.SpanKindRPCServer)
+	defer span.Finish()
+	ext.Component.Set(span, "gRPC")
+
+	resp, err := handler(opentracing.ContextWithSpan(ctx, span), req)
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.SetTag("grpc.code", status.Code(err).String())
+	}
+
+	return resp, err
+}
+
+// metadataCarrier lets the tracer read and write gRPC metadata like http headers.
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Set(key, value string) {
+	key = strings.ToLower(key)
+	m[key] = append(m[key], value)
+}
+
+func (m metadataCarrier) ForeachKey(handler func(key, value string) error) error {
+	for key, values := range m {
+		for _, value := range values {
+			if err := handler(key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/audit"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+)
+
+// AuditMiddleware puts the request id and ip address into the request context, where the repositories find
+// them for the audit log of a change. It has to run after the request id middleware.
+func (mw *MiddlewareManager) AuditMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		source := audit.Source{
+			RequestId: util.GetRequestId(c),
+			IPAddress: util.GetIPAddress(c),
+		}
+		c.SetRequest(c.Request().WithContext(audit.NewContext(c.Request().Context(), source)))
+
+		return next(c)
+	}
+}
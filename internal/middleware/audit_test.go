@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/sefikcan/kanbersky.ca/pkg/audit"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	mw := newTestMiddlewareManager(&config.Config{})
+	e := echo.New()
+	e.Use(echoMiddleware.RequestIDWithConfig(echoMiddleware.RequestIDConfig{Generator: func() string {
+		return "req-1"
+	}}))
+	e.Use(mw.AuditMiddleware)
+
+	var actor audit.Actor
+	e.DELETE("/currencies/:id", func(c echo.Context) error {
+		actor = audit.FromContext(c.Request().Context())
+		return c.NoContent(http.StatusNoContent)
+	})
+	req := httptest.NewRequest(http.MethodDelete, "/currencies/7", nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	if actor != (audit.Actor{Source: audit.Source{RequestId: "req-1", IPAddress: "10.0.0.1:4321"}}) {
+		t.Errorf("actor = %+v, want the request id and address without a subject", actor)
+	}
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+)
+
+const HeaderApiKey = "X-API-Key"
+
+// errMissingCredentials is recorded for requests without an api key or bearer token.
+var errMissingCredentials = errors.New("missing bearer token")
+
+// authFailureKey holds the authentication error IdentifyMiddleware got, AuthMiddleware answers with it
+// instead of authenticating the request again.
+const authFailureKey = "auth_failure"
+
+// IdentifyMiddleware authenticates the credentials of every request that has some, so middlewares like
+// RateLimitMiddleware know the caller before routes check it. Failures are not answered here, AuthMiddleware
+// answers them on the routes that need a principal. It does nothing when auth is disabled.
+func (mw *MiddlewareManager) IdentifyMiddleware(verifier auth.Verifier, keys auth.KeyAuthenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if !mw.cfg.Auth.Enabled {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			principal, err := mw.authenticate(c, verifier, keys)
+			switch {
+			case errors.Is(err, errMissingCredentials):
+			case err != nil:
+				c.Set(authFailureKey, err)
+			default:
+				c.SetRequest(c.Request().WithContext(auth.NewContext(c.Request().Context(), principal)))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// AuthMiddleware authenticates the api key of the X-API-Key header, or the bearer token when there is none,
+// and puts the principal into the request context, where auth.FromContext finds it. Missing or invalid
+// credentials get 401, scopes are checked by ScopeMiddleware. Requests IdentifyMiddleware already
+// authenticated are not authenticated again. It does nothing when auth is disabled.
+func (mw *MiddlewareManager) AuthMiddleware(verifier auth.Verifier, keys auth.KeyAuthenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if !mw.cfg.Auth.Enabled {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			if _, ok := auth.FromContext(c.Request().Context()); ok {
+				return next(c)
+			}
+
+			err, _ := c.Get(authFailureKey).(error)
+			if err == nil {
+				var principal auth.Principal
+				if principal, err = mw.authenticate(c, verifier, keys); err == nil {
+					c.SetRequest(c.Request().WithContext(auth.NewContext(c.Request().Context(), principal)))
+					return next(c)
+				}
+			}
+
+			return mw.unauthorized(c, err)
+		}
+	}
+}
+
+func (mw *MiddlewareManager) authenticate(c echo.Context, verifier auth.Verifier, keys auth.KeyAuthenticator) (auth.Principal, error) {
+	if key := c.Request().Header.Get(HeaderApiKey); key != "" {
+		principal, err := keys.AuthenticateKey(c.Request().Context(), key)
+		if errors.Is(err, auth.ErrInvalidKey) {
+			mw.logger.Warnf("Api key authentication failed, RequestId: %s, IPAddress: %s, Error: %s", util.GetRequestId(c), util.GetIPAddress(c), err)
+		}
+		return principal, err
+	}
+
+	token, ok := auth.BearerToken(c.Request().Header.Get(echo.HeaderAuthorization))
+	if !ok {
+		return auth.Principal{}, errMissingCredentials
+	}
+
+	principal, err := verifier.Verify(c.Request().Context(), token)
+	if err != nil {
+		mw.logger.Warnf("Authentication failed, RequestId: %s, IPAddress: %s, Error: %s", util.GetRequestId(c), util.GetIPAddress(c), err)
+	}
+
+	return principal, err
+}
+
+func (mw *MiddlewareManager) unauthorized(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errMissingCredentials):
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="api"`)
+		return c.JSON(http.StatusUnauthorized, util.NewHttpResponse(http.StatusUnauthorized, errMissingCredentials.Error(), nil))
+	case errors.Is(err, auth.ErrInvalidKey):
+		return c.JSON(http.StatusUnauthorized, util.NewHttpResponse(http.StatusUnauthorized, auth.ErrInvalidKey.Error(), nil))
+	case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrTokenExpired):
+		description := auth.ErrInvalidToken.Error()
+		if errors.Is(err, auth.ErrTokenExpired) {
+			description = auth.ErrTokenExpired.Error()
+		}
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, fmt.Sprintf(`Bearer realm="api", error="invalid_token", error_description=%q`, description))
+		return c.JSON(http.StatusUnauthorized, util.NewHttpResponse(http.StatusUnauthorized, description, nil))
+	default:
+		util.PrepareLogging(c, mw.logger, err)
+		resp := util.ParseError(err)
+		return c.JSON(resp.Status(), resp)
+	}
+}
+
+// ScopeMiddleware lets safe methods (GET, HEAD, OPTIONS) through with readScope and every other method
+// with writeScope, a principal without the scope gets 403. It does nothing when auth is disabled.
+func (mw *MiddlewareManager) ScopeMiddleware(readScope string, writeScope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if !mw.cfg.Auth.Enabled {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			scope := writeScope
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				scope = readScope
+			}
+
+			principal, ok := auth.FromContext(c.Request().Context())
+			if !ok {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="api"`)
+				return c.JSON(http.StatusUnauthorized, util.NewHttpResponse(http.StatusUnauthorized, "missing bearer token", nil))
+			}
+			if !principal.HasScope(scope) {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, fmt.Sprintf(`Bearer realm="api", error="insufficient_scope", scope=%q`, scope))
+				return c.JSON(http.StatusForbidden, util.NewHttpResponse(http.StatusForbidden, fmt.Sprintf("%s scope is required", scope), nil))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// PermissionMiddleware lets principals holding the permission through, others get 403. The principal comes
+// from AuthMiddleware, which has to run first. It does nothing when auth is disabled.
+func (mw *MiddlewareManager) PermissionMiddleware(authorizer auth.Authorizer, permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if !mw.cfg.Auth.Enabled {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			principal, ok := auth.FromContext(c.Request().Context())
+			if !ok {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="api"`)
+				return c.JSON(http.StatusUnauthorized, util.NewHttpResponse(http.StatusUnauthorized, "missing bearer token", nil))
+			}
+
+			allowed, err := authorizer.HasPermission(c.Request().Context(), principal, permission)
+			if err != nil {
+				util.PrepareLogging(c, mw.logger, err)
+				resp := util.ParseError(err)
+				return c.JSON(resp.Status(), resp)
+			}
+			if !allowed {
+				mw.logger.Warnf("Permission denied, RequestId: %s, Subject: %s, Permission: %s", util.GetRequestId(c), principal.Subject, permission)
+				return c.JSON(http.StatusForbidden, util.NewHttpResponse(http.StatusForbidden, fmt.Sprintf("%s permission is required", permission), nil))
+			}
+
+			return next(c)
+		}
+	}
+}
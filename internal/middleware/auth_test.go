@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+type stubKeys struct{}
+
+func (stubKeys) AuthenticateKey(context.Context, string) (auth.Principal, error) {
+	return auth.Principal{}, auth.ErrInvalidKey
+}
+
+type stubAuthorizer map[string]bool
+
+func (s stubAuthorizer) HasPermission(_ context.Context, principal auth.Principal, permission string) (bool, error) {
+	return s[principal.Subject+" "+permission], nil
+}
+
+func signToken(t *testing.T, subject string, scope string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": subject,
+		"scope": scope,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	signed, err := token.SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	return signed
+}
+
+// newAuthTestServer mirrors the chain of MapHandlers, IdentifyMiddleware on every request and AuthMiddleware
+// and the scope check on the group.
+func newAuthTestServer(t *testing.T, readScope, writeScope string, groupMiddlewares ...echo.MiddlewareFunc) *echo.Echo {
+	t.Helper()
+	cfg := &config.Config{Auth: config.AuthConfig{Enabled: true, Secret: testSecret}}
+	mw := newTestMiddlewareManager(cfg)
+	verifier, err := auth.NewJWTVerifier(cfg.Auth)
+	if err != nil {
+		t.Fatalf("verifier: %v", err)
+	}
+
+	e := echo.New()
+	e.Use(mw.IdentifyMiddleware(verifier, stubKeys{}))
+	middlewares := append([]echo.MiddlewareFunc{mw.AuthMiddleware(verifier, stubKeys{}), mw.ScopeMiddleware(readScope, writeScope)}, groupMiddlewares...)
+	g := e.Group("/api/v1/graphql", middlewares...)
+	ok := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	g.GET("", ok)
+	g.POST("", ok)
+
+	return e
+}
+
+func TestAuthAndScopeMiddleware(t *testing.T) {
+	e := newAuthTestServer(t, auth.ScopeCurrenciesRead, auth.ScopeCurrenciesRead)
+
+	tests := []struct {
+		name string
+		method string
+		authorization string
+		status int
+	}{
+		{name: "missing token", method: http.MethodGet, status: http.StatusUnauthorized},
+		{name: "invalid token", method: http.MethodGet, authorization: "Bearer nope", status: http.StatusUnauthorized},
+		{name: "missing scope", method: http.MethodGet, authorization: "Bearer " + signToken(t, "alice", auth.ScopeApiKeysManage), status: http.StatusForbidden},
+		{name: "read scope on GET", method: http.MethodGet, authorization: "Bearer " + signToken(t, "alice", auth.ScopeCurrenciesRead), status: http.StatusOK},
+		{name: "read scope on POST query", method: http.MethodPost, authorization: "Bearer " + signToken(t, "alice", auth.ScopeCurrenciesRead), status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/graphql", nil)
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get(echo.HeaderWWWAuthenticate) == "" {
+				t.Errorf("401 without %s header", echo.HeaderWWWAuthenticate)
+			}
+		})
+	}
+}
+
+func TestScopeMiddlewareSplitsReadAndWrite(t *testing.T) {
+	e := newAuthTestServer(t, auth.ScopeCurrenciesRead, auth.ScopeCurrenciesWrite)
+	token := "Bearer " + signToken(t, "alice", auth.ScopeCurrenciesRead)
+
+	for method, status := range map[string]int{http.MethodGet: http.StatusOK, http.MethodPost: http.StatusForbidden} {
+		req := httptest.NewRequest(method, "/api/v1/graphql", nil)
+		req.Header.Set(echo.HeaderAuthorization, token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != status {
+			t.Errorf("%s: status = %d, want %d", method, rec.Code, status)
+		}
+	}
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	cfg := &config.Config{Auth: config.AuthConfig{Enabled: true, Secret: testSecret}}
+	mw := newTestMiddlewareManager(cfg)
+	authorizer := stubAuthorizer{"admin " + auth.PermissionWebhookManage: true}
+	e := newAuthTestServer(t, auth.ScopeCurrenciesRead, auth.ScopeCurrenciesRead, mw.PermissionMiddleware(authorizer, auth.PermissionWebhookManage))
+
+	for subject, status := range map[string]int{"admin": http.StatusOK, "bob": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/graphql", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+signToken(t, subject, auth.ScopeCurrenciesRead))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != status {
+			t.Errorf("%s: status = %d, want %d", subject, rec.Code, status)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/certificate"
+)
+
+// ClientCertificateMiddleware puts the identity of the verified client certificate of a mutual tls request
+// into the request context, where handlers find it with certificate.FromContext. Requests without one pass
+// unchanged, when client certificates are required the handshake already refused them.
+func (mw *MiddlewareManager) ClientCertificateMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		state := c.Request().TLS
+		if state != nil && len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+			identity := certificate.NewIdentity(state.VerifiedChains[0][0])
+			c.SetRequest(c.Request().WithContext(certificate.NewContext(c.Request().Context(), identity)))
+		}
+
+		return next(c)
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/certificate"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientCertificateMiddleware(t *testing.T) {
+	client := &x509.Certificate{Raw: []byte("client"), SerialNumber: big.NewInt(7), Subject: pkix.Name{CommonName: "importer"}, Issuer: pkix.Name{CommonName: "client ca"}}
+	ca := &x509.Certificate{Raw: []byte("ca"), SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "client ca"}}
+
+	tests := []struct {
+		name string
+		state *tls.ConnectionState
+		commonName string
+	}{
+		{name: "plain http"},
+		{name: "tls without a client certificate", state: &tls.ConnectionState{}},
+		// peer certificates the handshake did not verify, e.g. with tls.RequestClientCert, are no identity
+		{name: "unverified certificate", state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{client}}},
+		{name: "verified certificate", state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{client}, VerifiedChains: [][]*x509.Certificate{{client, ca}}}, commonName: "importer"},
+	}
+
+	mw := newTestMiddlewareManager(&config.Config{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/currencies", nil)
+			req.TLS = tt.state
+
+			var identity certificate.Identity
+			var ok bool
+			err := mw.ClientCertificateMiddleware(func(c echo.Context) error {
+				identity, ok = certificate.FromContext(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			})(echo.New().NewContext(req, httptest.NewRecorder()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok != (tt.commonName != "") || identity.CommonName != tt.commonName {
+				t.Errorf("identity = %+v, %v, want %q", identity, ok, tt.commonName)
+			}
+			if ok && (identity.Issuer != "CN=client ca" || identity.SerialNumber != "7") {
+				t.Errorf("identity = %+v, want the leaf of the verified chain", identity)
+			}
+		})
+	}
+}
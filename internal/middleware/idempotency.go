@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sefikcan/kanbersky.ca/pkg/idempotency"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	idempotencyKeyPrefix = "idempotency:"
+	maxIdempotencyKeyLength = 255
+	// responses above it are not kept, a retry runs the request again
+	maxIdempotentResponseSize = 1 << 20
+	defaultIdempotencyTtl = time.Hour * 24
+	defaultIdempotencyLockTtl = time.Minute
+	defaultIdempotencyWaitTimeout = time.Second * 10
+	idempotencyPollInterval = time.Millisecond * 100
+	idempotencyStoreTimeout = time.Second * 5
+)
+
+// replayedHeaders are the response headers kept for replays, the others are set again by the middlewares
+// of the retry.
+var replayedHeaders = []string{echo.HeaderContentType, echo.HeaderLocation, "Content-Location", "ETag", echo.HeaderLastModified}
+
+// IdempotencyMiddleware runs POST and PATCH requests with an Idempotency-Key header once per client, tenant
+// and key. Retries get the response of the first request replayed, with Idempotent-Replayed: true. A retry
+// arriving while the first request still runs waits for its response, and gets 409 when it takes longer than
+// the wait timeout. Reusing a key for a different request gets 422. Server errors and responses like 401 or
+// 429 are not kept, so the retry runs the request again. It has to be registered with e.Use after
+// IdentifyMiddleware and TenantMiddleware, and it lets requests through without idempotency while the store
+// is unavailable.
+func (mw *MiddlewareManager) IdempotencyMiddleware(store idempotency.Store) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if !mw.cfg.Idempotency.Enabled {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			req := c.Request()
+			idempotencyKey := req.Header.Get(HeaderIdempotencyKey)
+			if idempotencyKey == "" || (req.Method != http.MethodPost && req.Method != http.MethodPatch) {
+				return next(c)
+			}
+			if len(idempotencyKey) > maxIdempotencyKeyLength {
+				return c.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", HeaderIdempotencyKey, maxIdempotencyKeyLength), nil))
+			}
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				util.PrepareLogging(c, mw.logger, err)
+				resp := util.ParseBindError(err)
+				return c.JSON(resp.Status(), resp)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			client, _ := clientId(c)
+			// the {client} hash tag keeps the keys of a client in one redis cluster slot
+			key := idempotencyKeyPrefix + "{" + client + "}:" + hash(req.Method, req.URL.Path, tenant.FromContext(req.Context()), idempotencyKey)
+			lock := idempotency.Record{
+				Fingerprint: hash(req.Method, req.URL.Path, string(body)),
+				Token: newLockToken(),
+			}
+
+			deadline := time.Now().Add(mw.idempotencyWaitTimeout())
+			for {
+				record, err := store.Lock(req.Context(), key, lock, mw.idempotencyLockTtl())
+				switch {
+				case err != nil:
+					breaker.LogError(mw.logger, "Idempotency lock failed, the request runs without it", err)
+					return next(c)
+				case record == nil:
+					return mw.runIdempotent(c, next, store, key, lock)
+				case record.Fingerprint != lock.Fingerprint:
+					return c.JSON(http.StatusUnprocessableEntity, util.NewHttpResponse(http.StatusUnprocessableEntity, fmt.Sprintf("%s was already used for a different request", HeaderIdempotencyKey), nil))
+				case record.Completed:
+					return replay(c, record)
+				case !time.Now().Before(deadline):
+					c.Response().Header().Set(HeaderRetryAfter, "1")
+					return c.JSON(http.StatusConflict, util.NewHttpResponse(http.StatusConflict, fmt.Sprintf("a request with this %s is still in progress", HeaderIdempotencyKey), nil))
+				}
+
+				select {
+				case <-req.Context().Done():
+					return req.Context().Err()
+				case <-time.After(idempotencyPollInterval):
+				}
+			}
+		}
+	}
+}
+
+// runIdempotent runs the request holding the lock and keeps its response for retries.
+func (mw *MiddlewareManager) runIdempotent(c echo.Context, next echo.HandlerFunc, store idempotency.Store, key string, lock idempotency.Record) error {
+	// the recover middleware answers panics, the lock is dropped so a retry does not wait for it to expire
+	defer func() {
+		if r := recover(); r != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), idempotencyStoreTimeout)
+			defer cancel()
+			if err := store.Unlock(ctx, key, lock); err != nil {
+				breaker.LogError(mw.logger, "Idempotency unlock failed", err)
+			}
+			panic(r)
+		}
+	}()
+
+	res := c.Response()
+	capture := &responseCapture{ResponseWriter: res.Writer}
+	res.Writer = capture
+	// errors are answered here, the response of the error handler has to be captured as well
+	if err := next(c); err != nil {
+		c.Error(err)
+	}
+	res.Writer = capture.ResponseWriter
+
+	// the client may be gone, retrying after a timeout is what idempotency keys are for
+	ctx, cancel := context.WithTimeout(context.Background(), idempotencyStoreTimeout)
+	defer cancel()
+
+	if !keepIdempotentResponse(res.Status) || capture.overflow {
+		if err := store.Unlock(ctx, key, lock); err != nil {
+			breaker.LogError(mw.logger, "Idempotency unlock failed", err)
+		}
+		return nil
+	}
+
+	record := idempotency.Record{
+		Fingerprint: lock.Fingerprint,
+		Completed: true,
+		Status: res.Status,
+		Header: make(http.Header),
+		Body: capture.body.Bytes(),
+	}
+	for _, name := range replayedHeaders {
+		if values := res.Header().Values(name); len(values) > 0 {
+			record.Header[name] = values
+		}
+	}
+	if err := store.Complete(ctx, key, lock, record, mw.idempotencyTtl()); err != nil {
+		breaker.LogError(mw.logger, "Idempotency response could not be kept", err)
+	}
+
+	return nil
+}
+
+func (mw *MiddlewareManager) idempotencyTtl() time.Duration {
+	if mw.cfg.Idempotency.Ttl <= 0 {
+		return defaultIdempotencyTtl
+	}
+
+	return time.Second * mw.cfg.Idempotency.Ttl
+}
+
+func (mw *MiddlewareManager) idempotencyLockTtl() time.Duration {
+	if mw.cfg.Idempotency.LockTtl <= 0 {
+		return defaultIdempotencyLockTtl
+	}
+
+	return time.Second * mw.cfg.Idempotency.LockTtl
+}
+
+func (mw *MiddlewareManager) idempotencyWaitTimeout() time.Duration {
+	if mw.cfg.Idempotency.WaitTimeout <= 0 {
+		return defaultIdempotencyWaitTimeout
+	}
+
+	return time.Second * mw.cfg.Idempotency.WaitTimeout
+}
+
+func replay(c echo.Context, record *idempotency.Record) error {
+	header := c.Response().Header()
+	for name, values := range record.Header {
+		header[name] = values
+	}
+	header.Set(HeaderIdempotentReplayed, "true")
+
+	c.Response().WriteHeader(record.Status)
+	_, err := c.Response().Write(record.Body)
+
+	return err
+}
+
+// keepIdempotentResponse keeps the outcomes of the request itself, a retry of a request rejected for
+// credentials, rate limits or conflicts, or failed by the server, may well succeed.
+func keepIdempotentResponse(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusConflict, http.StatusTooEarly, http.StatusTooManyRequests:
+		return false
+	default:
+		return status < http.StatusInternalServerError
+	}
+}
+
+func hash(parts ...string) string {
+	digest := sha256.New()
+	for _, part := range parts {
+		digest.Write([]byte(part))
+		digest.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+func newLockToken() string {
+	token := make([]byte, 16)
+	_, _ = rand.Read(token)
+
+	return hex.EncodeToString(token)
+}
+
+// responseCapture keeps a copy of the response body up to maxIdempotentResponseSize.
+type responseCapture struct {
+	http.ResponseWriter
+	body bytes.Buffer
+	overflow bool
+}
+
+func (r *responseCapture) Write(b []byte) (int, error) {
+	if !r.overflow {
+		if r.body.Len()+len(b) > maxIdempotentResponseSize {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(b)
+		}
+	}
+
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/idempotency"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// idempotencyTestServer creates currencies with ids counting up, the status of a request is taken from its
+// status query parameter and a request with ?block waits for release. started tells a test a request reached
+// the handler.
+type idempotencyTestServer struct {
+	*echo.Echo
+	mr *miniredis.Miniredis
+	calls atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func newIdempotencyTestServer(t *testing.T, idempotencyCfg config.IdempotencyConfig) *idempotencyTestServer {
+	t.Helper()
+	idempotencyCfg.Enabled = true
+	cfg := &config.Config{Auth: config.AuthConfig{Enabled: true, Secret: testSecret}, Idempotency: idempotencyCfg}
+	mw := newTestMiddlewareManager(cfg)
+	verifier, err := auth.NewJWTVerifier(cfg.Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() {
+		redisClient.Close()
+	})
+
+	s := &idempotencyTestServer{Echo: echo.New(), mr: mr, started: make(chan struct{}, 1), release: make(chan struct{})}
+	s.Use(echoMiddleware.Recover())
+	s.Use(mw.IdentifyMiddleware(verifier, stubKeys{}))
+	s.Use(mw.IdempotencyMiddleware(idempotency.NewRedisStore(redisClient)))
+	create := func(c echo.Context) error {
+		id := s.calls.Add(1)
+		select {
+		case s.started <- struct{}{}:
+		default:
+		}
+		if c.QueryParam("block") != "" {
+			<-s.release
+		}
+		if c.QueryParam("panic") != "" {
+			panic("handler failed")
+		}
+		status := http.StatusCreated
+		if c.QueryParam("status") != "" {
+			status, _ = strconv.Atoi(c.QueryParam("status"))
+		}
+		body, _ := io.ReadAll(c.Request().Body)
+		c.Response().Header().Set(echo.HeaderLocation, "/currencies/"+strconv.Itoa(int(id)))
+		c.Response().Header().Set("X-Request-Id", strconv.Itoa(int(id)))
+		return c.JSON(status, map[string]interface{}{"id": id, "request": string(body)})
+	}
+	s.POST("/currencies", create)
+	s.PUT("/currencies", create)
+
+	return s
+}
+
+func (s *idempotencyTestServer) post(method string, target string, key string, subject string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	if subject != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+subject)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestIdempotencyMiddlewareReplays(t *testing.T) {
+	s := newIdempotencyTestServer(t, config.IdempotencyConfig{})
+	alice := signToken(t, "alice", auth.ScopeCurrenciesWrite)
+
+	first := s.post(http.MethodPost, "/currencies", "create-euro", alice, `{"iso_code":"EUR"}`)
+	second := s.post(http.MethodPost, "/currencies", "create-euro", alice, `{"iso_code":"EUR"}`)
+	if first.Code != http.StatusCreated || second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Fatalf("responses %d %s and %d %s, want the first replayed", first.Code, first.Body, second.Code, second.Body)
+	}
+	if second.Header().Get(HeaderIdempotentReplayed) != "true" || first.Header().Get(HeaderIdempotentReplayed) != "" {
+		t.Errorf("replayed headers %q and %q", first.Header().Get(HeaderIdempotentReplayed), second.Header().Get(HeaderIdempotentReplayed))
+	}
+	// kept headers come back, the others are left to the middlewares of the retry
+	if second.Header().Get(echo.HeaderLocation) != "/currencies/1" || second.Header().Get("X-Request-Id") != "" {
+		t.Errorf("replayed headers = %v", second.Header())
+	}
+
+	tests := []struct {
+		name string
+		method string
+		key string
+		subject string
+		body string
+		status int
+	}{
+		{name: "another client", method: http.MethodPost, key: "create-euro", subject: signToken(t, "bob", auth.ScopeCurrenciesWrite), body: `{"iso_code":"EUR"}`, status: http.StatusCreated},
+		{name: "anonymous client", method: http.MethodPost, key: "create-euro", body: `{"iso_code":"EUR"}`, status: http.StatusCreated},
+		{name: "without a key", method: http.MethodPost, subject: alice, body: `{"iso_code":"EUR"}`, status: http.StatusCreated},
+		{name: "method without idempotency", method: http.MethodPut, key: "create-euro", subject: alice, body: `{"iso_code":"EUR"}`, status: http.StatusCreated},
+		{name: "another request with the key", method: http.MethodPost, key: "create-euro", subject: alice, body: `{"iso_code":"USD"}`, status: http.StatusUnprocessableEntity},
+		{name: "key too long", method: http.MethodPost, key: strings.Repeat("k", 256), subject: alice, body: `{"iso_code":"EUR"}`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := s.calls.Load()
+			rec := s.post(tt.method, "/currencies", tt.key, tt.subject, tt.body)
+			if rec.Code != tt.status || rec.Header().Get(HeaderIdempotentReplayed) != "" {
+				t.Errorf("status %d, replayed %q, want %d without a replay", rec.Code, rec.Header().Get(HeaderIdempotentReplayed), tt.status)
+			}
+			if ran := s.calls.Load() > calls; ran != (tt.status == http.StatusCreated) {
+				t.Errorf("handler ran = %t", ran)
+			}
+		})
+	}
+}
+
+func TestIdempotencyMiddlewareKeepsOnlyFinalOutcomes(t *testing.T) {
+	s := newIdempotencyTestServer(t, config.IdempotencyConfig{})
+	alice := signToken(t, "alice", auth.ScopeCurrenciesWrite)
+	tests := []struct {
+		status string
+		kept bool
+	}{
+		{status: "400", kept: true},
+		{status: "404", kept: true},
+		{status: "409"},
+		{status: "429"},
+		{status: "500"},
+		{status: "503"},
+	}
+
+	for _, tt := range tests {
+		key := "status-" + tt.status
+		s.post(http.MethodPost, "/currencies?status="+tt.status, key, alice, `{}`)
+		calls := s.calls.Load()
+		rec := s.post(http.MethodPost, "/currencies?status="+tt.status, key, alice, `{}`)
+		if replayed := rec.Header().Get(HeaderIdempotentReplayed) == "true"; replayed != tt.kept || (s.calls.Load() == calls) != tt.kept {
+			t.Errorf("%s: replayed %t, want %t", tt.status, replayed, tt.kept)
+		}
+	}
+
+	// a panic drops the lock, the retry runs right away instead of waiting for the lock to expire
+	if rec := s.post(http.MethodPost, "/currencies?panic=1", "panics", alice, `{}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("panic = %d, want 500", rec.Code)
+	}
+	if s.mr.Exists(idempotencyKeyPrefix + "{alice}:" + hash(http.MethodPost, "/currencies", "", "panics")) {
+		t.Error("the lock of the panicked request is still held")
+	}
+}
+
+func TestIdempotencyMiddlewareWaitsForTheRequestInFlight(t *testing.T) {
+	s := newIdempotencyTestServer(t, config.IdempotencyConfig{WaitTimeout: 5})
+	alice := signToken(t, "alice", auth.ScopeCurrenciesWrite)
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 2)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		responses[0] = s.post(http.MethodPost, "/currencies?block=1", "slow", alice, `{}`)
+	}()
+	<-s.started
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		responses[1] = s.post(http.MethodPost, "/currencies?block=1", "slow", alice, `{}`)
+	}()
+	time.Sleep(2 * idempotencyPollInterval)
+	close(s.release)
+	wg.Wait()
+
+	if s.calls.Load() != 1 {
+		t.Errorf("handler calls = %d, want 1", s.calls.Load())
+	}
+	if responses[1].Code != http.StatusCreated || responses[1].Header().Get(HeaderIdempotentReplayed) != "true" || responses[1].Body.String() != responses[0].Body.String() {
+		t.Errorf("retry = %d %s, want the replay of %s", responses[1].Code, responses[1].Body, responses[0].Body)
+	}
+}
+
+func TestIdempotencyMiddlewareGivesUpWaiting(t *testing.T) {
+	s := newIdempotencyTestServer(t, config.IdempotencyConfig{WaitTimeout: 1})
+	alice := signToken(t, "alice", auth.ScopeCurrenciesWrite)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.post(http.MethodPost, "/currencies?block=1", "slow", alice, `{}`)
+	}()
+	<-s.started
+	defer func() {
+		close(s.release)
+		<-done
+	}()
+
+	rec := s.post(http.MethodPost, "/currencies?block=1", "slow", alice, `{}`)
+	if rec.Code != http.StatusConflict || rec.Header().Get(HeaderRetryAfter) != "1" {
+		t.Errorf("status %d, Retry-After %q, want 409 and 1", rec.Code, rec.Header().Get(HeaderRetryAfter))
+	}
+}
+
+func TestIdempotencyMiddlewareWithoutTheStore(t *testing.T) {
+	s := newIdempotencyTestServer(t, config.IdempotencyConfig{})
+	s.mr.Close()
+	alice := signToken(t, "alice", auth.ScopeCurrenciesWrite)
+
+	for i := 0; i < 2; i++ {
+		if rec := s.post(http.MethodPost, "/currencies", "create-euro", alice, `{}`); rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want 201 without idempotency", rec.Code)
+		}
+	}
+	if s.calls.Load() != 2 {
+		t.Errorf("handler calls = %d, want 2", s.calls.Load())
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/ratelimit"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderRateLimitLimit = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset = "RateLimit-Reset"
+	HeaderRateLimitPolicy = "RateLimit-Policy"
+	HeaderRetryAfter = "Retry-After"
+
+	rateLimitKeyPrefix = "ratelimit:"
+)
+
+type routeLimit struct {
+	method string
+	path string
+	limit ratelimit.Limit
+}
+
+// RateLimitMiddleware limits each client by its plan and by the route limits matching the request, the
+// client is the principal IdentifyMiddleware found or else the ip. It answers 429 with Retry-After once a
+// limit is used up and sets the RateLimit headers of the most restrictive limit on every response. It has
+// to be registered with e.Use after IdentifyMiddleware, route paths are only known after routing.
+func (mw *MiddlewareManager) RateLimitMiddleware(limiter ratelimit.Limiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		cfg := mw.cfg.RateLimit
+		if !cfg.Enabled {
+			return next
+		}
+
+		plans := make(map[string]ratelimit.Limit, len(cfg.Plans))
+		for name, plan := range cfg.Plans {
+			plans[strings.ToLower(name)] = ratelimit.Limit{Requests: plan.Requests, Window: time.Second * plan.Window}
+		}
+		clientPlans := make(map[string]string, len(cfg.Clients))
+		for _, client := range cfg.Clients {
+			clientPlans[client.Subject] = strings.ToLower(client.Plan)
+		}
+		routes := make([]routeLimit, 0, len(cfg.Routes))
+		for _, route := range cfg.Routes {
+			routes = append(routes, routeLimit{
+				method: strings.ToUpper(route.Method),
+				path: route.Path,
+				limit: ratelimit.Limit{Requests: route.Requests, Window: time.Second * route.Window},
+			})
+		}
+
+		return func(c echo.Context) error {
+			path := c.Path()
+			for _, exempt := range cfg.Exempt {
+				if matchRoutePath(exempt, path) {
+					return next(c)
+				}
+			}
+
+			client, authenticated := clientId(c)
+			plan := cfg.AnonymousPlan
+			if authenticated {
+				plan = cfg.DefaultPlan
+				if clientPlan, ok := clientPlans[client]; ok {
+					plan = clientPlan
+				}
+			}
+
+			// the {client} hash tag keeps the buckets of a client in one redis cluster slot
+			keyPrefix := rateLimitKeyPrefix + "{" + client + "}:"
+			var buckets []ratelimit.Bucket
+			if limit, ok := plans[strings.ToLower(plan)]; ok && limit.Requests > 0 && limit.Window > 0 {
+				buckets = append(buckets, ratelimit.Bucket{Key: keyPrefix + "plan", Limit: limit})
+			}
+			for _, route := range routes {
+				if (route.method == "" || route.method == c.Request().Method) && matchRoutePath(route.path, path) && route.limit.Requests > 0 && route.limit.Window > 0 {
+					buckets = append(buckets, ratelimit.Bucket{Key: keyPrefix + "route:" + route.method + " " + route.path, Limit: route.limit})
+				}
+			}
+			if len(buckets) == 0 {
+				return next(c)
+			}
+
+			result, err := limiter.Allow(c.Request().Context(), buckets...)
+			if err != nil {
+				// failing open, the limiter only errs when even its fallback does
+				util.PrepareLogging(c, mw.logger, err)
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set(HeaderRateLimitLimit, strconv.Itoa(result.Limit.Requests))
+			header.Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+			header.Set(HeaderRateLimitReset, strconv.Itoa(seconds(result.Reset)))
+			header.Set(HeaderRateLimitPolicy, fmt.Sprintf("%d;w=%d", result.Limit.Requests, seconds(result.Limit.Window)))
+			if !result.Allowed {
+				retryAfter := seconds(result.RetryAfter)
+				mw.logger.Warnf("Rate limit exceeded, RequestId: %s, Client: %s, Path: %s", util.GetRequestId(c), client, path)
+				header.Set(HeaderRetryAfter, strconv.Itoa(retryAfter))
+				return c.JSON(http.StatusTooManyRequests, util.NewHttpResponse(http.StatusTooManyRequests, fmt.Sprintf("rate limit exceeded, retry in %d seconds", retryAfter), nil))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// clientId is the subject of the principal IdentifyMiddleware found, or the ip of anonymous clients.
+func clientId(c echo.Context) (string, bool) {
+	if principal, ok := auth.FromContext(c.Request().Context()); ok {
+		return principal.Subject, true
+	}
+
+	return "ip:" + c.RealIP(), false
+}
+
+// matchRoutePath matches a route path exactly, or by prefix when the pattern ends with *.
+func matchRoutePath(pattern string, path string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+
+	return pattern == path
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// RateLimitIPExtractor takes the client ip from X-Forwarded-For behind a trusted proxy, otherwise from the
+// connection, so clients can not pick their ip.
+func RateLimitIPExtractor(cfg config.RateLimitConfig) echo.IPExtractor {
+	if cfg.TrustProxy {
+		return echo.ExtractIPFromXFFHeader()
+	}
+
+	return echo.ExtractIPDirect()
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newRateLimitTestConfig() *config.Config {
+	return &config.Config{
+		Auth: config.AuthConfig{Enabled: true, Secret: testSecret},
+		RateLimit: config.RateLimitConfig{
+			Enabled: true,
+			DefaultPlan: "standard",
+			AnonymousPlan: "anonymous",
+			Plans: map[string]config.RateLimitPlanConfig{
+				"anonymous": {Requests: 1, Window: 60},
+				"standard": {Requests: 3, Window: 60},
+				"Partner": {Requests: 10, Window: 60},
+			},
+			Clients: []config.RateLimitClientConfig{{Subject: "partner", Plan: "partner"}},
+			Routes: []config.RateLimitRouteConfig{{Method: "post", Path: "/api/v1/currencies", Requests: 2, Window: 60}},
+			Exempt: []string{"/health*"},
+		},
+	}
+}
+
+// newRateLimitTestServer registers the middlewares like MapHandlers, the limit sees the principal
+// IdentifyMiddleware found.
+func newRateLimitTestServer(t *testing.T, cfg *config.Config, limiter ratelimit.Limiter) *echo.Echo {
+	t.Helper()
+	mw := newTestMiddlewareManager(cfg)
+	verifier, err := auth.NewJWTVerifier(cfg.Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.IPExtractor = RateLimitIPExtractor(cfg.RateLimit)
+	e.Use(mw.IdentifyMiddleware(verifier, stubKeys{}))
+	e.Use(mw.RateLimitMiddleware(limiter))
+	ok := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	e.GET("/api/v1/currencies", ok)
+	e.POST("/api/v1/currencies", ok)
+	e.GET("/health/ready", ok)
+
+	return e
+}
+
+// serve sends the request through a proxy in the private network, the only proxies echo trusts by default.
+func serve(e *echo.Echo, method string, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestRateLimitMiddlewarePlans(t *testing.T) {
+	tests := []struct {
+		name string
+		subject string
+		allowed int
+	}{
+		{name: "anonymous", allowed: 1},
+		{name: "default plan", subject: "alice", allowed: 3},
+		{name: "client plan", subject: "partner", allowed: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newRateLimitTestServer(t, newRateLimitTestConfig(), ratelimit.NewMemoryLimiter())
+			header := http.Header{}
+			if tt.subject != "" {
+				header.Set(echo.HeaderAuthorization, "Bearer "+signToken(t, tt.subject, auth.ScopeCurrenciesRead))
+			}
+
+			for i := 1; i <= tt.allowed; i++ {
+				if rec := serve(e, http.MethodGet, "/api/v1/currencies", header); rec.Code != http.StatusOK {
+					t.Fatalf("request %d = %d, want 200", i, rec.Code)
+				}
+			}
+			rec := serve(e, http.MethodGet, "/api/v1/currencies", header)
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("request %d = %d, want 429", tt.allowed+1, rec.Code)
+			}
+			retryAfter := map[int]string{1: "60", 3: "20", 10: "6"}[tt.allowed]
+			if rec.Header().Get(HeaderRetryAfter) != retryAfter || rec.Header().Get(HeaderRateLimitRemaining) != "0" || rec.Header().Get(HeaderRateLimitPolicy) != map[int]string{1: "1;w=60", 3: "3;w=60", 10: "10;w=60"}[tt.allowed] {
+				t.Errorf("headers = %v, want Retry-After %s", rec.Header(), retryAfter)
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddlewareRoutes(t *testing.T) {
+	e := newRateLimitTestServer(t, newRateLimitTestConfig(), ratelimit.NewMemoryLimiter())
+	header := http.Header{echo.HeaderAuthorization: {"Bearer " + signToken(t, "alice", auth.ScopeCurrenciesWrite)}}
+
+	rec := serve(e, http.MethodPost, "/api/v1/currencies", header)
+	// the route limit is tighter than the plan, its headers win
+	if rec.Code != http.StatusOK || rec.Header().Get(HeaderRateLimitLimit) != "2" || rec.Header().Get(HeaderRateLimitRemaining) != "1" || rec.Header().Get(HeaderRateLimitReset) != "30" {
+		t.Fatalf("status %d, headers %v, want the route limit", rec.Code, rec.Header())
+	}
+	serve(e, http.MethodPost, "/api/v1/currencies", header)
+	if rec = serve(e, http.MethodPost, "/api/v1/currencies", header); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third post = %d, want 429", rec.Code)
+	}
+
+	// reads only count against the plan, which the two posts used up to one request
+	if rec = serve(e, http.MethodGet, "/api/v1/currencies", header); rec.Code != http.StatusOK || rec.Header().Get(HeaderRateLimitRemaining) != "0" {
+		t.Errorf("get = %d, remaining %s, want 200 and 0", rec.Code, rec.Header().Get(HeaderRateLimitRemaining))
+	}
+
+	// exempt routes are never limited
+	for i := 0; i < 5; i++ {
+		if rec = serve(e, http.MethodGet, "/health/ready", nil); rec.Code != http.StatusOK || rec.Header().Get(HeaderRateLimitLimit) != "" {
+			t.Fatalf("health check = %d with headers %v", rec.Code, rec.Header())
+		}
+	}
+}
+
+func TestRateLimitMiddlewareClientIp(t *testing.T) {
+	forwardedFor := func(ip string) http.Header {
+		return http.Header{echo.HeaderXForwardedFor: {ip}}
+	}
+
+	// without a trusted proxy X-Forwarded-For is the client's word, it does not get a new bucket
+	e := newRateLimitTestServer(t, newRateLimitTestConfig(), ratelimit.NewMemoryLimiter())
+	serve(e, http.MethodGet, "/api/v1/currencies", forwardedFor("203.0.113.1"))
+	if rec := serve(e, http.MethodGet, "/api/v1/currencies", forwardedFor("203.0.113.2")); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("spoofed ip = %d, want 429", rec.Code)
+	}
+
+	cfg := newRateLimitTestConfig()
+	cfg.RateLimit.TrustProxy = true
+	e = newRateLimitTestServer(t, cfg, ratelimit.NewMemoryLimiter())
+	serve(e, http.MethodGet, "/api/v1/currencies", forwardedFor("203.0.113.1"))
+	if rec := serve(e, http.MethodGet, "/api/v1/currencies", forwardedFor("203.0.113.2")); rec.Code != http.StatusOK {
+		t.Errorf("another client behind the proxy = %d, want 200", rec.Code)
+	}
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, ...ratelimit.Bucket) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestRateLimitMiddlewareFailsOpen(t *testing.T) {
+	e := newRateLimitTestServer(t, newRateLimitTestConfig(), failingLimiter{})
+	for i := 0; i < 3; i++ {
+		if rec := serve(e, http.MethodGet, "/api/v1/currencies", nil); rec.Code != http.StatusOK {
+			t.Fatalf("request %d = %d, want 200 while the limiter fails", i, rec.Code)
+		}
+	}
+}
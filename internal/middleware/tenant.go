@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+)
+
+const HeaderTenantId = "X-Tenant-ID"
+
+// TenantMiddleware puts the tenant of the request into the request context, where the repositories find it
+// with tenant.FromContext. Principals with a tenant_id claim, or api keys of a tenant, are bound to their
+// tenant, an X-Tenant-ID header naming another one gets 403. Platform principals pick the tenant with the
+// header and act on the global records without it. Anonymous requests only see the global records, the
+// header gets 401 from them, anyone could name any tenant otherwise. It has to be registered with e.Use
+// after IdentifyMiddleware.
+func (mw *MiddlewareManager) TenantMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requested := c.Request().Header.Get(HeaderTenantId)
+		principal, ok := auth.FromContext(c.Request().Context())
+		tenantId, err := auth.ResolveTenant(principal, ok, requested)
+		switch {
+		case errors.Is(err, auth.ErrInvalidTenant):
+			return c.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, fmt.Sprintf("%s must be lower case letters, digits, dashes and underscores", HeaderTenantId), nil))
+		case errors.Is(err, auth.ErrAnonymousTenant):
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="api"`)
+			return c.JSON(http.StatusUnauthorized, util.NewHttpResponse(http.StatusUnauthorized, fmt.Sprintf("%s needs an authenticated caller", HeaderTenantId), nil))
+		case errors.Is(err, auth.ErrForeignTenant):
+			mw.logger.Warnf("Tenant denied, RequestId: %s, Subject: %s, Tenant: %s", util.GetRequestId(c), principal.Subject, requested)
+			return c.JSON(http.StatusForbidden, util.NewHttpResponse(http.StatusForbidden, err.Error(), nil))
+		}
+
+		c.SetRequest(c.Request().WithContext(tenant.NewContext(c.Request().Context(), tenantId)))
+
+		return next(c)
+	}
+}
+
+// PlatformMiddleware keeps tenants out of the routes that are not tenant aware, e.g. webhooks or the audit
+// log, which span all tenants. Requests for a tenant get 403.
+func (mw *MiddlewareManager) PlatformMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if tenant.FromContext(c.Request().Context()) != tenant.Global {
+			return c.JSON(http.StatusForbidden, util.NewHttpResponse(http.StatusForbidden, "the route is not available to tenants", nil))
+		}
+
+		return next(c)
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestMiddlewareManager(cfg *config.Config) *MiddlewareManager {
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	return NewMiddlewareManager(cfg, l)
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	platform := &auth.Principal{Subject: "ops"}
+	retail := &auth.Principal{Subject: "shop", Tenant: "retail"}
+
+	tests := []struct {
+		name string
+		principal *auth.Principal
+		header string
+		status int
+		tenant string
+	}{
+		{name: "anonymous sees global records", status: http.StatusOK, tenant: tenant.Global},
+		{name: "anonymous can not pick a tenant", header: "retail", status: http.StatusUnauthorized},
+		{name: "invalid header", principal: platform, header: "Retail!", status: http.StatusBadRequest},
+		{name: "platform works on global records", principal: platform, status: http.StatusOK, tenant: tenant.Global},
+		{name: "platform picks a tenant", principal: platform, header: "retail", status: http.StatusOK, tenant: "retail"},
+		{name: "tenant is bound to its tenant", principal: retail, status: http.StatusOK, tenant: "retail"},
+		{name: "tenant names its own tenant", principal: retail, header: "retail", status: http.StatusOK, tenant: "retail"},
+		{name: "tenant names another tenant", principal: retail, header: "wholesale", status: http.StatusForbidden},
+	}
+
+	mw := newTestMiddlewareManager(&config.Config{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/currencies", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderTenantId, tt.header)
+			}
+			if tt.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), *tt.principal))
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			var got string
+			err := mw.TenantMiddleware(func(c echo.Context) error {
+				got = tenant.FromContext(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			})(c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && got != tt.tenant {
+				t.Errorf("tenant = %q, want %q", got, tt.tenant)
+			}
+		})
+	}
+}
+
+func TestPlatformMiddleware(t *testing.T) {
+	mw := newTestMiddlewareManager(&config.Config{})
+	handler := mw.PlatformMiddleware(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	for id, status := range map[string]int{tenant.Global: http.StatusOK, "retail": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/webhooks", nil)
+		req = req.WithContext(tenant.NewContext(req.Context(), id))
+		rec := httptest.NewRecorder()
+		if err := handler(echo.New().NewContext(req, rec)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if rec.Code != status {
+			t.Errorf("tenant %q: status = %d, want %d", id, rec.Code, status)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	LatestApiVersion = 2
+
+	apiPathPrefix = "/api/v"
+	vendorMediaTypePrefix = "application/vnd.kanbersky.v"
+	vendorMediaTypeSuffix = "+json"
+	versionDateLayout = "2006-01-02"
+
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset = "Sunset"
+)
+
+// ApiVersionMiddleware lets clients pick the api version with Accept: application/vnd.kanbersky.v2+json
+// instead of the path. The media type wins over the path version, the request is rewritten to
+// /api/vN when the resource is one of versionedResources, which exist in every version. It rewrites
+// the path, so it has to be registered with e.Pre.
+func (mw *MiddlewareManager) ApiVersionMiddleware(versionedResources ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			pathVersion, resource, ok := splitVersionedPath(req.URL.Path)
+			if !ok {
+				return next(c)
+			}
+
+			versioned := hasResource(versionedResources, resource)
+			if versioned {
+				c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+			}
+
+			acceptVersion, negotiated := acceptedVersion(req.Header.Get(echo.HeaderAccept))
+			if !negotiated || acceptVersion == pathVersion {
+				if negotiated {
+					c.Response().Header().Set(echo.HeaderContentType, vendorMediaType(acceptVersion))
+				}
+				return next(c)
+			}
+
+			if acceptVersion < 1 || acceptVersion > LatestApiVersion {
+				return c.JSON(http.StatusNotAcceptable, util.NewHttpResponse(http.StatusNotAcceptable, fmt.Sprintf("api version %d is not supported", acceptVersion), nil))
+			}
+			if !versioned {
+				return c.JSON(http.StatusNotAcceptable, util.NewHttpResponse(http.StatusNotAcceptable, fmt.Sprintf("%s is not available in api version %d", resource, acceptVersion), nil))
+			}
+
+			req.URL.Path = apiPathPrefix + strconv.Itoa(acceptVersion) + resource
+			req.URL.RawPath = ""
+			c.Response().Header().Set(echo.HeaderContentType, vendorMediaType(acceptVersion))
+
+			return next(c)
+		}
+	}
+}
+
+// DeprecationMiddleware marks the responses of a superseded version with the Deprecation and Sunset
+// (RFC 8594) headers of the versioning config, plus a successor-version link to the newest version.
+func (mw *MiddlewareManager) DeprecationMiddleware() echo.MiddlewareFunc {
+	deprecation := "true"
+	if deprecatedAt, ok := mw.versionDate("deprecatedat", mw.cfg.Versioning.DeprecatedAt); ok {
+		deprecation = "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	}
+	sunset := ""
+	if sunsetAt, ok := mw.versionDate("sunsetat", mw.cfg.Versioning.SunsetAt); ok {
+		sunset = sunsetAt.Format(http.TimeFormat)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			header.Set(HeaderDeprecation, deprecation)
+			if sunset != "" {
+				header.Set(HeaderSunset, sunset)
+			}
+			if _, resource, ok := splitVersionedPath(c.Request().URL.Path); ok {
+				header.Add("Link", fmt.Sprintf(`<%s%d%s>; rel="successor-version"`, apiPathPrefix, LatestApiVersion, resource))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func (mw *MiddlewareManager) versionDate(name string, value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	date, err := time.Parse(versionDateLayout, value)
+	if err != nil {
+		mw.logger.Warnf("Versioning config %s is not a %s date, ignored: %s", name, versionDateLayout, err)
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// splitVersionedPath splits /api/v1/currencies/1 into 1 and /currencies/1.
+func splitVersionedPath(path string) (int, string, bool) {
+	if !strings.HasPrefix(path, apiPathPrefix) {
+		return 0, "", false
+	}
+
+	versionPart, resource, _ := strings.Cut(strings.TrimPrefix(path, apiPathPrefix), "/")
+	version, err := strconv.Atoi(versionPart)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return version, "/" + resource, true
+}
+
+func hasResource(resources []string, resource string) bool {
+	for _, r := range resources {
+		if resource == r || strings.HasPrefix(resource, r+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptedVersion returns the version of the first vendor media type in the Accept header.
+func acceptedVersion(accept string) (int, bool) {
+	for _, mediaType := range strings.Split(accept, ",") {
+		mediaType, _, _ = strings.Cut(mediaType, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if !strings.HasPrefix(mediaType, vendorMediaTypePrefix) || !strings.HasSuffix(mediaType, vendorMediaTypeSuffix) {
+			continue
+		}
+
+		version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(mediaType, vendorMediaTypePrefix), vendorMediaTypeSuffix))
+		if err != nil {
+			continue
+		}
+
+		return version, true
+	}
+
+	return 0, false
+}
+
+func vendorMediaType(version int) string {
+	return vendorMediaTypePrefix + strconv.Itoa(version) + vendorMediaTypeSuffix
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newVersioningTestServer serves currencies in both versions and rates only in v1, every handler answers
+// with the route it matched.
+func newVersioningTestServer(cfg *config.Config) *echo.Echo {
+	mw := newTestMiddlewareManager(cfg)
+	e := echo.New()
+	e.Pre(mw.ApiVersionMiddleware("/currencies"))
+
+	route := func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Path())
+	}
+	v1 := e.Group("/api/v1", mw.DeprecationMiddleware())
+	v1.GET("/currencies/:id", route)
+	v1.GET("/rates", route)
+	e.GET("/api/v2/currencies/:id", route)
+
+	return e
+}
+
+func TestApiVersionMiddleware(t *testing.T) {
+	e := newVersioningTestServer(&config.Config{})
+	tests := []struct {
+		name string
+		path string
+		accept string
+		status int
+		route string
+		contentType string
+	}{
+		{name: "path version", path: "/api/v1/currencies/7", status: http.StatusOK, route: "/api/v1/currencies/:id"},
+		{name: "plain json", path: "/api/v2/currencies/7", accept: echo.MIMEApplicationJSON, status: http.StatusOK, route: "/api/v2/currencies/:id"},
+		{name: "media type wins over the path", path: "/api/v1/currencies/7", accept: "application/vnd.kanbersky.v2+json", status: http.StatusOK, route: "/api/v2/currencies/:id", contentType: "application/vnd.kanbersky.v2+json"},
+		{name: "media type down to v1", path: "/api/v2/currencies/7", accept: "application/vnd.kanbersky.v1+json", status: http.StatusOK, route: "/api/v1/currencies/:id", contentType: "application/vnd.kanbersky.v1+json"},
+		{name: "media type matching the path", path: "/api/v1/currencies/7", accept: "application/vnd.kanbersky.v1+json", status: http.StatusOK, route: "/api/v1/currencies/:id", contentType: "application/vnd.kanbersky.v1+json"},
+		{name: "first vendor media type of a list", path: "/api/v1/currencies/7", accept: "text/html, application/vnd.kanbersky.v2+json;q=0.9", status: http.StatusOK, route: "/api/v2/currencies/:id", contentType: "application/vnd.kanbersky.v2+json"},
+		{name: "unknown version", path: "/api/v1/currencies/7", accept: "application/vnd.kanbersky.v3+json", status: http.StatusNotAcceptable},
+		{name: "resource without the version", path: "/api/v1/rates", accept: "application/vnd.kanbersky.v2+json", status: http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.route != "" && rec.Body.String() != tt.route {
+				t.Errorf("route = %s, want %s", rec.Body, tt.route)
+			}
+			if tt.contentType != "" && rec.Header().Get(echo.HeaderContentType) != tt.contentType {
+				t.Errorf("content type = %s, want %s", rec.Header().Get(echo.HeaderContentType), tt.contentType)
+			}
+		})
+	}
+
+	// caches have to keep the versions of a versioned resource apart
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/currencies/7", nil))
+	if rec.Header().Get(echo.HeaderVary) != echo.HeaderAccept {
+		t.Errorf("vary = %q, want Accept", rec.Header().Get(echo.HeaderVary))
+	}
+}
+
+func TestDeprecationMiddleware(t *testing.T) {
+	tests := []struct {
+		name string
+		versioning config.VersioningConfig
+		deprecation string
+		sunset string
+	}{
+		{name: "dates", versioning: config.VersioningConfig{DeprecatedAt: "2024-01-01", SunsetAt: "2025-06-30"}, deprecation: "@1704067200", sunset: "Mon, 30 Jun 2025 00:00:00 GMT"},
+		{name: "without dates", deprecation: "true"},
+		{name: "malformed dates are ignored", versioning: config.VersioningConfig{DeprecatedAt: "01/01/2024", SunsetAt: "soon"}, deprecation: "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newVersioningTestServer(&config.Config{Versioning: tt.versioning})
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/currencies/7", nil))
+
+			if got := rec.Header().Get(HeaderDeprecation); got != tt.deprecation {
+				t.Errorf("deprecation = %q, want %q", got, tt.deprecation)
+			}
+			if got := rec.Header().Get(HeaderSunset); got != tt.sunset {
+				t.Errorf("sunset = %q, want %q", got, tt.sunset)
+			}
+			if got := rec.Header().Get("Link"); got != `</api/v2/currencies/7>; rel="successor-version"` {
+				t.Errorf("link = %q, want the v2 successor", got)
+			}
+		})
+	}
+
+	// the current version is not deprecated
+	rec := httptest.NewRecorder()
+	newVersioningTestServer(&config.Config{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/currencies/7", nil))
+	if rec.Header().Get(HeaderDeprecation) != "" {
+		t.Errorf("v2 is marked deprecated")
+	}
+}
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const (
+	StatusPending = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed = "failed"
+)
+
+type OutboxEvent struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	AggregateType string `gorm:"index:idx_outbox_aggregate" json:"aggregate_type"`
+	AggregateID string `gorm:"index:idx_outbox_aggregate" json:"aggregate_id"`
+	EventType string `json:"event_type"`
+	Payload string `gorm:"type:jsonb" json:"payload"`
+	Status string `gorm:"index:idx_outbox_pending" json:"status"`
+	Attempts int `json:"attempts"`
+	NextAttemptAt time.Time `gorm:"index:idx_outbox_pending" json:"next_attempt_at"`
+	LockedUntil *time.Time `json:"locked_until"`
+	LastError string `json:"last_error"`
+	DeliveredAt *time.Time `json:"delivered_at"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+func NewOutboxEvent(aggregateType string, aggregateId interface{}, eventType string, payload any) (OutboxEvent, error) {
+	payloadByte, err := json.Marshal(payload)
+	if err != nil {
+		return OutboxEvent{}, errors.Wrap(err, "entity.NewOutboxEvent.Json.Marshal")
+	}
+
+	return OutboxEvent{
+		AggregateType: aggregateType,
+		AggregateID: fmt.Sprint(aggregateId),
+		EventType: eventType,
+		Payload: string(payloadByte),
+		Status: StatusPending,
+		NextAttemptAt: time.Now().UTC(),
+	}, nil
+}
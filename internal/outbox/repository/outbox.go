@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/outbox/entity"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+type OutboxRepository interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxEvent, error)
+	MarkDelivered(ctx context.Context, id int64) error
+	MarkRetry(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(ctx context.Context, id int64, lastError string) error
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// Claim leases due events to the caller. Rows locked by another relay are skipped and a lease
+// that is not released in time, e.g. after a crash, makes the event due again.
+func (o outboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxEvent, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "outboxRepository.Claim")
+	defer span.Finish()
+
+	var events []entity.OutboxEvent
+	err := o.db.WithContext(spanContext).Raw(`
+		UPDATE outbox_events SET locked_until = ?, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM outbox_events
+			WHERE status = ? AND next_attempt_at <= now() AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, time.Now().UTC().Add(lease), entity.StatusPending, limit).Scan(&events).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "outboxRepository.Claim.DbError")
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+
+	return events, nil
+}
+
+func (o outboxRepository) MarkDelivered(ctx context.Context, id int64) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "outboxRepository.MarkDelivered")
+	defer span.Finish()
+
+	err := o.db.WithContext(spanContext).Model(&entity.OutboxEvent{ID: id}).Updates(map[string]interface{}{
+		"status": entity.StatusDelivered,
+		"delivered_at": time.Now().UTC(),
+		"locked_until": nil,
+		"last_error": "",
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "outboxRepository.MarkDelivered.DbError")
+	}
+
+	return nil
+}
+
+func (o outboxRepository) MarkRetry(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "outboxRepository.MarkRetry")
+	defer span.Finish()
+
+	err := o.db.WithContext(spanContext).Model(&entity.OutboxEvent{ID: id}).Updates(map[string]interface{}{
+		"next_attempt_at": nextAttemptAt,
+		"locked_until": nil,
+		"last_error": lastError,
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "outboxRepository.MarkRetry.DbError")
+	}
+
+	return nil
+}
+
+func (o outboxRepository) MarkFailed(ctx context.Context, id int64, lastError string) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "outboxRepository.MarkFailed")
+	defer span.Finish()
+
+	err := o.db.WithContext(spanContext).Model(&entity.OutboxEvent{ID: id}).Updates(map[string]interface{}{
+		"status": entity.StatusFailed,
+		"locked_until": nil,
+		"last_error": lastError,
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "outboxRepository.MarkFailed.DbError")
+	}
+
+	return nil
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sefikcan/kanbersky.ca/internal/outbox/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/testutil"
+	"testing"
+	"time"
+)
+
+func newTestRepository(t *testing.T) (OutboxRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock := testutil.NewMockDB(t)
+
+	return NewOutboxRepository(db), mock
+}
+
+// leaseArgument matches a locked_until about lease from now.
+type leaseArgument time.Duration
+
+func (l leaseArgument) Match(value driver.Value) bool {
+	lockedUntil, ok := value.(time.Time)
+	if !ok {
+		return false
+	}
+	lease := time.Until(lockedUntil)
+
+	return lease > time.Duration(l)-time.Second && lease <= time.Duration(l)
+}
+
+func TestClaimLeasesDueEvents(t *testing.T) {
+	outbox, mock := newTestRepository(t)
+
+	// skip locked keeps relays from claiming the same rows, an expired lease makes a row due again
+	rows := sqlmock.NewRows([]string{"id", "event_type", "status", "attempts"}).
+		AddRow(3, "currency.deleted", entity.StatusPending, 1).
+		AddRow(1, "currency.created", entity.StatusPending, 2)
+	mock.ExpectQuery(`UPDATE outbox_events SET locked_until = \$1, attempts = attempts \+ 1\s+` +
+		`WHERE id IN \(\s+SELECT id FROM outbox_events\s+` +
+		`WHERE status = \$2 AND next_attempt_at <= now\(\) AND \(locked_until IS NULL OR locked_until < now\(\)\)\s+` +
+		`ORDER BY id\s+LIMIT \$3\s+FOR UPDATE SKIP LOCKED\s+\)\s+RETURNING \*`).
+		WithArgs(leaseArgument(time.Minute), entity.StatusPending, 10).
+		WillReturnRows(rows)
+
+	events, err := outbox.Claim(context.Background(), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	// returning does not keep the order of the sub query
+	if len(events) != 2 || events[0].ID != 1 || events[1].ID != 3 || events[0].Attempts != 2 {
+		t.Errorf("claimed %+v, want events 1 and 3 in id order", events)
+	}
+}
+
+func TestMarkReleasesTheLease(t *testing.T) {
+	tests := []struct {
+		name string
+		mark func(outbox OutboxRepository) error
+		args []driver.Value
+	}{
+		{
+			name: "delivered",
+			mark: func(outbox OutboxRepository) error { return outbox.MarkDelivered(context.Background(), 7) },
+			args: []driver.Value{sqlmock.AnyArg(), "", nil, entity.StatusDelivered, int64(7)},
+		},
+		{
+			name: "retry",
+			mark: func(outbox OutboxRepository) error {
+				return outbox.MarkRetry(context.Background(), 7, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), "stream is down")
+			},
+			args: []driver.Value{"stream is down", nil, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), int64(7)},
+		},
+		{
+			name: "failed",
+			mark: func(outbox OutboxRepository) error { return outbox.MarkFailed(context.Background(), 7, "stream is down") },
+			args: []driver.Value{"stream is down", nil, entity.StatusFailed, int64(7)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox, mock := newTestRepository(t)
+			mock.ExpectBegin()
+			mock.ExpectExec(`UPDATE "outbox_events" SET .*"locked_until"=.* WHERE "id" = `).WithArgs(tt.args...).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			if err := tt.mark(outbox); err != nil {
+				t.Fatalf("mark %s: %v", tt.name, err)
+			}
+		})
+	}
+}
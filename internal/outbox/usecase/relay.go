@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/outbox/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/outbox/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/event"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultBatchSize = 100
+	defaultMaxAttempts = 10
+	defaultPollInterval = 1
+	minRetryBackoff = time.Second
+	maxRetryBackoff = time.Minute * 5
+	claimLease = time.Minute
+)
+
+type OutboxRelayUseCase interface {
+	Run(ctx context.Context)
+	RelayPending(ctx context.Context) (int, error)
+}
+
+type outboxRelayUseCase struct {
+	cfg *config.Config
+	outboxRepository repository.OutboxRepository
+	publisher event.EventPublisher
+	logger logger.Logger
+}
+
+// Run relays pending events until ctx is done. A full batch is followed by the next one
+// right away, otherwise the relay waits for the poll interval.
+func (o outboxRelayUseCase) Run(ctx context.Context) {
+	pollInterval := o.cfg.Outbox.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	o.logger.Infof("Outbox relay started, PollInterval: %ds", pollInterval)
+	for {
+		relayed, err := o.RelayPending(ctx)
+		if err != nil {
+			o.logger.Errorf("outboxRelayUseCase.Run: %s", err)
+		}
+		if relayed == o.batchSize() {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			o.logger.Info("Outbox relay stopped")
+			return
+		case <-time.After(time.Second * pollInterval):
+		}
+	}
+}
+
+func (o outboxRelayUseCase) RelayPending(ctx context.Context) (int, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "outboxRelayUseCase.RelayPending")
+	defer span.Finish()
+
+	events, err := o.outboxRepository.Claim(spanContext, o.batchSize(), claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, outboxEvent := range events {
+		if err = o.publisher.Publish(spanContext, toEvent(outboxEvent)); err != nil {
+			o.handleFailure(spanContext, outboxEvent, err)
+			continue
+		}
+
+		if err = o.outboxRepository.MarkDelivered(spanContext, outboxEvent.ID); err != nil {
+			o.logger.Errorf("outboxRelayUseCase.RelayPending.MarkDelivered, EventId: %d, Error: %s", outboxEvent.ID, err)
+		}
+	}
+
+	return len(events), nil
+}
+
+func (o outboxRelayUseCase) handleFailure(ctx context.Context, outboxEvent entity.OutboxEvent, publishErr error) {
+	maxAttempts := o.cfg.Outbox.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	if outboxEvent.Attempts >= maxAttempts {
+		o.logger.Errorf("Outbox event gave up, EventId: %d, Attempts: %d, Error: %s", outboxEvent.ID, outboxEvent.Attempts, publishErr)
+		if err := o.outboxRepository.MarkFailed(ctx, outboxEvent.ID, publishErr.Error()); err != nil {
+			o.logger.Errorf("outboxRelayUseCase.handleFailure.MarkFailed, EventId: %d, Error: %s", outboxEvent.ID, err)
+		}
+		return
+	}
+
+	nextAttemptAt := time.Now().UTC().Add(retryBackoff(outboxEvent.Attempts))
+	o.logger.Warnf("Outbox event publish failed, EventId: %d, Attempts: %d, NextAttemptAt: %s, Error: %s", outboxEvent.ID, outboxEvent.Attempts, nextAttemptAt, publishErr)
+	if err := o.outboxRepository.MarkRetry(ctx, outboxEvent.ID, nextAttemptAt, publishErr.Error()); err != nil {
+		o.logger.Errorf("outboxRelayUseCase.handleFailure.MarkRetry, EventId: %d, Error: %s", outboxEvent.ID, err)
+	}
+}
+
+func (o outboxRelayUseCase) batchSize() int {
+	if o.cfg.Outbox.BatchSize <= 0 {
+		return defaultBatchSize
+	}
+
+	return o.cfg.Outbox.BatchSize
+}
+
+func retryBackoff(attempts int) time.Duration {
+	backoff := minRetryBackoff
+	for i := 1; i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+
+	return backoff
+}
+
+func toEvent(outboxEvent entity.OutboxEvent) event.Event {
+	return event.Event{
+		ID: strconv.FormatInt(outboxEvent.ID, 10),
+		Type: outboxEvent.EventType,
+		AggregateType: outboxEvent.AggregateType,
+		AggregateID: outboxEvent.AggregateID,
+		Payload: json.RawMessage(outboxEvent.Payload),
+		OccurredAt: outboxEvent.CreatedAt,
+	}
+}
+
+func NewOutboxRelayUseCase(cfg *config.Config, outboxRepository repository.OutboxRepository, publisher event.EventPublisher, logger logger.Logger) OutboxRelayUseCase {
+	return &outboxRelayUseCase{
+		cfg: cfg,
+		outboxRepository: outboxRepository,
+		publisher: publisher,
+		logger: logger,
+	}
+}
@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/outbox/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/outbox/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/event"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryOutbox claims like the outbox_events query does, due pending events without a live lease.
+type memoryOutbox struct {
+	repository.OutboxRepository
+	mu sync.Mutex
+	events map[int64]entity.OutboxEvent
+}
+
+func newMemoryOutbox(count int) *memoryOutbox {
+	m := &memoryOutbox{events: map[int64]entity.OutboxEvent{}}
+	for id := int64(1); id <= int64(count); id++ {
+		m.events[id] = entity.OutboxEvent{ID: id, EventType: "currency.created", AggregateType: "currency", AggregateID: "1", Payload: `{"id":1}`, Status: entity.StatusPending, NextAttemptAt: time.Now().UTC()}
+	}
+
+	return m
+}
+
+func (m *memoryOutbox) Claim(_ context.Context, limit int, lease time.Duration) ([]entity.OutboxEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	var due []entity.OutboxEvent
+	for _, outboxEvent := range m.events {
+		if outboxEvent.Status == entity.StatusPending && !outboxEvent.NextAttemptAt.After(now) && (outboxEvent.LockedUntil == nil || outboxEvent.LockedUntil.Before(now)) {
+			due = append(due, outboxEvent)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	lockedUntil := now.Add(lease)
+	for i := range due {
+		due[i].LockedUntil = &lockedUntil
+		due[i].Attempts++
+		m.events[due[i].ID] = due[i]
+	}
+
+	return due, nil
+}
+
+func (m *memoryOutbox) MarkDelivered(_ context.Context, id int64) error {
+	return m.update(id, func(e *entity.OutboxEvent) {
+		deliveredAt := time.Now().UTC()
+		e.Status = entity.StatusDelivered
+		e.DeliveredAt = &deliveredAt
+		e.LastError = ""
+	})
+}
+
+func (m *memoryOutbox) MarkRetry(_ context.Context, id int64, nextAttemptAt time.Time, lastError string) error {
+	return m.update(id, func(e *entity.OutboxEvent) {
+		e.NextAttemptAt = nextAttemptAt
+		e.LastError = lastError
+	})
+}
+
+func (m *memoryOutbox) MarkFailed(_ context.Context, id int64, lastError string) error {
+	return m.update(id, func(e *entity.OutboxEvent) {
+		e.Status = entity.StatusFailed
+		e.LastError = lastError
+	})
+}
+
+func (m *memoryOutbox) update(id int64, update func(e *entity.OutboxEvent)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	outboxEvent := m.events[id]
+	update(&outboxEvent)
+	outboxEvent.LockedUntil = nil
+	m.events[id] = outboxEvent
+
+	return nil
+}
+
+func (m *memoryOutbox) event(id int64) entity.OutboxEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.events[id]
+}
+
+func (m *memoryOutbox) makeDue(id int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	outboxEvent := m.events[id]
+	outboxEvent.NextAttemptAt = time.Now().UTC().Add(-time.Second)
+	m.events[id] = outboxEvent
+}
+
+// flakyPublisher fails while failures is above zero, then publishes to an in memory publisher.
+type flakyPublisher struct {
+	event.InMemoryPublisher
+	failures int
+}
+
+func (f *flakyPublisher) Publish(ctx context.Context, e event.Event) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("stream is down")
+	}
+
+	return f.InMemoryPublisher.Publish(ctx, e)
+}
+
+func newTestRelay(outbox repository.OutboxRepository, publisher event.EventPublisher, outboxConfig config.OutboxConfig) OutboxRelayUseCase {
+	cfg := &config.Config{Outbox: outboxConfig}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	return NewOutboxRelayUseCase(cfg, outbox, publisher, l)
+}
+
+func TestRelayPublishesInBatches(t *testing.T) {
+	outbox := newMemoryOutbox(3)
+	publisher := event.NewInMemoryPublisher()
+	relay := newTestRelay(outbox, publisher, config.OutboxConfig{BatchSize: 2})
+
+	for _, want := range []int{2, 1, 0} {
+		relayed, err := relay.RelayPending(context.Background())
+		if err != nil || relayed != want {
+			t.Fatalf("RelayPending = %d, %v, want %d", relayed, err, want)
+		}
+	}
+
+	events := publisher.Events()
+	if len(events) != 3 || events[0].ID != "1" || events[2].ID != "3" || events[0].Type != "currency.created" || string(events[0].Payload) != `{"id":1}` {
+		t.Fatalf("published %+v, want the 3 events in id order", events)
+	}
+	for id := int64(1); id <= 3; id++ {
+		if stored := outbox.event(id); stored.Status != entity.StatusDelivered || stored.LockedUntil != nil || stored.DeliveredAt == nil {
+			t.Errorf("event %d: status %s, locked until %v, delivered at %v", id, stored.Status, stored.LockedUntil, stored.DeliveredAt)
+		}
+	}
+}
+
+func TestRelayRetriesFailedPublishes(t *testing.T) {
+	outbox := newMemoryOutbox(1)
+	publisher := &flakyPublisher{InMemoryPublisher: event.NewInMemoryPublisher(), failures: 1}
+	relay := newTestRelay(outbox, publisher, config.OutboxConfig{})
+
+	before := time.Now().UTC()
+	if _, err := relay.RelayPending(context.Background()); err != nil {
+		t.Fatalf("RelayPending: %v", err)
+	}
+	stored := outbox.event(1)
+	if stored.Status != entity.StatusPending || stored.LastError != "stream is down" || stored.LockedUntil != nil {
+		t.Fatalf("after a failure: status %s, last error %q, locked until %v", stored.Status, stored.LastError, stored.LockedUntil)
+	}
+	if backoff := stored.NextAttemptAt.Sub(before); backoff < minRetryBackoff || backoff > minRetryBackoff+time.Second {
+		t.Errorf("next attempt in %s, want about %s", backoff, minRetryBackoff)
+	}
+
+	// the event is not due yet
+	if relayed, _ := relay.RelayPending(context.Background()); relayed != 0 {
+		t.Errorf("relayed %d events before the backoff passed", relayed)
+	}
+
+	outbox.makeDue(1)
+	if relayed, err := relay.RelayPending(context.Background()); err != nil || relayed != 1 {
+		t.Fatalf("RelayPending = %d, %v, want the retry", relayed, err)
+	}
+	if stored = outbox.event(1); stored.Status != entity.StatusDelivered || stored.Attempts != 2 || stored.LastError != "" {
+		t.Errorf("after the retry: status %s, attempts %d, last error %q", stored.Status, stored.Attempts, stored.LastError)
+	}
+	if len(publisher.Events()) != 1 {
+		t.Errorf("published %d events, want 1", len(publisher.Events()))
+	}
+}
+
+func TestRelayGivesUpAfterMaxAttempts(t *testing.T) {
+	outbox := newMemoryOutbox(1)
+	relay := newTestRelay(outbox, &flakyPublisher{InMemoryPublisher: event.NewInMemoryPublisher(), failures: 3}, config.OutboxConfig{MaxAttempts: 2})
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		outbox.makeDue(1)
+		if _, err := relay.RelayPending(context.Background()); err != nil {
+			t.Fatalf("RelayPending: %v", err)
+		}
+	}
+
+	if stored := outbox.event(1); stored.Status != entity.StatusFailed || stored.Attempts != 2 || stored.LastError != "stream is down" {
+		t.Errorf("status %s, attempts %d, last error %q, want failed after 2 attempts", stored.Status, stored.Attempts, stored.LastError)
+	}
+	outbox.makeDue(1)
+	if relayed, _ := relay.RelayPending(context.Background()); relayed != 0 {
+		t.Errorf("a failed event was claimed again")
+	}
+}
+
+func TestRelayLeavesLeasedEvents(t *testing.T) {
+	outbox := newMemoryOutbox(2)
+	// event 1 is leased by another relay, e.g. one that crashed, until its lease expires
+	if _, err := outbox.Claim(context.Background(), 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	publisher := event.NewInMemoryPublisher()
+	relay := newTestRelay(outbox, publisher, config.OutboxConfig{})
+
+	if relayed, err := relay.RelayPending(context.Background()); err != nil || relayed != 1 {
+		t.Fatalf("RelayPending = %d, %v, want only the unleased event", relayed, err)
+	}
+	if events := publisher.Events(); len(events) != 1 || events[0].ID != "2" {
+		t.Errorf("published %+v, want event 2", events)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := map[int]time.Duration{
+		0: time.Second,
+		1: time.Second,
+		2: time.Second * 2,
+		5: time.Second * 16,
+		9: time.Minute * 4 + time.Second * 16,
+		10: maxRetryBackoff,
+		50: maxRetryBackoff,
+	}
+
+	for attempts, want := range tests {
+		if got := retryBackoff(attempts); got != want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package entity
+
+const (
+	AggregateType = "rate"
+
+	RateCreatedEvent = "rate.created"
+)
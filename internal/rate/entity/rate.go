@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const pairSeparator = "/"
+
+type Rate struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	BaseCode string `gorm:"index:idx_rate_pair" json:"base_code"`
+	QuoteCode string `gorm:"index:idx_rate_pair" json:"quote_code"`
+	Value float64 `gorm:"type:numeric(24,10)" json:"value"`
+	Source string `json:"source"`
+	TenantID string `json:"tenant_id"`
+}
+
+func (r Rate) Pair() string {
+	return FormatPair(r.BaseCode, r.QuoteCode)
+}
+
+type Pair struct {
+	BaseCode string
+	QuoteCode string
+}
+
+func (p Pair) String() string {
+	return FormatPair(p.BaseCode, p.QuoteCode)
+}
+
+func FormatPair(baseCode, quoteCode string) string {
+	return baseCode + pairSeparator + quoteCode
+}
+
+// ParsePair parses "USD/TRY" style pairs, codes are upper cased.
+func ParsePair(pair string) (Pair, error) {
+	codes := strings.Split(strings.ToUpper(strings.TrimSpace(pair)), pairSeparator)
+	if len(codes) != 2 || codes[0] == "" || codes[1] == "" {
+		return Pair{}, fmt.Errorf("invalid currency pair %q, expected BASE/QUOTE", pair)
+	}
+
+	return Pair{BaseCode: codes[0], QuoteCode: codes[1]}, nil
+}
+
+// ParsePairs parses a comma separated pair list, an empty list means all pairs.
+func ParsePairs(pairs string) ([]Pair, error) {
+	var parsed []Pair
+	for _, pair := range strings.Split(pairs, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		p, err := ParsePair(pair)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, p)
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/request/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/stream"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/codec"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/metric"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+type RateHandlers interface {
+	Create() echo.HandlerFunc
+	GetLatest() echo.HandlerFunc
+	GetAll() echo.HandlerFunc
+	Stream() echo.HandlerFunc
+	WebSocket() echo.HandlerFunc
+}
+
+type rateHandlers struct {
+	cfg *config.Config
+	rateUseCase usecase.RateUseCase
+	hub stream.Hub
+	metrics metric.Metrics
+	logger logger.Logger
+
+	upgrader *websocket.Upgrader
+	connections *atomic.Int64
+}
+
+// Create godoc
+// @Summary Publish rate
+// @Description Ingests a new rate for a currency pair and pushes it to the stream subscribers, needs the rate.publish permission
+// @Tags Rate
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
+// @Param rateCreateRequest body rate.RateCreateRequest true "Create Rate"
+// @Success 201 {object} rate.RateResponse
+// @Success 202 {object} approval.ChangeRequestResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/rates [post]
+func (r rateHandlers) Create() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "rateHandler.Create")
+		defer span.Finish()
+
+		rateRequest := rate.RateCreateRequest{}
+		if err := e.Bind(&rateRequest); err != nil {
+			util.PrepareLogging(e, r.logger, err)
+			resp := util.ParseBindError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		createdRate, err := r.rateUseCase.Create(ctx, rateRequest)
+		if err != nil {
+			util.PrepareLogging(e, r.logger, err)
+			resp := util.ParseError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		return codec.Render(e, http.StatusCreated, createdRate)
+	}
+}
+
+// GetLatest godoc
+// @Summary Get latest rates
+// @Description Get the latest rate of the given pairs, or of every pair when none is given
+// @Tags Rate
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,text/csv,application/msgpack
+// @Param pairs query string false "comma separated pairs, e.g. USD/TRY,EUR/TRY"
+// @Success 200 {array} rate.RateResponse
+// @Router /v1/rates/latest [get]
+func (r rateHandlers) GetLatest() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "rateHandler.GetLatest")
+		defer span.Finish()
+
+		rates, err := r.rateUseCase.GetLatest(ctx, e.QueryParam("pairs"))
+		if err != nil {
+			util.PrepareLogging(e, r.logger, err)
+			resp := util.ParseError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		return codec.Render(e, http.StatusOK, rates)
+	}
+}
+
+// GetAll godoc
+// @Summary Get rate history
+// @Description Get rates with pagination, newest first
+// @Tags Rate
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,text/csv,application/msgpack
+// @Param pair query string false "currency pair, e.g. USD/TRY"
+// @Param page query int false "page number" Format(page)
+// @Param limit query int false "number of elements per page" Format(limit)
+// @Success 200 {object} rate.RateListResponse
+// @Router /v1/rates [get]
+func (r rateHandlers) GetAll() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "rateHandler.GetAll")
+		defer span.Finish()
+
+		ratePageableRequest := rate.RatePageableRequest{
+			Pair: e.QueryParam("pair"),
+		}
+		if page, err := strconv.Atoi(e.QueryParam("page")); err == nil {
+			ratePageableRequest.Page = page
+		}
+		if limit, err := strconv.Atoi(e.QueryParam("limit")); err == nil {
+			ratePageableRequest.Size = limit
+		}
+
+		rateList, err := r.rateUseCase.GetAll(ctx, &ratePageableRequest)
+		if err != nil {
+			util.PrepareLogging(e, r.logger, err)
+			resp := util.ParseError(err)
+			return codec.Render(e, resp.Status(), resp)
+		}
+
+		return codec.Render(e, http.StatusOK, rateList)
+	}
+}
+
+func NewRateHandler(cfg *config.Config, rateUseCase usecase.RateUseCase, hub stream.Hub, metrics metric.Metrics, logger logger.Logger) RateHandlers {
+	return &rateHandlers{
+		cfg: cfg,
+		rateUseCase: rateUseCase,
+		hub: hub,
+		metrics: metrics,
+		logger: logger,
+		upgrader: &websocket.Upgrader{
+			CheckOrigin: checkOrigin(cfg.WebSocket.AllowedOrigins),
+		},
+		connections: &atomic.Int64{},
+	}
+}
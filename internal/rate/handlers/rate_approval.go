@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	approvalHandlers "github.com/sefikcan/kanbersky.ca/internal/approval/handlers"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/request/rate"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+)
+
+type rateApprovalHandlers struct {
+	RateHandlers
+	propose func(permission string, bind approvalHandlers.Binder) echo.MiddlewareFunc
+}
+
+func (r rateApprovalHandlers) Create() echo.HandlerFunc {
+	return r.propose(auth.PermissionRatePublish, bindCreateProposal)(r.RateHandlers.Create())
+}
+
+// NewRateApprovalHandler turns the manual rates of callers whose roles require approval into change requests,
+// propose returns the middleware doing it, e.g. ChangeRequestHandlers.Propose.
+func NewRateApprovalHandler(next RateHandlers, propose func(permission string, bind approvalHandlers.Binder) echo.MiddlewareFunc) RateHandlers {
+	return &rateApprovalHandlers{
+		RateHandlers: next,
+		propose: propose,
+	}
+}
+
+func bindCreateProposal(e echo.Context) (interface{}, any, error) {
+	createRequest := rate.RateCreateRequest{}
+	if err := e.Bind(&createRequest); err != nil {
+		return nil, nil, util.ParseBindError(err)
+	}
+	if err := util.ValidateStruct(&createRequest); err != nil {
+		return nil, nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "rateHandler.bindCreateProposal.ValidateStruct"))
+	}
+
+	return nil, createRequest, nil
+}
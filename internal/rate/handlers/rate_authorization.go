@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+)
+
+// rateAuthorizationHandlers only guards publishing, rates stay readable and streamable without credentials.
+type rateAuthorizationHandlers struct {
+	RateHandlers
+	authenticate echo.MiddlewareFunc
+	authorize func(permission string) echo.MiddlewareFunc
+}
+
+func (r rateAuthorizationHandlers) Create() echo.HandlerFunc {
+	return r.authenticate(r.authorize(auth.PermissionRatePublish)(r.RateHandlers.Create()))
+}
+
+// NewRateAuthorizationHandler requires an authenticated principal with the rate.publish permission to
+// publish rates, authorize returns the middleware checking a permission, e.g. MiddlewareManager.PermissionMiddleware.
+func NewRateAuthorizationHandler(next RateHandlers, authenticate echo.MiddlewareFunc, authorize func(permission string) echo.MiddlewareFunc) RateHandlers {
+	return &rateAuthorizationHandlers{
+		RateHandlers: next,
+		authenticate: authenticate,
+		authorize: authorize,
+	}
+}
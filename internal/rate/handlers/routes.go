@@ -0,0 +1,11 @@
+package handlers
+
+import "github.com/labstack/echo/v4"
+
+func MapRateRoutes(rateRouteGroup *echo.Group, r RateHandlers) {
+	rateRouteGroup.POST("", r.Create())
+	rateRouteGroup.GET("", r.GetAll())
+	rateRouteGroup.GET("/latest", r.GetLatest())
+	rateRouteGroup.GET("/stream", r.Stream())
+	rateRouteGroup.GET("/ws", r.WebSocket())
+}
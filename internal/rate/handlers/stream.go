@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/stream"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerLastEventID = "Last-Event-ID"
+	defaultHeartbeatInterval = 15
+	retryMilliseconds = 3000
+)
+
+// Stream godoc
+// @Summary Stream rate updates
+// @Description Server-Sent Events stream of ingested rates. Each event id is the rate id, reconnecting with Last-Event-ID resumes from the replay buffer.
+// @Tags Rate
+// @Produce text/event-stream
+// @Param pairs query string false "comma separated pairs, e.g. USD/TRY,EUR/TRY"
+// @Param Last-Event-ID header int false "id of the last received event"
+// @Success 200 {object} rate.RateResponse
+// @Router /v1/rates/stream [get]
+func (r rateHandlers) Stream() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		pairs, err := entity.ParsePairs(e.QueryParam("pairs"))
+		if err != nil {
+			util.PrepareLogging(e, r.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		lastEventId, err := getLastEventId(e)
+		if err != nil {
+			util.PrepareLogging(e, r.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		subscription := r.hub.Subscribe(tenant.FromContext(e.Request().Context()), pairNames(pairs)...)
+		defer subscription.Close()
+
+		if r.metrics != nil {
+			r.metrics.IncreaseActiveConnections(transportSSE)
+			defer r.metrics.DecreaseActiveConnections(transportSSE)
+		}
+
+		// the server write timeout is meant for regular requests, a stream stays open until the client leaves
+		if err = http.NewResponseController(e.Response().Writer).SetWriteDeadline(time.Time{}); err != nil {
+			r.logger.Warnf("rateHandler.Stream.SetWriteDeadline: %s", err)
+		}
+
+		res := e.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+		res.Header().Set("Connection", "keep-alive")
+		res.Header().Set("X-Accel-Buffering", "no")
+		res.WriteHeader(http.StatusOK)
+
+		if _, err = fmt.Fprintf(res, "retry: %d\n\n", retryMilliseconds); err != nil {
+			return nil
+		}
+
+		lastSentId := lastEventId
+		if lastEventId > 0 {
+			for _, update := range r.hub.Replay(lastEventId, subscription) {
+				if err = writeEvent(res, update); err != nil {
+					return nil
+				}
+				lastSentId = update.ID
+			}
+		}
+		res.Flush()
+
+		heartbeat := time.NewTicker(r.heartbeatInterval())
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-e.Request().Context().Done():
+				return nil
+			case <-subscription.Done():
+				r.logger.Warnf("Rate stream client too slow, disconnected, RequestId: %s", util.GetRequestId(e))
+				return nil
+			case <-heartbeat.C:
+				if _, err = fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+					return nil
+				}
+				res.Flush()
+			case update := <-subscription.Updates():
+				// already sent from the replay buffer
+				if update.ID <= lastSentId {
+					continue
+				}
+				if err = writeEvent(res, update); err != nil {
+					return nil
+				}
+				res.Flush()
+			}
+		}
+	}
+}
+
+func (r rateHandlers) heartbeatInterval() time.Duration {
+	if r.cfg.Stream.HeartbeatInterval <= 0 {
+		return time.Second * defaultHeartbeatInterval
+	}
+
+	return time.Second * r.cfg.Stream.HeartbeatInterval
+}
+
+func writeEvent(res *echo.Response, update *rate.RateResponse) error {
+	rateByte, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(res, "id: %d\nevent: rate\ndata: %s\n\n", update.ID, rateByte)
+	return err
+}
+
+func getLastEventId(e echo.Context) (int64, error) {
+	lastEventId := e.Request().Header.Get(headerLastEventID)
+	if lastEventId == "" {
+		lastEventId = e.QueryParam("last_event_id")
+	}
+	if lastEventId == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(lastEventId, 10, 64)
+}
+
+func pairNames(pairs []entity.Pair) []string {
+	if len(pairs) == 0 {
+		return []string{stream.AllPairs}
+	}
+
+	names := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		names = append(names, pair.String())
+	}
+
+	return names
+}
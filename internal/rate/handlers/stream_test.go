@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/stream"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testChannel = "rates:test"
+
+// newTestServer serves the stream routes of tenant acme, the hub runs over miniredis.
+func newTestServer(t *testing.T, cfg *config.Config, rateUseCase usecase.RateUseCase) (*httptest.Server, stream.Hub) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	cfg.Stream.Channel = testChannel
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	hub := stream.NewHub(cfg, redisClient, l)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		hub.Run(ctx)
+	}()
+	deadline := time.Now().Add(time.Second * 5)
+	for mr.PubSubNumSub(testChannel)[testChannel] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("hub did not subscribe")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.SetRequest(c.Request().WithContext(tenant.NewContext(c.Request().Context(), "acme")))
+			return next(c)
+		}
+	})
+	MapRateRoutes(e.Group("/rates"), NewRateHandler(cfg, rateUseCase, hub, nil, l))
+	server := httptest.NewServer(e)
+	t.Cleanup(func() {
+		server.CloseClientConnections()
+		server.Close()
+		cancel()
+		<-stopped
+	})
+
+	return server, hub
+}
+
+func publish(t *testing.T, hub stream.Hub, updates ...*rate.RateResponse) {
+	t.Helper()
+	for _, update := range updates {
+		if err := hub.Publish(context.Background(), update); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+}
+
+// sseClient reads the events of a stream, a comment or the retry hint is an event without an id.
+type sseClient struct {
+	res *http.Response
+	events chan map[string]string
+}
+
+func openStream(t *testing.T, url string, lastEventId string) *sseClient {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventId != "" {
+		req.Header.Set(headerLastEventID, lastEventId)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+
+	c := &sseClient{res: res, events: make(chan map[string]string, 16)}
+	go func() {
+		defer close(c.events)
+		scanner := bufio.NewScanner(res.Body)
+		fields := map[string]string{}
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				c.events <- fields
+				fields = map[string]string{}
+				continue
+			}
+			name, value, _ := strings.Cut(line, ":")
+			fields[name] = strings.TrimSpace(value)
+		}
+	}()
+
+	return c
+}
+
+func (c *sseClient) next(t *testing.T) map[string]string {
+	t.Helper()
+	select {
+	case fields, ok := <-c.events:
+		if !ok {
+			t.Fatal("stream closed")
+		}
+		return fields
+	case <-time.After(time.Second * 5):
+		t.Fatal("no event received")
+		return nil
+	}
+}
+
+// nextRate skips heartbeats and returns the id of the next rate event.
+func (c *sseClient) nextRate(t *testing.T) string {
+	t.Helper()
+	for {
+		fields := c.next(t)
+		if fields["event"] == "rate" {
+			if !strings.Contains(fields["data"], `"id":`+fields["id"]) {
+				t.Errorf("data %s does not belong to event %s", fields["data"], fields["id"])
+			}
+			return fields["id"]
+		}
+	}
+}
+
+func TestStreamSendsMatchingRates(t *testing.T) {
+	server, hub := newTestServer(t, &config.Config{}, nil)
+	c := openStream(t, server.URL+"/rates/stream?pairs=usd/try", "")
+
+	if c.res.StatusCode != http.StatusOK || c.res.Header.Get(echo.HeaderContentType) != "text/event-stream" {
+		t.Fatalf("status %d, content type %s", c.res.StatusCode, c.res.Header.Get(echo.HeaderContentType))
+	}
+	if fields := c.next(t); fields["retry"] != "3000" {
+		t.Errorf("first event = %v, want the retry hint", fields)
+	}
+
+	publish(t, hub,
+		&rate.RateResponse{ID: 1, Pair: "USD/TRY", TenantID: "acme"},
+		&rate.RateResponse{ID: 2, Pair: "EUR/TRY", TenantID: "acme"},
+		&rate.RateResponse{ID: 3, Pair: "USD/TRY", TenantID: "globex"},
+		&rate.RateResponse{ID: 4, Pair: "USD/TRY", TenantID: tenant.Global},
+	)
+	for _, want := range []string{"1", "4"} {
+		if id := c.nextRate(t); id != want {
+			t.Errorf("event id = %s, want %s", id, want)
+		}
+	}
+}
+
+func TestStreamResumesFromLastEventId(t *testing.T) {
+	server, hub := newTestServer(t, &config.Config{}, nil)
+	// a first client sees the updates arrive, so they are in the replay buffer
+	first := openStream(t, server.URL+"/rates/stream", "")
+	publish(t, hub,
+		&rate.RateResponse{ID: 1, Pair: "USD/TRY", TenantID: "acme"},
+		&rate.RateResponse{ID: 2, Pair: "USD/TRY", TenantID: "acme"},
+		&rate.RateResponse{ID: 3, Pair: "EUR/TRY", TenantID: "acme"},
+	)
+	for _, want := range []string{"1", "2", "3"} {
+		if id := first.nextRate(t); id != want {
+			t.Fatalf("event id = %s, want %s", id, want)
+		}
+	}
+
+	resumed := openStream(t, server.URL+"/rates/stream", "1")
+	for _, want := range []string{"2", "3"} {
+		if id := resumed.nextRate(t); id != want {
+			t.Errorf("replayed event id = %s, want %s", id, want)
+		}
+	}
+	publish(t, hub, &rate.RateResponse{ID: 4, Pair: "USD/TRY", TenantID: "acme"})
+	if id := resumed.nextRate(t); id != "4" {
+		t.Errorf("live event id = %s, want 4", id)
+	}
+}
+
+func TestStreamSendsHeartbeats(t *testing.T) {
+	server, _ := newTestServer(t, &config.Config{Stream: config.StreamConfig{HeartbeatInterval: 1}}, nil)
+	c := openStream(t, server.URL+"/rates/stream", "")
+
+	c.next(t)
+	if fields := c.next(t); fields[""] != "heartbeat" {
+		t.Errorf("event = %v, want a heartbeat comment", fields)
+	}
+}
+
+func TestStreamRejectsBadRequests(t *testing.T) {
+	server, _ := newTestServer(t, &config.Config{}, nil)
+
+	for name, url := range map[string]string{
+		"bad pair": server.URL + "/rates/stream?pairs=USD",
+		"bad last event id": server.URL + "/rates/stream?last_event_id=abc",
+	} {
+		res, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, res.StatusCode)
+		}
+	}
+}
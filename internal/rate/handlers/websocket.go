@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/rate"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/stream"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	MessageTypeSubscribe = "subscribe"
+	MessageTypeUnsubscribe = "unsubscribe"
+	MessageTypeSnapshot = "snapshot"
+	MessageTypeUpdate = "update"
+	MessageTypeError = "error"
+
+	transportWebSocket = "websocket"
+	transportSSE = "sse"
+
+	defaultMaxConnections = 10000
+	defaultMaxPairs = 50
+	defaultMaxMessageSize = 4096
+	defaultIdleTimeout = 60
+	defaultWriteTimeout = 10
+	outgoingBufferSize = 16
+)
+
+// WebSocket godoc
+// @Summary Subscribe to rate updates over WebSocket
+// @Description Bidirectional rate stream. Clients send {"type":"subscribe|unsubscribe","pairs":["USD/TRY"]}, the server answers with snapshot, update and error messages. "*" subscribes to every pair.
+// @Tags Rate
+// @Success 101
+// @Failure 503 {object} util.HttpResponse
+// @Router /v1/rates/ws [get]
+func (r rateHandlers) WebSocket() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		if !r.acquireConnection() {
+			r.logger.Warnf("WebSocket connection limit reached, RequestId: %s", util.GetRequestId(e))
+			return e.JSON(http.StatusServiceUnavailable, util.NewHttpResponse(http.StatusServiceUnavailable, "too many websocket connections", nil))
+		}
+		defer r.releaseConnection()
+
+		// Upgrade writes the error response itself when the handshake fails
+		conn, err := r.upgrader.Upgrade(e.Response(), e.Request(), nil)
+		if err != nil {
+			util.PrepareLogging(e, r.logger, err)
+			return nil
+		}
+		defer conn.Close()
+
+		if r.metrics != nil {
+			r.metrics.IncreaseActiveConnections(transportWebSocket)
+			defer r.metrics.DecreaseActiveConnections(transportWebSocket)
+		}
+
+		session := &webSocketSession{
+			handler: r,
+			conn: conn,
+			subscription: r.hub.Subscribe(tenant.FromContext(e.Request().Context())),
+			outgoing: make(chan response.RateStreamMessage, outgoingBufferSize),
+			quit: make(chan struct{}),
+			writerDone: make(chan struct{}),
+			sent: make(map[string]int64),
+		}
+		defer session.subscription.Close()
+
+		go session.writeLoop()
+		session.readLoop(util.GetRequestCtx(e))
+
+		close(session.quit)
+		<-session.writerDone
+
+		return nil
+	}
+}
+
+// webSocketSession serves one connection. readLoop owns the reads and writeLoop owns the writes,
+// so the connection never sees concurrent readers or writers.
+type webSocketSession struct {
+	handler rateHandlers
+	conn *websocket.Conn
+	subscription stream.Subscription
+	outgoing chan response.RateStreamMessage
+	quit chan struct{}
+	writerDone chan struct{}
+
+	// sent is only touched by writeLoop, it keeps a snapshot from overwriting a newer update
+	sent map[string]int64
+}
+
+func (s *webSocketSession) readLoop(ctx context.Context) {
+	idleTimeout := s.handler.idleTimeout()
+	s.conn.SetReadLimit(s.handler.maxMessageSize())
+	_ = s.conn.SetReadDeadline(time.Now().Add(idleTimeout))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(idleTimeout))
+	})
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				s.handler.logger.Debugf("rateHandler.WebSocket.ReadMessage: %s", err)
+			}
+			return
+		}
+		_ = s.conn.SetReadDeadline(time.Now().Add(idleTimeout))
+
+		message := request.RateStreamMessage{}
+		if err = json.Unmarshal(data, &message); err != nil {
+			s.sendError("message must be a json object")
+			continue
+		}
+
+		switch message.Type {
+		case MessageTypeSubscribe:
+			s.subscribe(ctx, message.Pairs)
+		case MessageTypeUnsubscribe:
+			s.unsubscribe(message.Pairs)
+		default:
+			s.sendError("unknown message type " + message.Type)
+		}
+	}
+}
+
+func (s *webSocketSession) subscribe(ctx context.Context, pairs []string) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateHandler.WebSocket.subscribe")
+	defer span.Finish()
+
+	names, err := parsePairNames(pairs)
+	if err != nil {
+		s.sendError(err.Error())
+		return
+	}
+
+	if len(mergePairs(s.subscription.Pairs(), names)) > s.handler.maxPairs() {
+		s.sendError("subscription pair limit exceeded")
+		return
+	}
+	s.subscription.Add(names...)
+
+	// the latest rates are read after subscribing, so nothing published in between is lost
+	latestPairs := strings.Join(names, ",")
+	for _, name := range names {
+		if name == stream.AllPairs {
+			latestPairs = ""
+		}
+	}
+
+	rates, err := s.handler.rateUseCase.GetLatest(spanContext, latestPairs)
+	if err != nil {
+		s.handler.logger.Errorf("rateHandler.WebSocket.subscribe.GetLatest: %s", err)
+		s.sendError("latest rates are not available")
+	}
+
+	s.send(response.RateStreamMessage{
+		Type: MessageTypeSnapshot,
+		Pairs: s.subscription.Pairs(),
+		Rates: rates,
+	})
+}
+
+func (s *webSocketSession) unsubscribe(pairs []string) {
+	names, err := parsePairNames(pairs)
+	if err != nil {
+		s.sendError(err.Error())
+		return
+	}
+	s.subscription.Remove(names...)
+
+	s.send(response.RateStreamMessage{
+		Type: MessageTypeSnapshot,
+		Pairs: s.subscription.Pairs(),
+	})
+}
+
+func (s *webSocketSession) sendError(message string) {
+	s.send(response.RateStreamMessage{
+		Type: MessageTypeError,
+		Error: message,
+	})
+}
+
+func (s *webSocketSession) send(message response.RateStreamMessage) {
+	select {
+	case s.outgoing <- message:
+	case <-s.writerDone:
+	}
+}
+
+func (s *webSocketSession) writeLoop() {
+	defer close(s.writerDone)
+	// closing the connection unblocks readLoop when the writer gives up first
+	defer s.conn.Close()
+
+	ping := time.NewTicker(s.handler.pingInterval())
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-s.quit:
+			s.writeClose(websocket.CloseNormalClosure, "")
+			return
+		case <-s.subscription.Done():
+			s.handler.logger.Warnf("Rate websocket client too slow, disconnected")
+			s.writeClose(websocket.ClosePolicyViolation, "client too slow")
+			return
+		case <-ping.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(s.handler.writeTimeout())); err != nil {
+				return
+			}
+		case update := <-s.subscription.Updates():
+			// updates queued before an unsubscribe are dropped here
+			if !s.subscription.Matches(update.Pair) || update.ID <= s.sent[update.Pair] {
+				continue
+			}
+			s.sent[update.Pair] = update.ID
+			if err := s.write(response.RateStreamMessage{Type: MessageTypeUpdate, Rate: update}); err != nil {
+				return
+			}
+		case message := <-s.outgoing:
+			if message.Type == MessageTypeSnapshot {
+				s.forgetUnsubscribed()
+				message.Rates = s.newerThanSent(message.Rates)
+			}
+			if err := s.write(message); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *webSocketSession) forgetUnsubscribed() {
+	for pair := range s.sent {
+		if !s.subscription.Matches(pair) {
+			delete(s.sent, pair)
+		}
+	}
+}
+
+func (s *webSocketSession) newerThanSent(rates []*response.RateResponse) []*response.RateResponse {
+	newer := make([]*response.RateResponse, 0, len(rates))
+	for _, rate := range rates {
+		if rate.ID <= s.sent[rate.Pair] {
+			continue
+		}
+		s.sent[rate.Pair] = rate.ID
+		newer = append(newer, rate)
+	}
+
+	return newer
+}
+
+func (s *webSocketSession) write(message response.RateStreamMessage) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.handler.writeTimeout())); err != nil {
+		return errors.Wrap(err, "rateHandler.WebSocket.SetWriteDeadline")
+	}
+
+	return s.conn.WriteJSON(message)
+}
+
+func (s *webSocketSession) writeClose(code int, text string) {
+	_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(s.handler.writeTimeout()))
+}
+
+func (r rateHandlers) acquireConnection() bool {
+	if r.connections.Add(1) > int64(r.maxConnections()) {
+		r.connections.Add(-1)
+		return false
+	}
+
+	return true
+}
+
+func (r rateHandlers) releaseConnection() {
+	r.connections.Add(-1)
+}
+
+func checkOrigin(origins []string) func(req *http.Request) bool {
+	// without configured origins the upgrader falls back to its same origin check
+	if len(origins) == 0 {
+		return nil
+	}
+
+	return func(req *http.Request) bool {
+		origin := req.Header.Get(echo.HeaderOrigin)
+		for _, allowed := range origins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func (r rateHandlers) maxConnections() int {
+	if r.cfg.WebSocket.MaxConnections <= 0 {
+		return defaultMaxConnections
+	}
+
+	return r.cfg.WebSocket.MaxConnections
+}
+
+func (r rateHandlers) maxPairs() int {
+	if r.cfg.WebSocket.MaxPairs <= 0 {
+		return defaultMaxPairs
+	}
+
+	return r.cfg.WebSocket.MaxPairs
+}
+
+func (r rateHandlers) maxMessageSize() int64 {
+	if r.cfg.WebSocket.MaxMessageSize <= 0 {
+		return defaultMaxMessageSize
+	}
+
+	return r.cfg.WebSocket.MaxMessageSize
+}
+
+func (r rateHandlers) idleTimeout() time.Duration {
+	if r.cfg.WebSocket.IdleTimeout <= 0 {
+		return time.Second * defaultIdleTimeout
+	}
+
+	return time.Second * r.cfg.WebSocket.IdleTimeout
+}
+
+// pingInterval has to stay below the idle timeout, otherwise a quiet but healthy client is dropped.
+func (r rateHandlers) pingInterval() time.Duration {
+	idleTimeout := r.idleTimeout()
+	pingInterval := time.Second * r.cfg.WebSocket.PingInterval
+	if pingInterval <= 0 || pingInterval >= idleTimeout {
+		return idleTimeout * 9 / 10
+	}
+
+	return pingInterval
+}
+
+func (r rateHandlers) writeTimeout() time.Duration {
+	if r.cfg.WebSocket.WriteTimeout <= 0 {
+		return time.Second * defaultWriteTimeout
+	}
+
+	return time.Second * r.cfg.WebSocket.WriteTimeout
+}
+
+func parsePairNames(pairs []string) ([]string, error) {
+	if len(pairs) == 0 {
+		return nil, errors.New("pairs are required")
+	}
+
+	names := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		if pair == stream.AllPairs {
+			names = append(names, stream.AllPairs)
+			continue
+		}
+
+		parsed, err := entity.ParsePair(pair)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, parsed.String())
+	}
+
+	return names, nil
+}
+
+func mergePairs(current []string, added []string) map[string]struct{} {
+	merged := make(map[string]struct{}, len(current)+len(added))
+	for _, pair := range current {
+		merged[pair] = struct{}{}
+	}
+	for _, pair := range added {
+		merged[pair] = struct{}{}
+	}
+
+	return merged
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubRateUseCase answers GetLatest from a map of pairs, the methods it does not override panic.
+type stubRateUseCase struct {
+	usecase.RateUseCase
+	latest map[string]*rate.RateResponse
+}
+
+func (s *stubRateUseCase) GetLatest(_ context.Context, pairs string) ([]*rate.RateResponse, error) {
+	var rates []*rate.RateResponse
+	for pair, latest := range s.latest {
+		if pairs == "" || strings.Contains(","+pairs+",", ","+pair+",") {
+			rates = append(rates, latest)
+		}
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].ID < rates[j].ID
+	})
+
+	return rates, nil
+}
+
+func dial(t *testing.T, url string, header http.Header) *websocket.Conn {
+	t.Helper()
+	conn, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/rates/ws", header)
+	if err != nil {
+		status := 0
+		if res != nil {
+			status = res.StatusCode
+		}
+		t.Fatalf("dial: %v, status %d", err, status)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, messageType string, pairs ...string) {
+	t.Helper()
+	if err := conn.WriteJSON(request.RateStreamMessage{Type: messageType, Pairs: pairs}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) rate.RateStreamMessage {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	message := rate.RateStreamMessage{}
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	return message
+}
+
+func rateIds(rates []*rate.RateResponse) []int64 {
+	ids := make([]int64, 0, len(rates))
+	for _, r := range rates {
+		ids = append(ids, r.ID)
+	}
+
+	return ids
+}
+
+func TestWebSocketSubscriptions(t *testing.T) {
+	rates := &stubRateUseCase{latest: map[string]*rate.RateResponse{
+		"USD/TRY": {ID: 3, Pair: "USD/TRY", TenantID: "acme"},
+		"EUR/TRY": {ID: 4, Pair: "EUR/TRY", TenantID: "acme"},
+	}}
+	server, hub := newTestServer(t, &config.Config{}, rates)
+	conn := dial(t, server.URL, nil)
+
+	send(t, conn, MessageTypeSubscribe, "usd/try")
+	snapshot := receive(t, conn)
+	if snapshot.Type != MessageTypeSnapshot || len(snapshot.Pairs) != 1 || snapshot.Pairs[0] != "USD/TRY" || len(snapshot.Rates) != 1 || snapshot.Rates[0].ID != 3 {
+		t.Fatalf("snapshot = %+v, want USD/TRY with its latest rate", snapshot)
+	}
+
+	publish(t, hub,
+		&rate.RateResponse{ID: 5, Pair: "EUR/TRY", TenantID: "acme"},
+		&rate.RateResponse{ID: 6, Pair: "USD/TRY", TenantID: "acme"},
+	)
+	if update := receive(t, conn); update.Type != MessageTypeUpdate || update.Rate.ID != 6 {
+		t.Fatalf("message = %+v, want the USD/TRY update 6", update)
+	}
+
+	// the snapshot of a new pair leaves out the latest USD/TRY rate 3, the update 6 was already sent
+	send(t, conn, MessageTypeSubscribe, "EUR/TRY")
+	snapshot = receive(t, conn)
+	if ids := rateIds(snapshot.Rates); len(snapshot.Pairs) != 2 || len(ids) != 1 || ids[0] != 4 {
+		t.Errorf("snapshot = pairs %v, rates %v, want both pairs and only rate 4", snapshot.Pairs, ids)
+	}
+
+	send(t, conn, MessageTypeUnsubscribe, "USD/TRY")
+	if snapshot = receive(t, conn); len(snapshot.Pairs) != 1 || snapshot.Pairs[0] != "EUR/TRY" {
+		t.Errorf("pairs after unsubscribe = %v, want [EUR/TRY]", snapshot.Pairs)
+	}
+	publish(t, hub,
+		&rate.RateResponse{ID: 7, Pair: "USD/TRY", TenantID: "acme"},
+		&rate.RateResponse{ID: 8, Pair: "EUR/TRY", TenantID: "globex"},
+		&rate.RateResponse{ID: 9, Pair: "EUR/TRY", TenantID: "acme"},
+	)
+	if update := receive(t, conn); update.Type != MessageTypeUpdate || update.Rate.ID != 9 {
+		t.Errorf("message = %+v, want the EUR/TRY update 9", update)
+	}
+}
+
+func TestWebSocketRejectsBadMessages(t *testing.T) {
+	server, _ := newTestServer(t, &config.Config{WebSocket: config.WebSocketConfig{MaxPairs: 1}}, &stubRateUseCase{})
+	conn := dial(t, server.URL, nil)
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("not json")); err != nil {
+		t.Fatal(err)
+	}
+	send(t, conn, "publish", "USD/TRY")
+	send(t, conn, MessageTypeSubscribe)
+	send(t, conn, MessageTypeSubscribe, "USD")
+	send(t, conn, MessageTypeSubscribe, "USD/TRY", "EUR/TRY")
+
+	for _, want := range []string{
+		"message must be a json object",
+		"unknown message type publish",
+		"pairs are required",
+		"",
+		"subscription pair limit exceeded",
+	} {
+		message := receive(t, conn)
+		if message.Type != MessageTypeError || (want != "" && message.Error != want) {
+			t.Errorf("message = %+v, want error %q", message, want)
+		}
+	}
+}
+
+func TestWebSocketConnectionLimits(t *testing.T) {
+	server, _ := newTestServer(t, &config.Config{WebSocket: config.WebSocketConfig{MaxConnections: 1, AllowedOrigins: []string{"https://app.example"}}}, &stubRateUseCase{})
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/rates/ws"
+
+	_, res, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example"}})
+	if err == nil || res == nil || res.StatusCode != http.StatusForbidden {
+		t.Errorf("foreign origin: err %v, want 403", err)
+	}
+
+	dial(t, server.URL, http.Header{"Origin": {"https://app.example"}})
+	_, res, err = websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://app.example"}})
+	if err == nil || res == nil || res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("second connection: err %v, want 503", err)
+	}
+}
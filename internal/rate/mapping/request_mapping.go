@@ -0,0 +1,16 @@
+package mapping
+
+import (
+	"github.com/sefikcan/kanbersky.ca/internal/dto/request/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+	"strings"
+)
+
+func CreateMapEntity(rate *rate.RateCreateRequest) entity.Rate {
+	return entity.Rate{
+		BaseCode: strings.ToUpper(rate.BaseCode),
+		QuoteCode: strings.ToUpper(rate.QuoteCode),
+		Value: rate.Value,
+		Source: rate.Source,
+	}
+}
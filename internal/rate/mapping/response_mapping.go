@@ -0,0 +1,28 @@
+package mapping
+
+import (
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+)
+
+func MapDto(r entity.Rate) *rate.RateResponse {
+	return &rate.RateResponse{
+		ID: r.ID,
+		Pair: r.Pair(),
+		BaseCode: r.BaseCode,
+		QuoteCode: r.QuoteCode,
+		Value: r.Value,
+		Source: r.Source,
+		TenantID: r.TenantID,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func MapListDto(rates []entity.Rate) []*rate.RateResponse {
+	rateResp := make([]*rate.RateResponse, 0, len(rates))
+	for _, r := range rates {
+		rateResp = append(rateResp, MapDto(r))
+	}
+
+	return rateResp
+}
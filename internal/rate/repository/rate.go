@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	audit "github.com/sefikcan/kanbersky.ca/internal/audit/entity"
+	auditRepository "github.com/sefikcan/kanbersky.ca/internal/audit/repository"
+	outbox "github.com/sefikcan/kanbersky.ca/internal/outbox/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+type RateRepository interface {
+	Create(ctx context.Context, rate entity.Rate) (entity.Rate, error)
+	GetLatest(ctx context.Context, pairs []entity.Pair) ([]entity.Rate, error)
+	GetCount(ctx context.Context, pairs []entity.Pair) (int64, error)
+	GetAll(ctx context.Context, pairs []entity.Pair, query util.Pagination) ([]entity.Rate, error)
+	GetPrevious(ctx context.Context, pair entity.Pair, beforeId int64) (entity.Rate, error)
+	GetAsOf(ctx context.Context, pair entity.Pair, at time.Time) (entity.Rate, error)
+	GetRecent(ctx context.Context, pairs []entity.Pair, limit int) ([]entity.Rate, error)
+	Export(ctx context.Context, pairs []entity.Pair, from time.Time, to time.Time, fn func(entity.Rate) error) error
+}
+
+type rateRepository struct {
+	db *gorm.DB
+}
+
+func (r rateRepository) Create(ctx context.Context, rate entity.Rate) (entity.Rate, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRepository.Create")
+	defer span.Finish()
+
+	err := r.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		if result := tx.Create(&rate); result.Error != nil {
+			return errors.Wrap(result.Error, "rateRepository.Create.DbError")
+		}
+		if err := auditRepository.AddAuditLog(tx, entity.AggregateType, rate.ID, audit.ActionCreate, nil, rate); err != nil {
+			return err
+		}
+
+		outboxEvent, err := outbox.NewOutboxEvent(entity.AggregateType, rate.ID, entity.RateCreatedEvent, rate)
+		if err != nil {
+			return err
+		}
+		if result := tx.Create(&outboxEvent); result.Error != nil {
+			return errors.Wrap(result.Error, "rateRepository.Create.Outbox.DbError")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return entity.Rate{}, err
+	}
+
+	return rate, nil
+}
+
+// GetLatest returns the newest rate of every requested pair, or of every known pair when pairs is empty.
+// The rates a tenant publishes for a pair take precedence over the global rates of the pair.
+func (r rateRepository) GetLatest(ctx context.Context, pairs []entity.Pair) ([]entity.Rate, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRepository.GetLatest")
+	defer span.Finish()
+
+	var rates []entity.Rate
+	query := withVisibleTenants(r.db.WithContext(spanContext)).Model(&entity.Rate{}).
+		Select("DISTINCT ON (base_code, quote_code) *").
+		Order("base_code, quote_code, tenant_id desc, id desc")
+	if err := withPairs(query, pairs).Find(&rates).Error; err != nil {
+		return nil, errors.Wrap(err, "rateRepository.GetLatest.DbError")
+	}
+
+	return rates, nil
+}
+
+func (r rateRepository) GetCount(ctx context.Context, pairs []entity.Pair) (int64, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRepository.GetCount")
+	defer span.Finish()
+
+	var totalCount int64
+	if err := withPairs(withVisibleTenants(r.db.WithContext(spanContext)).Model(&entity.Rate{}), pairs).Count(&totalCount).Error; err != nil {
+		return 0, errors.Wrap(err, "rateRepository.GetCount.DbError")
+	}
+
+	return totalCount, nil
+}
+
+func (r rateRepository) GetAll(ctx context.Context, pairs []entity.Pair, query util.Pagination) ([]entity.Rate, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRepository.GetAll")
+	defer span.Finish()
+
+	var rates []entity.Rate
+	err := withPairs(withVisibleTenants(r.db.WithContext(spanContext)), pairs).
+		Offset(query.GetOffset()).Limit(query.GetLimit()).Order("id desc").Find(&rates).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "rateRepository.GetAll.DbError")
+	}
+
+	return rates, nil
+}
+
+func (r rateRepository) GetPrevious(ctx context.Context, pair entity.Pair, beforeId int64) (entity.Rate, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRepository.GetPrevious")
+	defer span.Finish()
+
+	rate := entity.Rate{}
+	err := withPairs(withVisibleTenants(r.db.WithContext(spanContext)), []entity.Pair{pair}).
+		Where(`id < ?`, beforeId).Order("id desc").First(&rate).Error
+	if err != nil {
+		return entity.Rate{}, errors.Wrap(err, "rateRepository.GetPrevious.DbError")
+	}
+
+	return rate, nil
+}
+
+// GetAsOf returns the rate that was the latest one of the pair at the given time, with the same precedence
+// of tenant rates as GetLatest.
+func (r rateRepository) GetAsOf(ctx context.Context, pair entity.Pair, at time.Time) (entity.Rate, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRepository.GetAsOf")
+	defer span.Finish()
+
+	rate := entity.Rate{}
+	err := withPairs(withVisibleTenants(r.db.WithContext(spanContext)), []entity.Pair{pair}).
+		Where(`created_at <= ?`, at).Order("tenant_id desc, created_at desc, id desc").First(&rate).Error
+	if err != nil {
+		return entity.Rate{}, errors.Wrap(err, "rateRepository.GetAsOf.DbError")
+	}
+
+	return rate, nil
+}
+
+// GetRecent returns up to limit newest rates of every requested pair in one query, newest first per pair.
+func (r rateRepository) GetRecent(ctx context.Context, pairs []entity.Pair, limit int) ([]entity.Rate, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRepository.GetRecent")
+	defer span.Finish()
+
+	ranked := withPairs(withVisibleTenants(r.db.WithContext(spanContext)).Model(&entity.Rate{}), pairs).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY base_code, quote_code ORDER BY id desc) AS position")
+
+	var rates []entity.Rate
+	err := r.db.WithContext(spanContext).Table("(?) AS ranked", ranked).
+		Where(`position <= ?`, limit).Order("base_code, quote_code, id desc").Find(&rates).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "rateRepository.GetRecent.DbError")
+	}
+
+	return rates, nil
+}
+
+// Export walks the rates created in [from, to) in id order with a database cursor, so the result is never
+// held in memory. Zero times leave the range open, an error of fn stops the walk.
+func (r rateRepository) Export(ctx context.Context, pairs []entity.Pair, from time.Time, to time.Time, fn func(entity.Rate) error) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRepository.Export")
+	defer span.Finish()
+
+	query := withPairs(withVisibleTenants(r.db.WithContext(spanContext)).Model(&entity.Rate{}), pairs)
+	rows, err := withCreatedBetween(query, from, to).Order("id asc").Rows()
+	if err != nil {
+		return errors.Wrap(err, "rateRepository.Export.DbError")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rate := entity.Rate{}
+		if err = r.db.ScanRows(rows, &rate); err != nil {
+			return errors.Wrap(err, "rateRepository.Export.ScanRows")
+		}
+		if err = fn(rate); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "rateRepository.Export.Rows")
+}
+
+// withVisibleTenants limits the query to the rates of the tenant of the request and the global ones. A tenant
+// id sorts after the empty global one, so "tenant_id desc" puts the rates of the tenant first.
+func withVisibleTenants(db *gorm.DB) *gorm.DB {
+	return db.Where(`tenant_id IN ?`, tenant.Visible(tenant.FromContext(db.Statement.Context)))
+}
+
+func withPairs(db *gorm.DB, pairs []entity.Pair) *gorm.DB {
+	if len(pairs) == 0 {
+		return db
+	}
+
+	conditions := make([]string, 0, len(pairs))
+	args := make([]interface{}, 0, len(pairs)*2)
+	for _, pair := range pairs {
+		conditions = append(conditions, "(base_code = ? AND quote_code = ?)")
+		args = append(args, pair.BaseCode, pair.QuoteCode)
+	}
+
+	return db.Where(strings.Join(conditions, " OR "), args...)
+}
+
+func withCreatedBetween(db *gorm.DB, from time.Time, to time.Time) *gorm.DB {
+	if !from.IsZero() {
+		db = db.Where(`created_at >= ?`, from)
+	}
+	if !to.IsZero() {
+		db = db.Where(`created_at < ?`, to)
+	}
+
+	return db
+}
+
+func NewRateRepository(db *gorm.DB) RateRepository {
+	return &rateRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"time"
+)
+
+const LatestRateCacheTtl = 86400
+
+type RateRedisRepository interface {
+	GetLatest(ctx context.Context, pairs []string) ([]*rate.RateResponse, error)
+	SetLatest(ctx context.Context, rates []*rate.RateResponse) error
+}
+
+type rateRedisRepository struct {
+	redisClient redis.UniversalClient
+}
+
+// GetLatest returns the cached rates in the order of pairs, with nil for every pair that is not cached.
+// The keys are read through a pipeline instead of MGET since they may live in different cluster slots.
+// Requests of a tenant only read the rates of the tenant, its pairs without own rates are misses.
+func (r rateRedisRepository) GetLatest(ctx context.Context, pairs []string) ([]*rate.RateResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRedisRepository.GetLatest")
+	defer span.Finish()
+
+	pipe := r.redisClient.Pipeline()
+	commands := make([]*redis.StringCmd, 0, len(pairs))
+	for _, pair := range pairs {
+		commands = append(commands, pipe.Get(spanContext, LatestRateCacheKey(tenant.FromContext(spanContext), pair)))
+	}
+
+	if _, err := pipe.Exec(spanContext); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, errors.Wrap(err, "rateRedisRepository.GetLatest.Pipeline.Exec")
+	}
+
+	rates := make([]*rate.RateResponse, len(commands))
+	for i, command := range commands {
+		rateByte, err := command.Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "rateRedisRepository.GetLatest.RedisClient.Get")
+		}
+
+		rates[i] = &rate.RateResponse{}
+		if err = json.Unmarshal(rateByte, rates[i]); err != nil {
+			return nil, errors.Wrap(err, "rateRedisRepository.GetLatest.Json.Unmarshal")
+		}
+	}
+
+	return rates, nil
+}
+
+func (r rateRedisRepository) SetLatest(ctx context.Context, rates []*rate.RateResponse) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRedisRepository.SetLatest")
+	defer span.Finish()
+
+	pipe := r.redisClient.Pipeline()
+	for _, latest := range rates {
+		rateByte, err := json.Marshal(latest)
+		if err != nil {
+			return errors.Wrap(err, "rateRedisRepository.SetLatest.Json.Marshal")
+		}
+		pipe.Set(spanContext, LatestRateCacheKey(latest.TenantID, latest.Pair), rateByte, time.Second * LatestRateCacheTtl)
+	}
+
+	if _, err := pipe.Exec(spanContext); err != nil {
+		return errors.Wrap(err, "rateRedisRepository.SetLatest.Pipeline.Exec")
+	}
+
+	return nil
+}
+
+// LatestRateCacheKey is the key of the latest rate a tenant published for the pair, the keys of tenants are
+// namespaced by their id.
+func LatestRateCacheKey(tenantId string, pair string) string {
+	if tenantId == tenant.Global {
+		return "rate:latest:" + pair
+	}
+
+	return "tenant:" + tenantId + ":rate:latest:" + pair
+}
+
+func NewRateRedisRepository(redisClient redis.UniversalClient) RateRedisRepository {
+	return &rateRedisRepository{
+		redisClient: redisClient,
+	}
+}
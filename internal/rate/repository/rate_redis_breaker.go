@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sony/gobreaker"
+)
+
+type rateRedisBreakerRepository struct {
+	next RateRedisRepository
+	breaker *gobreaker.CircuitBreaker
+}
+
+func (r rateRedisBreakerRepository) GetLatest(ctx context.Context, pairs []string) ([]*rate.RateResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRedisBreakerRepository.GetLatest")
+	defer span.Finish()
+
+	result, err := breaker.Execute(r.breaker, "rateRedisBreakerRepository.GetLatest", func() (interface{}, error) {
+		return r.next.GetLatest(spanContext, pairs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*rate.RateResponse), nil
+}
+
+func (r rateRedisBreakerRepository) SetLatest(ctx context.Context, rates []*rate.RateResponse) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateRedisBreakerRepository.SetLatest")
+	defer span.Finish()
+
+	_, err := breaker.Execute(r.breaker, "rateRedisBreakerRepository.SetLatest", func() (interface{}, error) {
+		return nil, r.next.SetLatest(spanContext, rates)
+	})
+
+	return err
+}
+
+func NewRateRedisBreakerRepository(next RateRedisRepository, breaker *gobreaker.CircuitBreaker) RateRedisRepository {
+	return &rateRedisBreakerRepository{
+		next: next,
+		breaker: breaker,
+	}
+}
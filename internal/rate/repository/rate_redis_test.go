@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"testing"
+)
+
+func TestLatestRatesAreCachedPerTenant(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() {
+		redisClient.Close()
+	})
+	rates := NewRateRedisRepository(redisClient)
+
+	err := rates.SetLatest(context.Background(), []*rate.RateResponse{
+		{ID: 7, Pair: "USD/TRY", Value: 32.1},
+		{ID: 8, Pair: "USD/TRY", Value: 32.5, TenantID: "retail"},
+		{ID: 9, Pair: "EUR/TRY", Value: 35},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists("rate:latest:USD/TRY") || !mr.Exists("tenant:retail:rate:latest:USD/TRY") {
+		t.Fatalf("keys = %v", mr.Keys())
+	}
+
+	tests := []struct {
+		name string
+		tenant string
+		ids []int64
+	}{
+		{name: "global", tenant: tenant.Global, ids: []int64{7, 9}},
+		// pairs the tenant does not publish are misses, the database decides which rate the tenant sees
+		{name: "tenant", tenant: "retail", ids: []int64{8, 0}},
+		{name: "other tenant", tenant: "wholesale", ids: []int64{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latest, err := rates.GetLatest(tenant.NewContext(context.Background(), tt.tenant), []string{"USD/TRY", "EUR/TRY"})
+			if err != nil || len(latest) != 2 {
+				t.Fatalf("GetLatest = %v, %v", latest, err)
+			}
+			for i, want := range tt.ids {
+				var got int64
+				if latest[i] != nil {
+					got = latest[i].ID
+				}
+				if got != want {
+					t.Errorf("rate %d = %d, want %d", i, got, want)
+				}
+			}
+		})
+	}
+}
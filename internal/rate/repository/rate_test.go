@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/testutil"
+	"testing"
+	"time"
+)
+
+// The global tenant is the empty id, ordering by tenant_id desc puts the rates of the tenant before the global
+// ones, so DISTINCT ON keeps the rate of the tenant for the pairs it publishes.
+func TestGetLatestPrefersTheRatesOfTheTenant(t *testing.T) {
+	db, mock := testutil.NewMockDB(t)
+	retail := tenant.NewContext(context.Background(), "retail")
+
+	mock.ExpectQuery(`SELECT DISTINCT ON \(base_code, quote_code\) \* FROM "rates" WHERE tenant_id IN \(\$1,\$2\) AND \(\(base_code = \$3 AND quote_code = \$4\)\) ORDER BY base_code, quote_code, tenant_id desc, id desc`).
+		WithArgs(tenant.Global, "retail", "USD", "TRY").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "base_code", "quote_code", "value", "tenant_id"}).AddRow(8, "USD", "TRY", 32.5, "retail"))
+	// platform callers only see the global rates
+	mock.ExpectQuery(`SELECT DISTINCT ON \(base_code, quote_code\) \* FROM "rates" WHERE tenant_id IN \(\$1\) ORDER BY base_code, quote_code, tenant_id desc, id desc`).
+		WithArgs(tenant.Global).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "base_code", "quote_code", "value", "tenant_id"}).AddRow(7, "USD", "TRY", 32.1, tenant.Global))
+
+	rates := NewRateRepository(db)
+	latest, err := rates.GetLatest(retail, []entity.Pair{{BaseCode: "USD", QuoteCode: "TRY"}})
+	if err != nil || len(latest) != 1 || latest[0].TenantID != "retail" {
+		t.Errorf("GetLatest = %+v, %v", latest, err)
+	}
+	if latest, err = rates.GetLatest(context.Background(), nil); err != nil || len(latest) != 1 || latest[0].TenantID != tenant.Global {
+		t.Errorf("GetLatest = %+v, %v", latest, err)
+	}
+}
+
+func TestGetAsOfPrefersTheRatesOfTheTenant(t *testing.T) {
+	db, mock := testutil.NewMockDB(t)
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT \* FROM "rates" WHERE tenant_id IN \(\$1,\$2\) AND \(\(base_code = \$3 AND quote_code = \$4\)\) AND created_at <= \$5 ORDER BY tenant_id desc, created_at desc, id desc`).
+		WithArgs(tenant.Global, "retail", "USD", "TRY", at).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "base_code", "quote_code", "tenant_id"}).AddRow(8, "USD", "TRY", "retail"))
+
+	rate, err := NewRateRepository(db).GetAsOf(tenant.NewContext(context.Background(), "retail"), entity.Pair{BaseCode: "USD", QuoteCode: "TRY"}, at)
+	if err != nil || rate.ID != 8 {
+		t.Errorf("GetAsOf = %+v, %v", rate, err)
+	}
+}
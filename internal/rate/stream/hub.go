@@ -0,0 +1,183 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"sync"
+)
+
+const (
+	AllPairs = "*"
+
+	defaultChannel = "rates:updates"
+	defaultReplayBufferSize = 1000
+	defaultClientBufferSize = 64
+)
+
+// Hub fans rate updates out to the streaming clients of this replica. Updates travel through
+// redis pub/sub, so a rate ingested on any replica reaches the clients of all replicas.
+type Hub interface {
+	Run(ctx context.Context)
+	Publish(ctx context.Context, rate *rate.RateResponse) error
+	Subscribe(tenantId string, pairs ...string) Subscription
+	Replay(afterId int64, subscription Subscription) []*rate.RateResponse
+}
+
+type hub struct {
+	cfg *config.Config
+	redisClient redis.UniversalClient
+	logger logger.Logger
+
+	mu sync.RWMutex
+	subscribers map[*subscription]struct{}
+	replay []*rate.RateResponse
+}
+
+func (h *hub) Run(ctx context.Context) {
+	pubSub := h.redisClient.Subscribe(ctx, h.channel())
+	defer pubSub.Close()
+
+	h.logger.Infof("Rate stream hub subscribed, Channel: %s", h.channel())
+	messages := pubSub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			update := &rate.RateResponse{}
+			if err := json.Unmarshal([]byte(message.Payload), update); err != nil {
+				h.logger.Errorf("hub.Run.Json.Unmarshal: %s", err)
+				continue
+			}
+			h.broadcast(update)
+		}
+	}
+}
+
+func (h *hub) Publish(ctx context.Context, update *rate.RateResponse) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "hub.Publish")
+	defer span.Finish()
+
+	rateByte, err := json.Marshal(update)
+	if err != nil {
+		return errors.Wrap(err, "hub.Publish.Json.Marshal")
+	}
+
+	if err = h.redisClient.Publish(spanContext, h.channel(), rateByte).Err(); err != nil {
+		return errors.Wrap(err, "hub.Publish.RedisClient.Publish")
+	}
+
+	return nil
+}
+
+// Subscribe gets the updates of the pairs the tenant sees, its own rates and the global ones.
+func (h *hub) Subscribe(tenantId string, pairs ...string) Subscription {
+	bufferSize := h.cfg.Stream.ClientBufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultClientBufferSize
+	}
+
+	s := &subscription{
+		hub: h,
+		tenant: tenantId,
+		pairs: make(map[string]struct{}),
+		updates: make(chan *rate.RateResponse, bufferSize),
+		done: make(chan struct{}),
+	}
+	s.Add(pairs...)
+
+	h.mu.Lock()
+	h.subscribers[s] = struct{}{}
+	h.mu.Unlock()
+
+	return s
+}
+
+// Replay returns the buffered updates newer than afterId that match the subscription, oldest first.
+func (h *hub) Replay(afterId int64, s Subscription) []*rate.RateResponse {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var updates []*rate.RateResponse
+	for _, update := range h.replay {
+		if update.ID > afterId && sees(s, update) {
+			updates = append(updates, update)
+		}
+	}
+
+	return updates
+}
+
+// broadcast never blocks on a client. A client whose buffer is full is disconnected, it can
+// reconnect and catch up from the replay buffer instead of slowing everyone else down.
+func (h *hub) broadcast(update *rate.RateResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.replay = append(h.replay, update)
+	if replaySize := h.replayBufferSize(); len(h.replay) > replaySize {
+		h.replay = h.replay[len(h.replay)-replaySize:]
+	}
+
+	for s := range h.subscribers {
+		if !sees(s, update) {
+			continue
+		}
+
+		select {
+		case s.updates <- update:
+		default:
+			delete(h.subscribers, s)
+			s.closeOnce.Do(func() {
+				close(s.done)
+			})
+		}
+	}
+}
+
+func sees(s Subscription, update *rate.RateResponse) bool {
+	return (update.TenantID == tenant.Global || update.TenantID == s.Tenant()) && s.Matches(update.Pair)
+}
+
+func (h *hub) remove(s *subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subscribers, s)
+}
+
+func (h *hub) channel() string {
+	if h.cfg.Stream.Channel == "" {
+		return defaultChannel
+	}
+
+	return h.cfg.Stream.Channel
+}
+
+func (h *hub) replayBufferSize() int {
+	if h.cfg.Stream.ReplayBufferSize <= 0 {
+		return defaultReplayBufferSize
+	}
+
+	return h.cfg.Stream.ReplayBufferSize
+}
+
+func NewHub(cfg *config.Config, redisClient redis.UniversalClient, logger logger.Logger) Hub {
+	return &hub{
+		cfg: cfg,
+		redisClient: redisClient,
+		logger: logger,
+		subscribers: make(map[*subscription]struct{}),
+	}
+}
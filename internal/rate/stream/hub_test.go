@@ -0,0 +1,173 @@
+package stream
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"testing"
+	"time"
+)
+
+// newTestHub runs a hub over miniredis and returns once it is subscribed to the channel.
+func newTestHub(t *testing.T, streamConfig config.StreamConfig) Hub {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	cfg := &config.Config{Stream: streamConfig}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	h := NewHub(cfg, redisClient, l)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		h.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	deadline := time.Now().Add(time.Second * 5)
+	for mr.PubSubNumSub(defaultChannel)[defaultChannel] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("hub did not subscribe")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	return h
+}
+
+func publish(t *testing.T, h Hub, updates ...*rate.RateResponse) {
+	t.Helper()
+	for _, update := range updates {
+		if err := h.Publish(context.Background(), update); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+}
+
+// waitForReplay waits until the hub received the update of the tenant with the given id, which makes it visible to Replay.
+func waitForReplay(t *testing.T, h Hub, tenantId string, id int64) {
+	t.Helper()
+	all := h.Subscribe(tenantId, AllPairs)
+	defer all.Close()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		for _, update := range h.Replay(id-1, all) {
+			if update.ID == id {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("update %d did not reach the hub", id)
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
+
+func next(t *testing.T, s Subscription) *rate.RateResponse {
+	t.Helper()
+	select {
+	case update := <-s.Updates():
+		return update
+	case <-time.After(time.Second * 5):
+		t.Fatal("no update received")
+		return nil
+	}
+}
+
+func TestHubDeliversWhatTheSubscriberSees(t *testing.T) {
+	h := newTestHub(t, config.StreamConfig{})
+	usdTry := h.Subscribe("acme", "USD/TRY")
+	defer usdTry.Close()
+	all := h.Subscribe("acme", AllPairs)
+	defer all.Close()
+
+	publish(t, h,
+		&rate.RateResponse{ID: 1, Pair: "USD/TRY", TenantID: "acme"},
+		&rate.RateResponse{ID: 2, Pair: "EUR/TRY", TenantID: "acme"},
+		// rates of another tenant are never seen, the global ones by everyone
+		&rate.RateResponse{ID: 3, Pair: "USD/TRY", TenantID: "globex"},
+		&rate.RateResponse{ID: 4, Pair: "USD/TRY", TenantID: tenant.Global},
+	)
+
+	for _, want := range []int64{1, 4} {
+		if update := next(t, usdTry); update.ID != want {
+			t.Errorf("USD/TRY subscriber got %d, want %d", update.ID, want)
+		}
+	}
+	for _, want := range []int64{1, 2, 4} {
+		if update := next(t, all); update.ID != want {
+			t.Errorf("all pairs subscriber got %d, want %d", update.ID, want)
+		}
+	}
+
+	usdTry.Remove("USD/TRY")
+	usdTry.Add("EUR/TRY")
+	if pairs := usdTry.Pairs(); len(pairs) != 1 || pairs[0] != "EUR/TRY" {
+		t.Errorf("pairs = %v, want [EUR/TRY]", pairs)
+	}
+	publish(t, h, &rate.RateResponse{ID: 5, Pair: "USD/TRY", TenantID: "acme"}, &rate.RateResponse{ID: 6, Pair: "EUR/TRY", TenantID: "acme"})
+	if update := next(t, usdTry); update.ID != 6 {
+		t.Errorf("after changing pairs got %d, want 6", update.ID)
+	}
+}
+
+func TestHubReplaysRecentUpdates(t *testing.T) {
+	h := newTestHub(t, config.StreamConfig{ReplayBufferSize: 3})
+	for id := int64(1); id <= 5; id++ {
+		publish(t, h, &rate.RateResponse{ID: id, Pair: "USD/TRY", TenantID: "acme"})
+	}
+	publish(t, h, &rate.RateResponse{ID: 6, Pair: "EUR/TRY", TenantID: "acme"})
+	waitForReplay(t, h, "acme", 6)
+
+	s := h.Subscribe("acme", "USD/TRY")
+	defer s.Close()
+	// the buffer keeps the last 3 updates, 4, 5 and 6, and 6 is another pair
+	replayed := h.Replay(0, s)
+	if len(replayed) != 2 || replayed[0].ID != 4 || replayed[1].ID != 5 {
+		t.Errorf("replay = %v, want updates 4 and 5", replayed)
+	}
+	if replayed = h.Replay(4, s); len(replayed) != 1 || replayed[0].ID != 5 {
+		t.Errorf("replay after 4 = %v, want update 5", replayed)
+	}
+	if replayed = h.Replay(0, h.Subscribe("globex", AllPairs)); len(replayed) != 0 {
+		t.Errorf("another tenant replayed %d updates", len(replayed))
+	}
+}
+
+func TestHubDisconnectsSlowSubscribers(t *testing.T) {
+	h := newTestHub(t, config.StreamConfig{ClientBufferSize: 1})
+	slow := h.Subscribe("acme", AllPairs)
+	closed := h.Subscribe("acme", AllPairs)
+	closed.Close()
+
+	publish(t, h, &rate.RateResponse{ID: 1, Pair: "USD/TRY", TenantID: "acme"}, &rate.RateResponse{ID: 2, Pair: "USD/TRY", TenantID: "acme"})
+
+	select {
+	case <-slow.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("a subscriber with a full buffer was not disconnected")
+	}
+	if update := next(t, slow); update.ID != 1 {
+		t.Errorf("buffered update = %d, want 1", update.ID)
+	}
+	select {
+	case update := <-closed.Updates():
+		t.Errorf("a closed subscription got update %d", update.ID)
+	default:
+	}
+	// closing after the hub gave up on the subscriber is safe
+	slow.Close()
+}
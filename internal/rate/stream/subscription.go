@@ -0,0 +1,93 @@
+package stream
+
+import (
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"sort"
+	"sync"
+)
+
+type Subscription interface {
+	// Updates is never closed, Done is closed once the subscription ends.
+	Updates() <-chan *rate.RateResponse
+	Done() <-chan struct{}
+	Add(pairs ...string)
+	Remove(pairs ...string)
+	Pairs() []string
+	Matches(pair string) bool
+	// Tenant is the tenant of the subscriber, which gets the updates of its own and the global rates.
+	Tenant() string
+	Close()
+}
+
+type subscription struct {
+	hub *hub
+	updates chan *rate.RateResponse
+	done chan struct{}
+	closeOnce sync.Once
+	tenant string
+
+	mu sync.RWMutex
+	pairs map[string]struct{}
+}
+
+func (s *subscription) Updates() <-chan *rate.RateResponse {
+	return s.updates
+}
+
+func (s *subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *subscription) Add(pairs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, pair := range pairs {
+		s.pairs[pair] = struct{}{}
+	}
+}
+
+func (s *subscription) Remove(pairs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, pair := range pairs {
+		delete(s.pairs, pair)
+	}
+}
+
+func (s *subscription) Pairs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pairs := make([]string, 0, len(s.pairs))
+	for pair := range s.pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	return pairs
+}
+
+func (s *subscription) Matches(pair string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.pairs[AllPairs]; ok {
+		return true
+	}
+	_, ok := s.pairs[pair]
+
+	return ok
+}
+
+func (s *subscription) Tenant() string {
+	return s.tenant
+}
+
+func (s *subscription) Close() {
+	s.hub.remove(s)
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
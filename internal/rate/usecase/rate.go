@@ -0,0 +1,268 @@
+package usecase
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/rate"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/rate"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/mapping"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/repository"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/stream"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strings"
+)
+
+// MaxHistoryLimit is the most rates GetHistory returns per pair.
+const MaxHistoryLimit = 500
+
+type RateUseCase interface {
+	Create(ctx context.Context, request request.RateCreateRequest) (*response.RateResponse, error)
+	GetLatest(ctx context.Context, pairs string) ([]*response.RateResponse, error)
+	GetAll(ctx context.Context, request *request.RatePageableRequest) (response.RateListResponse, error)
+	GetHistory(ctx context.Context, pairs []string, limit int) (map[string][]*response.RateResponse, error)
+	Convert(ctx context.Context, request request.RateConvertRequest) (*response.RateConversionResponse, error)
+	Export(ctx context.Context, request request.RateExportRequest, fn func(*response.RateResponse) error) error
+}
+
+type rateUseCase struct {
+	cfg *config.Config
+	rateRepository repository.RateRepository
+	rateRedisRepository repository.RateRedisRepository
+	hub stream.Hub
+	logger logger.Logger
+}
+
+func (r rateUseCase) Create(ctx context.Context, request request.RateCreateRequest) (*response.RateResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateUseCase.Create")
+	defer span.Finish()
+
+	if err := util.ValidateStruct(&request); err != nil {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "rateUseCase.Create.ValidateStruct"))
+	}
+
+	newRate := mapping.CreateMapEntity(&request)
+	newRate.TenantID = tenant.FromContext(spanContext)
+
+	rate, err := r.rateRepository.Create(spanContext, newRate)
+	if err != nil {
+		return nil, err
+	}
+
+	mappedResponse := mapping.MapDto(rate)
+
+	if err = r.rateRedisRepository.SetLatest(spanContext, []*response.RateResponse{mappedResponse}); err != nil {
+		breaker.LogError(r.logger, "rateUseCase.Create.SetCache", err)
+	}
+
+	if err = r.hub.Publish(spanContext, mappedResponse); err != nil {
+		r.logger.Errorf("rateUseCase.Create.Publish: %s", err)
+	}
+
+	return mappedResponse, nil
+}
+
+// GetLatest reads the cache first and only goes to the database for the pairs it misses.
+// Without pairs the latest rate of every known pair is returned.
+func (r rateUseCase) GetLatest(ctx context.Context, pairs string) ([]*response.RateResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateUseCase.GetLatest")
+	defer span.Finish()
+
+	parsedPairs, err := entity.ParsePairs(pairs)
+	if err != nil {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "rateUseCase.GetLatest.ParsePairs"))
+	}
+
+	if len(parsedPairs) == 0 {
+		rates, err := r.rateRepository.GetLatest(spanContext, nil)
+		if err != nil {
+			return nil, err
+		}
+		return mapping.MapListDto(rates), nil
+	}
+
+	pairNames := make([]string, 0, len(parsedPairs))
+	for _, pair := range parsedPairs {
+		pairNames = append(pairNames, pair.String())
+	}
+
+	cached, err := r.rateRedisRepository.GetLatest(spanContext, pairNames)
+	if err != nil {
+		breaker.LogError(r.logger, "rateUseCase.GetLatest.Redis", err)
+		cached = make([]*response.RateResponse, len(pairNames))
+	}
+
+	var missing []entity.Pair
+	latest := make([]*response.RateResponse, 0, len(parsedPairs))
+	for i, pair := range parsedPairs {
+		if cached[i] == nil {
+			missing = append(missing, pair)
+			continue
+		}
+		latest = append(latest, cached[i])
+	}
+
+	if len(missing) == 0 {
+		return latest, nil
+	}
+
+	rates, err := r.rateRepository.GetLatest(spanContext, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := mapping.MapListDto(rates)
+	if len(loaded) > 0 {
+		if err = r.rateRedisRepository.SetLatest(spanContext, loaded); err != nil {
+			breaker.LogError(r.logger, "rateUseCase.GetLatest.SetCache", err)
+		}
+	}
+
+	return append(latest, loaded...), nil
+}
+
+func (r rateUseCase) GetAll(ctx context.Context, pageableRequest *request.RatePageableRequest) (response.RateListResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateUseCase.GetAll")
+	defer span.Finish()
+
+	pairs, err := entity.ParsePairs(pageableRequest.Pair)
+	if err != nil {
+		return response.RateListResponse{}, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "rateUseCase.GetAll.ParsePairs"))
+	}
+
+	pagination := util.Pagination{
+		Page: pageableRequest.Page,
+		Limit: pageableRequest.Size,
+	}
+
+	totalCount, err := r.rateRepository.GetCount(spanContext, pairs)
+	if err != nil {
+		return response.RateListResponse{}, err
+	}
+
+	rates, err := r.rateRepository.GetAll(spanContext, pairs, pagination)
+	if err != nil {
+		return response.RateListResponse{}, err
+	}
+
+	return response.RateListResponse{
+		TotalCount: totalCount,
+		TotalPages: util.GetTotalPages(totalCount, pagination.GetLimit()),
+		Page: pagination.GetPage(),
+		Limit: pagination.GetLimit(),
+		Rates: mapping.MapListDto(rates),
+	}, nil
+}
+
+// GetHistory returns up to limit newest rates of every pair, keyed by pair name.
+func (r rateUseCase) GetHistory(ctx context.Context, pairs []string, limit int) (map[string][]*response.RateResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateUseCase.GetHistory")
+	defer span.Finish()
+
+	if len(pairs) == 0 {
+		return map[string][]*response.RateResponse{}, nil
+	}
+	if limit <= 0 || limit > MaxHistoryLimit {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.Errorf("rateUseCase.GetHistory: limit must be between 1 and %d", MaxHistoryLimit))
+	}
+
+	parsedPairs, err := entity.ParsePairs(strings.Join(pairs, ","))
+	if err != nil {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "rateUseCase.GetHistory.ParsePairs"))
+	}
+
+	rates, err := r.rateRepository.GetRecent(spanContext, parsedPairs, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(map[string][]*response.RateResponse, len(parsedPairs))
+	for _, rate := range rates {
+		history[rate.Pair()] = append(history[rate.Pair()], mapping.MapDto(rate))
+	}
+
+	return history, nil
+}
+
+// Convert prices the amount with the latest rate of the pair, or with the inverse of the opposite pair
+// when only that one is quoted.
+func (r rateUseCase) Convert(ctx context.Context, request request.RateConvertRequest) (*response.RateConversionResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateUseCase.Convert")
+	defer span.Finish()
+
+	if err := util.ValidateStruct(&request); err != nil {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "rateUseCase.Convert.ValidateStruct"))
+	}
+
+	from, to := strings.ToUpper(request.From), strings.ToUpper(request.To)
+	if from == to {
+		return &response.RateConversionResponse{From: from, To: to, Amount: request.Amount, Rate: 1, Result: request.Amount}, nil
+	}
+
+	direct, inverse := entity.FormatPair(from, to), entity.FormatPair(to, from)
+	latest, err := r.GetLatest(spanContext, direct+","+inverse)
+	if err != nil {
+		return nil, err
+	}
+
+	var quoted *response.RateResponse
+	for _, rate := range latest {
+		if rate.Pair == direct || (quoted == nil && rate.Pair == inverse) {
+			quoted = rate
+		}
+	}
+	if quoted == nil {
+		return nil, util.NewHttpResponse(http.StatusNotFound, util.NotFound.Error(), errors.Errorf("rateUseCase.Convert: no rate for %s", direct))
+	}
+
+	conversion := &response.RateConversionResponse{
+		From: from,
+		To: to,
+		Amount: request.Amount,
+		Rate: quoted.Value,
+		Inverted: quoted.Pair == inverse,
+		RateID: quoted.ID,
+		AsOf: quoted.CreatedAt,
+	}
+	if conversion.Inverted {
+		conversion.Rate = 1 / quoted.Value
+	}
+	conversion.Result = request.Amount * conversion.Rate
+
+	return conversion, nil
+}
+
+// Export passes the rates of the request to fn one by one in id order, straight from the database cursor.
+func (r rateUseCase) Export(ctx context.Context, exportRequest request.RateExportRequest, fn func(*response.RateResponse) error) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rateUseCase.Export")
+	defer span.Finish()
+
+	if err := util.ValidateStruct(&exportRequest); err != nil {
+		return util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "rateUseCase.Export.ValidateStruct"))
+	}
+
+	pairs, err := entity.ParsePairs(exportRequest.Pairs)
+	if err != nil {
+		return util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "rateUseCase.Export.ParsePairs"))
+	}
+
+	return r.rateRepository.Export(spanContext, pairs, exportRequest.From, exportRequest.To, func(rate entity.Rate) error {
+		return fn(mapping.MapDto(rate))
+	})
+}
+
+func NewRateUseCase(cfg *config.Config, rateRepository repository.RateRepository, rateRedisRepository repository.RateRedisRepository, hub stream.Hub, logger logger.Logger) RateUseCase {
+	return &rateUseCase{
+		cfg: cfg,
+		rateRepository: rateRepository,
+		rateRedisRepository: rateRedisRepository,
+		hub: hub,
+		logger: logger,
+	}
+}
@@ -0,0 +1,91 @@
+package entity
+
+import "time"
+
+// AnySubject binds a role to every authenticated principal.
+const AnySubject = "*"
+
+type Permission struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	Name string `json:"name"`
+	Description string `json:"description"`
+}
+
+type Role struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name string `json:"name"`
+	Description string `json:"description"`
+	// RequiresApproval holds the changes made with the permissions of the role for approval
+	RequiresApproval bool `json:"requires_approval"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+}
+
+// RoleBinding grants a role to the principal with the subject, a jwt sub or api-key:<id>.
+type RoleBinding struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Subject string `json:"subject"`
+	RoleID int64 `json:"role_id"`
+}
+
+// Grant is what a subject is allowed to do, the roles bound to it and their permissions. ApprovalPermissions
+// are the permissions the subject only holds through roles requiring approval.
+type Grant struct {
+	Subject string `json:"subject"`
+	Roles []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	ApprovalPermissions []string `json:"approval_permissions"`
+}
+
+func (g Grant) HasPermission(permission string) bool {
+	for _, p := range g.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g Grant) RequiresApproval(permission string) bool {
+	for _, p := range g.ApprovalPermissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewGrant merges the permissions of the roles, each permission is listed once. A permission one role holds
+// without approval does not need approval, even if another role holds it with approval.
+func NewGrant(subject string, roles []Role) Grant {
+	grant := Grant{
+		Subject: subject,
+		Roles: make([]string, 0, len(roles)),
+		Permissions: make([]string, 0),
+		ApprovalPermissions: make([]string, 0),
+	}
+	direct := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		grant.Roles = append(grant.Roles, role.Name)
+		for _, permission := range role.Permissions {
+			if !role.RequiresApproval {
+				direct[permission.Name] = true
+			}
+			if !seen[permission.Name] {
+				seen[permission.Name] = true
+				grant.Permissions = append(grant.Permissions, permission.Name)
+			}
+		}
+	}
+	for _, permission := range grant.Permissions {
+		if !direct[permission] {
+			grant.ApprovalPermissions = append(grant.ApprovalPermissions, permission)
+		}
+	}
+
+	return grant
+}
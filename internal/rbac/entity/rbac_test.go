@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func permissions(names ...string) []Permission {
+	list := make([]Permission, 0, len(names))
+	for _, name := range names {
+		list = append(list, Permission{Name: name})
+	}
+
+	return list
+}
+
+func TestNewGrant(t *testing.T) {
+	viewer := Role{Name: "viewer", Permissions: permissions("currency.read")}
+	editor := Role{Name: "editor", Permissions: permissions("currency.read", "currency.update")}
+	maker := Role{Name: "maker", RequiresApproval: true, Permissions: permissions("currency.create", "currency.update")}
+	tests := []struct {
+		name string
+		roles []Role
+		want Grant
+	}{
+		{name: "no roles", want: Grant{Subject: "alice", Roles: []string{}, Permissions: []string{}, ApprovalPermissions: []string{}}},
+		{name: "permissions are listed once", roles: []Role{viewer, editor}, want: Grant{Subject: "alice", Roles: []string{"viewer", "editor"}, Permissions: []string{"currency.read", "currency.update"}, ApprovalPermissions: []string{}}},
+		{name: "approval roles", roles: []Role{viewer, maker}, want: Grant{Subject: "alice", Roles: []string{"viewer", "maker"}, Permissions: []string{"currency.read", "currency.create", "currency.update"}, ApprovalPermissions: []string{"currency.create", "currency.update"}}},
+		{name: "a direct role lifts the approval", roles: []Role{maker, editor}, want: Grant{Subject: "alice", Roles: []string{"maker", "editor"}, Permissions: []string{"currency.create", "currency.update", "currency.read"}, ApprovalPermissions: []string{"currency.create"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if grant := NewGrant("alice", tt.roles); !reflect.DeepEqual(grant, tt.want) {
+				t.Errorf("NewGrant = %+v, want %+v", grant, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrantDecisions(t *testing.T) {
+	grant := Grant{Permissions: []string{"currency.read", "currency.create"}, ApprovalPermissions: []string{"currency.create"}}
+	tests := []struct {
+		permission string
+		allowed bool
+		approval bool
+	}{
+		{permission: "currency.read", allowed: true},
+		{permission: "currency.create", allowed: true, approval: true},
+		{permission: "currency.delete"},
+	}
+
+	for _, tt := range tests {
+		if grant.HasPermission(tt.permission) != tt.allowed || grant.RequiresApproval(tt.permission) != tt.approval {
+			t.Errorf("%s: allowed %t, approval %t, want %t and %t", tt.permission, grant.HasPermission(tt.permission), grant.RequiresApproval(tt.permission), tt.allowed, tt.approval)
+		}
+	}
+}
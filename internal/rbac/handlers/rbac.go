@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/rbac/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+)
+
+type RbacHandlers interface {
+	GetMyPermissions() echo.HandlerFunc
+}
+
+type rbacHandlers struct {
+	cfg *config.Config
+	rbacUseCase usecase.RbacUseCase
+	logger logger.Logger
+}
+
+// GetMyPermissions godoc
+// @Summary Get my permissions
+// @Description Roles and permissions of the caller, for UIs to show only what the caller may do
+// @Tags Rbac
+// @Accept json
+// @Produce json
+// @Success 200 {object} rbac.PermissionsResponse
+// @Failure 401 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/me/permissions [get]
+func (r rbacHandlers) GetMyPermissions() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "rbacHandler.GetMyPermissions")
+		defer span.Finish()
+
+		permissions, err := r.rbacUseCase.GetPermissions(ctx)
+		if err != nil {
+			util.PrepareLogging(e, r.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, permissions)
+	}
+}
+
+func NewRbacHandler(cfg *config.Config, rbacUseCase usecase.RbacUseCase, logger logger.Logger) RbacHandlers {
+	return &rbacHandlers{
+		cfg: cfg,
+		rbacUseCase: rbacUseCase,
+		logger: logger,
+	}
+}
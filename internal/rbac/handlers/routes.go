@@ -0,0 +1,7 @@
+package handlers
+
+import "github.com/labstack/echo/v4"
+
+func MapRbacRoutes(meRouteGroup *echo.Group, r RbacHandlers) {
+	meRouteGroup.GET("/permissions", r.GetMyPermissions())
+}
@@ -0,0 +1,15 @@
+package mapping
+
+import (
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/rbac"
+	"github.com/sefikcan/kanbersky.ca/internal/rbac/entity"
+)
+
+func MapDto(g entity.Grant) *rbac.PermissionsResponse {
+	return &rbac.PermissionsResponse{
+		Subject: g.Subject,
+		Roles: g.Roles,
+		Permissions: g.Permissions,
+		ApprovalPermissions: g.ApprovalPermissions,
+	}
+}
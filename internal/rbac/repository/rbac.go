@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/rbac/entity"
+	"gorm.io/gorm"
+)
+
+type RbacRepository interface {
+	GetRolesBySubject(ctx context.Context, subject string) ([]entity.Role, error)
+}
+
+type rbacRepository struct {
+	db *gorm.DB
+}
+
+// GetRolesBySubject returns the roles bound to the subject or to every subject, with their permissions.
+func (r rbacRepository) GetRolesBySubject(ctx context.Context, subject string) ([]entity.Role, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rbacRepository.GetRolesBySubject")
+	defer span.Finish()
+
+	var roles []entity.Role
+	err := r.db.WithContext(spanContext).
+		Preload("Permissions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("permissions.name")
+		}).
+		Where(`id IN (?)`, r.db.Model(&entity.RoleBinding{}).Select("role_id").Where(`subject IN ?`, []string{subject, entity.AnySubject})).
+		Order("name").
+		Find(&roles).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "rbacRepository.GetRolesBySubject.DbError")
+	}
+
+	return roles, nil
+}
+
+func NewRbacRepository(db *gorm.DB) RbacRepository {
+	return &rbacRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/rbac/entity"
+	"time"
+)
+
+const grantCachePrefix = "rbac:grant:"
+
+type RbacRedisRepository interface {
+	// GetGrant returns redis.Nil on a cache miss
+	GetGrant(ctx context.Context, subject string) (*entity.Grant, error)
+	SetGrant(ctx context.Context, grant entity.Grant, ttl time.Duration) error
+}
+
+type rbacRedisRepository struct {
+	redisClient redis.UniversalClient
+}
+
+func (r rbacRedisRepository) GetGrant(ctx context.Context, subject string) (*entity.Grant, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rbacRedisRepository.GetGrant")
+	defer span.Finish()
+
+	grantByte, err := r.redisClient.Get(spanContext, GrantCacheKey(subject)).Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "rbacRedisRepository.GetGrant.RedisClient.Get")
+	}
+
+	grant := &entity.Grant{}
+	if err = json.Unmarshal(grantByte, grant); err != nil {
+		return nil, errors.Wrap(err, "rbacRedisRepository.GetGrant.Json.Unmarshal")
+	}
+
+	return grant, nil
+}
+
+func (r rbacRedisRepository) SetGrant(ctx context.Context, grant entity.Grant, ttl time.Duration) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rbacRedisRepository.SetGrant")
+	defer span.Finish()
+
+	grantByte, err := json.Marshal(grant)
+	if err != nil {
+		return errors.Wrap(err, "rbacRedisRepository.SetGrant.Json.Marshal")
+	}
+	if err = r.redisClient.Set(spanContext, GrantCacheKey(grant.Subject), grantByte, ttl).Err(); err != nil {
+		return errors.Wrap(err, "rbacRedisRepository.SetGrant.RedisClient.Set")
+	}
+
+	return nil
+}
+
+func GrantCacheKey(subject string) string {
+	return grantCachePrefix + subject
+}
+
+func NewRbacRedisRepository(redisClient redis.UniversalClient) RbacRedisRepository {
+	return &rbacRedisRepository{
+		redisClient: redisClient,
+	}
+}
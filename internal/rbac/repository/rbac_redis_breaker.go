@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/rbac/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sony/gobreaker"
+	"time"
+)
+
+type rbacRedisBreakerRepository struct {
+	next RbacRedisRepository
+	breaker *gobreaker.CircuitBreaker
+}
+
+func (r rbacRedisBreakerRepository) GetGrant(ctx context.Context, subject string) (*entity.Grant, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rbacRedisBreakerRepository.GetGrant")
+	defer span.Finish()
+
+	result, err := breaker.Execute(r.breaker, "rbacRedisBreakerRepository.GetGrant", func() (interface{}, error) {
+		return r.next.GetGrant(spanContext, subject)
+	}, redis.Nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*entity.Grant), nil
+}
+
+func (r rbacRedisBreakerRepository) SetGrant(ctx context.Context, grant entity.Grant, ttl time.Duration) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rbacRedisBreakerRepository.SetGrant")
+	defer span.Finish()
+
+	_, err := breaker.Execute(r.breaker, "rbacRedisBreakerRepository.SetGrant", func() (interface{}, error) {
+		return nil, r.next.SetGrant(spanContext, grant, ttl)
+	})
+
+	return err
+}
+
+func NewRbacRedisBreakerRepository(next RbacRedisRepository, breaker *gobreaker.CircuitBreaker) RbacRedisRepository {
+	return &rbacRedisBreakerRepository{
+		next: next,
+		breaker: breaker,
+	}
+}
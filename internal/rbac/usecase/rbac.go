@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/rbac"
+	"github.com/sefikcan/kanbersky.ca/internal/rbac/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/rbac/mapping"
+	"github.com/sefikcan/kanbersky.ca/internal/rbac/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"time"
+)
+
+const defaultCacheTtl = time.Minute
+
+// RbacUseCase resolves the roles and permissions bound to principals.
+type RbacUseCase interface {
+	auth.Authorizer
+	auth.ApprovalPolicy
+	GetPermissions(ctx context.Context) (*response.PermissionsResponse, error)
+}
+
+type rbacUseCase struct {
+	cfg *config.Config
+	rbacRepository repository.RbacRepository
+	rbacRedisRepository repository.RbacRedisRepository
+	logger logger.Logger
+}
+
+func (r rbacUseCase) HasPermission(ctx context.Context, principal auth.Principal, permission string) (bool, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rbacUseCase.HasPermission")
+	defer span.Finish()
+
+	grant, err := r.getGrant(spanContext, principal.Subject)
+	if err != nil {
+		return false, err
+	}
+
+	return grant.HasPermission(permission), nil
+}
+
+func (r rbacUseCase) RequiresApproval(ctx context.Context, principal auth.Principal, permission string) (bool, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rbacUseCase.RequiresApproval")
+	defer span.Finish()
+
+	grant, err := r.getGrant(spanContext, principal.Subject)
+	if err != nil {
+		return false, err
+	}
+
+	return grant.RequiresApproval(permission), nil
+}
+
+// GetPermissions returns the roles and permissions of the caller.
+func (r rbacUseCase) GetPermissions(ctx context.Context) (*response.PermissionsResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "rbacUseCase.GetPermissions")
+	defer span.Finish()
+
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, util.NewHttpResponse(http.StatusUnauthorized, "the request is not authenticated", nil)
+	}
+
+	grant, err := r.getGrant(spanContext, principal.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapDto(grant), nil
+}
+
+// getGrant serves grants from the cache, so role changes apply once the cached grant expires.
+func (r rbacUseCase) getGrant(ctx context.Context, subject string) (entity.Grant, error) {
+	cached, err := r.rbacRedisRepository.GetGrant(ctx, subject)
+	if err == nil {
+		return *cached, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		breaker.LogError(r.logger, "rbacUseCase.getGrant.Redis", err)
+	}
+
+	roles, err := r.rbacRepository.GetRolesBySubject(ctx, subject)
+	if err != nil {
+		return entity.Grant{}, err
+	}
+
+	grant := entity.NewGrant(subject, roles)
+	if err = r.rbacRedisRepository.SetGrant(ctx, grant, r.cacheTtl()); err != nil {
+		breaker.LogError(r.logger, "rbacUseCase.getGrant.SetCache", err)
+	}
+
+	return grant, nil
+}
+
+func (r rbacUseCase) cacheTtl() time.Duration {
+	if r.cfg.Rbac.CacheTtl <= 0 {
+		return defaultCacheTtl
+	}
+
+	return time.Second * r.cfg.Rbac.CacheTtl
+}
+
+func NewRbacUseCase(cfg *config.Config, rbacRepository repository.RbacRepository, rbacRedisRepository repository.RbacRedisRepository, logger logger.Logger) RbacUseCase {
+	return &rbacUseCase{
+		cfg: cfg,
+		rbacRepository: rbacRepository,
+		rbacRedisRepository: rbacRedisRepository,
+		logger: logger,
+	}
+}
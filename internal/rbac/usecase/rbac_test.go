@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sefikcan/kanbersky.ca/internal/rbac/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/rbac/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// stubRoles binds roles by subject, the AnySubject roles are added like the query does.
+type stubRoles struct {
+	bindings map[string][]entity.Role
+	calls int
+}
+
+func (s *stubRoles) GetRolesBySubject(_ context.Context, subject string) ([]entity.Role, error) {
+	s.calls++
+	return append(append([]entity.Role{}, s.bindings[subject]...), s.bindings[entity.AnySubject]...), nil
+}
+
+func newTestUseCase(t *testing.T, roles *stubRoles) (RbacUseCase, *miniredis.Miniredis) {
+	t.Helper()
+	cfg := &config.Config{Rbac: config.RbacConfig{CacheTtl: 30}}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() {
+		redisClient.Close()
+	})
+
+	return NewRbacUseCase(cfg, roles, repository.NewRbacRedisRepository(redisClient), l), mr
+}
+
+func TestPermissionDecisions(t *testing.T) {
+	roles := &stubRoles{bindings: map[string][]entity.Role{
+		entity.AnySubject: {{Name: "viewer", Permissions: []entity.Permission{{Name: auth.PermissionCurrencyRead}}}},
+		"alice": {{Name: "maker", RequiresApproval: true, Permissions: []entity.Permission{{Name: auth.PermissionCurrencyUpdate}}}},
+		"bob": {{Name: "checker", Permissions: []entity.Permission{{Name: auth.PermissionChangeApprove}}}},
+	}}
+	uc, _ := newTestUseCase(t, roles)
+	tests := []struct {
+		subject string
+		permission string
+		allowed bool
+		approval bool
+	}{
+		{subject: "carol", permission: auth.PermissionCurrencyRead, allowed: true},
+		{subject: "carol", permission: auth.PermissionCurrencyUpdate},
+		{subject: "alice", permission: auth.PermissionCurrencyUpdate, allowed: true, approval: true},
+		{subject: "alice", permission: auth.PermissionChangeApprove},
+		{subject: "bob", permission: auth.PermissionChangeApprove, allowed: true},
+		{subject: "bob", permission: auth.PermissionCurrencyRead, allowed: true},
+	}
+
+	for _, tt := range tests {
+		principal := auth.Principal{Subject: tt.subject}
+		allowed, err := uc.HasPermission(context.Background(), principal, tt.permission)
+		if err != nil {
+			t.Fatal(err)
+		}
+		approval, err := uc.RequiresApproval(context.Background(), principal, tt.permission)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != tt.allowed || approval != tt.approval {
+			t.Errorf("%s %s: allowed %t, approval %t, want %t and %t", tt.subject, tt.permission, allowed, approval, tt.allowed, tt.approval)
+		}
+	}
+	// one database read per subject, the rest comes from the cache
+	if roles.calls != 3 {
+		t.Errorf("database reads = %d, want 3", roles.calls)
+	}
+}
+
+func TestRoleChangesApplyWhenTheGrantExpires(t *testing.T) {
+	roles := &stubRoles{bindings: map[string][]entity.Role{}}
+	uc, mr := newTestUseCase(t, roles)
+	principal := auth.Principal{Subject: "alice"}
+
+	if allowed, _ := uc.HasPermission(context.Background(), principal, auth.PermissionCurrencyDelete); allowed {
+		t.Fatal("alice can delete without a role")
+	}
+	roles.bindings["alice"] = []entity.Role{{Name: "admin", Permissions: []entity.Permission{{Name: auth.PermissionCurrencyDelete}}}}
+	if allowed, _ := uc.HasPermission(context.Background(), principal, auth.PermissionCurrencyDelete); allowed {
+		t.Fatal("the cached grant was not used")
+	}
+
+	mr.FastForward(30 * time.Second)
+	if allowed, _ := uc.HasPermission(context.Background(), principal, auth.PermissionCurrencyDelete); !allowed {
+		t.Error("the new role does not apply after the cache ttl")
+	}
+
+	// without the cache every decision reads the database
+	mr.Close()
+	calls := roles.calls
+	if allowed, err := uc.HasPermission(context.Background(), principal, auth.PermissionCurrencyDelete); !allowed || err != nil || roles.calls != calls+1 {
+		t.Errorf("HasPermission without the cache = %t, %v after %d reads", allowed, err, roles.calls-calls)
+	}
+}
+
+func TestGetPermissions(t *testing.T) {
+	uc, _ := newTestUseCase(t, &stubRoles{bindings: map[string][]entity.Role{
+		"alice": {{Name: "maker", RequiresApproval: true, Permissions: []entity.Permission{{Name: auth.PermissionCurrencyCreate}}}},
+	}})
+
+	if _, err := uc.GetPermissions(context.Background()); util.ParseError(err).Status() != http.StatusUnauthorized {
+		t.Errorf("GetPermissions without a principal = %v, want 401", err)
+	}
+
+	permissions, err := uc.GetPermissions(auth.NewContext(context.Background(), auth.Principal{Subject: "alice"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if permissions.Subject != "alice" || len(permissions.Roles) != 1 || len(permissions.ApprovalPermissions) != 1 || permissions.ApprovalPermissions[0] != auth.PermissionCurrencyCreate {
+		t.Errorf("permissions = %+v", permissions)
+	}
+}
@@ -4,16 +4,54 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	_ "github.com/sefikcan/kanbersky.ca/docs"
+	alertHandlers "github.com/sefikcan/kanbersky.ca/internal/alert/handlers"
+	"github.com/sefikcan/kanbersky.ca/internal/alert/notifier"
+	alertRepository "github.com/sefikcan/kanbersky.ca/internal/alert/repository"
+	alertUseCase "github.com/sefikcan/kanbersky.ca/internal/alert/usecase"
+	approvalHandlers "github.com/sefikcan/kanbersky.ca/internal/approval/handlers"
+	approvalRepository "github.com/sefikcan/kanbersky.ca/internal/approval/repository"
+	approvalUseCase "github.com/sefikcan/kanbersky.ca/internal/approval/usecase"
+	apiKeyHandlers "github.com/sefikcan/kanbersky.ca/internal/apikey/handlers"
+	apiKeyRepository "github.com/sefikcan/kanbersky.ca/internal/apikey/repository"
+	apiKeyUseCase "github.com/sefikcan/kanbersky.ca/internal/apikey/usecase"
+	auditHandlers "github.com/sefikcan/kanbersky.ca/internal/audit/handlers"
+	auditRepository "github.com/sefikcan/kanbersky.ca/internal/audit/repository"
+	auditUseCase "github.com/sefikcan/kanbersky.ca/internal/audit/usecase"
+	cacheHandlers "github.com/sefikcan/kanbersky.ca/internal/cache/handlers"
+	cacheUseCase "github.com/sefikcan/kanbersky.ca/internal/cache/usecase"
 	"github.com/sefikcan/kanbersky.ca/internal/currency/handlers"
 	"github.com/sefikcan/kanbersky.ca/internal/currency/repository"
 	"github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	exportHandlers "github.com/sefikcan/kanbersky.ca/internal/export/handlers"
+	"github.com/sefikcan/kanbersky.ca/internal/graph"
 	mw "github.com/sefikcan/kanbersky.ca/internal/middleware"
+	outboxRepository "github.com/sefikcan/kanbersky.ca/internal/outbox/repository"
+	outboxUseCase "github.com/sefikcan/kanbersky.ca/internal/outbox/usecase"
+	rateHandlers "github.com/sefikcan/kanbersky.ca/internal/rate/handlers"
+	rateRepository "github.com/sefikcan/kanbersky.ca/internal/rate/repository"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/stream"
+	rateUseCase "github.com/sefikcan/kanbersky.ca/internal/rate/usecase"
+	rbacHandlers "github.com/sefikcan/kanbersky.ca/internal/rbac/handlers"
+	rbacRepository "github.com/sefikcan/kanbersky.ca/internal/rbac/repository"
+	rbacUseCase "github.com/sefikcan/kanbersky.ca/internal/rbac/usecase"
+	webhookHandlers "github.com/sefikcan/kanbersky.ca/internal/webhook/handlers"
+	webhookRepository "github.com/sefikcan/kanbersky.ca/internal/webhook/repository"
+	webhookUseCase "github.com/sefikcan/kanbersky.ca/internal/webhook/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sefikcan/kanbersky.ca/pkg/codec"
+	"github.com/sefikcan/kanbersky.ca/pkg/event"
+	"github.com/sefikcan/kanbersky.ca/pkg/idempotency"
 	"github.com/sefikcan/kanbersky.ca/pkg/metric"
+	"github.com/sefikcan/kanbersky.ca/pkg/ratelimit"
 	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"github.com/sony/gobreaker"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"net/http"
 )
 
+const defaultEventStream = "currency-events"
+
 func (s *Server) MapHandlers(e *echo.Echo) error {
 	metrics, err := metric.CreateMetrics(s.cfg.Metric.Url, s.cfg.Metric.ServiceName)
 	if err != nil {
@@ -22,18 +60,73 @@ func (s *Server) MapHandlers(e *echo.Echo) error {
 	s.logger.Infof("Metrics available URL: %s, ServiceName: %s", s.cfg.Metric.Url, s.cfg.Metric.ServiceName)
 
 	currencyRepository := repository.NewCurrencyRepository(s.db)
-	currencyRedisRepository := repository.NewCurrencyRedisRepository(s.redisClient)
+	outboxEventRepository := outboxRepository.NewOutboxRepository(s.db)
+	webhookSubscriptionRepository := webhookRepository.NewWebhookRepository(s.db)
+	rateDbRepository := rateRepository.NewRateRepository(s.db)
+	alertRuleRepository := alertRepository.NewAlertRepository(s.db)
+	apiKeyDbRepository := apiKeyRepository.NewApiKeyRepository(s.db)
+	rbacDbRepository := rbacRepository.NewRbacRepository(s.db)
+	auditLogRepository := auditRepository.NewAuditRepository(s.db)
+	changeRequestRepository := approvalRepository.NewChangeRequestRepository(s.db)
+	redisBreaker := breaker.NewCircuitBreaker("redis", s.cfg.CircuitBreaker, metrics, s.logger)
+	currencyRedisRepository := repository.NewCurrencyRedisBreakerRepository(repository.NewCurrencyRedisRepository(s.redisClient), redisBreaker)
+	rateRedisRepository := rateRepository.NewRateRedisBreakerRepository(rateRepository.NewRateRedisRepository(s.redisClient), redisBreaker)
+	apiKeyRedisRepository := apiKeyRepository.NewApiKeyRedisBreakerRepository(apiKeyRepository.NewApiKeyRedisRepository(s.redisClient), redisBreaker)
+	rbacRedisRepository := rbacRepository.NewRbacRedisBreakerRepository(rbacRepository.NewRbacRedisRepository(s.redisClient), redisBreaker)
 
 	currencyUseCase := usecase.NewCurrencyUseCase(s.cfg, currencyRepository, currencyRedisRepository, s.logger)
 
+	s.rateHub = stream.NewHub(s.cfg, s.redisClient, s.logger)
+	currencyRateUseCase := rateUseCase.NewRateUseCase(s.cfg, rateDbRepository, rateRedisRepository, s.rateHub, s.logger)
+
+	s.cacheWarmUpUseCase = cacheUseCase.NewCacheWarmUpUseCase(s.cfg, currencyRepository, currencyRedisRepository, rateDbRepository, rateRedisRepository, s.logger)
+	s.cacheSyncUseCase = cacheUseCase.NewCacheSyncUseCase(s.cfg, currencyRepository, currencyRedisRepository, s.logger)
+	webhookSubscriptionUseCase := webhookUseCase.NewWebhookUseCase(s.cfg, webhookSubscriptionRepository, s.logger)
+	s.webhookDeliveryUseCase = webhookUseCase.NewWebhookDeliveryUseCase(s.cfg, webhookSubscriptionRepository, nil, s.logger)
+	alertRuleUseCase := alertUseCase.NewAlertUseCase(s.cfg, alertRuleRepository, s.logger)
+	apiKeyManagementUseCase := apiKeyUseCase.NewApiKeyUseCase(s.cfg, apiKeyDbRepository, apiKeyRedisRepository, s.logger)
+	accessControlUseCase := rbacUseCase.NewRbacUseCase(s.cfg, rbacDbRepository, rbacRedisRepository, s.logger)
+	auditLogUseCase := auditUseCase.NewAuditUseCase(s.cfg, auditLogRepository, s.logger)
+	s.changeRequestUseCase = approvalUseCase.NewChangeRequestUseCase(s.cfg, changeRequestRepository, currencyUseCase, currencyRateUseCase, accessControlUseCase, accessControlUseCase, s.logger)
+	s.alertNotificationUseCase = alertUseCase.NewAlertNotificationUseCase(s.cfg, alertRuleRepository, notifier.NewNotifiers(s.cfg, s.logger), s.logger)
+	eventPublisher := event.NewMultiPublisher(
+		s.newEventPublisher(),
+		webhookUseCase.NewWebhookDispatcher(webhookSubscriptionRepository),
+		alertUseCase.NewAlertEvaluator(alertRuleRepository, rateDbRepository, s.logger),
+	)
+	s.outboxRelayUseCase = outboxUseCase.NewOutboxRelayUseCase(s.cfg, outboxEventRepository, eventPublisher, s.logger)
+
 	currencyHandler := handlers.NewCurrencyHandler(s.cfg, currencyUseCase, s.logger)
+	currencyV2Handler := handlers.NewCurrencyV2Handler(s.cfg, currencyUseCase, s.logger)
+	cacheHandler := cacheHandlers.NewCacheHandler(s.cfg, s.cacheWarmUpUseCase, s.logger)
+	webhookHandler := webhookHandlers.NewWebhookHandler(s.cfg, webhookSubscriptionUseCase, s.logger)
+	alertHandler := alertHandlers.NewAlertHandler(s.cfg, alertRuleUseCase, s.logger)
+	apiKeyHandler := apiKeyHandlers.NewApiKeyHandler(s.cfg, apiKeyManagementUseCase, s.logger)
+	rbacHandler := rbacHandlers.NewRbacHandler(s.cfg, accessControlUseCase, s.logger)
+	auditHandler := auditHandlers.NewAuditHandler(s.cfg, auditLogUseCase, s.logger)
+	changeRequestHandler := approvalHandlers.NewChangeRequestHandler(s.cfg, s.changeRequestUseCase, s.logger)
+	rateHandler := rateHandlers.NewRateHandler(s.cfg, currencyRateUseCase, s.rateHub, metrics, s.logger)
+	exportHandler := exportHandlers.NewExportHandler(s.cfg, currencyUseCase, currencyRateUseCase, s.logger)
+	graphQLHandler := graph.NewGraphQLHandler(s.cfg, currencyUseCase, currencyRateUseCase, s.logger)
+
+	var authVerifier auth.Verifier
+	if s.cfg.Auth.Enabled {
+		if authVerifier, err = auth.NewJWTVerifier(s.cfg.Auth); err != nil {
+			return err
+		}
+	}
+	s.grpcServer = s.newGrpcServer(metrics, currencyUseCase, s.changeRequestUseCase, authVerifier, apiKeyManagementUseCase, accessControlUseCase)
 
 	middlewareManager := mw.NewMiddlewareManager(s.cfg, s.logger)
+	e.Binder = &codec.Binder{}
+	e.IPExtractor = mw.RateLimitIPExtractor(s.cfg.RateLimit)
+	e.Pre(middlewareManager.ApiVersionMiddleware("/currencies"))
 	e.Use(middlewareManager.RequestLoggerMiddleware)
 
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderXRequestID},
+		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderXRequestID, echo.HeaderAuthorization, mw.HeaderApiKey, mw.HeaderIdempotencyKey, mw.HeaderTenantId},
+		ExposeHeaders: []string{mw.HeaderDeprecation, mw.HeaderSunset, "Link", echo.HeaderWWWAuthenticate, mw.HeaderRateLimitLimit, mw.HeaderRateLimitRemaining, mw.HeaderRateLimitReset, mw.HeaderRateLimitPolicy, mw.HeaderRetryAfter, mw.HeaderIdempotentReplayed},
 	}))
 	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
 		StackSize: 1 << 10, //1kb
@@ -41,21 +134,84 @@ func (s *Server) MapHandlers(e *echo.Echo) error {
 		DisableStackAll: true,
 	}))
 	e.Use(middleware.RequestID())
+	e.Use(middlewareManager.AuditMiddleware)
 	e.Use(middlewareManager.MetricsMiddleware(metrics))
+	e.Use(middlewareManager.ClientCertificateMiddleware)
+	e.Use(middlewareManager.IdentifyMiddleware(authVerifier, apiKeyManagementUseCase))
+	e.Use(middlewareManager.TenantMiddleware)
+	e.Use(middlewareManager.RateLimitMiddleware(ratelimit.NewFallbackLimiter(ratelimit.NewRedisLimiter(s.redisClient), ratelimit.NewMemoryLimiter(), redisBreaker, s.logger)))
 	e.Use(middleware.Secure())
 	e.Use(middleware.BodyLimit("2M"))
+	e.Use(middlewareManager.IdempotencyMiddleware(idempotency.NewBreakerStore(idempotency.NewRedisStore(s.redisClient), redisBreaker)))
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
+	authenticate := middlewareManager.AuthMiddleware(authVerifier, apiKeyManagementUseCase)
+	authorize := func(permission string) echo.MiddlewareFunc {
+		return middlewareManager.PermissionMiddleware(accessControlUseCase, permission)
+	}
+	currencyAuth := []echo.MiddlewareFunc{
+		authenticate,
+		middlewareManager.ScopeMiddleware(auth.ScopeCurrenciesRead, auth.ScopeCurrenciesWrite),
+	}
+	apiKeyAuth := []echo.MiddlewareFunc{
+		authenticate,
+		middlewareManager.ScopeMiddleware(auth.ScopeApiKeysManage, auth.ScopeApiKeysManage),
+	}
 	v1 := e.Group("/api/v1")
 	health := v1.Group("/health")
-	currencyGroup := v1.Group("/currencies")
+	currencyGroup := v1.Group("/currencies", middlewareManager.DeprecationMiddleware())
+	currencyGroup.Use(currencyAuth...)
+	adminGroup := v1.Group("/admin")
+	cacheGroup := adminGroup.Group("/cache", authenticate, middlewareManager.PlatformMiddleware, authorize(auth.PermissionCacheManage))
+	apiKeyGroup := adminGroup.Group("/api-keys", apiKeyAuth...)
+	webhookGroup := v1.Group("/webhooks", authenticate, middlewareManager.PlatformMiddleware, authorize(auth.PermissionWebhookManage))
+	rateGroup := v1.Group("/rates")
+	alertGroup := v1.Group("/alerts", authenticate, middlewareManager.PlatformMiddleware, authorize(auth.PermissionAlertManage))
+	// graphql only has queries, POST bodies carry them too
+	graphQLGroup := v1.Group("/graphql", authenticate, middlewareManager.ScopeMiddleware(auth.ScopeCurrenciesRead, auth.ScopeCurrenciesRead))
+	meGroup := v1.Group("/me", authenticate)
+	exportGroup := v1.Group("/export", authenticate, middlewareManager.ScopeMiddleware(auth.ScopeCurrenciesRead, auth.ScopeCurrenciesRead))
+	auditGroup := v1.Group("/audit", authenticate, middlewareManager.PlatformMiddleware, authorize(auth.PermissionAuditRead))
+	changeRequestGroup := v1.Group("/change-requests", authenticate)
+	v2 := e.Group("/api/v2")
+	currencyV2Group := v2.Group("/currencies", currencyAuth...)
 
-	handlers.MapCurrencyRoutes(currencyGroup, currencyHandler)
+	handlers.MapCurrencyRoutes(currencyGroup, handlers.NewCurrencyAuthorizationHandler(handlers.NewCurrencyApprovalHandler(currencyHandler, changeRequestHandler.Propose), authorize))
+	handlers.MapCurrencyV2Routes(currencyV2Group, handlers.NewCurrencyV2AuthorizationHandler(handlers.NewCurrencyV2ApprovalHandler(currencyV2Handler, changeRequestHandler.Propose), authorize))
+	cacheHandlers.MapCacheRoutes(cacheGroup, cacheHandler)
+	apiKeyHandlers.MapApiKeyRoutes(apiKeyGroup, apiKeyHandler)
+	webhookHandlers.MapWebhookRoutes(webhookGroup, webhookHandler)
+	rateHandlers.MapRateRoutes(rateGroup, rateHandlers.NewRateAuthorizationHandler(rateHandlers.NewRateApprovalHandler(rateHandler, changeRequestHandler.Propose), authenticate, authorize))
+	rbacHandlers.MapRbacRoutes(meGroup, rbacHandler)
+	alertHandlers.MapAlertRoutes(alertGroup, alertHandler)
+	exportHandlers.MapExportRoutes(exportGroup, exportHandler)
+	auditHandlers.MapAuditRoutes(auditGroup, auditHandler)
+	approvalHandlers.MapChangeRequestRoutes(changeRequestGroup, approvalHandlers.NewChangeRequestAuthorizationHandler(changeRequestHandler, authorize))
+	if s.cfg.GraphQL.Enabled {
+		graph.MapGraphQLRoutes(graphQLGroup, graphQLHandler)
+	}
 
 	health.GET("", func(c echo.Context) error {
 		s.logger.Infof("Health check RequestID: %s", util.GetRequestId(c))
-		return c.JSON(http.StatusOK, map[string]string{"status": "OK"})
+		status := "OK"
+		if redisBreaker.State() != gobreaker.StateClosed {
+			status = "DEGRADED"
+		}
+		return c.JSON(http.StatusOK, map[string]string{"status": status, "redis_circuit_breaker": redisBreaker.State().String()})
 	})
 
 	return nil
 }
+
+func (s *Server) newEventPublisher() event.EventPublisher {
+	if s.cfg.Outbox.Publisher == "memory" {
+		return event.NewInMemoryPublisher()
+	}
+
+	streamName := s.cfg.Outbox.Stream
+	if streamName == "" {
+		streamName = defaultEventStream
+	}
+
+	return event.NewRedisStreamPublisher(s.redisClient, streamName, s.cfg.Outbox.StreamMaxLen)
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	currencyGrpc "github.com/sefikcan/kanbersky.ca/internal/currency/grpc"
+	"github.com/sefikcan/kanbersky.ca/internal/currency/usecase"
+	"github.com/sefikcan/kanbersky.ca/internal/interceptor"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/metric"
+	currencyv1 "github.com/sefikcan/kanbersky.ca/pkg/pb/currency/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+	"net"
+	"time"
+)
+
+// newGrpcServer checks credentials, tenants and permissions with the same rules as the http routes, and serves
+// tls with the certificate of the http server when server.ssl is set.
+func (s *Server) newGrpcServer(metrics metric.Metrics, currencyUseCase usecase.CurrencyUseCase, changeProposer currencyGrpc.ChangeProposer, verifier auth.Verifier, keys auth.KeyAuthenticator, authorizer auth.Authorizer) *grpc.Server {
+	interceptorManager := interceptor.NewInterceptorManager(s.cfg, s.logger)
+	interceptors := []grpc.UnaryServerInterceptor{
+		interceptorManager.RecoveryInterceptor,
+		interceptorManager.RequestIdInterceptor,
+		interceptorManager.TracingInterceptor,
+		interceptorManager.LoggerInterceptor,
+	}
+	if metrics != nil {
+		interceptors = append(interceptors, interceptorManager.MetricsInterceptor(metrics))
+	}
+	interceptors = append(interceptors,
+		interceptorManager.AuthInterceptor(verifier, keys),
+		interceptorManager.TenantInterceptor,
+		interceptorManager.AuthorizationInterceptor(authorizer, currencyGrpc.MethodPermissions),
+	)
+
+	options := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle: time.Second * s.cfg.Grpc.MaxConnectionIdle,
+			MaxConnectionAge: time.Second * s.cfg.Grpc.MaxConnectionAge,
+			Time: time.Second * s.cfg.Grpc.Time,
+			Timeout: time.Second * s.cfg.Grpc.Timeout,
+		}),
+		grpc.ChainUnaryInterceptor(interceptors...),
+	}
+	if s.tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(s.tlsConfig.Clone())))
+	}
+	grpcServer := grpc.NewServer(options...)
+
+	currencyv1.RegisterCurrencyServiceServer(grpcServer, currencyGrpc.NewCurrencyServer(s.cfg, currencyUseCase, changeProposer, s.logger))
+
+	s.grpcHealth = health.NewServer()
+	s.grpcHealth.SetServingStatus(currencyv1.CurrencyService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, s.grpcHealth)
+
+	if s.cfg.Grpc.Reflection {
+		reflection.Register(grpcServer)
+	}
+
+	return grpcServer
+}
+
+func (s *Server) runGrpcServer() {
+	address := fmt.Sprintf("%s:%s", s.cfg.Server.Host, s.cfg.Grpc.Port)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		s.logger.Fatalf("Error starting grpc server: %s", err)
+	}
+
+	s.logger.Infof("gRPC server is listening on PORT: %s", s.cfg.Grpc.Port)
+	if err = s.grpcServer.Serve(listener); err != nil {
+		s.logger.Errorf("Error grpc Serve: %s", err)
+	}
+}
+
+// shutdownGrpcServer reports NOT_SERVING first so balancers stop routing, then waits for in-flight
+// calls until ctx expires.
+func (s *Server) shutdownGrpcServer(ctx context.Context) {
+	s.grpcHealth.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+	}
+}
@@ -2,11 +2,24 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	alertUseCase "github.com/sefikcan/kanbersky.ca/internal/alert/usecase"
+	approvalUseCase "github.com/sefikcan/kanbersky.ca/internal/approval/usecase"
+	cacheUseCase "github.com/sefikcan/kanbersky.ca/internal/cache/usecase"
+	outboxUseCase "github.com/sefikcan/kanbersky.ca/internal/outbox/usecase"
+	"github.com/sefikcan/kanbersky.ca/internal/rate/stream"
+	webhookUseCase "github.com/sefikcan/kanbersky.ca/internal/webhook/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/certificate"
 	"github.com/sefikcan/kanbersky.ca/pkg/config"
 	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/storage/postgres"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
 	"gorm.io/gorm"
 	"net/http"
 	"os"
@@ -19,11 +32,23 @@ type Server struct {
 	echo *echo.Echo
 	cfg *config.Config
 	db *gorm.DB
-	redisClient *redis.Client
+	redisClient redis.UniversalClient
 	logger logger.Logger
+	cacheWarmUpUseCase cacheUseCase.CacheWarmUpUseCase
+	cacheSyncUseCase cacheUseCase.CacheSyncUseCase
+	outboxRelayUseCase outboxUseCase.OutboxRelayUseCase
+	webhookDeliveryUseCase webhookUseCase.WebhookDeliveryUseCase
+	rateHub stream.Hub
+	alertNotificationUseCase alertUseCase.AlertNotificationUseCase
+	changeRequestUseCase approvalUseCase.ChangeRequestUseCase
+	grpcServer *grpc.Server
+	grpcHealth *health.Server
+	certificateReloader certificate.Reloader
+	tlsConfig *tls.Config
+	redirectServer *http.Server
 }
 
-func NewServer(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, logger logger.Logger) *Server {
+func NewServer(cfg *config.Config, db *gorm.DB, redisClient redis.UniversalClient, logger logger.Logger) *Server {
 	return &Server{
 		echo: echo.New(),
 		cfg: cfg,
@@ -33,7 +58,27 @@ func NewServer(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, logge
 	}
 }
 
+// checkEventConsumers refuses consumers the outbox relay feeds when the relay does not run, they would stay
+// idle without a word.
+func checkEventConsumers(cfg *config.Config) error {
+	if cfg.Alert.Enabled && !cfg.Outbox.Enabled {
+		return errors.New("alert.enabled needs outbox.enabled, alert rules are evaluated by the outbox relay")
+	}
+	if cfg.Webhook.Enabled && !cfg.Outbox.Enabled {
+		return errors.New("webhook.enabled needs outbox.enabled, webhook deliveries are created by the outbox relay")
+	}
+
+	return nil
+}
+
 func (s *Server) Run() error  {
+	if err := checkEventConsumers(s.cfg); err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	server := &http.Server{
 		Addr: fmt.Sprintf("%s:%s", s.cfg.Server.Host, s.cfg.Server.Port),
 		ReadTimeout: time.Second * s.cfg.Server.ReadTimeout,
@@ -41,6 +86,26 @@ func (s *Server) Run() error  {
 		MaxHeaderBytes: s.cfg.Server.MaxHeaderBytes,
 	}
 
+	// echo serves tls on its own listener, http2 has to be configured here since http.Server only does it in ServeTLS
+	if s.cfg.Server.SSL {
+		tlsConfig, err := s.newTLSConfig()
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = tlsConfig
+		// the grpc server gets a copy without the http2 settings of the http server
+		s.tlsConfig = tlsConfig.Clone()
+		if err = http2.ConfigureServer(server, &http2.Server{}); err != nil {
+			return err
+		}
+		go s.certificateReloader.Run(runCtx)
+
+		if s.cfg.Server.TLS.RedirectPort != "" {
+			s.redirectServer = s.newRedirectServer()
+			go s.runRedirectServer()
+		}
+	}
+
 	go func() {
 		s.logger.Infof("Server is listening on PORT: %s", s.cfg.Server.Port)
 		if err := s.echo.StartServer(server); err != nil {
@@ -59,12 +124,69 @@ func (s *Server) Run() error  {
 		return err
 	}
 
+	go s.rateHub.Run(runCtx)
+
+	if s.cfg.Cache.WarmUpOnStart {
+		if _, err := s.cacheWarmUpUseCase.StartWarmUp(runCtx); err != nil {
+			s.logger.Errorf("Cache warm-up: %s", err)
+		}
+	}
+
+	if s.cfg.Cache.SyncEnabled {
+		go s.listenCurrencyChanges(runCtx)
+	}
+
+	if s.cfg.Outbox.Enabled {
+		go s.outboxRelayUseCase.Run(runCtx)
+	}
+
+	if s.cfg.Webhook.Enabled {
+		go s.webhookDeliveryUseCase.Run(runCtx)
+	}
+
+	if s.cfg.Alert.Enabled {
+		go s.alertNotificationUseCase.Run(runCtx)
+	}
+
+	go s.changeRequestUseCase.Run(runCtx)
+
+	if s.cfg.Grpc.Enabled {
+		go s.runGrpcServer()
+	}
+
 	// gracefull shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	ctx, shutdown := context.WithTimeout(context.Background(), s.cfg.Server.CtxTimeout * time.Second)
 	defer shutdown()
+	if s.cfg.Grpc.Enabled {
+		s.shutdownGrpcServer(ctx)
+	}
+	if s.redirectServer != nil {
+		if err := s.redirectServer.Shutdown(ctx); err != nil {
+			s.logger.Errorf("Redirect server shutdown: %s", err)
+		}
+	}
 	s.logger.Info("Server exited properly")
 	return s.echo.Server.Shutdown(ctx)
-}
\ No newline at end of file
+}
+
+func (s *Server) listenCurrencyChanges(ctx context.Context) {
+	listener := postgres.NewListener(s.cfg, s.logger)
+	onNotification := func(ctx context.Context, payload string) {
+		if err := s.cacheSyncUseCase.HandleCurrencyChange(ctx, payload); err != nil {
+			s.logger.Errorf("Cache sync, Payload: %s, Error: %s", payload, err)
+		}
+	}
+	// changes made while the connection was down were never notified, reload everything instead
+	onReconnect := func(ctx context.Context) {
+		if _, err := s.cacheWarmUpUseCase.StartWarmUp(ctx); err != nil {
+			s.logger.Errorf("Cache warm-up after reconnect: %s", err)
+		}
+	}
+
+	if err := listener.Listen(ctx, cacheUseCase.CurrencyChangeChannel, onNotification, onReconnect); err != nil {
+		s.logger.Errorf("Cache sync listener: %s", err)
+	}
+}
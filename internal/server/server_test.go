@@ -0,0 +1,31 @@
+package server
+
+import (
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"testing"
+)
+
+func TestCheckEventConsumers(t *testing.T) {
+	tests := []struct {
+		name string
+		alert bool
+		webhook bool
+		outbox bool
+		fails bool
+	}{
+		{name: "alerts with the relay", alert: true, outbox: true},
+		{name: "alerts without the relay", alert: true, fails: true},
+		{name: "webhooks with the relay", webhook: true, outbox: true},
+		{name: "webhooks without the relay", webhook: true, fails: true},
+		{name: "neither without the relay"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Alert: config.AlertConfig{Enabled: tt.alert}, Webhook: config.WebhookConfig{Enabled: tt.webhook}, Outbox: config.OutboxConfig{Enabled: tt.outbox}}
+			if err := checkEventConsumers(cfg); (err != nil) != tt.fails {
+				t.Errorf("checkEventConsumers = %v, want error %t", err, tt.fails)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/certificate"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	clientAuthRequire = "require"
+	clientAuthOptional = "optional"
+)
+
+// newTLSConfig serves the certificate of the reloader, a client ca file turns on client certificates. The
+// config is cloned per handshake then, so a reloaded ca bundle applies to new connections.
+func (s *Server) newTLSConfig() (*tls.Config, error) {
+	tlsCfg := s.cfg.Server.TLS
+	reloader, err := certificate.NewReloader(tlsCfg.CertFile, tlsCfg.KeyFile, tlsCfg.ClientCAFile, time.Second * tlsCfg.ReloadInterval, s.logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "Server.newTLSConfig.NewReloader")
+	}
+	s.certificateReloader = reloader
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if tlsCfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	switch strings.ToLower(tlsCfg.ClientAuth) {
+	case "", clientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	case clientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, errors.Errorf("Server.newTLSConfig: unknown clientauth %q", tlsCfg.ClientAuth)
+	}
+	tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		config := tlsConfig.Clone()
+		config.ClientCAs = reloader.ClientCAs()
+		config.GetConfigForClient = nil
+		return config, nil
+	}
+
+	return tlsConfig, nil
+}
+
+func (s *Server) newRedirectServer() *http.Server {
+	return &http.Server{
+		Addr: fmt.Sprintf("%s:%s", s.cfg.Server.Host, s.cfg.Server.TLS.RedirectPort),
+		ReadTimeout: time.Second * s.cfg.Server.ReadTimeout,
+		WriteTimeout: time.Second * s.cfg.Server.WriteTimeout,
+		MaxHeaderBytes: s.cfg.Server.MaxHeaderBytes,
+		Handler: http.HandlerFunc(s.redirectToHttps),
+	}
+}
+
+func (s *Server) runRedirectServer() {
+	s.logger.Infof("Redirect server is listening on PORT: %s", s.cfg.Server.TLS.RedirectPort)
+	if err := s.redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.logger.Errorf("Error redirect ListenAndServe: %s", err)
+	}
+}
+
+// redirectToHttps keeps the host the client asked for and swaps the port, 308 keeps the method and body of
+// the request.
+func (s *Server) redirectToHttps(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host == "" {
+		host = s.cfg.Server.Host
+	}
+	if s.cfg.Server.Port != "443" {
+		host = net.JoinHostPort(host, s.cfg.Server.Port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key *ecdsa.PrivateKey
+	pem []byte
+}
+
+func newTestCA(t *testing.T, name string) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{CommonName: name},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter: time.Now().Add(time.Hour),
+		IsCA: true,
+		BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs a certificate for localhost that serves both ends of a mutual tls connection.
+func (ca testCA) issue(t *testing.T, commonName string) (certPEM []byte, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject: pkix.Name{CommonName: commonName},
+		DNSNames: []string{"localhost"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter: time.Now().Add(time.Hour),
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func (ca testCA) clientCertificate(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+	certificate, err := tls.X509KeyPair(ca.issue(t, commonName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certificate
+}
+
+func writeFile(t *testing.T, path string, data []byte, at time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTLSTestServer serves the common name of the client certificate of each request over the tls config of
+// the server, clientCA is written as the client ca file unless it is nil.
+func newTLSTestServer(t *testing.T, serverCA testCA, clientCA *testCA, clientAuth string) (*Server, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	cfg.Server.TLS = config.ServerTLSConfig{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key"), ClientAuth: clientAuth, ReloadInterval: 1}
+	certPEM, keyPEM := serverCA.issue(t, "localhost")
+	at := time.Now().Add(-time.Minute)
+	writeFile(t, cfg.Server.TLS.CertFile, certPEM, at)
+	writeFile(t, cfg.Server.TLS.KeyFile, keyPEM, at)
+	if clientCA != nil {
+		cfg.Server.TLS.ClientCAFile = filepath.Join(dir, "ca.crt")
+		writeFile(t, cfg.Server.TLS.ClientCAFile, clientCA.pem, at)
+	}
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	s := &Server{cfg: cfg, logger: l}
+	tlsConfig, err := s.newTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := &http.Server{ErrorLog: log.New(io.Discard, "", 0), Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			_, _ = io.WriteString(w, "anonymous")
+			return
+		}
+		_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	})}
+	go func() {
+		_ = httpServer.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		httpServer.Close()
+	})
+
+	return s, "https://" + listener.Addr().String(), cfg.Server.TLS.ClientCAFile
+}
+
+// get makes each request on a new connection, so every request goes through a handshake. The client
+// certificate is sent even when the server does not name its ca, the server has to refuse it then.
+func get(serverCA testCA, url string, certificates ...tls.Certificate) (string, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	client := &http.Client{Transport: &http.Transport{
+		DisableKeepAlives: true,
+		TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost", GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if len(certificates) == 0 {
+				return &tls.Certificate{}, nil
+			}
+			return &certificates[0], nil
+		}},
+	}}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+
+	return string(body), err
+}
+
+func TestTLSClientAuth(t *testing.T) {
+	serverCA := newTestCA(t, "server ca")
+	clientCA := newTestCA(t, "client ca")
+	strangerCA := newTestCA(t, "stranger ca")
+
+	tests := []struct {
+		name string
+		clientCA *testCA
+		clientAuth string
+		certificates []tls.Certificate
+		body string
+	}{
+		{name: "tls only", body: "anonymous"},
+		{name: "tls only ignores client certificates", certificates: []tls.Certificate{clientCA.clientCertificate(t, "importer")}, body: "anonymous"},
+		{name: "required", clientCA: &clientCA, certificates: []tls.Certificate{clientCA.clientCertificate(t, "importer")}, body: "importer"},
+		{name: "required without a certificate", clientCA: &clientCA},
+		{name: "required with a certificate of another ca", clientCA: &clientCA, certificates: []tls.Certificate{strangerCA.clientCertificate(t, "stranger")}},
+		{name: "optional without a certificate", clientCA: &clientCA, clientAuth: "optional", body: "anonymous"},
+		{name: "optional with a certificate", clientCA: &clientCA, clientAuth: "Optional", certificates: []tls.Certificate{clientCA.clientCertificate(t, "importer")}, body: "importer"},
+		{name: "optional with a certificate of another ca", clientCA: &clientCA, clientAuth: "optional", certificates: []tls.Certificate{strangerCA.clientCertificate(t, "stranger")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, url, _ := newTLSTestServer(t, serverCA, tt.clientCA, tt.clientAuth)
+			body, err := get(serverCA, url, tt.certificates...)
+			if tt.body == "" {
+				if err == nil {
+					t.Errorf("request succeeded with %q, want the handshake refused", body)
+				}
+				return
+			}
+			if err != nil || body != tt.body {
+				t.Errorf("body = %q, %v, want %q", body, err, tt.body)
+			}
+		})
+	}
+}
+
+func TestTLSConfigRejectsUnknownClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "ca")
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	cfg.Server.TLS = config.ServerTLSConfig{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key"), ClientCAFile: filepath.Join(dir, "ca.crt"), ClientAuth: "request"}
+	certPEM, keyPEM := ca.issue(t, "localhost")
+	writeFile(t, cfg.Server.TLS.CertFile, certPEM, time.Now())
+	writeFile(t, cfg.Server.TLS.KeyFile, keyPEM, time.Now())
+	writeFile(t, cfg.Server.TLS.ClientCAFile, ca.pem, time.Now())
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	if _, err := (&Server{cfg: cfg, logger: l}).newTLSConfig(); err == nil {
+		t.Error("newTLSConfig accepted clientauth request")
+	}
+}
+
+func TestReloadedClientCAsApplyToNewConnections(t *testing.T) {
+	serverCA := newTestCA(t, "server ca")
+	oldCA := newTestCA(t, "old client ca")
+	newCA := newTestCA(t, "new client ca")
+	s, url, caFile := newTLSTestServer(t, serverCA, &oldCA, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.certificateReloader.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if body, err := get(serverCA, url, oldCA.clientCertificate(t, "importer")); err != nil || body != "importer" {
+		t.Fatalf("body = %q, %v before the rotation", body, err)
+	}
+
+	writeFile(t, caFile, newCA.pem, time.Now())
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		body, err := get(serverCA, url, newCA.clientCertificate(t, "exporter"))
+		if err == nil && body == "exporter" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("body = %q, %v, the rotated client ca was not applied", body, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if _, err := get(serverCA, url, oldCA.clientCertificate(t, "importer")); err == nil {
+		t.Error("the certificate of the replaced client ca is still accepted")
+	}
+}
+
+func TestRedirectToHttps(t *testing.T) {
+	tests := []struct {
+		name string
+		port string
+		host string
+		target string
+		location string
+	}{
+		{name: "keeps the path and query", port: "8443", host: "api.kanbersky.ca:8080", target: "/api/v1/currencies?page=2", location: "https://api.kanbersky.ca:8443/api/v1/currencies?page=2"},
+		{name: "default port", port: "443", host: "api.kanbersky.ca", target: "/", location: "https://api.kanbersky.ca/"},
+		{name: "ipv6 on the default port", port: "443", host: "[::1]:8080", target: "/", location: "https://[::1]/"},
+		{name: "ipv6", port: "8443", host: "[::1]:8080", target: "/", location: "https://[::1]:8443/"},
+		{name: "no host header", port: "8443", target: "/", location: "https://" + net.JoinHostPort("localhost", "8443") + "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Server.Host = "localhost"
+			cfg.Server.Port = tt.port
+			req := httptest.NewRequest(http.MethodPost, tt.target, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+
+			(&Server{cfg: cfg}).redirectToHttps(rec, req)
+			if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != tt.location {
+				t.Errorf("redirect = %d %s, want 308 %s", rec.Code, rec.Header().Get("Location"), tt.location)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	DeliveryStatusPending = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed = "failed"
+
+	AllEvents = "*"
+
+	AuditEntityType = "webhook"
+)
+
+type Subscription struct {
+	ID int `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	URL string `json:"url"`
+	Events string `json:"events"`
+	Secret string `json:"-"`
+	Active bool `json:"active"`
+}
+
+func (Subscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+func (s Subscription) EventList() []string {
+	if s.Events == "" {
+		return []string{}
+	}
+
+	return strings.Split(s.Events, ",")
+}
+
+// Matches reports whether the event type passes the subscription filter, a filter is either
+// an exact event type, a prefix like "currency.*" or "*" for everything.
+func (s Subscription) Matches(eventType string) bool {
+	for _, filter := range s.EventList() {
+		if filter == AllEvents || filter == eventType {
+			return true
+		}
+		if strings.HasSuffix(filter, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(filter, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Delivery struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	SubscriptionID int `gorm:"index:idx_webhook_delivery_event,unique" json:"subscription_id"`
+	EventID string `gorm:"index:idx_webhook_delivery_event,unique" json:"event_id"`
+	EventType string `json:"event_type"`
+	Payload string `gorm:"type:jsonb" json:"payload"`
+	Status string `json:"status"`
+	Attempts int `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LockedUntil *time.Time `json:"locked_until"`
+	LastStatusCode int `json:"last_status_code"`
+	LastError string `json:"last_error"`
+	DeliveredAt *time.Time `json:"delivered_at"`
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+type DeliveryAttempt struct {
+	ID int64 `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	DeliveryID int64 `gorm:"index" json:"delivery_id"`
+	StatusCode int `json:"status_code"`
+	ResponseBody string `json:"response_body"`
+	Error string `json:"error"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+func (DeliveryAttempt) TableName() string {
+	return "webhook_delivery_attempts"
+}
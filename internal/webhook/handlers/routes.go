@@ -0,0 +1,13 @@
+package handlers
+
+import "github.com/labstack/echo/v4"
+
+func MapWebhookRoutes(webhookRouteGroup *echo.Group, w WebhookHandlers) {
+	webhookRouteGroup.POST("", w.Create())
+	webhookRouteGroup.DELETE("/:id", w.Delete())
+	webhookRouteGroup.GET("/:id", w.GetById())
+	webhookRouteGroup.GET("", w.GetAll())
+	webhookRouteGroup.GET("/:id/deliveries", w.GetDeliveries())
+	webhookRouteGroup.GET("/:id/deliveries/:deliveryId", w.GetDelivery())
+	webhookRouteGroup.POST("/:id/deliveries/:deliveryId/replay", w.ReplayDelivery())
+}
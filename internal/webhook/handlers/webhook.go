@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/request/webhook"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/usecase"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type WebhookHandlers interface {
+	Create() echo.HandlerFunc
+	GetById() echo.HandlerFunc
+	Delete() echo.HandlerFunc
+	GetAll() echo.HandlerFunc
+	GetDeliveries() echo.HandlerFunc
+	GetDelivery() echo.HandlerFunc
+	ReplayDelivery() echo.HandlerFunc
+}
+
+type webhookHandlers struct {
+	cfg *config.Config
+	webhookUseCase usecase.WebhookUseCase
+	logger logger.Logger
+}
+
+// Create godoc
+// @Summary Create webhook
+// @Description Subscribes a url to events, the signing secret is only returned here
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param webhookCreateRequest body webhook.WebhookCreateRequest true "Create Webhook"
+// @Success 201 {object} webhook.WebhookResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/webhooks [post]
+func (w webhookHandlers) Create() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "webhookHandler.Create")
+		defer span.Finish()
+
+		webhookRequest := webhook.WebhookCreateRequest{}
+		if err := e.Bind(&webhookRequest); err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		createdWebhook, err := w.webhookUseCase.Create(ctx, webhookRequest)
+		if err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusCreated, createdWebhook)
+	}
+}
+
+// GetById godoc
+// @Summary Get by id webhook
+// @Description Get by id webhook handler
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} webhook.WebhookResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/webhooks/{id} [get]
+func (w webhookHandlers) GetById() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "webhookHandler.GetById")
+		defer span.Finish()
+
+		id, err := strconv.Atoi(e.Param("id"))
+		if err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		currentWebhook, err := w.webhookUseCase.GetById(ctx, id)
+		if err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, currentWebhook)
+	}
+}
+
+// Delete godoc
+// @Summary Delete webhook
+// @Description Delete by id webhook handler, pending deliveries are dropped
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Success 204
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/webhooks/{id} [delete]
+func (w webhookHandlers) Delete() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "webhookHandler.Delete")
+		defer span.Finish()
+
+		id, err := strconv.Atoi(e.Param("id"))
+		if err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		if err = w.webhookUseCase.Delete(ctx, id); err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.NoContent(http.StatusNoContent)
+	}
+}
+
+// GetAll godoc
+// @Summary Get all webhooks
+// @Description Get all webhooks with pagination
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param page query int false "page number" Format(page)
+// @Param limit query int false "number of elements per page" Format(limit)
+// @Success 200 {object} webhook.WebhookListResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/webhooks [get]
+func (w webhookHandlers) GetAll() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "webhookHandler.GetAll")
+		defer span.Finish()
+
+		webhookList, err := w.webhookUseCase.GetAll(ctx, getPageableRequest(e))
+		if err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, webhookList)
+	}
+}
+
+// GetDeliveries godoc
+// @Summary Get webhook deliveries
+// @Description Get deliveries of a webhook with pagination, newest first
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param page query int false "page number" Format(page)
+// @Param limit query int false "number of elements per page" Format(limit)
+// @Success 200 {object} webhook.WebhookDeliveryListResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/webhooks/{id}/deliveries [get]
+func (w webhookHandlers) GetDeliveries() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "webhookHandler.GetDeliveries")
+		defer span.Finish()
+
+		id, err := strconv.Atoi(e.Param("id"))
+		if err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		deliveryList, err := w.webhookUseCase.GetDeliveries(ctx, id, getPageableRequest(e))
+		if err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, deliveryList)
+	}
+}
+
+// GetDelivery godoc
+// @Summary Get webhook delivery
+// @Description Get a delivery with all of its attempts and responses
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param deliveryId path int true "deliveryId"
+// @Success 200 {object} webhook.WebhookDeliveryResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/webhooks/{id}/deliveries/{deliveryId} [get]
+func (w webhookHandlers) GetDelivery() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "webhookHandler.GetDelivery")
+		defer span.Finish()
+
+		id, deliveryId, err := getDeliveryParams(e)
+		if err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		delivery, err := w.webhookUseCase.GetDelivery(ctx, id, deliveryId)
+		if err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusOK, delivery)
+	}
+}
+
+// ReplayDelivery godoc
+// @Summary Replay webhook delivery
+// @Description Queues a delivery to be sent again
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param deliveryId path int true "deliveryId"
+// @Success 202 {object} webhook.WebhookDeliveryResponse
+// @Failure 401 {object} util.HttpResponse
+// @Failure 403 {object} util.HttpResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/webhooks/{id}/deliveries/{deliveryId}/replay [post]
+func (w webhookHandlers) ReplayDelivery() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		span, ctx := opentracing.StartSpanFromContext(util.GetRequestCtx(e), "webhookHandler.ReplayDelivery")
+		defer span.Finish()
+
+		id, deliveryId, err := getDeliveryParams(e)
+		if err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			return e.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		delivery, err := w.webhookUseCase.ReplayDelivery(ctx, id, deliveryId)
+		if err != nil {
+			util.PrepareLogging(e, w.logger, err)
+			resp := util.ParseError(err)
+			return e.JSON(resp.Status(), resp)
+		}
+
+		return e.JSON(http.StatusAccepted, delivery)
+	}
+}
+
+func getPageableRequest(e echo.Context) *webhook.WebhookPageableRequest {
+	pageableRequest := &webhook.WebhookPageableRequest{}
+	if page, err := strconv.Atoi(e.QueryParam("page")); err == nil {
+		pageableRequest.Page = page
+	}
+	if limit, err := strconv.Atoi(e.QueryParam("limit")); err == nil {
+		pageableRequest.Size = limit
+	}
+
+	return pageableRequest
+}
+
+func getDeliveryParams(e echo.Context) (int, int64, error) {
+	id, err := strconv.Atoi(e.Param("id"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	deliveryId, err := strconv.ParseInt(e.Param("deliveryId"), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return id, deliveryId, nil
+}
+
+func NewWebhookHandler(cfg *config.Config, webhookUseCase usecase.WebhookUseCase, logger logger.Logger) WebhookHandlers {
+	return &webhookHandlers{
+		cfg: cfg,
+		webhookUseCase: webhookUseCase,
+		logger: logger,
+	}
+}
@@ -0,0 +1,67 @@
+package mapping
+
+import (
+	"encoding/json"
+	"github.com/sefikcan/kanbersky.ca/internal/dto/response/webhook"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/entity"
+)
+
+func MapDto(s entity.Subscription) *webhook.WebhookResponse {
+	return &webhook.WebhookResponse{
+		ID: s.ID,
+		URL: s.URL,
+		Events: s.EventList(),
+		Active: s.Active,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+func MapListDto(subscriptions []entity.Subscription) []*webhook.WebhookResponse {
+	webhookResp := make([]*webhook.WebhookResponse, 0, len(subscriptions))
+	for _, s := range subscriptions {
+		webhookResp = append(webhookResp, MapDto(s))
+	}
+
+	return webhookResp
+}
+
+func MapDeliveryDto(d entity.Delivery) *webhook.WebhookDeliveryResponse {
+	return &webhook.WebhookDeliveryResponse{
+		ID: d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID: d.EventID,
+		EventType: d.EventType,
+		Payload: json.RawMessage(d.Payload),
+		Status: d.Status,
+		Attempts: d.Attempts,
+		NextAttemptAt: d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError: d.LastError,
+		DeliveredAt: d.DeliveredAt,
+		CreatedAt: d.CreatedAt,
+	}
+}
+
+func MapDeliveryListDto(deliveries []entity.Delivery) []*webhook.WebhookDeliveryResponse {
+	deliveryResp := make([]*webhook.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		deliveryResp = append(deliveryResp, MapDeliveryDto(d))
+	}
+
+	return deliveryResp
+}
+
+func MapAttemptListDto(attempts []entity.DeliveryAttempt) []*webhook.WebhookDeliveryAttemptResponse {
+	attemptResp := make([]*webhook.WebhookDeliveryAttemptResponse, 0, len(attempts))
+	for _, a := range attempts {
+		attemptResp = append(attemptResp, &webhook.WebhookDeliveryAttemptResponse{
+			StatusCode: a.StatusCode,
+			ResponseBody: a.ResponseBody,
+			Error: a.Error,
+			DurationMs: a.DurationMs,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+
+	return attemptResp
+}
@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	audit "github.com/sefikcan/kanbersky.ca/internal/audit/entity"
+	auditRepository "github.com/sefikcan/kanbersky.ca/internal/audit/repository"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"time"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, subscription entity.Subscription) (entity.Subscription, error)
+	GetById(ctx context.Context, id int) (entity.Subscription, error)
+	Delete(ctx context.Context, id int) error
+	GetCount(ctx context.Context) (int64, error)
+	GetAll(ctx context.Context, query util.Pagination) ([]entity.Subscription, error)
+	GetActive(ctx context.Context) ([]entity.Subscription, error)
+
+	CreateDeliveries(ctx context.Context, deliveries []entity.Delivery) error
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.Delivery, error)
+	GetDeliveryById(ctx context.Context, id int64) (entity.Delivery, error)
+	GetDeliveryCount(ctx context.Context, subscriptionId int) (int64, error)
+	GetDeliveries(ctx context.Context, subscriptionId int, query util.Pagination) ([]entity.Delivery, error)
+	GetAttempts(ctx context.Context, deliveryId int64) ([]entity.DeliveryAttempt, error)
+	SaveAttempt(ctx context.Context, delivery entity.Delivery, attempt entity.DeliveryAttempt) error
+	ResetDelivery(ctx context.Context, id int64) error
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func (w webhookRepository) Create(ctx context.Context, subscription entity.Subscription) (entity.Subscription, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.Create")
+	defer span.Finish()
+
+	err := w.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		if result := tx.Create(&subscription); result.Error != nil {
+			return errors.Wrap(result.Error, "webhookRepository.Create.DbError")
+		}
+
+		return auditRepository.AddAuditLog(tx, entity.AuditEntityType, subscription.ID, audit.ActionCreate, nil, subscription)
+	})
+	if err != nil {
+		return entity.Subscription{}, err
+	}
+
+	return subscription, nil
+}
+
+func (w webhookRepository) GetById(ctx context.Context, id int) (entity.Subscription, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.GetById")
+	defer span.Finish()
+
+	subscription := entity.Subscription{}
+	if err := w.db.WithContext(spanContext).Where(`id = ?`, id).First(&subscription).Error; err != nil {
+		return entity.Subscription{}, errors.Wrap(err, "webhookRepository.GetById.DbError")
+	}
+
+	return subscription, nil
+}
+
+func (w webhookRepository) Delete(ctx context.Context, id int) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.Delete")
+	defer span.Finish()
+
+	return w.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		before := entity.Subscription{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(`id = ?`, id).First(&before).Error; err != nil {
+			return errors.Wrap(err, "webhookRepository.Delete.GetForUpdate")
+		}
+		if result := tx.Delete(&entity.Subscription{ID: id}); result.Error != nil {
+			return errors.Wrap(result.Error, "webhookRepository.Delete.DbError")
+		}
+
+		return auditRepository.AddAuditLog(tx, entity.AuditEntityType, id, audit.ActionDelete, before, nil)
+	})
+}
+
+func (w webhookRepository) GetCount(ctx context.Context) (int64, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.GetCount")
+	defer span.Finish()
+
+	var totalCount int64
+	if err := w.db.WithContext(spanContext).Model(&entity.Subscription{}).Count(&totalCount).Error; err != nil {
+		return 0, errors.Wrap(err, "webhookRepository.GetCount.DbError")
+	}
+
+	return totalCount, nil
+}
+
+func (w webhookRepository) GetAll(ctx context.Context, query util.Pagination) ([]entity.Subscription, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.GetAll")
+	defer span.Finish()
+
+	var subscriptions []entity.Subscription
+	if err := w.db.WithContext(spanContext).Offset(query.GetOffset()).Limit(query.GetLimit()).Order("id desc").Find(&subscriptions).Error; err != nil {
+		return nil, errors.Wrap(err, "webhookRepository.GetAll.DbError")
+	}
+
+	return subscriptions, nil
+}
+
+func (w webhookRepository) GetActive(ctx context.Context) ([]entity.Subscription, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.GetActive")
+	defer span.Finish()
+
+	var subscriptions []entity.Subscription
+	if err := w.db.WithContext(spanContext).Where(`active = ?`, true).Find(&subscriptions).Error; err != nil {
+		return nil, errors.Wrap(err, "webhookRepository.GetActive.DbError")
+	}
+
+	return subscriptions, nil
+}
+
+// CreateDeliveries ignores deliveries that already exist for the same subscription and event,
+// so an event relayed more than once is still delivered once per subscription.
+func (w webhookRepository) CreateDeliveries(ctx context.Context, deliveries []entity.Delivery) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.CreateDeliveries")
+	defer span.Finish()
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if result := w.db.WithContext(spanContext).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries); result.Error != nil {
+		return errors.Wrap(result.Error, "webhookRepository.CreateDeliveries.DbError")
+	}
+
+	return nil
+}
+
+func (w webhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.Delivery, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.ClaimDeliveries")
+	defer span.Finish()
+
+	var deliveries []entity.Delivery
+	err := w.db.WithContext(spanContext).Raw(`
+		UPDATE webhook_deliveries SET locked_until = ?, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= now() AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, time.Now().UTC().Add(lease), entity.DeliveryStatusPending, limit).Scan(&deliveries).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "webhookRepository.ClaimDeliveries.DbError")
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+
+	return deliveries, nil
+}
+
+func (w webhookRepository) GetDeliveryById(ctx context.Context, id int64) (entity.Delivery, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.GetDeliveryById")
+	defer span.Finish()
+
+	delivery := entity.Delivery{}
+	if err := w.db.WithContext(spanContext).Where(`id = ?`, id).First(&delivery).Error; err != nil {
+		return entity.Delivery{}, errors.Wrap(err, "webhookRepository.GetDeliveryById.DbError")
+	}
+
+	return delivery, nil
+}
+
+func (w webhookRepository) GetDeliveryCount(ctx context.Context, subscriptionId int) (int64, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.GetDeliveryCount")
+	defer span.Finish()
+
+	var totalCount int64
+	if err := w.db.WithContext(spanContext).Model(&entity.Delivery{}).Where(`subscription_id = ?`, subscriptionId).Count(&totalCount).Error; err != nil {
+		return 0, errors.Wrap(err, "webhookRepository.GetDeliveryCount.DbError")
+	}
+
+	return totalCount, nil
+}
+
+func (w webhookRepository) GetDeliveries(ctx context.Context, subscriptionId int, query util.Pagination) ([]entity.Delivery, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.GetDeliveries")
+	defer span.Finish()
+
+	var deliveries []entity.Delivery
+	err := w.db.WithContext(spanContext).Where(`subscription_id = ?`, subscriptionId).
+		Offset(query.GetOffset()).Limit(query.GetLimit()).Order("id desc").Find(&deliveries).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "webhookRepository.GetDeliveries.DbError")
+	}
+
+	return deliveries, nil
+}
+
+func (w webhookRepository) GetAttempts(ctx context.Context, deliveryId int64) ([]entity.DeliveryAttempt, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.GetAttempts")
+	defer span.Finish()
+
+	var attempts []entity.DeliveryAttempt
+	if err := w.db.WithContext(spanContext).Where(`delivery_id = ?`, deliveryId).Order("id asc").Find(&attempts).Error; err != nil {
+		return nil, errors.Wrap(err, "webhookRepository.GetAttempts.DbError")
+	}
+
+	return attempts, nil
+}
+
+func (w webhookRepository) SaveAttempt(ctx context.Context, delivery entity.Delivery, attempt entity.DeliveryAttempt) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.SaveAttempt")
+	defer span.Finish()
+
+	return w.db.WithContext(spanContext).Transaction(func(tx *gorm.DB) error {
+		if result := tx.Create(&attempt); result.Error != nil {
+			return errors.Wrap(result.Error, "webhookRepository.SaveAttempt.CreateAttempt.DbError")
+		}
+
+		err := tx.Model(&entity.Delivery{ID: delivery.ID}).Updates(map[string]interface{}{
+			"status": delivery.Status,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"locked_until": nil,
+			"last_status_code": delivery.LastStatusCode,
+			"last_error": delivery.LastError,
+			"delivered_at": delivery.DeliveredAt,
+		}).Error
+		if err != nil {
+			return errors.Wrap(err, "webhookRepository.SaveAttempt.UpdateDelivery.DbError")
+		}
+
+		return nil
+	})
+}
+
+func (w webhookRepository) ResetDelivery(ctx context.Context, id int64) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookRepository.ResetDelivery")
+	defer span.Finish()
+
+	err := w.db.WithContext(spanContext).Model(&entity.Delivery{ID: id}).Updates(map[string]interface{}{
+		"status": entity.DeliveryStatusPending,
+		"attempts": 0,
+		"next_attempt_at": time.Now().UTC(),
+		"locked_until": nil,
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "webhookRepository.ResetDelivery.DbError")
+	}
+
+	return nil
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{
+		db: db,
+	}
+}
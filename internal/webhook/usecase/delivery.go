@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultBatchSize = 50
+	defaultMaxAttempts = 8
+	defaultPollInterval = 1
+	defaultTimeout = 10
+	minRetryBackoff = time.Second * 5
+	maxRetryBackoff = time.Hour
+	claimLease = time.Minute * 2
+	maxResponseBodySize = 4 << 10
+)
+
+type WebhookDeliveryUseCase interface {
+	Run(ctx context.Context)
+	DeliverPending(ctx context.Context) (int, error)
+}
+
+type webhookDeliveryUseCase struct {
+	cfg *config.Config
+	webhookRepository repository.WebhookRepository
+	httpClient *http.Client
+	logger logger.Logger
+}
+
+func (w webhookDeliveryUseCase) Run(ctx context.Context) {
+	pollInterval := w.cfg.Webhook.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	w.logger.Infof("Webhook delivery worker started, PollInterval: %ds", pollInterval)
+	for {
+		delivered, err := w.DeliverPending(ctx)
+		if err != nil {
+			w.logger.Errorf("webhookDeliveryUseCase.Run: %s", err)
+		}
+		if delivered == w.batchSize() {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Webhook delivery worker stopped")
+			return
+		case <-time.After(time.Second * pollInterval):
+		}
+	}
+}
+
+func (w webhookDeliveryUseCase) DeliverPending(ctx context.Context) (int, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookDeliveryUseCase.DeliverPending")
+	defer span.Finish()
+
+	deliveries, err := w.webhookRepository.ClaimDeliveries(spanContext, w.batchSize(), claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		w.deliver(spanContext, delivery)
+	}
+
+	return len(deliveries), nil
+}
+
+func (w webhookDeliveryUseCase) deliver(ctx context.Context, delivery entity.Delivery) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookDeliveryUseCase.deliver")
+	defer span.Finish()
+
+	// a failed lookup is recorded as a failed attempt, so the claim is released and the delivery retried or,
+	// when the subscription is gone, given up
+	var attempt entity.DeliveryAttempt
+	subscription, lookupErr := w.webhookRepository.GetById(spanContext, delivery.SubscriptionID)
+	if lookupErr != nil {
+		w.logger.Errorf("webhookDeliveryUseCase.deliver.GetById, DeliveryId: %d, Error: %s", delivery.ID, lookupErr)
+		attempt = entity.DeliveryAttempt{Error: "subscription lookup failed: " + lookupErr.Error()}
+	} else {
+		attempt = w.send(spanContext, subscription, delivery)
+	}
+	attempt.DeliveryID = delivery.ID
+
+	delivery.LastStatusCode = attempt.StatusCode
+	delivery.LastError = attempt.Error
+	switch {
+	case attempt.Error == "":
+		deliveredAt := time.Now().UTC()
+		delivery.Status = entity.DeliveryStatusDelivered
+		delivery.DeliveredAt = &deliveredAt
+	case errors.Is(lookupErr, gorm.ErrRecordNotFound), delivery.Attempts >= w.maxAttempts():
+		delivery.Status = entity.DeliveryStatusFailed
+		w.logger.Warnf("Webhook delivery gave up, DeliveryId: %d, Url: %s, Attempts: %d, Error: %s", delivery.ID, subscription.URL, delivery.Attempts, attempt.Error)
+	default:
+		delivery.NextAttemptAt = time.Now().UTC().Add(retryBackoff(delivery.Attempts))
+	}
+
+	if err := w.webhookRepository.SaveAttempt(spanContext, delivery, attempt); err != nil {
+		w.logger.Errorf("webhookDeliveryUseCase.deliver.SaveAttempt, DeliveryId: %d, Error: %s", delivery.ID, err)
+	}
+}
+
+// send posts the payload once. Anything but a 2xx answer is a failed attempt.
+func (w webhookDeliveryUseCase) send(ctx context.Context, subscription entity.Subscription, delivery entity.Delivery) entity.DeliveryAttempt {
+	start := time.Now()
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return entity.DeliveryAttempt{Error: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Kanbersky-Webhooks/"+w.cfg.Server.AppVersion)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, time.Now().Unix(), body))
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return entity.DeliveryAttempt{Error: err.Error(), DurationMs: time.Since(start).Milliseconds()}
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	attempt := entity.DeliveryAttempt{
+		StatusCode: resp.StatusCode,
+		ResponseBody: string(responseBody),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		attempt.Error = fmt.Sprintf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return attempt
+}
+
+func (w webhookDeliveryUseCase) batchSize() int {
+	if w.cfg.Webhook.BatchSize <= 0 {
+		return defaultBatchSize
+	}
+
+	return w.cfg.Webhook.BatchSize
+}
+
+func (w webhookDeliveryUseCase) maxAttempts() int {
+	if w.cfg.Webhook.MaxAttempts <= 0 {
+		return defaultMaxAttempts
+	}
+
+	return w.cfg.Webhook.MaxAttempts
+}
+
+func retryBackoff(attempts int) time.Duration {
+	backoff := minRetryBackoff
+	for i := 1; i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+
+	return backoff
+}
+
+// NewWebhookDeliveryUseCase uses a default client with the configured timeout when httpClient is nil, it
+// refuses to connect to private addresses unless webhook.allowprivatenetworks is set.
+func NewWebhookDeliveryUseCase(cfg *config.Config, webhookRepository repository.WebhookRepository, httpClient *http.Client, logger logger.Logger) WebhookDeliveryUseCase {
+	if httpClient == nil {
+		timeout := cfg.Webhook.Timeout
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		httpClient = newDeliveryClient(time.Second*timeout, cfg.Webhook.AllowPrivateNetworks)
+	}
+
+	return &webhookDeliveryUseCase{
+		cfg: cfg,
+		webhookRepository: webhookRepository,
+		httpClient: httpClient,
+		logger: logger,
+	}
+}
@@ -0,0 +1,406 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef"
+
+// memoryRepository keeps subscriptions, deliveries and attempts in memory, ClaimDeliveries follows the
+// claim rules of the sql of webhookRepository.
+type memoryRepository struct {
+	repository.WebhookRepository
+	mu sync.Mutex
+	subscriptions map[int]entity.Subscription
+	deliveries map[int64]entity.Delivery
+	attempts []entity.DeliveryAttempt
+	lookupErr error
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		subscriptions: map[int]entity.Subscription{},
+		deliveries: map[int64]entity.Delivery{},
+	}
+}
+
+func (m *memoryRepository) GetById(_ context.Context, id int) (entity.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.lookupErr != nil {
+		return entity.Subscription{}, m.lookupErr
+	}
+	subscription, ok := m.subscriptions[id]
+	if !ok {
+		return entity.Subscription{}, gorm.ErrRecordNotFound
+	}
+
+	return subscription, nil
+}
+
+func (m *memoryRepository) ClaimDeliveries(_ context.Context, limit int, lease time.Duration) ([]entity.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	var claimed []entity.Delivery
+	for id := int64(1); id <= int64(len(m.deliveries)) && len(claimed) < limit; id++ {
+		delivery, ok := m.deliveries[id]
+		if !ok || delivery.Status != entity.DeliveryStatusPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		if delivery.LockedUntil != nil && delivery.LockedUntil.After(now) {
+			continue
+		}
+		lockedUntil := now.Add(lease)
+		delivery.LockedUntil = &lockedUntil
+		delivery.Attempts++
+		m.deliveries[id] = delivery
+		claimed = append(claimed, delivery)
+	}
+
+	return claimed, nil
+}
+
+func (m *memoryRepository) GetDeliveryById(_ context.Context, id int64) (entity.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delivery, ok := m.deliveries[id]
+	if !ok {
+		return entity.Delivery{}, gorm.ErrRecordNotFound
+	}
+
+	return delivery, nil
+}
+
+func (m *memoryRepository) GetAttempts(_ context.Context, deliveryId int64) ([]entity.DeliveryAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var attempts []entity.DeliveryAttempt
+	for _, attempt := range m.attempts {
+		if attempt.DeliveryID == deliveryId {
+			attempts = append(attempts, attempt)
+		}
+	}
+
+	return attempts, nil
+}
+
+func (m *memoryRepository) SaveAttempt(_ context.Context, delivery entity.Delivery, attempt entity.DeliveryAttempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt.ID = int64(len(m.attempts) + 1)
+	m.attempts = append(m.attempts, attempt)
+
+	stored := m.deliveries[delivery.ID]
+	stored.Status = delivery.Status
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.LockedUntil = nil
+	stored.LastStatusCode = delivery.LastStatusCode
+	stored.LastError = delivery.LastError
+	stored.DeliveredAt = delivery.DeliveredAt
+	m.deliveries[delivery.ID] = stored
+
+	return nil
+}
+
+func (m *memoryRepository) ResetDelivery(_ context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delivery := m.deliveries[id]
+	delivery.Status = entity.DeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC()
+	delivery.LockedUntil = nil
+	m.deliveries[id] = delivery
+
+	return nil
+}
+
+func (m *memoryRepository) addDelivery(subscriptionId int, payload string) entity.Delivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delivery := entity.Delivery{
+		ID: int64(len(m.deliveries) + 1),
+		SubscriptionID: subscriptionId,
+		EventID: strconv.Itoa(len(m.deliveries) + 1),
+		EventType: "currency.created",
+		Payload: payload,
+		Status: entity.DeliveryStatusPending,
+		NextAttemptAt: time.Now().UTC().Add(-time.Second),
+	}
+	m.deliveries[delivery.ID] = delivery
+
+	return delivery
+}
+
+func (m *memoryRepository) delivery(id int64) entity.Delivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deliveries[id]
+}
+
+// makeDue moves the next attempt of a delivery waiting for its backoff to now.
+func (m *memoryRepository) makeDue(id int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delivery := m.deliveries[id]
+	delivery.NextAttemptAt = time.Now().UTC().Add(-time.Second)
+	m.deliveries[id] = delivery
+}
+
+type receivedRequest struct {
+	header http.Header
+	body string
+}
+
+// receiver answers with the queued status codes, 200 once they are used up.
+type receiver struct {
+	mu sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: string(body)})
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "answered %d", status)
+}
+
+func newTestDelivery(t *testing.T, cfg *config.Config, statuses ...int) (*memoryRepository, *receiver, WebhookDeliveryUseCase, WebhookUseCase) {
+	t.Helper()
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	rcv := &receiver{statuses: statuses}
+	server := httptest.NewServer(rcv)
+	t.Cleanup(server.Close)
+
+	repo := newMemoryRepository()
+	repo.subscriptions[1] = entity.Subscription{ID: 1, URL: server.URL, Events: entity.AllEvents, Secret: testSecret, Active: true}
+
+	return repo, rcv, NewWebhookDeliveryUseCase(cfg, repo, server.Client(), l), NewWebhookUseCase(cfg, repo, l)
+}
+
+func verifySignature(t *testing.T, header string, body string) {
+	t.Helper()
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		switch {
+		case strings.HasPrefix(part, "t="):
+			timestamp = strings.TrimPrefix(part, "t=")
+		case strings.HasPrefix(part, "v1="):
+			signature = strings.TrimPrefix(part, "v1=")
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(timestamp + "." + body))
+	if want := hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("%s = %q, want v1=%s", HeaderSignature, header, want)
+	}
+	if seconds, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(seconds, 0)) > time.Minute {
+		t.Errorf("signature timestamp %q is not the send time", timestamp)
+	}
+}
+
+func TestDeliverySignsThePayload(t *testing.T) {
+	repo, rcv, worker, _ := newTestDelivery(t, &config.Config{})
+	delivery := repo.addDelivery(1, `{"id":1,"iso_code":"EUR"}`)
+
+	if delivered, err := worker.DeliverPending(context.Background()); err != nil || delivered != 1 {
+		t.Fatalf("DeliverPending = %d, %v", delivered, err)
+	}
+
+	if len(rcv.requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(rcv.requests))
+	}
+	request := rcv.requests[0]
+	if request.body != delivery.Payload {
+		t.Errorf("body = %s, want %s", request.body, delivery.Payload)
+	}
+	if request.header.Get(HeaderEvent) != delivery.EventType || request.header.Get(HeaderDelivery) != "1" {
+		t.Errorf("event headers = %q, %q", request.header.Get(HeaderEvent), request.header.Get(HeaderDelivery))
+	}
+	verifySignature(t, request.header.Get(HeaderSignature), request.body)
+
+	stored := repo.delivery(delivery.ID)
+	if stored.Status != entity.DeliveryStatusDelivered || stored.DeliveredAt == nil || stored.LockedUntil != nil {
+		t.Errorf("delivery = %+v, want delivered and released", stored)
+	}
+}
+
+func TestDeliveryBacksOffAfterFailure(t *testing.T) {
+	repo, rcv, worker, _ := newTestDelivery(t, &config.Config{}, http.StatusInternalServerError)
+	delivery := repo.addDelivery(1, `{}`)
+
+	before := time.Now().UTC()
+	if _, err := worker.DeliverPending(context.Background()); err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+
+	stored := repo.delivery(delivery.ID)
+	if stored.Status != entity.DeliveryStatusPending || stored.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("delivery = %+v, want pending after a 500", stored)
+	}
+	if wait := stored.NextAttemptAt.Sub(before); wait < minRetryBackoff || wait > minRetryBackoff+time.Second {
+		t.Errorf("next attempt in %s, want %s", wait, minRetryBackoff)
+	}
+
+	// the backoff keeps the delivery from being claimed again right away
+	if delivered, _ := worker.DeliverPending(context.Background()); delivered != 0 {
+		t.Errorf("claimed %d deliveries during the backoff", delivered)
+	}
+
+	repo.makeDue(delivery.ID)
+	if _, err := worker.DeliverPending(context.Background()); err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+	if stored = repo.delivery(delivery.ID); stored.Status != entity.DeliveryStatusDelivered || stored.Attempts != 2 {
+		t.Errorf("delivery = %+v, want delivered on the second attempt", stored)
+	}
+	if len(rcv.requests) != 2 {
+		t.Errorf("receiver got %d requests, want 2", len(rcv.requests))
+	}
+}
+
+func TestRetryBackoffDoubles(t *testing.T) {
+	tests := map[int]time.Duration{1: minRetryBackoff, 2: minRetryBackoff * 2, 3: minRetryBackoff * 4, 100: maxRetryBackoff}
+	for attempts, want := range tests {
+		if got := retryBackoff(attempts); got != want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestDeliveryRecordsAttemptsAndGivesUp(t *testing.T) {
+	repo, _, worker, webhooks := newTestDelivery(t, &config.Config{Webhook: config.WebhookConfig{MaxAttempts: 2}}, http.StatusBadGateway, http.StatusServiceUnavailable)
+	delivery := repo.addDelivery(1, `{}`)
+
+	for i := 0; i < 2; i++ {
+		repo.makeDue(delivery.ID)
+		if _, err := worker.DeliverPending(context.Background()); err != nil {
+			t.Fatalf("DeliverPending: %v", err)
+		}
+	}
+
+	stored := repo.delivery(delivery.ID)
+	if stored.Status != entity.DeliveryStatusFailed {
+		t.Errorf("status = %s, want %s after max attempts", stored.Status, entity.DeliveryStatusFailed)
+	}
+
+	deliveryResponse, err := webhooks.GetDelivery(context.Background(), 1, delivery.ID)
+	if err != nil {
+		t.Fatalf("GetDelivery: %v", err)
+	}
+	if len(deliveryResponse.AttemptHistory) != 2 {
+		t.Fatalf("recorded %d attempts, want 2", len(deliveryResponse.AttemptHistory))
+	}
+	for i, status := range []int{http.StatusBadGateway, http.StatusServiceUnavailable} {
+		attempt := deliveryResponse.AttemptHistory[i]
+		if attempt.StatusCode != status || attempt.ResponseBody != fmt.Sprintf("answered %d", status) || attempt.Error == "" {
+			t.Errorf("attempt %d = %+v, want status %d with its response", i, attempt, status)
+		}
+	}
+}
+
+func TestReplayDeliversAgain(t *testing.T) {
+	repo, rcv, worker, webhooks := newTestDelivery(t, &config.Config{})
+	delivery := repo.addDelivery(1, `{}`)
+	if _, err := worker.DeliverPending(context.Background()); err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+
+	replayed, err := webhooks.ReplayDelivery(context.Background(), 1, delivery.ID)
+	if err != nil {
+		t.Fatalf("ReplayDelivery: %v", err)
+	}
+	if replayed.Status != entity.DeliveryStatusPending || replayed.Attempts != 0 {
+		t.Errorf("replayed = %+v, want pending with no attempts", replayed)
+	}
+
+	if _, err = worker.DeliverPending(context.Background()); err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+	if len(rcv.requests) != 2 {
+		t.Errorf("receiver got %d requests, want 2", len(rcv.requests))
+	}
+	if attempts, _ := repo.GetAttempts(context.Background(), delivery.ID); len(attempts) != 2 {
+		t.Errorf("replay dropped the attempt history, %d attempts", len(attempts))
+	}
+
+	if _, err = webhooks.ReplayDelivery(context.Background(), 2, delivery.ID); util.ParseError(err).Status() != http.StatusNotFound {
+		t.Errorf("replay through another webhook = %v, want 404", err)
+	}
+}
+
+func TestDeliveryReleasesFailedLookups(t *testing.T) {
+	repo, rcv, worker, _ := newTestDelivery(t, &config.Config{})
+	gone := repo.addDelivery(2, `{}`)
+
+	repo.lookupErr = errors.New("connection refused")
+	retried := repo.addDelivery(1, `{}`)
+	if _, err := worker.DeliverPending(context.Background()); err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+
+	// a lookup failure before the subscription is known is retried with a backoff
+	if stored := repo.delivery(retried.ID); stored.Status != entity.DeliveryStatusPending || stored.LockedUntil != nil || !stored.NextAttemptAt.After(time.Now()) {
+		t.Errorf("delivery = %+v, want pending, released and backed off", stored)
+	}
+
+	repo.lookupErr = nil
+	repo.makeDue(gone.ID)
+	repo.makeDue(retried.ID)
+	if _, err := worker.DeliverPending(context.Background()); err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+
+	// the deliveries of a deleted subscription are given up instead of staying claimed
+	if stored := repo.delivery(gone.ID); stored.Status != entity.DeliveryStatusFailed || stored.LockedUntil != nil || stored.LastError == "" {
+		t.Errorf("delivery = %+v, want failed and released", stored)
+	}
+	if stored := repo.delivery(retried.ID); stored.Status != entity.DeliveryStatusDelivered {
+		t.Errorf("delivery = %+v, want delivered once the lookup works", stored)
+	}
+	if len(rcv.requests) != 1 {
+		t.Errorf("receiver got %d requests, want 1", len(rcv.requests))
+	}
+}
@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/event"
+	"time"
+)
+
+type webhookDispatcher struct {
+	webhookRepository repository.WebhookRepository
+}
+
+// Publish fans an event out into one pending delivery per matching subscription,
+// the delivery worker sends them. Subscriptions are managed by the platform and get the events of every
+// tenant, the payloads name their tenant in tenant_id.
+func (w webhookDispatcher) Publish(ctx context.Context, e event.Event) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookDispatcher.Publish")
+	defer span.Finish()
+
+	subscriptions, err := w.webhookRepository.GetActive(spanContext)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "webhookDispatcher.Publish.Json.Marshal")
+	}
+
+	var deliveries []entity.Delivery
+	for _, subscription := range subscriptions {
+		if !subscription.Matches(e.Type) {
+			continue
+		}
+
+		deliveries = append(deliveries, entity.Delivery{
+			SubscriptionID: subscription.ID,
+			EventID: e.ID,
+			EventType: e.Type,
+			Payload: string(payload),
+			Status: entity.DeliveryStatusPending,
+			NextAttemptAt: time.Now().UTC(),
+		})
+	}
+
+	return w.webhookRepository.CreateDeliveries(spanContext, deliveries)
+}
+
+func NewWebhookDispatcher(webhookRepository repository.WebhookRepository) event.EventPublisher {
+	return &webhookDispatcher{
+		webhookRepository: webhookRepository,
+	}
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"github.com/pkg/errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrUnsupportedScheme = errors.New("webhook url must use http or https")
+	ErrPrivateAddress = errors.New("webhook url resolves to a private address")
+)
+
+// sharedAddressSpace is the carrier grade nat range, net.IP.IsPrivate leaves it out
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// validateURL checks what is known before delivery, a host name is checked once it is resolved.
+func validateURL(rawURL string, allowPrivateNetworks bool) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ErrUnsupportedScheme
+	}
+	if parsed.Hostname() == "" {
+		return errors.New("webhook url has no host")
+	}
+	if ip := net.ParseIP(parsed.Hostname()); ip != nil && !allowPrivateNetworks && isPrivateAddress(ip) {
+		return ErrPrivateAddress
+	}
+
+	return nil
+}
+
+// isPrivateAddress tells the addresses a receiver must not be reachable at, loopback, link-local with the
+// cloud metadata endpoints, rfc1918 and unique local, shared and unspecified addresses.
+func isPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsPrivate() ||
+		ip.IsUnspecified() || ip.IsMulticast() || sharedAddressSpace.Contains(ip)
+}
+
+// publicDialControl runs after the host is resolved, so the check covers every address the name resolves to,
+// redirects and names that change their address between lookups.
+func publicDialControl(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isPrivateAddress(ip) {
+		return errors.Wrapf(ErrPrivateAddress, "dial %s", address)
+	}
+
+	return nil
+}
+
+// newDeliveryClient does not use the environment proxy, a proxy would hide the address of the receiver
+// from the dial check.
+func newDeliveryClient(timeout time.Duration, allowPrivateNetworks bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: time.Second * 30}
+	if !allowPrivateNetworks {
+		dialer.Control = publicDialControl
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout: time.Second * 90,
+			ForceAttemptHTTP2: true,
+		},
+	}
+}
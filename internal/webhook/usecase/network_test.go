@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/webhook"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/entity"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsPrivateAddress(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1": true,
+		"::1": true,
+		"169.254.169.254": true,
+		"fe80::1": true,
+		"10.1.2.3": true,
+		"172.16.0.1": true,
+		"192.168.1.1": true,
+		"fd00::1": true,
+		"100.64.0.1": true,
+		"0.0.0.0": true,
+		"93.184.216.34": false,
+		"2606:4700::1111": false,
+	}
+
+	for address, private := range tests {
+		if got := isPrivateAddress(net.ParseIP(address)); got != private {
+			t.Errorf("isPrivateAddress(%s) = %t, want %t", address, got, private)
+		}
+	}
+}
+
+func TestCreateValidatesURL(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+	webhooks := NewWebhookUseCase(cfg, newMemoryRepository(), l)
+
+	for _, url := range []string{"ftp://example.com/hook", "file:///etc/passwd", "http://127.0.0.1:8080/hook", "http://169.254.169.254/latest/meta-data", "https://[::1]/hook"} {
+		_, err := webhooks.Create(context.Background(), request.WebhookCreateRequest{URL: url, Events: []string{entity.AllEvents}})
+		if util.ParseError(err).Status() != http.StatusBadRequest {
+			t.Errorf("%s: err = %v, want 400", url, err)
+		}
+	}
+}
+
+func TestDeliveryRefusesPrivateAddresses(t *testing.T) {
+	tests := []struct {
+		name string
+		allowPrivateNetworks bool
+		status string
+	}{
+		{name: "private networks refused", status: entity.DeliveryStatusPending},
+		{name: "private networks allowed", allowPrivateNetworks: true, status: entity.DeliveryStatusDelivered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Webhook: config.WebhookConfig{AllowPrivateNetworks: tt.allowPrivateNetworks}}
+			cfg.Logger.Level = "fatal"
+			l := logger.NewLogger(cfg)
+			l.InitLogger()
+
+			rcv := &receiver{}
+			server := httptest.NewServer(rcv)
+			defer server.Close()
+
+			// the receiver listens on loopback, the default client has to refuse it unless allowed
+			repo := newMemoryRepository()
+			repo.subscriptions[1] = entity.Subscription{ID: 1, URL: server.URL, Events: entity.AllEvents, Secret: testSecret, Active: true}
+			delivery := repo.addDelivery(1, `{}`)
+			if _, err := NewWebhookDeliveryUseCase(cfg, repo, nil, l).DeliverPending(context.Background()); err != nil {
+				t.Fatalf("DeliverPending: %v", err)
+			}
+
+			stored := repo.delivery(delivery.ID)
+			if stored.Status != tt.status {
+				t.Errorf("status = %s, want %s (%s)", stored.Status, tt.status, stored.LastError)
+			}
+			if !tt.allowPrivateNetworks && (len(rcv.requests) != 0 || !strings.Contains(stored.LastError, ErrPrivateAddress.Error())) {
+				t.Errorf("receiver got %d requests, last error %q", len(rcv.requests), stored.LastError)
+			}
+		})
+	}
+}
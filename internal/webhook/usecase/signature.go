@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+const (
+	HeaderSignature = "X-Kanbersky-Signature"
+	HeaderEvent = "X-Kanbersky-Event"
+	HeaderDelivery = "X-Kanbersky-Delivery"
+)
+
+// Sign returns the signature header value for a payload. The timestamp is part of the signed
+// content so receivers can reject replayed requests, they verify by recomputing
+// hex(HMAC-SHA256(secret, "<t>.<body>")) and comparing it with v1.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
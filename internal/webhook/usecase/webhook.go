@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	request "github.com/sefikcan/kanbersky.ca/internal/dto/request/webhook"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/webhook"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/entity"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/mapping"
+	"github.com/sefikcan/kanbersky.ca/internal/webhook/repository"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strings"
+)
+
+type WebhookUseCase interface {
+	Create(ctx context.Context, request request.WebhookCreateRequest) (*response.WebhookResponse, error)
+	GetById(ctx context.Context, id int) (*response.WebhookResponse, error)
+	Delete(ctx context.Context, id int) error
+	GetAll(ctx context.Context, request *request.WebhookPageableRequest) (response.WebhookListResponse, error)
+	GetDeliveries(ctx context.Context, id int, request *request.WebhookPageableRequest) (response.WebhookDeliveryListResponse, error)
+	GetDelivery(ctx context.Context, id int, deliveryId int64) (*response.WebhookDeliveryResponse, error)
+	ReplayDelivery(ctx context.Context, id int, deliveryId int64) (*response.WebhookDeliveryResponse, error)
+}
+
+type webhookUseCase struct {
+	cfg *config.Config
+	webhookRepository repository.WebhookRepository
+	logger logger.Logger
+}
+
+// Create returns the signing secret once, it is never part of any later response.
+func (w webhookUseCase) Create(ctx context.Context, request request.WebhookCreateRequest) (*response.WebhookResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookUseCase.Create")
+	defer span.Finish()
+
+	if err := util.ValidateStruct(&request); err != nil {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, util.BadRequest.Error(), errors.WithMessage(err, "webhookUseCase.Create.ValidateStruct"))
+	}
+	if err := validateURL(request.URL, w.cfg.Webhook.AllowPrivateNetworks); err != nil {
+		return nil, util.NewHttpResponse(http.StatusBadRequest, err.Error(), errors.WithMessage(err, "webhookUseCase.Create.validateURL"))
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		generated, err := generateSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	subscription, err := w.webhookRepository.Create(spanContext, entity.Subscription{
+		URL: request.URL,
+		Events: strings.Join(request.Events, ","),
+		Secret: secret,
+		Active: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mappedResponse := mapping.MapDto(subscription)
+	mappedResponse.Secret = secret
+
+	return mappedResponse, nil
+}
+
+func (w webhookUseCase) GetById(ctx context.Context, id int) (*response.WebhookResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookUseCase.GetById")
+	defer span.Finish()
+
+	subscription, err := w.webhookRepository.GetById(spanContext, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapDto(subscription), nil
+}
+
+func (w webhookUseCase) Delete(ctx context.Context, id int) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookUseCase.Delete")
+	defer span.Finish()
+
+	if _, err := w.webhookRepository.GetById(spanContext, id); err != nil {
+		return err
+	}
+
+	return w.webhookRepository.Delete(spanContext, id)
+}
+
+func (w webhookUseCase) GetAll(ctx context.Context, pageableRequest *request.WebhookPageableRequest) (response.WebhookListResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookUseCase.GetAll")
+	defer span.Finish()
+
+	pagination := util.Pagination{
+		Page: pageableRequest.Page,
+		Limit: pageableRequest.Size,
+	}
+
+	totalCount, err := w.webhookRepository.GetCount(spanContext)
+	if err != nil {
+		return response.WebhookListResponse{}, err
+	}
+
+	subscriptions, err := w.webhookRepository.GetAll(spanContext, pagination)
+	if err != nil {
+		return response.WebhookListResponse{}, err
+	}
+
+	return response.WebhookListResponse{
+		TotalCount: totalCount,
+		TotalPages: util.GetTotalPages(totalCount, pagination.GetLimit()),
+		Page: pagination.GetPage(),
+		Limit: pagination.GetLimit(),
+		Webhooks: mapping.MapListDto(subscriptions),
+	}, nil
+}
+
+func (w webhookUseCase) GetDeliveries(ctx context.Context, id int, pageableRequest *request.WebhookPageableRequest) (response.WebhookDeliveryListResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookUseCase.GetDeliveries")
+	defer span.Finish()
+
+	if _, err := w.webhookRepository.GetById(spanContext, id); err != nil {
+		return response.WebhookDeliveryListResponse{}, err
+	}
+
+	pagination := util.Pagination{
+		Page: pageableRequest.Page,
+		Limit: pageableRequest.Size,
+	}
+
+	totalCount, err := w.webhookRepository.GetDeliveryCount(spanContext, id)
+	if err != nil {
+		return response.WebhookDeliveryListResponse{}, err
+	}
+
+	deliveries, err := w.webhookRepository.GetDeliveries(spanContext, id, pagination)
+	if err != nil {
+		return response.WebhookDeliveryListResponse{}, err
+	}
+
+	return response.WebhookDeliveryListResponse{
+		TotalCount: totalCount,
+		TotalPages: util.GetTotalPages(totalCount, pagination.GetLimit()),
+		Page: pagination.GetPage(),
+		Limit: pagination.GetLimit(),
+		Deliveries: mapping.MapDeliveryListDto(deliveries),
+	}, nil
+}
+
+func (w webhookUseCase) GetDelivery(ctx context.Context, id int, deliveryId int64) (*response.WebhookDeliveryResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookUseCase.GetDelivery")
+	defer span.Finish()
+
+	delivery, err := w.getDelivery(spanContext, id, deliveryId)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := w.webhookRepository.GetAttempts(spanContext, deliveryId)
+	if err != nil {
+		return nil, err
+	}
+
+	mappedResponse := mapping.MapDeliveryDto(delivery)
+	mappedResponse.AttemptHistory = mapping.MapAttemptListDto(attempts)
+
+	return mappedResponse, nil
+}
+
+// ReplayDelivery queues a delivery again regardless of its status, the attempt history is kept.
+func (w webhookUseCase) ReplayDelivery(ctx context.Context, id int, deliveryId int64) (*response.WebhookDeliveryResponse, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "webhookUseCase.ReplayDelivery")
+	defer span.Finish()
+
+	if _, err := w.getDelivery(spanContext, id, deliveryId); err != nil {
+		return nil, err
+	}
+
+	if err := w.webhookRepository.ResetDelivery(spanContext, deliveryId); err != nil {
+		return nil, err
+	}
+
+	return w.GetDelivery(spanContext, id, deliveryId)
+}
+
+func (w webhookUseCase) getDelivery(ctx context.Context, id int, deliveryId int64) (entity.Delivery, error) {
+	delivery, err := w.webhookRepository.GetDeliveryById(ctx, deliveryId)
+	if err != nil {
+		return entity.Delivery{}, err
+	}
+	if delivery.SubscriptionID != id {
+		return entity.Delivery{}, util.NewHttpResponse(http.StatusNotFound, util.NotFound.Error(), errors.New("webhookUseCase.getDelivery: delivery belongs to another webhook"))
+	}
+
+	return delivery, nil
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", errors.Wrap(err, "webhookUseCase.generateSecret")
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+func NewWebhookUseCase(cfg *config.Config, webhookRepository repository.WebhookRepository, logger logger.Logger) WebhookUseCase {
+	return &webhookUseCase{
+		cfg: cfg,
+		webhookRepository: webhookRepository,
+		logger: logger,
+	}
+}
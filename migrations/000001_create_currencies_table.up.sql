@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS currencies
+(
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    title      TEXT,
+    iso_code   TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_title ON currencies (title);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_iso_code ON currencies (iso_code);
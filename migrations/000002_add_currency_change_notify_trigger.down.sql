@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS currency_change_notify ON currencies;
+DROP FUNCTION IF EXISTS notify_currency_change();
@@ -0,0 +1,17 @@
+CREATE OR REPLACE FUNCTION notify_currency_change() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_notify('currency_changes', json_build_object(
+            'op', TG_OP,
+            'id', CASE WHEN TG_OP = 'DELETE' THEN OLD.id ELSE NEW.id END
+        )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS currency_change_notify ON currencies;
+CREATE TRIGGER currency_change_notify
+    AFTER INSERT OR UPDATE OR DELETE
+    ON currencies
+    FOR EACH ROW
+EXECUTE PROCEDURE notify_currency_change();
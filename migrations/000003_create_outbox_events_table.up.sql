@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS outbox_events
+(
+    id              BIGSERIAL PRIMARY KEY,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    aggregate_type  TEXT                     NOT NULL,
+    aggregate_id    TEXT                     NOT NULL,
+    event_type      TEXT                     NOT NULL,
+    payload         JSONB                    NOT NULL,
+    status          TEXT                     NOT NULL DEFAULT 'pending',
+    attempts        INT                      NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    locked_until    TIMESTAMP WITH TIME ZONE,
+    last_error      TEXT                     NOT NULL DEFAULT '',
+    delivered_at    TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_aggregate ON outbox_events (aggregate_type, aggregate_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox_events (status, next_attempt_at) WHERE status = 'pending';
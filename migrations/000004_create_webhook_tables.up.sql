@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions
+(
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    url        TEXT    NOT NULL,
+    events     TEXT    NOT NULL,
+    secret     TEXT    NOT NULL,
+    active     BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id               BIGSERIAL PRIMARY KEY,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    subscription_id  BIGINT                   NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id         TEXT                     NOT NULL,
+    event_type       TEXT                     NOT NULL,
+    payload          JSONB                    NOT NULL,
+    status           TEXT                     NOT NULL DEFAULT 'pending',
+    attempts         INT                      NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    locked_until     TIMESTAMP WITH TIME ZONE,
+    last_status_code INT                      NOT NULL DEFAULT 0,
+    last_error       TEXT                     NOT NULL DEFAULT '',
+    delivered_at     TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_delivery_event ON webhook_deliveries (subscription_id, event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_pending ON webhook_deliveries (status, next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts
+(
+    id            BIGSERIAL PRIMARY KEY,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    delivery_id   BIGINT                   NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    status_code   INT                      NOT NULL DEFAULT 0,
+    response_body TEXT                     NOT NULL DEFAULT '',
+    error         TEXT                     NOT NULL DEFAULT '',
+    duration_ms   BIGINT                   NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery_id ON webhook_delivery_attempts (delivery_id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS rates
+(
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    base_code  TEXT                     NOT NULL,
+    quote_code TEXT                     NOT NULL,
+    value      NUMERIC(24, 10)          NOT NULL,
+    source     TEXT                     NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_pair ON rates (base_code, quote_code, id DESC);
@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS alert_rules
+(
+    id                BIGSERIAL PRIMARY KEY,
+    created_at        TIMESTAMP WITH TIME ZONE,
+    updated_at        TIMESTAMP WITH TIME ZONE,
+    name              TEXT            NOT NULL,
+    base_code         TEXT            NOT NULL,
+    quote_code        TEXT            NOT NULL,
+    condition         TEXT            NOT NULL,
+    threshold         NUMERIC(24, 10) NOT NULL,
+    window_seconds    INT             NOT NULL DEFAULT 0,
+    channel           TEXT            NOT NULL,
+    target            TEXT            NOT NULL DEFAULT '',
+    cooldown_seconds  INT             NOT NULL DEFAULT 0,
+    active            BOOLEAN         NOT NULL DEFAULT TRUE,
+    last_triggered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rule_pair ON alert_rules (base_code, quote_code) WHERE active;
+
+CREATE TABLE IF NOT EXISTS alerts
+(
+    id              BIGSERIAL PRIMARY KEY,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    rule_id         BIGINT                   NOT NULL REFERENCES alert_rules (id) ON DELETE CASCADE,
+    rate_id         BIGINT                   NOT NULL,
+    pair            TEXT                     NOT NULL,
+    value           NUMERIC(24, 10)          NOT NULL,
+    reference_value NUMERIC(24, 10),
+    message         TEXT                     NOT NULL DEFAULT '',
+    channel         TEXT                     NOT NULL,
+    target          TEXT                     NOT NULL DEFAULT '',
+    status          TEXT                     NOT NULL DEFAULT 'pending',
+    attempts        INT                      NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    locked_until    TIMESTAMP WITH TIME ZONE,
+    last_error      TEXT                     NOT NULL DEFAULT '',
+    triggered_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    sent_at         TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alert_rule_rate ON alerts (rule_id, rate_id);
+CREATE INDEX IF NOT EXISTS idx_alert_pending ON alerts (status, next_attempt_at) WHERE status = 'pending';
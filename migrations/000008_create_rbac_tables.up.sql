@@ -0,0 +1,65 @@
+CREATE TABLE IF NOT EXISTS roles
+(
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    name        TEXT                     NOT NULL UNIQUE,
+    description TEXT                     NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions
+(
+    id          BIGSERIAL PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role_id       BIGINT NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission_id BIGINT NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+-- subject is the principal subject, a jwt sub or api-key:<id>, '*' binds every authenticated principal
+CREATE TABLE IF NOT EXISTS role_bindings
+(
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    subject    TEXT                     NOT NULL,
+    role_id    BIGINT                   NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    UNIQUE (subject, role_id)
+);
+
+INSERT INTO permissions (name, description)
+VALUES ('currency.read', 'Read currencies'),
+       ('currency.create', 'Create currencies'),
+       ('currency.update', 'Update currencies'),
+       ('currency.delete', 'Delete currencies'),
+       ('rate.publish', 'Publish manual rates')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description)
+VALUES ('viewer', 'Reads currencies'),
+       ('editor', 'Maintains currencies'),
+       ('treasury', 'Publishes manual rates'),
+       ('admin', 'Everything')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+         JOIN permissions p ON (r.name, p.name) IN (
+                                                   ('viewer', 'currency.read'),
+                                                   ('editor', 'currency.read'),
+                                                   ('editor', 'currency.create'),
+                                                   ('editor', 'currency.update'),
+                                                   ('treasury', 'currency.read'),
+                                                   ('treasury', 'rate.publish')
+    ) OR r.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_bindings (subject, role_id)
+SELECT '*', id
+FROM roles
+WHERE name = 'viewer'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name = 'audit.read';
+DROP TABLE IF EXISTS audit_logs;
+DROP FUNCTION IF EXISTS reject_audit_log_change();
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS audit_logs
+(
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    entity_type TEXT                     NOT NULL,
+    entity_id   TEXT                     NOT NULL,
+    action      TEXT                     NOT NULL,
+    actor       TEXT                     NOT NULL DEFAULT '',
+    request_id  TEXT                     NOT NULL DEFAULT '',
+    ip_address  TEXT                     NOT NULL DEFAULT '',
+    before      JSONB,
+    after       JSONB,
+    changes     JSONB                    NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit_logs (entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_actor ON audit_logs (actor);
+CREATE INDEX IF NOT EXISTS idx_audit_created_at ON audit_logs (created_at);
+
+-- the audit trail is append only, rows can be inserted but never changed or removed
+CREATE OR REPLACE FUNCTION reject_audit_log_change() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+CREATE TRIGGER audit_logs_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE
+    ON audit_logs
+    FOR EACH STATEMENT
+EXECUTE PROCEDURE reject_audit_log_change();
+
+INSERT INTO permissions (name, description)
+VALUES ('audit.read', 'Read the audit trail')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+         JOIN permissions p ON p.name = 'audit.read'
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+DELETE FROM roles WHERE name IN ('operator', 'checker');
+DELETE FROM permissions WHERE name = 'change.approve';
+DROP TABLE IF EXISTS change_requests;
+ALTER TABLE roles
+    DROP COLUMN IF EXISTS requires_approval;
@@ -0,0 +1,46 @@
+ALTER TABLE roles
+    ADD COLUMN IF NOT EXISTS requires_approval BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS change_requests
+(
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    operation  TEXT                     NOT NULL,
+    entity_id  TEXT                     NOT NULL DEFAULT '',
+    payload    JSONB,
+    status     TEXT                     NOT NULL DEFAULT 'pending',
+    maker      TEXT                     NOT NULL,
+    checker    TEXT                     NOT NULL DEFAULT '',
+    reason     TEXT                     NOT NULL DEFAULT '',
+    result     JSONB,
+    error      TEXT                     NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    decided_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_change_request_status ON change_requests (status, expires_at);
+
+INSERT INTO permissions (name, description)
+VALUES ('change.approve', 'Approve or reject change requests of other principals')
+ON CONFLICT (name) DO NOTHING;
+
+-- operators maintain currencies and publish rates, every change they make waits for a checker
+INSERT INTO roles (name, description, requires_approval)
+VALUES ('operator', 'Proposes currency and rate changes', true),
+       ('checker', 'Approves change requests', false)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+         JOIN permissions p ON (r.name, p.name) IN (
+                                                   ('operator', 'currency.read'),
+                                                   ('operator', 'currency.create'),
+                                                   ('operator', 'currency.update'),
+                                                   ('operator', 'currency.delete'),
+                                                   ('operator', 'rate.publish'),
+                                                   ('checker', 'currency.read'),
+                                                   ('checker', 'change.approve')
+    ) OR (r.name = 'admin' AND p.name = 'change.approve')
+ON CONFLICT DO NOTHING;
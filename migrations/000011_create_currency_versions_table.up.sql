@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS currency_versions
+(
+    id          BIGSERIAL PRIMARY KEY,
+    currency_id BIGINT                   NOT NULL,
+    version     INTEGER                  NOT NULL,
+    title       TEXT                     NOT NULL DEFAULT '',
+    iso_code    TEXT                     NOT NULL DEFAULT '',
+    valid_from  TIMESTAMP WITH TIME ZONE NOT NULL,
+    valid_to    TIMESTAMP WITH TIME ZONE,
+    CHECK (valid_to IS NULL OR valid_to >= valid_from)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_currency_version ON currency_versions (currency_id, version);
+-- a currency has at most one open version, the one valid now
+CREATE UNIQUE INDEX IF NOT EXISTS idx_currency_version_open ON currency_versions (currency_id) WHERE valid_to IS NULL;
+
+-- the existing currencies start their history with their current state
+INSERT INTO currency_versions (currency_id, version, title, iso_code, valid_from)
+SELECT c.id, 1, COALESCE(c.title, ''), COALESCE(c.iso_code, ''), COALESCE(c.created_at, now())
+FROM currencies c
+WHERE NOT EXISTS (SELECT 1 FROM currency_versions v WHERE v.currency_id = c.id);
@@ -0,0 +1,35 @@
+CREATE OR REPLACE FUNCTION notify_currency_change() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_notify('currency_changes', json_build_object(
+            'op', TG_OP,
+            'id', CASE WHEN TG_OP = 'DELETE' THEN OLD.id ELSE NEW.id END
+        )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- the records of tenants have no place in a single tenant schema
+DELETE FROM currencies WHERE tenant_id <> '';
+DELETE FROM currency_versions WHERE tenant_id <> '';
+DELETE FROM rates WHERE tenant_id <> '';
+DELETE FROM change_requests WHERE tenant_id <> '';
+DELETE FROM api_keys WHERE tenant_id <> '';
+
+DROP INDEX IF EXISTS idx_api_key_tenant;
+ALTER TABLE api_keys
+    DROP COLUMN IF EXISTS tenant_id;
+DROP INDEX IF EXISTS idx_change_request_tenant;
+ALTER TABLE change_requests
+    DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE rates
+    DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE currency_versions
+    DROP COLUMN IF EXISTS tenant_id;
+
+DROP INDEX IF EXISTS idx_currency_tenant_title;
+DROP INDEX IF EXISTS idx_currency_tenant_iso_code;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_title ON currencies (title);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_iso_code ON currencies (iso_code);
+ALTER TABLE currencies
+    DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,36 @@
+-- an empty tenant_id marks the global records every tenant sees, e.g. the ISO currencies
+ALTER TABLE currencies
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+
+-- titles and iso codes are unique per tenant instead of across all of them
+DROP INDEX IF EXISTS idx_title;
+DROP INDEX IF EXISTS idx_iso_code;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_currency_tenant_title ON currencies (tenant_id, title);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_currency_tenant_iso_code ON currencies (tenant_id, iso_code);
+
+ALTER TABLE currency_versions
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE rates
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE change_requests
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_change_request_tenant ON change_requests (tenant_id);
+
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_api_key_tenant ON api_keys (tenant_id);
+
+-- the cache sync needs the tenant to find the cache key of a changed currency
+CREATE OR REPLACE FUNCTION notify_currency_change() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_notify('currency_changes', json_build_object(
+            'op', TG_OP,
+            'id', CASE WHEN TG_OP = 'DELETE' THEN OLD.id ELSE NEW.id END,
+            'tenant_id', CASE WHEN TG_OP = 'DELETE' THEN OLD.tenant_id ELSE NEW.tenant_id END
+        )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
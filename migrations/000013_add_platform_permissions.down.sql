@@ -0,0 +1 @@
+DELETE FROM permissions WHERE name IN ('cache.manage', 'webhook.manage', 'alert.manage');
@@ -0,0 +1,12 @@
+INSERT INTO permissions (name, description)
+VALUES ('cache.manage', 'Warm up the cache'),
+       ('webhook.manage', 'Manage webhook subscriptions and replay deliveries'),
+       ('alert.manage', 'Manage alert rules and read alert history')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+         JOIN permissions p ON p.name IN ('cache.manage', 'webhook.manage', 'alert.manage')
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"context"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+)
+
+type sourceKey struct{}
+
+// Source is where a change came from, the request id and ip address of the http request that made it.
+type Source struct {
+	RequestId string
+	IPAddress string
+}
+
+// Actor is who made a change and where it came from.
+type Actor struct {
+	Subject string
+	Source
+}
+
+func NewContext(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// FromContext returns the actor of the request, the subject is empty for unauthenticated requests and the
+// source is empty for changes that were not made over http, e.g. through gRPC.
+func FromContext(ctx context.Context) Actor {
+	source, _ := ctx.Value(sourceKey{}).(Source)
+	principal, _ := auth.FromContext(ctx)
+
+	return Actor{
+		Subject: principal.Subject,
+		Source: source,
+	}
+}
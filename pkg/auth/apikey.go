@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+const bearerPrefix = "Bearer "
+
+var ErrInvalidKey = errors.New("invalid api key")
+
+// BearerToken returns the token of an authorization header with the bearer scheme.
+func BearerToken(header string) (string, bool) {
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(header[len(bearerPrefix):])
+	return token, token != ""
+}
+
+// KeyAuthenticator authenticates the api keys of machine clients.
+type KeyAuthenticator interface {
+	AuthenticateKey(ctx context.Context, key string) (Principal, error)
+}
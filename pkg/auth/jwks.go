@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJwksRefreshInterval = time.Minute * 15
+	// minJwksRefreshInterval throttles refreshes, tokens with made up key ids must not hammer the jwks source
+	minJwksRefreshInterval = time.Second * 30
+	jwksFetchTimeout = time.Second * 10
+	maxJwksSize = 1 << 20
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySet holds the public keys of a JWKS document, read from a file or a url.
+type KeySet interface {
+	// Key returns the key with the key id, an empty id matches a set of one key
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N string `json:"n"`
+	E string `json:"e"`
+	Crv string `json:"crv"`
+	X string `json:"x"`
+	Y string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// keySet caches the keys for refreshInterval. An unknown key id refreshes the set early, that is how a
+// key rotation shows up. When the source is down the cached keys keep being used.
+type keySet struct {
+	url string
+	file string
+	refreshInterval time.Duration
+	client *http.Client
+
+	mu sync.Mutex
+	keys map[string]crypto.PublicKey
+	fetchedAt time.Time
+	attemptedAt time.Time
+}
+
+func (k *keySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, ok := k.lookup(kid)
+	now := time.Now()
+	if (!ok || now.Sub(k.fetchedAt) > k.refreshInterval) && now.Sub(k.attemptedAt) > minJwksRefreshInterval {
+		k.attemptedAt = now
+		keys, err := k.load(ctx)
+		if err != nil && k.keys == nil {
+			return nil, err
+		}
+		if err == nil {
+			k.keys = keys
+			k.fetchedAt = now
+		}
+		key, ok = k.lookup(kid)
+	}
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownKey, "kid %q", kid)
+	}
+
+	return key, nil
+}
+
+func (k *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+func (k *keySet) load(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var document []byte
+	var err error
+	if k.file != "" {
+		document, err = os.ReadFile(k.file)
+	} else {
+		document, err = k.fetch(ctx)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "keySet.load")
+	}
+
+	return parseKeySet(document)
+}
+
+func (k *keySet) fetch(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, jwksFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("jwks url answered %d", res.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(res.Body, maxJwksSize))
+}
+
+// parseKeySet keeps the RSA and EC signing keys of the document, other keys are skipped.
+func parseKeySet(document []byte) (map[string]crypto.PublicKey, error) {
+	set := jsonWebKeySet{}
+	if err := json.Unmarshal(document, &set); err != nil {
+		return nil, errors.Wrap(err, "parseKeySet.Json.Unmarshal")
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = rsaKey(jwk)
+		case "EC":
+			key, err = ecKey(jwk)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "parseKeySet: key %q", jwk.Kid)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func rsaKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid rsa exponent")
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func ecKey(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+
+	x, err := decodeBigInt(jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+// NewKeySet reads the JWKS document from file, or from url when file is empty. A refresh interval of 0
+// means 15 minutes.
+func NewKeySet(url string, file string, refreshInterval time.Duration) KeySet {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJwksRefreshInterval
+	}
+
+	return &keySet{
+		url: url,
+		file: file,
+		refreshInterval: refreshInterval,
+		client: &http.Client{Timeout: jwksFetchTimeout},
+	}
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// newJwk describes the public key of an RSA or ECDSA private key.
+func newJwk(t *testing.T, kid string, key crypto.Signer) jsonWebKey {
+	t.Helper()
+	switch public := key.Public().(type) {
+	case *rsa.PublicKey:
+		return jsonWebKey{Kty: "RSA", Kid: kid, Use: "sig", N: encodeBigInt(public.N), E: encodeBigInt(big.NewInt(int64(public.E)))}
+	case *ecdsa.PublicKey:
+		return jsonWebKey{Kty: "EC", Kid: kid, Crv: public.Curve.Params().Name, X: encodeBigInt(public.X), Y: encodeBigInt(public.Y)}
+	default:
+		t.Fatalf("unexpected key %T", public)
+		return jsonWebKey{}
+	}
+}
+
+func newEcKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+// jwksServer serves whatever key set it currently holds, or fails while down is set.
+type jwksServer struct {
+	mu sync.Mutex
+	keys []jsonWebKey
+	down bool
+	requests int
+}
+
+func (j *jwksServer) set(keys ...jsonWebKey) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.keys = keys
+}
+
+func (j *jwksServer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.requests++
+	if j.down {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(jsonWebKeySet{Keys: j.keys})
+}
+
+func TestKeySetRefreshesOnRotation(t *testing.T) {
+	first, second := newEcKey(t), newEcKey(t)
+	source := &jwksServer{}
+	source.set(newJwk(t, "first", first))
+	server := httptest.NewServer(source)
+	defer server.Close()
+
+	set := NewKeySet(server.URL, "", time.Hour).(*keySet)
+	ctx := context.Background()
+	key, err := set.Key(ctx, "first")
+	if err != nil || !first.PublicKey.Equal(key) {
+		t.Fatalf("Key(first) = %v, %v", key, err)
+	}
+	// a set of one key also answers tokens without a kid
+	if key, err = set.Key(ctx, ""); err != nil || !first.PublicKey.Equal(key) {
+		t.Fatalf("Key() = %v, %v", key, err)
+	}
+
+	// an unknown kid right after a fetch is throttled, made up kids must not hammer the source
+	source.set(newJwk(t, "first", first), newJwk(t, "second", second))
+	if _, err = set.Key(ctx, "second"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Key(second) = %v, want %v while throttled", err, ErrUnknownKey)
+	}
+	if source.requests != 1 {
+		t.Fatalf("requests = %d, want 1", source.requests)
+	}
+
+	set.attemptedAt = time.Now().Add(-minJwksRefreshInterval - time.Second)
+	if key, err = set.Key(ctx, "second"); err != nil || !second.PublicKey.Equal(key) {
+		t.Fatalf("Key(second) = %v, %v after the refresh", key, err)
+	}
+
+	// the cached keys outlive an outage of the source
+	source.down = true
+	set.attemptedAt, set.fetchedAt = time.Time{}, time.Time{}
+	if key, err = set.Key(ctx, "first"); err != nil || !first.PublicKey.Equal(key) {
+		t.Errorf("Key(first) = %v, %v while the source is down", key, err)
+	}
+}
+
+func TestKeySetFailsWithoutKeys(t *testing.T) {
+	source := &jwksServer{down: true}
+	server := httptest.NewServer(source)
+	defer server.Close()
+
+	if _, err := NewKeySet(server.URL, "", 0).Key(context.Background(), "first"); err == nil || errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Key = %v, want the fetch error", err)
+	}
+}
+
+func TestParseKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey := newEcKey(t)
+	encryption := newJwk(t, "encryption", ecKey)
+	encryption.Use = "enc"
+
+	document, err := json.Marshal(jsonWebKeySet{Keys: []jsonWebKey{
+		newJwk(t, "rsa", rsaKey),
+		newJwk(t, "ec", ecKey),
+		encryption,
+		{Kty: "oct", Kid: "symmetric"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := parseKeySet(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || !rsaKey.PublicKey.Equal(keys["rsa"]) || !ecKey.PublicKey.Equal(keys["ec"]) {
+		t.Errorf("keys = %v, want only the rsa and ec signing keys", keys)
+	}
+
+	offCurve := newJwk(t, "ec", ecKey)
+	offCurve.Y = offCurve.X
+	badExponent := newJwk(t, "rsa", rsaKey)
+	badExponent.E = encodeBigInt(big.NewInt(1))
+	unknownCurve := newJwk(t, "ec", ecKey)
+	unknownCurve.Crv = "secp256k1"
+	for name, jwk := range map[string]jsonWebKey{"point off the curve": offCurve, "rsa exponent": badExponent, "curve": unknownCurve} {
+		document, err = json.Marshal(jsonWebKeySet{Keys: []jsonWebKey{jwk}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = parseKeySet(document); err == nil {
+			t.Errorf("%s: parseKeySet accepted the key", name)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token is expired")
+)
+
+// Verifier authenticates bearer tokens.
+type Verifier interface {
+	Verify(ctx context.Context, token string) (Principal, error)
+}
+
+// scopeList reads the scp claim, which some issuers send as an array and others as a space separated string.
+type scopeList []string
+
+func (s *scopeList) UnmarshalJSON(data []byte) error {
+	var scopes []string
+	if err := json.Unmarshal(data, &scopes); err == nil {
+		*s = scopes
+		return nil
+	}
+
+	var scope string
+	if err := json.Unmarshal(data, &scope); err != nil {
+		return err
+	}
+	*s = strings.Fields(scope)
+
+	return nil
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Scope string `json:"scope"`
+	Scp scopeList `json:"scp"`
+	TenantId string `json:"tenant_id"`
+}
+
+func (c claims) scopes() []string {
+	return append(strings.Fields(c.Scope), c.Scp...)
+}
+
+type jwtVerifier struct {
+	cfg config.AuthConfig
+	secret []byte
+	keySet KeySet
+	parser *jwt.Parser
+}
+
+func (j jwtVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	tokenClaims := claims{}
+	_, err := j.parser.ParseWithClaims(token, &tokenClaims, func(token *jwt.Token) (interface{}, error) {
+		return j.key(ctx, token)
+	})
+	if err != nil {
+		return Principal{}, errors.Wrap(ErrInvalidToken, err.Error())
+	}
+
+	// claims are checked here instead of by the parser, jwt/v4 has no leeway for clock skew
+	now := time.Now()
+	leeway := time.Second * j.cfg.Leeway
+	switch {
+	case !tokenClaims.VerifyExpiresAt(now.Add(-leeway), true):
+		return Principal{}, ErrTokenExpired
+	case !tokenClaims.VerifyNotBefore(now.Add(leeway), false):
+		return Principal{}, errors.Wrap(ErrInvalidToken, "token is not valid yet")
+	case j.cfg.Issuer != "" && !tokenClaims.VerifyIssuer(j.cfg.Issuer, true):
+		return Principal{}, errors.Wrap(ErrInvalidToken, "unexpected issuer")
+	case j.cfg.Audience != "" && !tokenClaims.VerifyAudience(j.cfg.Audience, true):
+		return Principal{}, errors.Wrap(ErrInvalidToken, "unexpected audience")
+	case tokenClaims.Subject == "":
+		return Principal{}, errors.Wrap(ErrInvalidToken, "token has no subject")
+	case tokenClaims.TenantId != tenant.Global && !tenant.Valid(tokenClaims.TenantId):
+		return Principal{}, errors.Wrap(ErrInvalidToken, "invalid tenant_id claim")
+	}
+
+	return Principal{
+		Subject: tokenClaims.Subject,
+		Scopes: tokenClaims.scopes(),
+		Tenant: tokenClaims.TenantId,
+	}, nil
+}
+
+// key picks the secret for HMAC tokens and the JWKS key of the kid header for RSA and ECDSA tokens, so a
+// token can never choose the kind of key it is checked with.
+func (j jwtVerifier) key(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return j.secret, nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		kid, _ := token.Header["kid"].(string)
+		return j.keySet.Key(ctx, kid)
+	default:
+		return nil, errors.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
+
+// NewJWTVerifier accepts HS256 when a secret is configured and RS256/ES256 when a JWKS file or url is.
+func NewJWTVerifier(cfg config.AuthConfig) (Verifier, error) {
+	var methods []string
+	verifier := jwtVerifier{cfg: cfg}
+	if cfg.Secret != "" {
+		verifier.secret = []byte(cfg.Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JwksFile != "" || cfg.JwksUrl != "" {
+		verifier.keySet = NewKeySet(cfg.JwksUrl, cfg.JwksFile, time.Second*cfg.JwksRefreshInterval)
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("auth.NewJWTVerifier: a secret, jwks file or jwks url is required")
+	}
+
+	verifier.parser = jwt.NewParser(jwt.WithValidMethods(methods), jwt.WithoutClaimsValidation())
+
+	return verifier, nil
+}
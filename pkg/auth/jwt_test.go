@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	return signed
+}
+
+// validClaims are accepted by the verifier of newTestVerifier, tests change what they are about.
+func validClaims(changes jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"sub": "alice",
+		"iss": "https://issuer.test",
+		"aud": "kanbersky",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+
+	return claims
+}
+
+func newTestVerifier(t *testing.T, cfg config.AuthConfig) Verifier {
+	t.Helper()
+	cfg.Issuer = "https://issuer.test"
+	cfg.Audience = "kanbersky"
+	verifier, err := NewJWTVerifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return verifier
+}
+
+func TestVerifyHMACTokens(t *testing.T) {
+	verifier := newTestVerifier(t, config.AuthConfig{Secret: testSecret, Leeway: 30})
+	tests := []struct {
+		name string
+		claims jwt.MapClaims
+		principal Principal
+		err error
+	}{
+		{name: "scope string", claims: validClaims(jwt.MapClaims{"scope": "currencies:read currencies:write", "tenant_id": "acme"}), principal: Principal{Subject: "alice", Scopes: []string{ScopeCurrenciesRead, ScopeCurrenciesWrite}, Tenant: "acme"}},
+		{name: "scp array", claims: validClaims(jwt.MapClaims{"scope": "currencies:read", "scp": []string{"api-keys:manage"}}), principal: Principal{Subject: "alice", Scopes: []string{ScopeCurrenciesRead, ScopeApiKeysManage}}},
+		{name: "scp string", claims: validClaims(jwt.MapClaims{"scp": "currencies:read"}), principal: Principal{Subject: "alice", Scopes: []string{ScopeCurrenciesRead}}},
+		{name: "expired within the leeway", claims: validClaims(jwt.MapClaims{"exp": time.Now().Add(-10 * time.Second).Unix()}), principal: Principal{Subject: "alice"}},
+		{name: "expired", claims: validClaims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}), err: ErrTokenExpired},
+		{name: "without expiry", claims: validClaims(jwt.MapClaims{"exp": nil}), err: ErrTokenExpired},
+		{name: "not valid yet", claims: validClaims(jwt.MapClaims{"nbf": time.Now().Add(time.Minute).Unix()}), err: ErrInvalidToken},
+		{name: "issuer", claims: validClaims(jwt.MapClaims{"iss": "https://evil.test"}), err: ErrInvalidToken},
+		{name: "audience", claims: validClaims(jwt.MapClaims{"aud": []string{"billing"}}), err: ErrInvalidToken},
+		{name: "subject", claims: validClaims(jwt.MapClaims{"sub": nil}), err: ErrInvalidToken},
+		{name: "tenant", claims: validClaims(jwt.MapClaims{"tenant_id": "Acme Corp"}), err: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(context.Background(), sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", tt.claims))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Verify = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && (principal.Subject != tt.principal.Subject || principal.Tenant != tt.principal.Tenant || strings.Join(principal.Scopes, " ") != strings.Join(tt.principal.Scopes, " ")) {
+				t.Errorf("principal = %+v, want %+v", principal, tt.principal)
+			}
+		})
+	}
+
+	if _, err := verifier.Verify(context.Background(), sign(t, jwt.SigningMethodHS256, []byte("other-secret"), "", validClaims(nil))); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify with another secret = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := verifier.Verify(context.Background(), sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims(nil))); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify of an unsigned token = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestVerifyJWKSTokens(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey := newEcKey(t)
+	document, err := json.Marshal(jsonWebKeySet{Keys: []jsonWebKey{newJwk(t, "rsa", rsaKey), newJwk(t, "ec", ecKey)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(jwksFile, document, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	verifier := newTestVerifier(t, config.AuthConfig{JwksFile: jwksFile})
+
+	for name, token := range map[string]string{
+		"RS256": sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", validClaims(nil)),
+		"ES256": sign(t, jwt.SigningMethodES256, ecKey, "ec", validClaims(nil)),
+	} {
+		if principal, err := verifier.Verify(context.Background(), token); err != nil || principal.Subject != "alice" {
+			t.Errorf("%s: Verify = %+v, %v", name, principal, err)
+		}
+	}
+
+	// a token can not pick the key it is checked with, neither another kid nor HMAC over the public key
+	rejected := map[string]string{
+		"key of another kid": sign(t, jwt.SigningMethodRS256, rsaKey, "ec", validClaims(nil)),
+		"unknown kid": sign(t, jwt.SigningMethodES256, ecKey, "rotated", validClaims(nil)),
+		"hmac without a secret": sign(t, jwt.SigningMethodHS256, []byte(document), "rsa", validClaims(nil)),
+		"unsupported algorithm": sign(t, jwt.SigningMethodRS512, rsaKey, "rsa", validClaims(nil)),
+	}
+	for name, token := range rejected {
+		if _, err := verifier.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: Verify = %v, want %v", name, err, ErrInvalidToken)
+		}
+	}
+}
+
+func TestNewJWTVerifierNeedsAKey(t *testing.T) {
+	if _, err := NewJWTVerifier(config.AuthConfig{Issuer: "https://issuer.test"}); err == nil {
+		t.Error("NewJWTVerifier accepted a config without a secret or jwks")
+	}
+}
@@ -0,0 +1,28 @@
+package auth
+
+import "context"
+
+// Permissions are granted through roles, unlike scopes which the token or api key carries itself.
+const (
+	PermissionCurrencyRead = "currency.read"
+	PermissionCurrencyCreate = "currency.create"
+	PermissionCurrencyUpdate = "currency.update"
+	PermissionCurrencyDelete = "currency.delete"
+	PermissionRatePublish = "rate.publish"
+	PermissionAuditRead = "audit.read"
+	PermissionChangeApprove = "change.approve"
+	PermissionCacheManage = "cache.manage"
+	PermissionWebhookManage = "webhook.manage"
+	PermissionAlertManage = "alert.manage"
+)
+
+// Authorizer tells whether a principal holds a permission.
+type Authorizer interface {
+	HasPermission(ctx context.Context, principal Principal, permission string) (bool, error)
+}
+
+// ApprovalPolicy tells whether the changes a principal makes with a permission have to be approved by
+// another principal before they apply.
+type ApprovalPolicy interface {
+	RequiresApproval(ctx context.Context, principal Principal, permission string) (bool, error)
+}
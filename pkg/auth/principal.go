@@ -0,0 +1,39 @@
+package auth
+
+import "context"
+
+const (
+	ScopeCurrenciesRead = "currencies:read"
+	ScopeCurrenciesWrite = "currencies:write"
+	ScopeApiKeysManage = "api-keys:manage"
+)
+
+type principalKey struct{}
+
+// Principal is the authenticated caller of a request. Tenant is empty for platform callers, which are not
+// bound to a tenant.
+type Principal struct {
+	Subject string
+	Scopes []string
+	Tenant string
+}
+
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal of an authenticated request.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
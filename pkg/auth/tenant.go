@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+)
+
+var (
+	ErrInvalidTenant = errors.New("tenant id must be lower case letters, digits, dashes and underscores")
+	ErrAnonymousTenant = errors.New("picking a tenant needs an authenticated caller")
+	ErrForeignTenant = errors.New("the caller belongs to another tenant")
+)
+
+// ResolveTenant returns the tenant a request acts in, requested is the tenant the caller names, e.g. with the
+// X-Tenant-ID header, or tenant.Global. Principals of a tenant are bound to it, platform principals act in the
+// tenant they name. Anonymous callers only get the global records, anyone could name any tenant otherwise.
+func ResolveTenant(principal Principal, authenticated bool, requested string) (string, error) {
+	if requested != tenant.Global && !tenant.Valid(requested) {
+		return "", ErrInvalidTenant
+	}
+
+	switch {
+	case !authenticated && requested != tenant.Global:
+		return "", ErrAnonymousTenant
+	case !authenticated:
+		return tenant.Global, nil
+	case principal.Tenant != tenant.Global && requested != tenant.Global && requested != principal.Tenant:
+		return "", ErrForeignTenant
+	case principal.Tenant != tenant.Global:
+		return principal.Tenant, nil
+	default:
+		return requested, nil
+	}
+}
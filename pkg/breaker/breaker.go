@@ -0,0 +1,97 @@
+package breaker
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/metric"
+	"github.com/sony/gobreaker"
+	"time"
+)
+
+const (
+	defaultMaxFailures = 5
+	defaultTimeout = 30
+)
+
+var ErrUnavailable = errors.New("unavailable, circuit breaker is open")
+
+func NewCircuitBreaker(name string, cfg config.CircuitBreakerConfig, metrics metric.Metrics, logger logger.Logger) *gobreaker.CircuitBreaker {
+	maxFailures := cfg.MaxFailures
+	if maxFailures == 0 {
+		maxFailures = defaultMaxFailures
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	if metrics != nil {
+		metrics.SetCircuitBreakerState(name, int(gobreaker.StateClosed))
+	}
+
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name: name,
+		MaxRequests: cfg.HalfOpenRequests,
+		Interval: time.Second * cfg.Interval,
+		Timeout: time.Second * timeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= maxFailures
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			logger.Warnf("Circuit breaker %s changed state from %s to %s", name, from, to)
+			if metrics != nil {
+				metrics.SetCircuitBreakerState(name, int(to))
+			}
+		},
+	})
+}
+
+// Wrap reports calls rejected by an open or saturated breaker as ErrUnavailable,
+// other errors are returned unchanged.
+func Wrap(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return errors.Wrap(ErrUnavailable, message)
+	}
+
+	return err
+}
+
+// Execute runs fn through the breaker and wraps the error like Wrap. Errors matching one of answers mean the
+// backend answered, e.g. a cache miss, they are returned as they are and do not count towards tripping.
+func Execute(cb *gobreaker.CircuitBreaker, message string, fn func() (interface{}, error), answers ...error) (interface{}, error) {
+	var answerErr error
+	result, err := cb.Execute(func() (interface{}, error) {
+		result, err := fn()
+		for _, answer := range answers {
+			if errors.Is(err, answer) {
+				answerErr = err
+				return nil, nil
+			}
+		}
+		return result, err
+	})
+	if err != nil {
+		return nil, Wrap(err, message)
+	}
+	if answerErr != nil {
+		return nil, answerErr
+	}
+
+	return result, nil
+}
+
+// LogError logs calls rejected by an open breaker at debug level, the breaker logged opening already.
+// Other errors are logged as errors.
+func LogError(logger logger.Logger, message string, err error) {
+	if errors.Is(err, ErrUnavailable) {
+		logger.Debugf("%s: %s", message, err)
+		return
+	}
+
+	logger.Errorf("%s: %s", message, err)
+}
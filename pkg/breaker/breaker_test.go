@@ -0,0 +1,149 @@
+package breaker
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sony/gobreaker"
+	"testing"
+	"time"
+)
+
+var errRedisDown = errors.New("dial tcp: connection refused")
+
+// stubMetrics records the circuit breaker states, the other metrics are not used.
+type stubMetrics struct {
+	states []int
+}
+
+func (s *stubMetrics) IncreaseHits(int, string, string) {}
+
+func (s *stubMetrics) ObserveResponseTime(int, string, string, float64) {}
+
+func (s *stubMetrics) SetCircuitBreakerState(_ string, state int) {
+	s.states = append(s.states, state)
+}
+
+func (s *stubMetrics) IncreaseActiveConnections(string) {}
+
+func (s *stubMetrics) DecreaseActiveConnections(string) {}
+
+func newTestBreaker(cfg config.CircuitBreakerConfig, metrics *stubMetrics) *gobreaker.CircuitBreaker {
+	loggerCfg := &config.Config{}
+	loggerCfg.Logger.Level = "fatal"
+	l := logger.NewLogger(loggerCfg)
+	l.InitLogger()
+
+	return NewCircuitBreaker("redis", cfg, metrics, l)
+}
+
+func fail() (interface{}, error) {
+	return nil, errRedisDown
+}
+
+func succeed() (interface{}, error) {
+	return "ok", nil
+}
+
+func TestCircuitBreakerTripsAfterConsecutiveFailures(t *testing.T) {
+	metrics := &stubMetrics{}
+	cb := newTestBreaker(config.CircuitBreakerConfig{MaxFailures: 3, Timeout: 60}, metrics)
+
+	for i := 0; i < 2; i++ {
+		_, _ = cb.Execute(fail)
+	}
+	// a success resets the consecutive failures
+	_, _ = cb.Execute(succeed)
+	for i := 0; i < 2; i++ {
+		_, _ = cb.Execute(fail)
+	}
+	if cb.State() != gobreaker.StateClosed {
+		t.Fatalf("state = %s after 2 consecutive failures, want closed", cb.State())
+	}
+
+	_, err := cb.Execute(fail)
+	if !errors.Is(err, errRedisDown) {
+		t.Errorf("err = %v, the failure that trips is returned as is", err)
+	}
+	if cb.State() != gobreaker.StateOpen {
+		t.Fatalf("state = %s after 3 consecutive failures, want open", cb.State())
+	}
+
+	called := false
+	_, err = cb.Execute(func() (interface{}, error) {
+		called = true
+		return nil, nil
+	})
+	if called || !errors.Is(Wrap(err, "cache.Get"), ErrUnavailable) {
+		t.Errorf("open breaker called redis %t, err = %v", called, err)
+	}
+
+	if want := []int{int(gobreaker.StateClosed), int(gobreaker.StateOpen)}; len(metrics.states) != 2 || metrics.states[1] != want[1] {
+		t.Errorf("reported states %v, want %v", metrics.states, want)
+	}
+}
+
+func TestCircuitBreakerHalfOpensAfterTimeout(t *testing.T) {
+	cb := newTestBreaker(config.CircuitBreakerConfig{MaxFailures: 1, HalfOpenRequests: 1, Timeout: 1}, &stubMetrics{})
+	if _, _ = cb.Execute(fail); cb.State() != gobreaker.StateOpen {
+		t.Fatalf("state = %s, want open", cb.State())
+	}
+
+	// the timeout is in seconds
+	time.Sleep(time.Millisecond * 1100)
+	if cb.State() != gobreaker.StateHalfOpen {
+		t.Fatalf("state = %s, want half-open", cb.State())
+	}
+	if _, err := cb.Execute(succeed); err != nil || cb.State() != gobreaker.StateClosed {
+		t.Errorf("probe err = %v, state = %s, want closed", err, cb.State())
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		err error
+		unavailable bool
+	}{
+		{err: gobreaker.ErrOpenState, unavailable: true},
+		{err: gobreaker.ErrTooManyRequests, unavailable: true},
+		{err: errRedisDown},
+	}
+
+	for _, tt := range tests {
+		got := Wrap(tt.err, "cache.Get")
+		if errors.Is(got, ErrUnavailable) != tt.unavailable {
+			t.Errorf("Wrap(%v) = %v, want unavailable %t", tt.err, got, tt.unavailable)
+		}
+		if !tt.unavailable && got != tt.err {
+			t.Errorf("Wrap(%v) = %v, other errors are returned unchanged", tt.err, got)
+		}
+	}
+	if Wrap(nil, "cache.Get") != nil {
+		t.Error("Wrap(nil) is not nil")
+	}
+}
+
+var errMiss = errors.New("redis: nil")
+
+func TestExecuteIgnoresAnswers(t *testing.T) {
+	cb := newTestBreaker(config.CircuitBreakerConfig{MaxFailures: 2, Timeout: 60}, &stubMetrics{})
+
+	for i := 0; i < 3; i++ {
+		if _, err := Execute(cb, "cache.Get", func() (interface{}, error) { return nil, errors.Wrap(errMiss, "get") }, errMiss); !errors.Is(err, errMiss) {
+			t.Fatalf("err = %v, want the miss", err)
+		}
+	}
+	if cb.State() != gobreaker.StateClosed {
+		t.Fatalf("state = %s after misses, want closed", cb.State())
+	}
+	if result, err := Execute(cb, "cache.Get", succeed, errMiss); err != nil || result != "ok" {
+		t.Errorf("Execute = %v, %v", result, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, _ = Execute(cb, "cache.Get", fail, errMiss)
+	}
+	if _, err := Execute(cb, "cache.Get", succeed, errMiss); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("err = %v, want ErrUnavailable once failures tripped the breaker", err)
+	}
+}
@@ -0,0 +1,57 @@
+package certificate
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"time"
+)
+
+type identityKey struct{}
+
+// Identity is the verified client certificate of a mutual tls request. Fingerprint is the hex sha256 of the
+// certificate, it tells apart certificates that share a subject.
+type Identity struct {
+	Subject string
+	CommonName string
+	Organization []string
+	DNSNames []string
+	EmailAddresses []string
+	URIs []string
+	Issuer string
+	SerialNumber string
+	Fingerprint string
+	NotAfter time.Time
+}
+
+func NewIdentity(cert *x509.Certificate) Identity {
+	uris := make([]string, 0, len(cert.URIs))
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	return Identity{
+		Subject: cert.Subject.String(),
+		CommonName: cert.Subject.CommonName,
+		Organization: cert.Subject.Organization,
+		DNSNames: cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		URIs: uris,
+		Issuer: cert.Issuer.String(),
+		SerialNumber: cert.SerialNumber.String(),
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+		NotAfter: cert.NotAfter,
+	}
+}
+
+func NewContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the client certificate identity of a request that presented a verified one.
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,52 @@
+package certificate
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewIdentity(t *testing.T) {
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	spiffe, _ := url.Parse("spiffe://kanbersky/importer")
+	cert := &x509.Certificate{
+		Raw: []byte("der"),
+		SerialNumber: big.NewInt(42),
+		Subject: pkix.Name{CommonName: "importer", Organization: []string{"kanbersky"}},
+		Issuer: pkix.Name{CommonName: "test ca"},
+		DNSNames: []string{"importer.internal"},
+		EmailAddresses: []string{"ops@kanbersky.ca"},
+		URIs: []*url.URL{spiffe},
+		NotAfter: notAfter,
+	}
+
+	want := Identity{
+		Subject: "CN=importer,O=kanbersky",
+		CommonName: "importer",
+		Organization: []string{"kanbersky"},
+		DNSNames: []string{"importer.internal"},
+		EmailAddresses: []string{"ops@kanbersky.ca"},
+		URIs: []string{"spiffe://kanbersky/importer"},
+		Issuer: "CN=test ca",
+		SerialNumber: "42",
+		// sha256 of the raw certificate
+		Fingerprint: "5050d80d22ecd471ea55df7034042c1e041341ff4066e7f6ff38e20b720df8ea",
+		NotAfter: notAfter,
+	}
+	identity := NewIdentity(cert)
+	if !reflect.DeepEqual(identity, want) {
+		t.Errorf("identity = %+v, want %+v", identity, want)
+	}
+
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("identity without a client certificate")
+	}
+	if got, ok := FromContext(NewContext(context.Background(), identity)); !ok || got.Fingerprint != identity.Fingerprint {
+		t.Errorf("FromContext = %+v, %v", got, ok)
+	}
+}
@@ -0,0 +1,158 @@
+package certificate
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultReloadInterval = time.Second * 30
+
+// Reloader serves the certificate and the client ca bundle of files, which are read again when their
+// modification time changes, e.g. after a renewal. A changed file that can not be read is tried again on the
+// next check, the previous certificate keeps being served until then.
+type Reloader interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+	// ClientCAs is nil without a ca file
+	ClientCAs() *x509.CertPool
+	// Run checks the files every interval until ctx is done
+	Run(ctx context.Context)
+}
+
+type reloader struct {
+	certFile string
+	keyFile string
+	caFile string
+	interval time.Duration
+	logger logger.Logger
+
+	mu sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+// NewReloader loads the files once, an unreadable certificate fails here instead of at the first handshake.
+// caFile may be empty.
+func NewReloader(certFile, keyFile, caFile string, interval time.Duration, logger logger.Logger) (Reloader, error) {
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+
+	r := &reloader{
+		certFile: certFile,
+		keyFile: keyFile,
+		caFile: caFile,
+		interval: interval,
+		logger: logger,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.certificate, nil
+}
+
+func (r *reloader) ClientCAs() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.clientCAs
+}
+
+func (r *reloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				r.logger.Errorf("Certificate reload: %s", err)
+				continue
+			}
+			r.logger.Infof("Certificate reloaded, CertFile: %s", r.certFile)
+		}
+	}
+}
+
+func (r *reloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+
+	return files
+}
+
+func (r *reloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			// a missing file is usually a renewal in progress, the next check picks it up
+			continue
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reload takes the modification times before reading, a file written in between is read again on the next check.
+func (r *reloader) reload() error {
+	modTimes := make(map[string]time.Time, 3)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return errors.Wrap(err, "reloader.reload.Stat")
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "reloader.reload.LoadX509KeyPair")
+	}
+
+	var clientCAs *x509.CertPool
+	if r.caFile != "" {
+		bundle, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return errors.Wrap(err, "reloader.reload.ReadCAFile")
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(bundle) {
+			return errors.New("reloader.reload: no certificates found in cafile")
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.certificate = &certificate
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package certificate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key *ecdsa.PrivateKey
+	pem []byte
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{CommonName: "test ca"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter: time.Now().Add(time.Hour),
+		IsCA: true,
+		BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs a certificate for localhost, serial tells the certificates of a test apart.
+func (ca testCA) issue(t *testing.T, serial int64) (certPEM []byte, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject: pkix.Name{CommonName: "localhost"},
+		DNSNames: []string{"localhost"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter: time.Now().Add(time.Hour),
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+// writeFile writes the file with a modification time of at, so rewrites within the resolution of the file
+// system still look changed.
+func writeFile(t *testing.T, path string, data []byte, at time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestLogger() logger.Logger {
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	return l
+}
+
+func serial(t *testing.T, r Reloader) int64 {
+	t.Helper()
+	certificate, err := r.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return leaf.SerialNumber.Int64()
+}
+
+func waitForSerial(t *testing.T, r Reloader, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for serial(t, r) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("serial = %d, want %d", serial(t, r), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReloaderPicksUpRenewedCertificates(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	ca := newTestCA(t)
+	at := time.Now().Add(-time.Minute)
+
+	certPEM, keyPEM := ca.issue(t, 1)
+	writeFile(t, certFile, certPEM, at)
+	writeFile(t, keyFile, keyPEM, at)
+	writeFile(t, caFile, ca.pem, at)
+
+	r, err := NewReloader(certFile, keyFile, caFile, 10*time.Millisecond, newTestLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serial(t, r) != 1 || !trusts(t, r.ClientCAs(), ca) {
+		t.Fatalf("serial = %d, want 1 and the ca trusted", serial(t, r))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	certPEM, keyPEM = ca.issue(t, 2)
+	writeFile(t, certFile, certPEM, at.Add(time.Second))
+	writeFile(t, keyFile, keyPEM, at.Add(time.Second))
+	waitForSerial(t, r, 2)
+
+	// a broken renewal keeps the previous certificate until the files are fixed
+	writeFile(t, certFile, []byte("not a certificate"), at.Add(2*time.Second))
+	time.Sleep(50 * time.Millisecond)
+	if serial(t, r) != 2 {
+		t.Fatalf("serial = %d after a broken renewal, want 2", serial(t, r))
+	}
+
+	certPEM, keyPEM = ca.issue(t, 3)
+	writeFile(t, certFile, certPEM, at.Add(3*time.Second))
+	writeFile(t, keyFile, keyPEM, at.Add(3*time.Second))
+	waitForSerial(t, r, 3)
+
+	// the ca bundle reloads with the certificate
+	other := newTestCA(t)
+	writeFile(t, caFile, other.pem, at.Add(4*time.Second))
+	deadline := time.Now().Add(2 * time.Second)
+	for !trusts(t, r.ClientCAs(), other) {
+		if time.Now().After(deadline) {
+			t.Fatal("the client ca bundle was not reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if trusts(t, r.ClientCAs(), ca) {
+		t.Error("the replaced ca is still trusted")
+	}
+}
+
+// trusts tells whether the pool verifies a certificate of the ca, x509.CertPool does not list its certificates.
+func trusts(t *testing.T, pool *x509.CertPool, ca testCA) bool {
+	t.Helper()
+	certPEM, _ := ca.issue(t, 9)
+	block, _ := pem.Decode(certPEM)
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{Roots: pool})
+
+	return err == nil
+}
+
+func TestNewReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, 1)
+	writeFile(t, certFile, certPEM, time.Now())
+	writeFile(t, keyFile, keyPEM, time.Now())
+	emptyCAFile := filepath.Join(dir, "empty.crt")
+	writeFile(t, emptyCAFile, []byte("# no certificates\n"), time.Now())
+
+	r, err := NewReloader(certFile, keyFile, "", 0, newTestLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ClientCAs() != nil {
+		t.Error("client cas without a ca file")
+	}
+
+	for name, files := range map[string][3]string{
+		"missing certificate": {filepath.Join(dir, "missing.crt"), keyFile, ""},
+		"key of the certificate swapped": {keyFile, certFile, ""},
+		"missing ca": {certFile, keyFile, filepath.Join(dir, "missing-ca.crt")},
+		"ca without certificates": {certFile, keyFile, emptyCAFile},
+	} {
+		if _, err = NewReloader(files[0], files[1], files[2], 0, newTestLogger()); err == nil {
+			t.Errorf("%s: NewReloader succeeded", name)
+		}
+	}
+}
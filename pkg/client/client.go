@@ -0,0 +1,281 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	cryptoRand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout = time.Second * 30
+	defaultMaxRetries = 3
+	defaultMinBackoff = time.Millisecond * 200
+	defaultMaxBackoff = time.Second * 5
+	defaultUserAgent = "kanbersky-go-client"
+	apiPrefix = "/api/v1"
+)
+
+// Client is a typed client of the kanbersky.ca REST API. It is safe for concurrent use.
+type Client struct {
+	Currencies CurrencyService
+	Rates RateService
+
+	baseURL *url.URL
+	httpClient *http.Client
+	userAgent string
+	token string
+	apiKey string
+	tenant string
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+type Option func(c *Client)
+
+// WithHTTPClient replaces the default http client, e.g. to add transport level auth or tracing.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times a failed request is retried, 0 disables retries.
+func WithRetries(maxRetries int) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+	}
+}
+
+// WithBackoff sets the bounds of the exponential backoff between retries.
+func WithBackoff(min time.Duration, max time.Duration) Option {
+	return func(c *Client) {
+		c.minBackoff = min
+		c.maxBackoff = max
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithBearerToken sends the token in the Authorization header of every request.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithApiKey sends the api key in the X-API-Key header of every request, it is used instead of a bearer token.
+func WithApiKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithTenant sends the tenant in the X-Tenant-ID header of every request. Platform callers pick the tenant they
+// act for with it, callers bound to a tenant do not need it.
+func WithTenant(tenantId string) Option {
+	return func(c *Client) {
+		c.tenant = tenantId
+	}
+}
+
+// New creates a client for the API served at baseURL, e.g. http://localhost:5000.
+func New(baseURL string, options ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, errors.Wrap(err, "client.New.ParseBaseURL")
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, errors.Errorf("client.New: base url %q must be absolute", baseURL)
+	}
+
+	c := &Client{
+		baseURL: parsed,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		userAgent: defaultUserAgent,
+		maxRetries: defaultMaxRetries,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	c.Currencies = &currencyService{client: c}
+	c.Rates = &rateService{client: c}
+
+	return c, nil
+}
+
+// do sends the request and decodes a 2xx json body into out, a change held for approval (202) returns a
+// PendingApprovalError. Failed requests are retried with exponential backoff on 5xx, 429 and transport
+// errors. POST requests carry an Idempotency-Key that stays the same across retries, so the server runs
+// them once however often they are sent.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return errors.Wrap(err, "client.do.Json.Marshal")
+		}
+	}
+
+	idempotencyKey := ""
+	if method == http.MethodPost {
+		idempotencyKey = newIdempotencyKey()
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, method, path, query, payload, idempotencyKey)
+		if err == nil && res.StatusCode == http.StatusAccepted {
+			return newPendingApprovalError(res)
+		}
+		if err == nil && res.StatusCode < http.StatusBadRequest {
+			return decodeBody(res, out)
+		}
+
+		var apiErr error
+		retryAfter := time.Duration(0)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			apiErr = errors.Wrapf(err, "client.do.%s", method)
+		} else {
+			apiErr = newError(res)
+			retryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+		}
+
+		if attempt >= c.maxRetries || !retryable(method, res, idempotencyKey != "") {
+			return apiErr
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.backoff(attempt, retryAfter)):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method string, path string, query url.Values, payload []byte, idempotencyKey string) (*http.Response, error) {
+	endpoint := *c.baseURL
+	endpoint.Path += apiPrefix + path
+	endpoint.RawQuery = query.Encode()
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	c.authorize(req)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func (c *Client) authorize(req *http.Request) {
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant-ID", c.tenant)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+		return
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	backoff := c.minBackoff
+	for i := 0; i < attempt && backoff < c.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > c.maxBackoff {
+		backoff = c.maxBackoff
+	}
+
+	// full jitter keeps clients that failed together from retrying together
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// retryable retries POST only when it is idempotent, or on 429, which means the server did not process it.
+// A 409 with Retry-After is the answer to a retry of a request the server still runs.
+func retryable(method string, res *http.Response, idempotent bool) bool {
+	safe := method != http.MethodPost || idempotent
+	if res == nil {
+		return safe
+	}
+	if res.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if res.StatusCode == http.StatusConflict && idempotent && res.Header.Get("Retry-After") != "" {
+		return true
+	}
+
+	return res.StatusCode >= http.StatusInternalServerError && safe
+}
+
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	_, _ = cryptoRand.Read(key)
+
+	return hex.EncodeToString(key)
+}
+
+func decodeBody(res *http.Response, out interface{}) error {
+	defer res.Body.Close()
+
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "client.decodeBody.Json.Decode")
+	}
+
+	return nil
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Second * time.Duration(seconds)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+
+	return 0
+}
@@ -0,0 +1,373 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	currencyRepository "github.com/sefikcan/kanbersky.ca/internal/currency/repository"
+	response "github.com/sefikcan/kanbersky.ca/internal/dto/response/currency"
+	rbac "github.com/sefikcan/kanbersky.ca/internal/rbac/entity"
+	rbacRepository "github.com/sefikcan/kanbersky.ca/internal/rbac/repository"
+	"github.com/sefikcan/kanbersky.ca/internal/server"
+	"github.com/sefikcan/kanbersky.ca/pkg/auth"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sefikcan/kanbersky.ca/pkg/tenant"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testSecret = "client-test-secret"
+	throttledSubject = "throttled"
+)
+
+var currencyColumns = []string{"id", "created_at", "updated_at", "tenant_id", "title", "iso_code"}
+
+// testApi is the api of MapHandlers served by httptest, postgres is sqlmock and redis miniredis. The
+// prometheus metrics of MapHandlers register globally, so the tests share one api and do not run in parallel.
+var testApi struct {
+	url string
+	mock sqlmock.Sqlmock
+	redis *miniredis.Miniredis
+	redisClient redis.UniversalClient
+	// requests records the requests per method and path, the retries of the client included
+	mu sync.Mutex
+	requests map[string][]*http.Request
+}
+
+func TestMain(m *testing.M) {
+	os.Exit(runTestApi(m))
+}
+
+func runTestApi(m *testing.M) int {
+	mr, err := miniredis.Run()
+	if err != nil {
+		panic(err)
+	}
+	defer mr.Close()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer sqlDB.Close()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	if err != nil {
+		panic(err)
+	}
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Mode: "Development"},
+		Metric: config.MetricConfig{Url: "127.0.0.1:0", ServiceName: "client_test"},
+		Auth: config.AuthConfig{Enabled: true, Secret: testSecret},
+		Idempotency: config.IdempotencyConfig{Enabled: true},
+		RateLimit: config.RateLimitConfig{
+			Enabled: true,
+			DefaultPlan: "standard",
+			AnonymousPlan: "standard",
+			Plans: map[string]config.RateLimitPlanConfig{
+				"standard": {Requests: 1000, Window: 60},
+				"tight": {Requests: 1, Window: 1},
+			},
+			Clients: []config.RateLimitClientConfig{{Subject: throttledSubject, Plan: "tight"}},
+		},
+	}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	e := echo.New()
+	if err = server.NewServer(cfg, db, redisClient, l).MapHandlers(e); err != nil {
+		panic(err)
+	}
+
+	testApi.mock = mock
+	testApi.redis = mr
+	testApi.redisClient = redisClient
+	testApi.requests = make(map[string][]*http.Request)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testApi.mu.Lock()
+		testApi.requests[r.Method+" "+r.URL.Path] = append(testApi.requests[r.Method+" "+r.URL.Path], r.Clone(context.Background()))
+		testApi.mu.Unlock()
+		e.ServeHTTP(w, r)
+	}))
+	defer api.Close()
+	testApi.url = api.URL
+
+	return m.Run()
+}
+
+// newTestClient signs a token for subject and caches its grant, so no role lookup reaches the database.
+func newTestClient(t *testing.T, grant rbac.Grant, options ...Option) *Client {
+	t.Helper()
+	t.Cleanup(func() {
+		if err := testApi.mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		testApi.redis.FlushAll()
+		testApi.mu.Lock()
+		testApi.requests = make(map[string][]*http.Request)
+		testApi.mu.Unlock()
+	})
+
+	if err := rbacRepository.NewRbacRedisRepository(testApi.redisClient).SetGrant(context.Background(), grant, time.Minute); err != nil {
+		t.Fatalf("set grant: %v", err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": grant.Subject,
+		"scope": auth.ScopeCurrenciesRead + " " + auth.ScopeCurrenciesWrite,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	options = append([]Option{WithBearerToken(token), WithBackoff(time.Millisecond, time.Millisecond*10)}, options...)
+	c, err := New(testApi.url, options...)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	return c
+}
+
+func requests(method string, path string) []*http.Request {
+	testApi.mu.Lock()
+	defer testApi.mu.Unlock()
+
+	return testApi.requests[method+" "+path]
+}
+
+func reader(subject string) rbac.Grant {
+	return rbac.Grant{Subject: subject, Roles: []string{"viewer"}, Permissions: []string{auth.PermissionCurrencyRead}}
+}
+
+func cacheCurrency(t *testing.T, currency *response.CurrencyResponse) {
+	t.Helper()
+	key := currencyRepository.CurrencyCacheKey(tenant.Global, currency.ID)
+	if err := currencyRepository.NewCurrencyRedisRepository(testApi.redisClient).Set(context.Background(), key, currencyRepository.CurrencyCacheTtl, currency); err != nil {
+		t.Fatalf("cache currency: %v", err)
+	}
+}
+
+func currencyRow(id int, title string, isoCode string) []driver.Value {
+	now := time.Now()
+	return []driver.Value{id, now, now, tenant.Global, title, isoCode}
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	c := newTestClient(t, reader("alice"))
+	testApi.mock.ExpectQuery(`SELECT \* FROM "currencies"`).WillReturnError(errors.New("connection reset by peer"))
+	testApi.mock.ExpectQuery(`SELECT \* FROM "currencies"`).WillReturnRows(sqlmock.NewRows(currencyColumns).AddRow(currencyRow(1, "Euro", "EUR")...))
+
+	currency, err := c.Currencies.Get(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if currency.IsoCode != "EUR" {
+		t.Errorf("currency = %+v", currency)
+	}
+	if got := len(requests(http.MethodGet, "/api/v1/currencies/1")); got != 2 {
+		t.Errorf("sent %d requests, want the 500 and its retry", got)
+	}
+}
+
+func TestRetriesRateLimitedRequestsAfterRetryAfter(t *testing.T) {
+	c := newTestClient(t, reader(throttledSubject))
+	cacheCurrency(t, &response.CurrencyResponse{ID: 1, Title: "Euro", IsoCode: "EUR"})
+
+	if _, err := c.Currencies.Get(context.Background(), 1); err != nil {
+		t.Fatalf("first get: %v", err)
+	}
+	started := time.Now()
+	if _, err := c.Currencies.Get(context.Background(), 1); err != nil {
+		t.Fatalf("throttled get: %v", err)
+	}
+
+	// the tight plan allows a request a second, the retry has to wait for the Retry-After of the 429
+	if waited := time.Since(started); waited < time.Millisecond*500 {
+		t.Errorf("retried after %s, before Retry-After", waited)
+	}
+	if got := len(requests(http.MethodGet, "/api/v1/currencies/1")); got != 3 {
+		t.Errorf("sent %d requests, want 3", got)
+	}
+
+	noRetries := newTestClient(t, reader(throttledSubject), WithRetries(0))
+	cacheCurrency(t, &response.CurrencyResponse{ID: 1, Title: "Euro", IsoCode: "EUR"})
+	_, _ = noRetries.Currencies.Get(context.Background(), 1)
+	if _, err := noRetries.Currencies.Get(context.Background(), 1); !IsRateLimited(err) {
+		t.Errorf("err = %v, want a rate limited error", err)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	answer := func(status int, retryAfter string) *http.Response {
+		res := &http.Response{StatusCode: status, Header: http.Header{}}
+		if retryAfter != "" {
+			res.Header.Set("Retry-After", retryAfter)
+		}
+		return res
+	}
+
+	tests := []struct {
+		name string
+		method string
+		res *http.Response
+		idempotent bool
+		retryable bool
+	}{
+		{name: "GET on 500", method: http.MethodGet, res: answer(http.StatusInternalServerError, ""), retryable: true},
+		{name: "GET on transport error", method: http.MethodGet, retryable: true},
+		{name: "GET on 404", method: http.MethodGet, res: answer(http.StatusNotFound, "")},
+		{name: "POST without key on 500", method: http.MethodPost, res: answer(http.StatusInternalServerError, "")},
+		{name: "POST without key on transport error", method: http.MethodPost},
+		{name: "POST without key on 429", method: http.MethodPost, res: answer(http.StatusTooManyRequests, "1"), retryable: true},
+		{name: "POST with key on 500", method: http.MethodPost, res: answer(http.StatusInternalServerError, ""), idempotent: true, retryable: true},
+		{name: "POST with key in progress", method: http.MethodPost, res: answer(http.StatusConflict, "1"), idempotent: true, retryable: true},
+		{name: "POST with key on a real conflict", method: http.MethodPost, res: answer(http.StatusConflict, ""), idempotent: true},
+		{name: "POST without key on 409", method: http.MethodPost, res: answer(http.StatusConflict, "1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.method, tt.res, tt.idempotent); got != tt.retryable {
+				t.Errorf("retryable = %t, want %t", got, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestRetriedPostKeepsItsIdempotencyKey(t *testing.T) {
+	maker := rbac.Grant{
+		Subject: "maker",
+		Roles: []string{"maker"},
+		Permissions: []string{auth.PermissionCurrencyRead, auth.PermissionCurrencyCreate},
+		ApprovalPermissions: []string{auth.PermissionCurrencyCreate},
+	}
+	c := newTestClient(t, maker)
+	testApi.mock.ExpectBegin().WillReturnError(errors.New("connection reset by peer"))
+	testApi.mock.ExpectBegin()
+	testApi.mock.ExpectQuery(`INSERT INTO "change_requests"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	testApi.mock.ExpectQuery(`INSERT INTO "audit_logs"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	testApi.mock.ExpectCommit()
+
+	_, err := c.Currencies.Create(context.Background(), CreateCurrencyRequest{Title: "Euro", IsoCode: "EUR"})
+
+	var pendingErr *PendingApprovalError
+	if !errors.As(err, &pendingErr) || !IsPendingApproval(err) {
+		t.Fatalf("err = %v, want a PendingApprovalError", err)
+	}
+	if pendingErr.ChangeRequestID != 42 || pendingErr.Operation != auth.PermissionCurrencyCreate || pendingErr.Location != "/api/v1/change-requests/42" {
+		t.Errorf("pending = %+v", pendingErr)
+	}
+	if pendingErr.ExpiresAt.Before(time.Now()) {
+		t.Errorf("expires at %s, want the future", pendingErr.ExpiresAt)
+	}
+
+	sent := requests(http.MethodPost, "/api/v1/currencies")
+	if len(sent) != 2 {
+		t.Fatalf("sent %d requests, want the 500 and its retry", len(sent))
+	}
+	if key := sent[0].Header.Get("Idempotency-Key"); key == "" || sent[1].Header.Get("Idempotency-Key") != key {
+		t.Errorf("idempotency keys %q and %q, want one key for both", key, sent[1].Header.Get("Idempotency-Key"))
+	}
+}
+
+func TestDecodesApiErrors(t *testing.T) {
+	c := newTestClient(t, reader("alice"))
+
+	// the reader has no grant of currency.create
+	_, err := c.Currencies.Create(context.Background(), CreateCurrencyRequest{Title: "Euro", IsoCode: "EUR"})
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !IsForbidden(err) {
+		t.Fatalf("err = %v, want a 403 *Error", err)
+	}
+	if !strings.Contains(apiErr.Message, auth.PermissionCurrencyCreate) || apiErr.RequestID == "" {
+		t.Errorf("error = %+v, want the message and request id of the response", apiErr)
+	}
+	if got := len(requests(http.MethodPost, "/api/v1/currencies")); got != 1 {
+		t.Errorf("sent %d requests, a 403 is not retried", got)
+	}
+
+	anonymous, _ := New(testApi.url)
+	_, err = anonymous.Currencies.Get(context.Background(), 1)
+	if !errors.As(err, &apiErr) || !IsUnauthorized(err) || IsPendingApproval(err) {
+		t.Fatalf("anonymous err = %v, want a 401 *Error", err)
+	}
+	if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message == "" {
+		t.Errorf("error = %+v", apiErr)
+	}
+}
+
+func TestIteratorWalksEveryPage(t *testing.T) {
+	c := newTestClient(t, reader("alice"))
+	codes := []string{"EUR", "USD", "GBP", "JPY", "CHF"}
+	for page := 0; page < 3; page++ {
+		rows := sqlmock.NewRows(currencyColumns)
+		for i := page * 2; i < len(codes) && i < page*2+2; i++ {
+			rows.AddRow(currencyRow(i+1, "Currency "+codes[i], codes[i])...)
+		}
+		testApi.mock.ExpectQuery(`SELECT count\(\*\) FROM "currencies"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(len(codes)))
+		testApi.mock.ExpectQuery(`SELECT \* FROM "currencies"`).WillReturnRows(rows)
+	}
+
+	var got []string
+	it := c.Currencies.Iterate(ListCurrenciesOptions{Size: 2})
+	for it.Next(context.Background()) {
+		got = append(got, it.Value().IsoCode)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("iterate: %v", err)
+	}
+
+	if strings.Join(got, ",") != strings.Join(codes, ",") {
+		t.Errorf("iterated %v, want %v", got, codes)
+	}
+	sent := requests(http.MethodGet, "/api/v1/currencies")
+	if len(sent) != 3 {
+		t.Fatalf("fetched %d pages, want 3", len(sent))
+	}
+	for i, req := range sent {
+		if page := req.URL.Query().Get("page"); page != strconv.Itoa(i+1) {
+			t.Errorf("request %d fetched page %s", i, page)
+		}
+	}
+}
+
+func TestIteratorStopsAtFailedPage(t *testing.T) {
+	c := newTestClient(t, reader("alice"), WithRetries(0))
+	testApi.mock.ExpectQuery(`SELECT count\(\*\) FROM "currencies"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	testApi.mock.ExpectQuery(`SELECT \* FROM "currencies"`).WillReturnRows(sqlmock.NewRows(currencyColumns).AddRow(currencyRow(1, "Euro", "EUR")...).AddRow(currencyRow(2, "Dollar", "USD")...))
+
+	it := c.Currencies.Iterate(ListCurrenciesOptions{Size: 2})
+	count := 0
+	for it.Next(context.Background()) {
+		count++
+		if count == 2 {
+			// the second page is refused, the token loses its grant
+			testApi.redis.FlushAll()
+			_ = rbacRepository.NewRbacRedisRepository(testApi.redisClient).SetGrant(context.Background(), rbac.Grant{Subject: "alice"}, time.Minute)
+		}
+	}
+
+	if count != 2 || !IsForbidden(it.Err()) {
+		t.Errorf("iterated %d currencies, err = %v, want 2 and 403", count, it.Err())
+	}
+}
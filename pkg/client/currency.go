@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type Currency struct {
+	ID int `json:"id"`
+	Title string `json:"title"`
+	IsoCode string `json:"iso_code"`
+}
+
+type CurrencyList struct {
+	TotalCount int64 `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+	Page int `json:"page"`
+	Limit int `json:"limit"`
+	Currencies []Currency `json:"currencies"`
+}
+
+type CreateCurrencyRequest struct {
+	Title string `json:"title"`
+	IsoCode string `json:"iso_code"`
+}
+
+type UpdateCurrencyRequest struct {
+	Title string `json:"title"`
+	IsoCode string `json:"iso_code"`
+}
+
+// ListCurrenciesOptions zero values fall back to the server defaults.
+type ListCurrenciesOptions struct {
+	Page int
+	Size int
+	Sort string
+}
+
+type CurrencyService interface {
+	Create(ctx context.Context, request CreateCurrencyRequest) (*Currency, error)
+	Update(ctx context.Context, id int, request UpdateCurrencyRequest) (*Currency, error)
+	Get(ctx context.Context, id int) (*Currency, error)
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, options ListCurrenciesOptions) (*CurrencyList, error)
+	Iterate(options ListCurrenciesOptions) *Iterator[Currency]
+}
+
+type currencyService struct {
+	client *Client
+}
+
+func (c currencyService) Create(ctx context.Context, request CreateCurrencyRequest) (*Currency, error) {
+	currency := &Currency{}
+	if err := c.client.do(ctx, http.MethodPost, "/currencies", nil, request, currency); err != nil {
+		return nil, err
+	}
+
+	return currency, nil
+}
+
+func (c currencyService) Update(ctx context.Context, id int, request UpdateCurrencyRequest) (*Currency, error) {
+	currency := &Currency{}
+	if err := c.client.do(ctx, http.MethodPut, currencyPath(id), nil, request, currency); err != nil {
+		return nil, err
+	}
+
+	return currency, nil
+}
+
+func (c currencyService) Get(ctx context.Context, id int) (*Currency, error) {
+	currency := &Currency{}
+	if err := c.client.do(ctx, http.MethodGet, currencyPath(id), nil, nil, currency); err != nil {
+		return nil, err
+	}
+
+	return currency, nil
+}
+
+func (c currencyService) Delete(ctx context.Context, id int) error {
+	return c.client.do(ctx, http.MethodDelete, currencyPath(id), nil, nil, nil)
+}
+
+func (c currencyService) List(ctx context.Context, options ListCurrenciesOptions) (*CurrencyList, error) {
+	query := url.Values{}
+	setInt(query, "page", options.Page)
+	setInt(query, "limit", options.Size)
+	if options.Sort != "" {
+		query.Set("sort", options.Sort)
+	}
+
+	currencies := &CurrencyList{}
+	if err := c.client.do(ctx, http.MethodGet, "/currencies", query, nil, currencies); err != nil {
+		return nil, err
+	}
+
+	return currencies, nil
+}
+
+// Iterate walks every currency starting at options.Page.
+func (c currencyService) Iterate(options ListCurrenciesOptions) *Iterator[Currency] {
+	return newIterator(options.Page, func(ctx context.Context, page int) ([]Currency, int, error) {
+		options.Page = page
+		currencies, err := c.List(ctx, options)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return currencies.Currencies, currencies.TotalPages, nil
+	})
+}
+
+func currencyPath(id int) string {
+	return "/currencies/" + strconv.Itoa(id)
+}
+
+func setInt(query url.Values, key string, value int) {
+	if value > 0 {
+		query.Set(key, strconv.Itoa(value))
+	}
+}
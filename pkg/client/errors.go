@@ -0,0 +1,105 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+const maxErrorBodySize = 64 << 10
+
+// Error is a non 2xx response of the API, it mirrors the server's {"status", "error"} body.
+type Error struct {
+	StatusCode int `json:"status"`
+	Message string `json:"error"`
+	RequestID string `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.RequestID == "" {
+		return fmt.Sprintf("kanbersky: status %d: %s", e.StatusCode, e.Message)
+	}
+
+	return fmt.Sprintf("kanbersky: status %d: %s (request id %s)", e.StatusCode, e.Message, e.RequestID)
+}
+
+// PendingApprovalError is returned for changes the server held for approval, because the roles of the caller
+// require it. The change applies once another principal approves the change request.
+type PendingApprovalError struct {
+	ChangeRequestID int64 `json:"id"`
+	Operation string `json:"operation"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Location string `json:"-"`
+}
+
+func (e *PendingApprovalError) Error() string {
+	return fmt.Sprintf("kanbersky: %s is pending approval as change request %d", e.Operation, e.ChangeRequestID)
+}
+
+// IsPendingApproval reports whether err is a change held for approval.
+func IsPendingApproval(err error) bool {
+	var pendingErr *PendingApprovalError
+	return errors.As(err, &pendingErr)
+}
+
+// IsNotFound reports whether err is an API error with status 404.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsBadRequest reports whether err is an API error with status 400.
+func IsBadRequest(err error) bool {
+	return hasStatus(err, http.StatusBadRequest)
+}
+
+// IsUnauthorized reports whether err is an API error with status 401, the token or api key is missing, invalid or expired.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether err is an API error with status 403, the token or api key lacks the required scope.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsRateLimited reports whether err is an API error with status 429.
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+func newPendingApprovalError(res *http.Response) error {
+	defer res.Body.Close()
+
+	pendingErr := &PendingApprovalError{}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	_ = json.Unmarshal(body, pendingErr)
+	pendingErr.Location = res.Header.Get("Location")
+
+	return pendingErr
+}
+
+// newError reads the error body of the response, bodies that are not the server's json error keep
+// the status text as message.
+func newError(res *http.Response) error {
+	defer res.Body.Close()
+
+	apiErr := &Error{}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	_ = json.Unmarshal(body, apiErr)
+
+	apiErr.StatusCode = res.StatusCode
+	apiErr.RequestID = res.Header.Get("X-Request-Id")
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(res.StatusCode)
+	}
+
+	return apiErr
+}
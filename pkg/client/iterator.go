@@ -0,0 +1,71 @@
+package client
+
+import "context"
+
+type pageFunc[T any] func(ctx context.Context, page int) (items []T, totalPages int, err error)
+
+// Iterator walks a paginated list page by page, fetching the next page when the current one is used up:
+//
+//	it := c.Currencies.Iterate(client.ListCurrenciesOptions{Size: 50})
+//	for it.Next(ctx) {
+//		currency := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type Iterator[T any] struct {
+	fetch pageFunc[T]
+	page int
+	totalPages int
+	items []T
+	index int
+	current T
+	err error
+}
+
+func newIterator[T any](firstPage int, fetch pageFunc[T]) *Iterator[T] {
+	if firstPage <= 0 {
+		firstPage = 1
+	}
+
+	return &Iterator[T]{
+		fetch: fetch,
+		page: firstPage - 1,
+		totalPages: -1,
+	}
+}
+
+// Next advances to the next item, it returns false when the list is exhausted or a page failed.
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	for it.index >= len(it.items) {
+		if it.err != nil || (it.totalPages >= 0 && it.page >= it.totalPages) {
+			return false
+		}
+
+		items, totalPages, err := it.fetch(ctx, it.page+1)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page++
+		it.totalPages = totalPages
+		it.items = items
+		it.index = 0
+		if len(items) == 0 {
+			return false
+		}
+	}
+
+	it.current = it.items[it.index]
+	it.index++
+
+	return true
+}
+
+func (it *Iterator[T]) Value() T {
+	return it.current
+}
+
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
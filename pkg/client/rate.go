@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Rate struct {
+	ID int64 `json:"id"`
+	Pair string `json:"pair"`
+	BaseCode string `json:"base_code"`
+	QuoteCode string `json:"quote_code"`
+	Value float64 `json:"value"`
+	Source string `json:"source"`
+	TenantID string `json:"tenant_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RateList struct {
+	TotalCount int64 `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+	Page int `json:"page"`
+	Limit int `json:"limit"`
+	Rates []Rate `json:"rates"`
+}
+
+type CreateRateRequest struct {
+	BaseCode string `json:"base_code"`
+	QuoteCode string `json:"quote_code"`
+	Value float64 `json:"value"`
+	Source string `json:"source,omitempty"`
+}
+
+// ListRatesOptions zero values fall back to the server defaults, an empty Pair lists every pair.
+type ListRatesOptions struct {
+	Pair string
+	Page int
+	Size int
+}
+
+// StreamOptions selects the pairs to stream, no pairs streams every pair. LastEventID resumes a
+// previous stream from the server's replay buffer.
+type StreamOptions struct {
+	Pairs []string
+	LastEventID int64
+}
+
+type RateService interface {
+	Create(ctx context.Context, request CreateRateRequest) (*Rate, error)
+	Latest(ctx context.Context, pairs ...string) ([]Rate, error)
+	List(ctx context.Context, options ListRatesOptions) (*RateList, error)
+	Iterate(options ListRatesOptions) *Iterator[Rate]
+	Stream(ctx context.Context, options StreamOptions) (*RateStream, error)
+}
+
+type rateService struct {
+	client *Client
+}
+
+func (r rateService) Create(ctx context.Context, request CreateRateRequest) (*Rate, error) {
+	rate := &Rate{}
+	if err := r.client.do(ctx, http.MethodPost, "/rates", nil, request, rate); err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
+
+// Latest returns the latest rate of every given pair, or of every known pair without pairs.
+func (r rateService) Latest(ctx context.Context, pairs ...string) ([]Rate, error) {
+	query := url.Values{}
+	if len(pairs) > 0 {
+		query.Set("pairs", strings.Join(pairs, ","))
+	}
+
+	var rates []Rate
+	if err := r.client.do(ctx, http.MethodGet, "/rates/latest", query, nil, &rates); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+func (r rateService) List(ctx context.Context, options ListRatesOptions) (*RateList, error) {
+	query := url.Values{}
+	setInt(query, "page", options.Page)
+	setInt(query, "limit", options.Size)
+	if options.Pair != "" {
+		query.Set("pair", options.Pair)
+	}
+
+	rates := &RateList{}
+	if err := r.client.do(ctx, http.MethodGet, "/rates", query, nil, rates); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// Iterate walks the rate history newest first, starting at options.Page.
+func (r rateService) Iterate(options ListRatesOptions) *Iterator[Rate] {
+	return newIterator(options.Page, func(ctx context.Context, page int) ([]Rate, int, error) {
+		options.Page = page
+		rates, err := r.List(ctx, options)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return rates.Rates, rates.TotalPages, nil
+	})
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	rateEvent = "rate"
+	maxEventSize = 1 << 20
+)
+
+// RateStream reads the Server-Sent Events rate stream. It does not reconnect by itself, after a
+// failure open a new stream with LastEventID set to the value of the failed one to resume.
+type RateStream struct {
+	body io.ReadCloser
+	scanner *bufio.Scanner
+	current Rate
+	lastEventID int64
+	err error
+}
+
+// Stream opens the live rate stream. The stream ends when ctx is cancelled or Close is called.
+func (r rateService) Stream(ctx context.Context, options StreamOptions) (*RateStream, error) {
+	endpoint := *r.client.baseURL
+	endpoint.Path += apiPrefix + "/rates/stream"
+	query := url.Values{}
+	if len(options.Pairs) > 0 {
+		query.Set("pairs", strings.Join(options.Pairs, ","))
+	}
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "rateService.Stream.NewRequest")
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("User-Agent", r.client.userAgent)
+	r.client.authorize(req)
+	if options.LastEventID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(options.LastEventID, 10))
+	}
+
+	// the client timeout would cut a long running stream, cancellation is left to ctx
+	streamClient := *r.client.httpClient
+	streamClient.Timeout = 0
+	res, err := streamClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "rateService.Stream.Do")
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, newError(res)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 4096), maxEventSize)
+
+	return &RateStream{
+		body: res.Body,
+		scanner: scanner,
+		lastEventID: options.LastEventID,
+	}, nil
+}
+
+// Next blocks until the next rate arrives, it returns false when the stream ended or failed.
+func (s *RateStream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+
+	event, id, data := "", "", strings.Builder{}
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line != "" {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "id":
+				id = value
+			case "data":
+				data.WriteString(value)
+			}
+			continue
+		}
+
+		// a blank line dispatches the event, comments like heartbeats and retry hints carry no data
+		if event != rateEvent || data.Len() == 0 {
+			event, id = "", ""
+			data.Reset()
+			continue
+		}
+
+		rate := Rate{}
+		if err := json.Unmarshal([]byte(data.String()), &rate); err != nil {
+			s.err = errors.Wrap(err, "RateStream.Next.Json.Unmarshal")
+			return false
+		}
+		if parsed, err := strconv.ParseInt(id, 10, 64); err == nil {
+			s.lastEventID = parsed
+		}
+		s.current = rate
+
+		return true
+	}
+
+	s.err = s.scanner.Err()
+	if s.err == nil {
+		s.err = io.EOF
+	}
+
+	return false
+}
+
+func (s *RateStream) Rate() Rate {
+	return s.current
+}
+
+// LastEventID is the id of the last received rate, pass it to StreamOptions to resume.
+func (s *RateStream) LastEventID() int64 {
+	return s.lastEventID
+}
+
+// Err returns why the stream ended, io.EOF when the server closed it.
+func (s *RateStream) Err() error {
+	return s.err
+}
+
+func (s *RateStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,169 @@
+package codec
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MediaTypeJSON = "application/json"
+	MediaTypeXML = "application/xml"
+	MediaTypeTextXML = "text/xml"
+	MediaTypeCSV = "text/csv"
+	MediaTypeMsgPack = "application/msgpack"
+	MediaTypeXMsgPack = "application/x-msgpack"
+	MediaTypeVndMsgPack = "application/vnd.msgpack"
+)
+
+var (
+	ErrNotAcceptable = errors.New("none of the accepted media types is supported")
+	ErrUnsupportedMediaType = errors.New("request media type is not supported")
+	// ErrNotTabular is returned by encoders that can only write lists, like CSV
+	ErrNotTabular = errors.New("value is not a list")
+)
+
+type Codec interface {
+	ContentType() string
+	// CanEncode reports whether the codec can write v, CSV only writes lists
+	CanEncode(v interface{}) bool
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return MediaTypeJSON
+}
+
+func (jsonCodec) CanEncode(interface{}) bool {
+	return true
+}
+
+func (jsonCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (jsonCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+var (
+	JSON Codec = jsonCodec{}
+	XML Codec = xmlCodec{}
+	CSV Codec = csvCodec{}
+	MsgPack Codec = msgPackCodec{}
+)
+
+// codecs maps every accepted media type to its codec, vendor json types like
+// application/vnd.kanbersky.v2+json are matched by their +json suffix.
+var codecs = map[string]Codec{
+	MediaTypeJSON: JSON,
+	MediaTypeXML: XML,
+	MediaTypeTextXML: XML,
+	MediaTypeCSV: CSV,
+	MediaTypeMsgPack: MsgPack,
+	MediaTypeXMsgPack: MsgPack,
+	MediaTypeVndMsgPack: MsgPack,
+}
+
+// ForContentType returns the codec of a request Content-Type, an empty content type means JSON.
+func ForContentType(contentType string) (Codec, error) {
+	mediaType := parseMediaType(contentType)
+	if mediaType == "" {
+		return JSON, nil
+	}
+	if c, ok := lookup(mediaType); ok {
+		return c, nil
+	}
+
+	return nil, ErrUnsupportedMediaType
+}
+
+// Negotiate picks the codec for v from the Accept header, honouring q values. Wildcards and an
+// empty header mean JSON. A codec that cannot write v, like CSV for a single item, is skipped.
+func Negotiate(accept string, v interface{}) (Codec, error) {
+	if strings.TrimSpace(accept) == "" {
+		return JSON, nil
+	}
+
+	for _, mediaType := range acceptedMediaTypes(accept) {
+		var c Codec
+		switch {
+		case mediaType == "*/*" || mediaType == "application/*":
+			c = JSON
+		case mediaType == "text/*":
+			c = CSV
+		default:
+			var ok bool
+			if c, ok = lookup(mediaType); !ok {
+				continue
+			}
+		}
+		if c.CanEncode(v) {
+			return c, nil
+		}
+	}
+
+	return nil, ErrNotAcceptable
+}
+
+func lookup(mediaType string) (Codec, bool) {
+	if c, ok := codecs[mediaType]; ok {
+		return c, true
+	}
+	if strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json") {
+		return JSON, true
+	}
+	if strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+xml") {
+		return XML, true
+	}
+
+	return nil, false
+}
+
+// acceptedMediaTypes returns the media types of the Accept header by descending q value, q=0 means not acceptable.
+func acceptedMediaTypes(accept string) []string {
+	type weighted struct {
+		mediaType string
+		q float64
+	}
+
+	var accepted []weighted
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if mediaType = parseMediaType(mediaType); mediaType == "" || q <= 0 {
+			continue
+		}
+		accepted = append(accepted, weighted{mediaType: mediaType, q: q})
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].q > accepted[j].q
+	})
+
+	mediaTypes := make([]string, 0, len(accepted))
+	for _, a := range accepted {
+		mediaTypes = append(mediaTypes, a.mediaType)
+	}
+
+	return mediaTypes
+}
+
+func parseMediaType(value string) string {
+	mediaType, _, _ := strings.Cut(value, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
@@ -0,0 +1,110 @@
+package codec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	ID int `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name"`
+}
+
+type itemList struct {
+	Total int `json:"total" xml:"total"`
+	Items []item `json:"items" xml:"items>item"`
+}
+
+func TestNegotiate(t *testing.T) {
+	list := itemList{Items: []item{{ID: 1, Name: "euro"}}}
+	single := item{ID: 1}
+	tests := []struct {
+		name string
+		accept string
+		v interface{}
+		codec Codec
+		fails bool
+	}{
+		{name: "no accept header", v: single, codec: JSON},
+		{name: "wildcard", accept: "*/*", v: single, codec: JSON},
+		{name: "xml", accept: "application/xml", v: single, codec: XML},
+		{name: "text xml", accept: "text/xml", v: single, codec: XML},
+		{name: "vendor json", accept: "application/vnd.kanbersky.v2+json", v: single, codec: JSON},
+		{name: "vendor xml", accept: "application/atom+xml", v: single, codec: XML},
+		{name: "msgpack aliases", accept: "application/x-msgpack", v: single, codec: MsgPack},
+		{name: "csv list", accept: "text/csv", v: list, codec: CSV},
+		{name: "csv single item falls through", accept: "text/csv, application/json;q=0.5", v: single, codec: JSON},
+		{name: "csv only for a single item", accept: "text/csv", v: single, fails: true},
+		{name: "q values order", accept: "application/json;q=0.4, application/xml;q=0.9", v: single, codec: XML},
+		{name: "q zero excludes", accept: "application/xml;q=0, application/msgpack", v: single, codec: MsgPack},
+		{name: "text wildcard is csv", accept: "text/*", v: list, codec: CSV},
+		{name: "media type parameters and case", accept: "Application/XML; charset=utf-8", v: single, codec: XML},
+		{name: "nothing supported", accept: "image/png", v: single, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Negotiate(tt.accept, tt.v)
+			if tt.fails {
+				if err != ErrNotAcceptable {
+					t.Errorf("Negotiate = %v, %v, want %v", c, err, ErrNotAcceptable)
+				}
+				return
+			}
+			if err != nil || c != tt.codec {
+				t.Errorf("Negotiate = %v, %v, want %s", c, err, tt.codec.ContentType())
+			}
+		})
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	tests := map[string]Codec{
+		"": JSON,
+		"application/json; charset=UTF-8": JSON,
+		"application/vnd.kanbersky.v2+json": JSON,
+		"text/xml": XML,
+		"application/vnd.msgpack": MsgPack,
+		"text/csv": CSV,
+	}
+	for contentType, want := range tests {
+		if c, err := ForContentType(contentType); err != nil || c != want {
+			t.Errorf("ForContentType(%q) = %v, %v, want %s", contentType, c, err, want.ContentType())
+		}
+	}
+
+	if _, err := ForContentType("application/pdf"); err != ErrUnsupportedMediaType {
+		t.Errorf("ForContentType(application/pdf) = %v, want %v", err, ErrUnsupportedMediaType)
+	}
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	want := item{ID: 7, Name: "lira"}
+	for _, c := range []Codec{JSON, XML, MsgPack} {
+		buf := &bytes.Buffer{}
+		if err := c.Encode(buf, want); err != nil {
+			t.Fatalf("%s encode: %v", c.ContentType(), err)
+		}
+
+		got := item{}
+		if err := c.Decode(buf, &got); err != nil {
+			t.Fatalf("%s decode: %v", c.ContentType(), err)
+		}
+		if got != want {
+			t.Errorf("%s round trip = %+v, want %+v", c.ContentType(), got, want)
+		}
+	}
+}
+
+func TestXMLWrapsTopLevelLists(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := XML.Encode(buf, []item{{ID: 1, Name: "euro"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	body := strings.TrimPrefix(buf.String(), `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	if body == buf.String() || !strings.HasPrefix(body, "<items>") || !strings.HasSuffix(body, "</items>") || !strings.Contains(body, "<id>1</id><name>euro</name>") {
+		t.Errorf("xml = %s, want the header and a single items root", buf)
+	}
+}
@@ -0,0 +1,177 @@
+package codec
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// csvCodec writes lists as one row per item with a header row of the json field names. Nested structs
+// are flattened into parent.child columns. Single items and request bodies are not supported.
+type csvCodec struct{}
+
+type csvColumn struct {
+	name string
+	index []int
+}
+
+func (csvCodec) ContentType() string {
+	return MediaTypeCSV
+}
+
+func (csvCodec) CanEncode(v interface{}) bool {
+	_, ok := tableRows(reflect.ValueOf(v))
+	return ok
+}
+
+func (csvCodec) Encode(w io.Writer, v interface{}) error {
+	rows, ok := tableRows(reflect.ValueOf(v))
+	if !ok {
+		return ErrNotTabular
+	}
+
+	columns := csvColumns(indirectType(rows.Type().Elem()), nil, "")
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader(columns)); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	for i := 0; i < rows.Len(); i++ {
+		if err := writer.Write(csvRecord(rows.Index(i), columns, record)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func (csvCodec) Decode(io.Reader, interface{}) error {
+	return ErrUnsupportedMediaType
+}
+
+// tableRows finds the rows of v, either v itself when it is a slice of structs or the first slice of
+// structs field of a list response like CurrencyListResponse.
+func tableRows(v reflect.Value) (reflect.Value, bool) {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return reflect.Value{}, false
+	}
+	if isStructSlice(v.Type()) {
+		return v, true
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).IsExported() && isStructSlice(v.Field(i).Type()) {
+			return v.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+func isStructSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && indirectType(t.Elem()).Kind() == reflect.Struct && indirectType(t.Elem()) != timeType
+}
+
+func csvColumns(t reflect.Type, parent []int, prefix string) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		index := append(append([]int{}, parent...), i)
+		fieldType := indirectType(field.Type)
+		switch {
+		case fieldType.Kind() == reflect.Struct && fieldType != timeType:
+			columns = append(columns, csvColumns(fieldType, index, prefix+name+".")...)
+		case fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Map:
+			continue
+		default:
+			columns = append(columns, csvColumn{name: prefix + name, index: index})
+		}
+	}
+
+	return columns
+}
+
+func csvHeader(columns []csvColumn) []string {
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column.name)
+	}
+
+	return header
+}
+
+// csvRecord fills record with the column values of row and returns it.
+func csvRecord(row reflect.Value, columns []csvColumn, record []string) []string {
+	row = reflect.Indirect(row)
+	for i, column := range columns {
+		record[i] = csvValue(fieldByIndex(row, column.index))
+	}
+
+	return record
+}
+
+// fieldByIndex follows the index through pointers, a nil pointer on the way yields an invalid value.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		v = reflect.Indirect(v)
+		if !v.IsValid() {
+			return v
+		}
+		v = v.Field(i)
+	}
+
+	return reflect.Indirect(v)
+}
+
+func csvValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
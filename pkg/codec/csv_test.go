@@ -0,0 +1,73 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type csvLinks struct {
+	Self string `json:"self"`
+}
+
+type csvRow struct {
+	ID int64 `json:"id"`
+	Title string `json:"title"`
+	Value float64 `json:"value"`
+	Active bool `json:"active"`
+	Reference *float64 `json:"reference"`
+	CreatedAt time.Time `json:"created_at"`
+	Tags []string `json:"tags"`
+	Secret string `json:"-"`
+	Links *csvLinks `json:"_links"`
+}
+
+type csvList struct {
+	TotalCount int `json:"total_count"`
+	Rows []*csvRow `json:"rows"`
+}
+
+func TestCSVEncodesListRows(t *testing.T) {
+	reference := 31.5
+	createdAt := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	list := csvList{TotalCount: 2, Rows: []*csvRow{
+		{ID: 1, Title: "Dollar, US", Value: 32.25, Active: true, Reference: &reference, CreatedAt: createdAt, Tags: []string{"major"}, Secret: "hidden", Links: &csvLinks{Self: "/api/v2/currencies/1"}},
+		{ID: 2, Title: "Euro", Value: 35, CreatedAt: createdAt},
+	}}
+
+	buf := &bytes.Buffer{}
+	if err := CSV.Encode(buf, list); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	// slices are left out, nested structs are flattened and nil pointers are empty cells
+	want := "id,title,value,active,reference,created_at,_links.self\n" +
+		"1,\"Dollar, US\",32.25,true,31.5,2024-03-01T09:30:00Z,/api/v2/currencies/1\n" +
+		"2,Euro,35,false,,2024-03-01T09:30:00Z,\n"
+	if buf.String() != want {
+		t.Errorf("csv =\n%s\nwant\n%s", buf, want)
+	}
+}
+
+func TestCSVOnlyEncodesLists(t *testing.T) {
+	for name, v := range map[string]interface{}{
+		"single item": csvRow{ID: 1},
+		"strings": []string{"a"},
+		"times": []time.Time{time.Now()},
+		"nil": nil,
+	} {
+		if CSV.CanEncode(v) {
+			t.Errorf("%s: CanEncode = true", name)
+		}
+		if err := CSV.Encode(&bytes.Buffer{}, v); err != ErrNotTabular {
+			t.Errorf("%s: Encode = %v, want %v", name, err, ErrNotTabular)
+		}
+	}
+
+	if !CSV.CanEncode([]csvRow{}) {
+		t.Error("an empty slice of rows is not tabular")
+	}
+	if err := CSV.Decode(&bytes.Buffer{}, &csvRow{}); err != ErrUnsupportedMediaType {
+		t.Errorf("Decode = %v, want %v", err, ErrUnsupportedMediaType)
+	}
+}
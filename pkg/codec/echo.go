@@ -0,0 +1,74 @@
+package codec
+
+import (
+	"bytes"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"net/http"
+	"strings"
+)
+
+// Render writes v in the format negotiated from the Accept header, 406 when none fits. Error
+// responses fall back to JSON instead, so the client still learns what went wrong.
+func Render(e echo.Context, status int, v interface{}) error {
+	c, err := Negotiate(e.Request().Header.Get(echo.HeaderAccept), v)
+	if err != nil {
+		if status >= http.StatusBadRequest {
+			return e.JSON(status, v)
+		}
+		return e.JSON(http.StatusNotAcceptable, util.NewHttpResponse(http.StatusNotAcceptable, strings.ToLower(err.Error()), nil))
+	}
+
+	// e.JSON keeps a vendor content type set by the version negotiation
+	if c == JSON {
+		return e.JSON(status, v)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = c.Encode(buf, v); err != nil {
+		return errors.Wrap(err, "codec.Render.Encode")
+	}
+	contentType := c.ContentType()
+	if c != MsgPack {
+		contentType += "; charset=UTF-8"
+	}
+	e.Response().Header().Set(echo.HeaderContentType, contentType)
+
+	return e.Blob(status, contentType, buf.Bytes())
+}
+
+// Binder extends the echo binder with MessagePack and vendor json bodies, other formats are bound by
+// echo itself. Unsupported body formats fail with 415.
+type Binder struct {
+	echo.DefaultBinder
+}
+
+func (b *Binder) Bind(i interface{}, e echo.Context) error {
+	req := e.Request()
+	mediaType := parseMediaType(req.Header.Get(echo.HeaderContentType))
+	if mediaType == "" || mediaType == MediaTypeJSON || mediaType == MediaTypeXML || mediaType == MediaTypeTextXML ||
+		mediaType == echo.MIMEApplicationForm || mediaType == echo.MIMEMultipartForm {
+		return b.DefaultBinder.Bind(i, e)
+	}
+
+	c, ok := lookup(mediaType)
+	if !ok {
+		return echo.ErrUnsupportedMediaType
+	}
+	if err := b.BindPathParams(e, i); err != nil {
+		return err
+	}
+	if req.ContentLength == 0 {
+		return nil
+	}
+
+	if err := c.Decode(req.Body, i); err != nil {
+		if errors.Is(err, ErrUnsupportedMediaType) {
+			return echo.ErrUnsupportedMediaType
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+
+	return nil
+}
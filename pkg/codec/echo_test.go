@@ -0,0 +1,100 @@
+package codec
+
+import (
+	"bytes"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/kanbersky.ca/pkg/util"
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(accept string, status int, v interface{}) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if accept != "" {
+		req.Header.Set(echo.HeaderAccept, accept)
+	}
+	rec := httptest.NewRecorder()
+	_ = Render(e.NewContext(req, rec), status, v)
+
+	return rec
+}
+
+func TestRender(t *testing.T) {
+	single := item{ID: 7, Name: "lira"}
+	tests := []struct {
+		name string
+		accept string
+		status int
+		v interface{}
+		wantStatus int
+		contentType string
+	}{
+		{name: "json by default", status: http.StatusOK, v: single, wantStatus: http.StatusOK, contentType: echo.MIMEApplicationJSONCharsetUTF8},
+		{name: "xml", accept: "application/xml", status: http.StatusCreated, v: single, wantStatus: http.StatusCreated, contentType: "application/xml; charset=UTF-8"},
+		{name: "msgpack without charset", accept: "application/msgpack", status: http.StatusOK, v: single, wantStatus: http.StatusOK, contentType: MediaTypeMsgPack},
+		{name: "csv list", accept: "text/csv", status: http.StatusOK, v: []item{single}, wantStatus: http.StatusOK, contentType: "text/csv; charset=UTF-8"},
+		{name: "not acceptable", accept: "text/csv", status: http.StatusOK, v: single, wantStatus: http.StatusNotAcceptable, contentType: echo.MIMEApplicationJSONCharsetUTF8},
+		{name: "errors fall back to json", accept: "image/png", status: http.StatusNotFound, v: util.NewHttpResponse(http.StatusNotFound, "not found", nil), wantStatus: http.StatusNotFound, contentType: echo.MIMEApplicationJSONCharsetUTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := render(tt.accept, tt.status, tt.v)
+			if rec.Code != tt.wantStatus || rec.Header().Get(echo.HeaderContentType) != tt.contentType {
+				t.Errorf("status %d, content type %s, want %d and %s", rec.Code, rec.Header().Get(echo.HeaderContentType), tt.wantStatus, tt.contentType)
+			}
+		})
+	}
+
+	decoded := item{}
+	if err := MsgPack.Decode(render("application/msgpack", http.StatusOK, single).Body, &decoded); err != nil || decoded != single {
+		t.Errorf("msgpack body = %+v, %v, want %+v", decoded, err, single)
+	}
+}
+
+func TestBinder(t *testing.T) {
+	packed, err := msgpack.Marshal(map[string]interface{}{"id": 7, "name": "lira"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		contentType string
+		body []byte
+		status int
+	}{
+		{name: "json", contentType: echo.MIMEApplicationJSON, body: []byte(`{"id":7,"name":"lira"}`)},
+		{name: "vendor json", contentType: "application/vnd.kanbersky.v2+json", body: []byte(`{"id":7,"name":"lira"}`)},
+		{name: "xml", contentType: echo.MIMEApplicationXML, body: []byte(`<item><id>7</id><name>lira</name></item>`)},
+		{name: "msgpack", contentType: MediaTypeXMsgPack, body: packed},
+		{name: "malformed msgpack", contentType: MediaTypeMsgPack, body: []byte{0xc1}, status: http.StatusBadRequest},
+		{name: "csv bodies", contentType: MediaTypeCSV, body: []byte("id,name\n7,lira\n"), status: http.StatusUnsupportedMediaType},
+		{name: "unknown", contentType: "application/pdf", body: []byte("%PDF"), status: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, tt.contentType)
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			bound := item{}
+			err := (&Binder{}).Bind(&bound, c)
+			if tt.status != 0 {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != tt.status {
+					t.Errorf("Bind = %v, want %d", err, tt.status)
+				}
+				return
+			}
+			if err != nil || bound.ID != 7 || !strings.EqualFold(bound.Name, "lira") {
+				t.Errorf("Bind = %+v, %v, want id 7 and lira", bound, err)
+			}
+		})
+	}
+}
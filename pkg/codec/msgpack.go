@@ -0,0 +1,33 @@
+package codec
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+)
+
+// structTag makes MessagePack reuse the json field names, so both formats share one schema.
+const structTag = "json"
+
+type msgPackCodec struct{}
+
+func (msgPackCodec) ContentType() string {
+	return MediaTypeMsgPack
+}
+
+func (msgPackCodec) CanEncode(interface{}) bool {
+	return true
+}
+
+func (msgPackCodec) Encode(w io.Writer, v interface{}) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag(structTag)
+
+	return encoder.Encode(v)
+}
+
+func (msgPackCodec) Decode(r io.Reader, v interface{}) error {
+	decoder := msgpack.NewDecoder(r)
+	decoder.SetCustomStructTag(structTag)
+
+	return decoder.Decode(v)
+}
@@ -0,0 +1,96 @@
+package codec
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+const MediaTypeNDJSON = "application/x-ndjson"
+
+// RowWriter writes a list one item at a time, for exports that should never hold the whole list.
+type RowWriter interface {
+	Write(v interface{}) error
+	// Flush writes buffered rows to the underlying writer
+	Flush() error
+}
+
+// NewRowWriter returns the CSV or NDJSON row writer of the media type. row is an item of the list, the
+// CSV header is taken from its type so an empty list still gets one.
+func NewRowWriter(mediaType string, w io.Writer, row interface{}) (RowWriter, error) {
+	switch mediaType {
+	case MediaTypeCSV:
+		return &csvRowWriter{
+			writer: csv.NewWriter(w),
+			columns: csvColumns(indirectType(reflect.TypeOf(row)), nil, ""),
+		}, nil
+	case MediaTypeNDJSON:
+		return &ndjsonRowWriter{encoder: json.NewEncoder(w)}, nil
+	default:
+		return nil, ErrNotAcceptable
+	}
+}
+
+// NegotiateRows picks CSV or NDJSON from the Accept header, wildcards and an empty header mean CSV.
+func NegotiateRows(accept string) (string, error) {
+	mediaTypes := acceptedMediaTypes(accept)
+	if len(mediaTypes) == 0 {
+		return MediaTypeCSV, nil
+	}
+
+	for _, mediaType := range mediaTypes {
+		switch mediaType {
+		case MediaTypeCSV, "text/*", "*/*":
+			return MediaTypeCSV, nil
+		case MediaTypeNDJSON, "application/*":
+			return MediaTypeNDJSON, nil
+		}
+	}
+
+	return "", ErrNotAcceptable
+}
+
+type csvRowWriter struct {
+	writer *csv.Writer
+	columns []csvColumn
+	record []string
+}
+
+func (c *csvRowWriter) Write(v interface{}) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	return c.writer.Write(csvRecord(reflect.ValueOf(v), c.columns, c.record))
+}
+
+func (c *csvRowWriter) Flush() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.writer.Flush()
+
+	return c.writer.Error()
+}
+
+func (c *csvRowWriter) writeHeader() error {
+	if c.record != nil {
+		return nil
+	}
+
+	c.record = make([]string, len(c.columns))
+	return c.writer.Write(csvHeader(c.columns))
+}
+
+type ndjsonRowWriter struct {
+	encoder *json.Encoder
+}
+
+func (n *ndjsonRowWriter) Write(v interface{}) error {
+	return n.encoder.Encode(v)
+}
+
+func (n *ndjsonRowWriter) Flush() error {
+	return nil
+}
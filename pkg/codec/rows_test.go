@@ -0,0 +1,66 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNegotiateRows(t *testing.T) {
+	tests := map[string]string{
+		"": MediaTypeCSV,
+		"*/*": MediaTypeCSV,
+		"text/*": MediaTypeCSV,
+		"application/x-ndjson": MediaTypeNDJSON,
+		"application/*": MediaTypeNDJSON,
+		"text/csv;q=0.5, application/x-ndjson": MediaTypeNDJSON,
+		"application/json, text/csv;q=0.1": MediaTypeCSV,
+	}
+	for accept, want := range tests {
+		if mediaType, err := NegotiateRows(accept); err != nil || mediaType != want {
+			t.Errorf("NegotiateRows(%q) = %s, %v, want %s", accept, mediaType, err, want)
+		}
+	}
+
+	if _, err := NegotiateRows("application/json"); err != ErrNotAcceptable {
+		t.Errorf("NegotiateRows(application/json) = %v, want %v", err, ErrNotAcceptable)
+	}
+}
+
+func TestRowWriters(t *testing.T) {
+	rows := []*csvRow{{ID: 1, Title: "Euro"}, {ID: 2, Title: "Lira"}}
+	tests := []struct {
+		mediaType string
+		rows []*csvRow
+		want string
+	}{
+		{mediaType: MediaTypeCSV, want: "id,title,value,active,reference,created_at,_links.self\n"},
+		{mediaType: MediaTypeCSV, rows: rows, want: "id,title,value,active,reference,created_at,_links.self\n" +
+			"1,Euro,0,false,,0001-01-01T00:00:00Z,\n2,Lira,0,false,,0001-01-01T00:00:00Z,\n"},
+		{mediaType: MediaTypeNDJSON, rows: rows, want: `{"id":1,"title":"Euro","value":0,"active":false,"reference":null,"created_at":"0001-01-01T00:00:00Z","tags":null,"_links":null}` + "\n" +
+			`{"id":2,"title":"Lira","value":0,"active":false,"reference":null,"created_at":"0001-01-01T00:00:00Z","tags":null,"_links":null}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+		w, err := NewRowWriter(tt.mediaType, buf, &csvRow{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range tt.rows {
+			if err = w.Write(row); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		if buf.String() != tt.want {
+			t.Errorf("%s rows = %q, want %q", tt.mediaType, buf, tt.want)
+		}
+	}
+
+	if _, err := NewRowWriter(MediaTypeXML, &bytes.Buffer{}, &csvRow{}); err != ErrNotAcceptable {
+		t.Errorf("NewRowWriter(xml) = %v, want %v", err, ErrNotAcceptable)
+	}
+}
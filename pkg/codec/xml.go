@@ -0,0 +1,39 @@
+package codec
+
+import (
+	"encoding/xml"
+	"io"
+	"reflect"
+)
+
+type xmlCodec struct{}
+
+// xmlList gives top level slices the single root element an XML document needs.
+type xmlList struct {
+	XMLName xml.Name `xml:"items"`
+	Items interface{}
+}
+
+func (xmlCodec) ContentType() string {
+	return MediaTypeXML
+}
+
+func (xmlCodec) CanEncode(interface{}) bool {
+	return true
+}
+
+func (xmlCodec) Encode(w io.Writer, v interface{}) error {
+	if value := reflect.ValueOf(v); value.Kind() == reflect.Slice {
+		v = xmlList{Items: v}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (xmlCodec) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
@@ -25,6 +25,24 @@ type Config struct {
 	Metric MetricConfig `mapstructure:"metric"`
 	Logger LoggerConfig `mapstructure:"logger"`
 	Jaeger JaegerConfig `mapstructure:"jaeger"`
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuitbreaker"`
+	Cache CacheConfig `mapstructure:"cache"`
+	Outbox OutboxConfig `mapstructure:"outbox"`
+	Webhook WebhookConfig `mapstructure:"webhook"`
+	Stream StreamConfig `mapstructure:"stream"`
+	WebSocket WebSocketConfig `mapstructure:"websocket"`
+	Alert AlertConfig `mapstructure:"alert"`
+	Smtp SmtpConfig `mapstructure:"smtp"`
+	Grpc GrpcConfig `mapstructure:"grpc"`
+	GraphQL GraphQLConfig `mapstructure:"graphql"`
+	Versioning VersioningConfig `mapstructure:"versioning"`
+	Export ExportConfig `mapstructure:"export"`
+	Auth AuthConfig `mapstructure:"auth"`
+	ApiKey ApiKeyConfig `mapstructure:"apikey"`
+	Rbac RbacConfig `mapstructure:"rbac"`
+	RateLimit RateLimitConfig `mapstructure:"ratelimit"`
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	Approval ApprovalConfig `mapstructure:"approval"`
 }
 
 type ServerConfig struct {
@@ -37,6 +55,19 @@ type ServerConfig struct {
 	SSL bool `mapstructure:"ssl"`
 	MaxHeaderBytes int `mapstructure:"maxheaderbytes"`
 	CtxTimeout time.Duration `mapstructure:"ctxtimeout"`
+	TLS ServerTLSConfig `mapstructure:"tls"`
+}
+
+// ServerTLSConfig applies when ServerConfig.SSL is set. The files are checked for changes every ReloadInterval
+// seconds. A ClientCAFile turns on client certificates, ClientAuth is require, the default, or optional.
+// RedirectPort, when set, serves plain http that redirects to https.
+type ServerTLSConfig struct {
+	CertFile string `mapstructure:"certfile"`
+	KeyFile string `mapstructure:"keyfile"`
+	ClientCAFile string `mapstructure:"clientcafile"`
+	ClientAuth string `mapstructure:"clientauth"`
+	ReloadInterval time.Duration `mapstructure:"reloadinterval"`
+	RedirectPort string `mapstructure:"redirectport"`
 }
 
 type LoggerConfig struct {
@@ -61,6 +92,68 @@ type PostgresConfig struct {
 
 type RedisConfig struct {
 	Url string `mapstructure:"url"`
+	Mode string `mapstructure:"mode"`
+	Addrs []string `mapstructure:"addrs"`
+	MasterName string `mapstructure:"mastername"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	SentinelUsername string `mapstructure:"sentinelusername"`
+	SentinelPassword string `mapstructure:"sentinelpassword"`
+	DB int `mapstructure:"db"`
+	PoolSize int `mapstructure:"poolsize"`
+	MinIdleConns int `mapstructure:"minidleconns"`
+	DialTimeout time.Duration `mapstructure:"dialtimeout"`
+	ReadTimeout time.Duration `mapstructure:"readtimeout"`
+	WriteTimeout time.Duration `mapstructure:"writetimeout"`
+	TLS RedisTLSConfig `mapstructure:"tls"`
+}
+
+type RedisTLSConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	InsecureSkipVerify bool `mapstructure:"insecureskipverify"`
+	ServerName string `mapstructure:"servername"`
+	CAFile string `mapstructure:"cafile"`
+	CertFile string `mapstructure:"certfile"`
+	KeyFile string `mapstructure:"keyfile"`
+}
+
+type CircuitBreakerConfig struct {
+	MaxFailures uint32 `mapstructure:"maxfailures"`
+	HalfOpenRequests uint32 `mapstructure:"halfopenrequests"`
+	Interval time.Duration `mapstructure:"interval"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+type CacheConfig struct {
+	WarmUpOnStart bool `mapstructure:"warmuponstart"`
+	WarmUpBatchSize int `mapstructure:"warmupbatchsize"`
+	SyncEnabled bool `mapstructure:"syncenabled"`
+}
+
+type OutboxConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Publisher string `mapstructure:"publisher"`
+	Stream string `mapstructure:"stream"`
+	StreamMaxLen int64 `mapstructure:"streammaxlen"`
+	PollInterval time.Duration `mapstructure:"pollinterval"`
+	BatchSize int `mapstructure:"batchsize"`
+	MaxAttempts int `mapstructure:"maxattempts"`
+}
+
+type WebhookConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	PollInterval time.Duration `mapstructure:"pollinterval"`
+	BatchSize int `mapstructure:"batchsize"`
+	MaxAttempts int `mapstructure:"maxattempts"`
+	Timeout time.Duration `mapstructure:"timeout"`
+	AllowPrivateNetworks bool `mapstructure:"allowprivatenetworks"`
+}
+
+type StreamConfig struct {
+	Channel string `mapstructure:"channel"`
+	ReplayBufferSize int `mapstructure:"replaybuffersize"`
+	ClientBufferSize int `mapstructure:"clientbuffersize"`
+	HeartbeatInterval time.Duration `mapstructure:"heartbeatinterval"`
 }
 
 type MongoConfig struct {
@@ -154,3 +247,134 @@ var ReadConfig = func(c *Config, env string) *Config {
 	return c
 }
 
+
+type WebSocketConfig struct {
+	MaxConnections int `mapstructure:"maxconnections"`
+	MaxPairs int `mapstructure:"maxpairs"`
+	MaxMessageSize int64 `mapstructure:"maxmessagesize"`
+	IdleTimeout time.Duration `mapstructure:"idletimeout"`
+	PingInterval time.Duration `mapstructure:"pinginterval"`
+	WriteTimeout time.Duration `mapstructure:"writetimeout"`
+	AllowedOrigins []string `mapstructure:"allowedorigins"`
+}
+
+type AlertConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	PollInterval time.Duration `mapstructure:"pollinterval"`
+	BatchSize int `mapstructure:"batchsize"`
+	MaxAttempts int `mapstructure:"maxattempts"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+type SmtpConfig struct {
+	Host string `mapstructure:"host"`
+	Port int `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From string `mapstructure:"from"`
+}
+
+type GrpcConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Port string `mapstructure:"port"`
+	Reflection bool `mapstructure:"reflection"`
+	Mutations bool `mapstructure:"mutations"`
+	MaxConnectionIdle time.Duration `mapstructure:"maxconnectionidle"`
+	MaxConnectionAge time.Duration `mapstructure:"maxconnectionage"`
+	Time time.Duration `mapstructure:"time"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+type GraphQLConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Introspection bool `mapstructure:"introspection"`
+	MaxDepth int `mapstructure:"maxdepth"`
+	MaxComplexity int `mapstructure:"maxcomplexity"`
+	MaxBatch int `mapstructure:"maxbatch"`
+}
+
+// VersioningConfig dates are formatted 2006-01-02 and apply to the superseded api version.
+type VersioningConfig struct {
+	DeprecatedAt string `mapstructure:"deprecatedat"`
+	SunsetAt string `mapstructure:"sunsetat"`
+}
+
+type ExportConfig struct {
+	FlushRows int `mapstructure:"flushrows"`
+	CompressionLevel int `mapstructure:"compressionlevel"`
+}
+
+// AuthConfig validates HS256 tokens with Secret and RS256/ES256 tokens with the keys of JwksFile or JwksUrl.
+// Issuer and Audience are only checked when set.
+type AuthConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Secret string `mapstructure:"secret"`
+	JwksUrl string `mapstructure:"jwksurl"`
+	JwksFile string `mapstructure:"jwksfile"`
+	JwksRefreshInterval time.Duration `mapstructure:"jwksrefreshinterval"`
+	Issuer string `mapstructure:"issuer"`
+	Audience string `mapstructure:"audience"`
+	Leeway time.Duration `mapstructure:"leeway"`
+}
+
+// ApiKeyConfig CacheTtl is how long a key lookup is cached, LastUsedInterval how often last_used_at of a
+// key is written at most.
+type ApiKeyConfig struct {
+	CacheTtl time.Duration `mapstructure:"cachettl"`
+	LastUsedInterval time.Duration `mapstructure:"lastusedinterval"`
+}
+
+// RbacConfig CacheTtl is how long the roles of a subject are cached, role binding changes apply after it.
+type RbacConfig struct {
+	CacheTtl time.Duration `mapstructure:"cachettl"`
+}
+
+// RateLimitConfig limits clients by plan, authenticated clients get the plan of their subject in Clients or
+// DefaultPlan, anonymous ones AnonymousPlan and are told apart by ip. Routes add limits to single routes on
+// top of the plan. Exempt lists route paths that are never limited, e.g. health checks.
+type RateLimitConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	TrustProxy bool `mapstructure:"trustproxy"`
+	DefaultPlan string `mapstructure:"defaultplan"`
+	AnonymousPlan string `mapstructure:"anonymousplan"`
+	Plans map[string]RateLimitPlanConfig `mapstructure:"plans"`
+	Clients []RateLimitClientConfig `mapstructure:"clients"`
+	Routes []RateLimitRouteConfig `mapstructure:"routes"`
+	Exempt []string `mapstructure:"exempt"`
+}
+
+// RateLimitPlanConfig Window is in seconds.
+type RateLimitPlanConfig struct {
+	Requests int `mapstructure:"requests"`
+	Window time.Duration `mapstructure:"window"`
+}
+
+type RateLimitClientConfig struct {
+	Subject string `mapstructure:"subject"`
+	Plan string `mapstructure:"plan"`
+}
+
+// RateLimitRouteConfig Path is the route path, e.g. /api/v1/currencies/:id, an empty Method matches every
+// method. Window is in seconds.
+type RateLimitRouteConfig struct {
+	Method string `mapstructure:"method"`
+	Path string `mapstructure:"path"`
+	Requests int `mapstructure:"requests"`
+	Window time.Duration `mapstructure:"window"`
+}
+
+// IdempotencyConfig Ttl is how long responses are replayed, LockTtl how long a request may run before a
+// retry runs it again and WaitTimeout how long a retry waits for the request still running, all in seconds.
+type IdempotencyConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Ttl time.Duration `mapstructure:"ttl"`
+	LockTtl time.Duration `mapstructure:"lockttl"`
+	WaitTimeout time.Duration `mapstructure:"waittimeout"`
+}
+
+// ApprovalConfig sets how long change requests wait for a checker before they expire and how often stale
+// ones are expired, both in seconds.
+type ApprovalConfig struct {
+	Ttl time.Duration `mapstructure:"ttl"`
+	ExpireInterval time.Duration `mapstructure:"expireinterval"`
+}
@@ -0,0 +1,117 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWait = time.Millisecond * 2
+	defaultMaxBatch = 100
+)
+
+// BatchFunc loads every key of a batch at once. Keys missing from the result load as the zero value.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader collects the keys requested within a short wait window and loads them with a single BatchFunc
+// call. Results are cached for the lifetime of the loader, so a loader is meant to live for one request.
+type Loader[K comparable, V any] struct {
+	fetch BatchFunc[K, V]
+	wait time.Duration
+	maxBatch int
+
+	mu sync.Mutex
+	cache map[K]*batch[K, V]
+	pending *batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys []K
+	results map[K]V
+	err error
+	dispatched bool
+	done chan struct{}
+}
+
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	b, ok := l.cache[key]
+	if !ok {
+		b = l.enqueue(ctx, key)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return b.results[key], b.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// enqueue adds the key to the pending batch, the caller holds the lock.
+func (l *Loader[K, V]) enqueue(ctx context.Context, key K) *batch[K, V] {
+	if l.pending == nil {
+		l.pending = &batch[K, V]{done: make(chan struct{})}
+		pending := l.pending
+		time.AfterFunc(l.wait, func() {
+			l.dispatch(ctx, pending)
+		})
+	}
+
+	b := l.pending
+	b.keys = append(b.keys, key)
+	l.cache[key] = b
+	if len(b.keys) >= l.maxBatch {
+		l.pending = nil
+		go l.dispatch(ctx, b)
+	}
+
+	return b
+}
+
+func (l *Loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	l.mu.Lock()
+	// a full batch is dispatched right away, its timer must not run it a second time
+	if b.dispatched {
+		l.mu.Unlock()
+		return
+	}
+	b.dispatched = true
+	if l.pending == b {
+		l.pending = nil
+	}
+	keys := b.keys
+	l.mu.Unlock()
+
+	results, err := l.fetch(ctx, keys)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b.results, b.err = results, err
+	if err != nil {
+		// failed keys are retried by the next load instead of serving the error for the rest of the request
+		for _, key := range keys {
+			delete(l.cache, key)
+		}
+	}
+	close(b.done)
+}
+
+func New[K comparable, V any](fetch BatchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	if wait <= 0 {
+		wait = defaultWait
+	}
+	if maxBatch <= 0 {
+		maxBatch = defaultMaxBatch
+	}
+
+	return &Loader[K, V]{
+		fetch: fetch,
+		wait: wait,
+		maxBatch: maxBatch,
+		cache: make(map[K]*batch[K, V]),
+	}
+}
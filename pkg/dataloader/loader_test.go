@@ -0,0 +1,137 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder doubles every key and records the batches it was called with.
+type recorder struct {
+	mu sync.Mutex
+	batches [][]int
+	err error
+}
+
+func (r *recorder) fetch(_ context.Context, keys []int) (map[int]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batch := append([]int(nil), keys...)
+	sort.Ints(batch)
+	r.batches = append(r.batches, batch)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	results := make(map[int]int, len(keys))
+	for _, key := range keys {
+		if key >= 0 {
+			results[key] = key * 2
+		}
+	}
+
+	return results, nil
+}
+
+func (r *recorder) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.batches)
+}
+
+func loadAll(t *testing.T, loader *Loader[int, int], keys ...int) map[int]int {
+	t.Helper()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[int]int, len(keys))
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key int) {
+			defer wg.Done()
+			value, err := loader.Load(context.Background(), key)
+			if err != nil {
+				t.Errorf("Load(%d): %v", key, err)
+			}
+			mu.Lock()
+			results[key] = value
+			mu.Unlock()
+		}(key)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func TestLoaderBatchesConcurrentLoads(t *testing.T) {
+	r := &recorder{}
+	loader := New(r.fetch, time.Millisecond * 20, 0)
+
+	results := loadAll(t, loader, 1, 2, 3, 2, -1)
+	if r.calls() != 1 || len(r.batches[0]) != 4 {
+		t.Fatalf("batches = %v, want one batch of the 4 distinct keys", r.batches)
+	}
+	if results[1] != 2 || results[3] != 6 || results[-1] != 0 {
+		t.Errorf("results = %v, want doubled keys and the zero value for a missing key", results)
+	}
+
+	// loaded keys are served from the cache
+	loadAll(t, loader, 1, 3)
+	if r.calls() != 1 {
+		t.Errorf("cached keys were fetched again, batches = %v", r.batches)
+	}
+}
+
+func TestLoaderSplitsFullBatches(t *testing.T) {
+	r := &recorder{}
+	loader := New(r.fetch, time.Millisecond * 20, 2)
+
+	loadAll(t, loader, 1, 2, 3, 4, 5)
+	if r.calls() != 3 {
+		t.Errorf("batches = %v, want 3 batches of at most 2 keys", r.batches)
+	}
+	for _, batch := range r.batches {
+		if len(batch) > 2 {
+			t.Errorf("batch %v is larger than the limit", batch)
+		}
+	}
+}
+
+func TestLoaderRetriesFailedKeys(t *testing.T) {
+	r := &recorder{err: errors.New("database is down")}
+	loader := New(r.fetch, 0, 0)
+
+	if _, err := loader.Load(context.Background(), 1); err == nil {
+		t.Fatal("Load did not return the batch error")
+	}
+
+	r.mu.Lock()
+	r.err = nil
+	r.mu.Unlock()
+	value, err := loader.Load(context.Background(), 1)
+	if err != nil || value != 2 {
+		t.Errorf("Load after the failure = %d, %v, want 2", value, err)
+	}
+	if r.calls() != 2 {
+		t.Errorf("batches = %v, want the failed key fetched again", r.batches)
+	}
+}
+
+func TestLoaderStopsWaitingWhenTheContextIsDone(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	loader := New(func(ctx context.Context, keys []int) (map[int]int, error) {
+		<-release
+		return nil, nil
+	}, 0, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond * 20)
+	defer cancel()
+	if _, err := loader.Load(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Load = %v, want the context error", err)
+	}
+}
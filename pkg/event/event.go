@@ -0,0 +1,21 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type Event struct {
+	ID string `json:"id"`
+	Type string `json:"type"`
+	AggregateType string `json:"aggregate_type"`
+	AggregateID string `json:"aggregate_id"`
+	Payload json.RawMessage `json:"payload"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// EventPublisher delivers events at least once, consumers should deduplicate by Event.ID.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
@@ -0,0 +1,56 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+type InMemoryPublisher interface {
+	EventPublisher
+	Events() []Event
+	Subscribe(buffer int) <-chan Event
+}
+
+type inMemoryPublisher struct {
+	mu sync.RWMutex
+	events []Event
+	subscribers []chan Event
+}
+
+func (m *inMemoryPublisher) Publish(ctx context.Context, event Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, event)
+	for _, subscriber := range m.subscribers {
+		// a slow subscriber only misses events, it never blocks the publisher
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (m *inMemoryPublisher) Events() []Event {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := make([]Event, len(m.events))
+	copy(events, m.events)
+	return events
+}
+
+func (m *inMemoryPublisher) Subscribe(buffer int) <-chan Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscriber := make(chan Event, buffer)
+	m.subscribers = append(m.subscribers, subscriber)
+	return subscriber
+}
+
+func NewInMemoryPublisher() InMemoryPublisher {
+	return &inMemoryPublisher{}
+}
@@ -0,0 +1,28 @@
+package event
+
+import (
+	"context"
+	"github.com/pkg/errors"
+)
+
+type multiPublisher struct {
+	publishers []EventPublisher
+}
+
+// Publish hands the event to every publisher. When one of them fails the whole event
+// is retried, so the others may see it again.
+func (m multiPublisher) Publish(ctx context.Context, event Event) error {
+	for _, publisher := range m.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return errors.WithMessage(err, "multiPublisher.Publish")
+		}
+	}
+
+	return nil
+}
+
+func NewMultiPublisher(publishers ...EventPublisher) EventPublisher {
+	return &multiPublisher{
+		publishers: publishers,
+	}
+}
@@ -0,0 +1,47 @@
+package event
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"time"
+)
+
+type redisStreamPublisher struct {
+	redisClient redis.UniversalClient
+	stream string
+	maxLen int64
+}
+
+func (r redisStreamPublisher) Publish(ctx context.Context, event Event) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "redisStreamPublisher.Publish")
+	defer span.Finish()
+
+	err := r.redisClient.XAdd(spanContext, &redis.XAddArgs{
+		Stream: r.stream,
+		MaxLen: r.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"id": event.ID,
+			"type": event.Type,
+			"aggregate_type": event.AggregateType,
+			"aggregate_id": event.AggregateID,
+			"payload": string(event.Payload),
+			"occurred_at": event.OccurredAt.Format(time.RFC3339Nano),
+		},
+	}).Err()
+	if err != nil {
+		return errors.Wrap(err, "redisStreamPublisher.Publish.RedisClient.XAdd")
+	}
+
+	return nil
+}
+
+func NewRedisStreamPublisher(redisClient redis.UniversalClient, stream string, maxLen int64) EventPublisher {
+	return &redisStreamPublisher{
+		redisClient: redisClient,
+		stream: stream,
+		maxLen: maxLen,
+	}
+}
@@ -0,0 +1,48 @@
+package idempotency
+
+import (
+	"context"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sony/gobreaker"
+	"time"
+)
+
+type breakerStore struct {
+	next Store
+	breaker *gobreaker.CircuitBreaker
+}
+
+func (b breakerStore) Lock(ctx context.Context, key string, lock Record, ttl time.Duration) (*Record, error) {
+	result, err := breaker.Execute(b.breaker, "breakerStore.Lock", func() (interface{}, error) {
+		return b.next.Lock(ctx, key, lock, ttl)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*Record), nil
+}
+
+func (b breakerStore) Complete(ctx context.Context, key string, lock Record, record Record, ttl time.Duration) error {
+	_, err := breaker.Execute(b.breaker, "breakerStore.Complete", func() (interface{}, error) {
+		return nil, b.next.Complete(ctx, key, lock, record, ttl)
+	})
+
+	return err
+}
+
+func (b breakerStore) Unlock(ctx context.Context, key string, lock Record) error {
+	_, err := breaker.Execute(b.breaker, "breakerStore.Unlock", func() (interface{}, error) {
+		return nil, b.next.Unlock(ctx, key, lock)
+	})
+
+	return err
+}
+
+// NewBreakerStore calls next through the circuit breaker.
+func NewBreakerStore(next Store, breaker *gobreaker.CircuitBreaker) Store {
+	return &breakerStore{
+		next: next,
+		breaker: breaker,
+	}
+}
@@ -0,0 +1,111 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"time"
+)
+
+var (
+	lockScript = redis.NewScript(`
+local record = redis.call('GET', KEYS[1])
+if record then
+	return record
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return false
+`)
+	// the lock is compared as a whole, its token makes it unique
+	completeScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+	return 1
+end
+return 0
+`)
+	unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+)
+
+type redisStore struct {
+	redisClient redis.UniversalClient
+}
+
+func (r redisStore) Lock(ctx context.Context, key string, lock Record, ttl time.Duration) (*Record, error) {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "redisStore.Lock")
+	defer span.Finish()
+
+	lockByte, err := json.Marshal(lock)
+	if err != nil {
+		return nil, errors.Wrap(err, "redisStore.Lock.Json.Marshal")
+	}
+
+	recordByte, err := lockScript.Run(spanContext, r.redisClient, []string{key}, lockByte, ttl.Milliseconds()).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "redisStore.Lock.Script.Run")
+	}
+
+	return unmarshalRecord(recordByte)
+}
+
+func (r redisStore) Complete(ctx context.Context, key string, lock Record, record Record, ttl time.Duration) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "redisStore.Complete")
+	defer span.Finish()
+
+	lockByte, err := json.Marshal(lock)
+	if err != nil {
+		return errors.Wrap(err, "redisStore.Complete.Json.Marshal")
+	}
+	recordByte, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "redisStore.Complete.Json.Marshal")
+	}
+
+	if err = completeScript.Run(spanContext, r.redisClient, []string{key}, lockByte, recordByte, ttl.Milliseconds()).Err(); err != nil {
+		return errors.Wrap(err, "redisStore.Complete.Script.Run")
+	}
+
+	return nil
+}
+
+func (r redisStore) Unlock(ctx context.Context, key string, lock Record) error {
+	span, spanContext := opentracing.StartSpanFromContext(ctx, "redisStore.Unlock")
+	defer span.Finish()
+
+	lockByte, err := json.Marshal(lock)
+	if err != nil {
+		return errors.Wrap(err, "redisStore.Unlock.Json.Marshal")
+	}
+
+	if err = unlockScript.Run(spanContext, r.redisClient, []string{key}, lockByte).Err(); err != nil {
+		return errors.Wrap(err, "redisStore.Unlock.Script.Run")
+	}
+
+	return nil
+}
+
+func unmarshalRecord(recordByte string) (*Record, error) {
+	record := &Record{}
+	if err := json.Unmarshal([]byte(recordByte), record); err != nil {
+		return nil, errors.Wrap(err, "redisStore.Json.Unmarshal")
+	}
+
+	return record, nil
+}
+
+// NewRedisStore shares the idempotency keys between all replicas using the redis client.
+func NewRedisStore(redisClient redis.UniversalClient) Store {
+	return &redisStore{
+		redisClient: redisClient,
+	}
+}
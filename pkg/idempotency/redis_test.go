@@ -0,0 +1,84 @@
+package idempotency
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) (Store, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() {
+		redisClient.Close()
+	})
+
+	return NewRedisStore(redisClient), mr
+}
+
+func TestRedisStoreLocksOnce(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	first := Record{Fingerprint: "post /currencies", Token: "first"}
+	second := Record{Fingerprint: "post /currencies", Token: "second"}
+
+	if record, err := store.Lock(ctx, "key", first, time.Minute); err != nil || record != nil {
+		t.Fatalf("Lock = %+v, %v, want the lock", record, err)
+	}
+	if ttl := mr.TTL("key"); ttl != time.Minute {
+		t.Errorf("lock ttl = %s, want 1m", ttl)
+	}
+	record, err := store.Lock(ctx, "key", second, time.Minute)
+	if err != nil || record == nil || record.Token != "first" || record.Completed {
+		t.Fatalf("Lock = %+v, %v, want the in flight record of the first request", record, err)
+	}
+
+	completed := Record{Fingerprint: first.Fingerprint, Completed: true, Status: http.StatusCreated, Header: http.Header{"Location": {"/currencies/7"}}, Body: []byte(`{"id":7}`)}
+	if err = store.Complete(ctx, "key", first, completed, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if record, err = store.Lock(ctx, "key", second, time.Minute); err != nil || !reflect.DeepEqual(*record, completed) {
+		t.Errorf("Lock = %+v, %v, want the completed record", record, err)
+	}
+	if ttl := mr.TTL("key"); ttl != time.Hour {
+		t.Errorf("record ttl = %s, want 1h", ttl)
+	}
+}
+
+func TestRedisStoreOnlyTheLockHolderCompletes(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	first := Record{Fingerprint: "post /currencies", Token: "first"}
+	second := Record{Fingerprint: "post /currencies", Token: "second"}
+
+	// the lock of the first request expired and a retry took the key over
+	if _, err := store.Lock(ctx, "key", first, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(time.Minute)
+	if record, err := store.Lock(ctx, "key", second, time.Minute); err != nil || record != nil {
+		t.Fatalf("Lock = %+v, %v, want the expired lock replaced", record, err)
+	}
+
+	if err := store.Complete(ctx, "key", first, Record{Fingerprint: first.Fingerprint, Completed: true, Status: http.StatusCreated}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Unlock(ctx, "key", first); err != nil {
+		t.Fatal(err)
+	}
+	if record, _ := store.Lock(ctx, "key", first, time.Minute); record == nil || record.Token != "second" {
+		t.Fatalf("record = %+v, want the lock of the retry untouched", record)
+	}
+
+	if err := store.Unlock(ctx, "key", second); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("key") {
+		t.Error("Unlock left the lock of its holder")
+	}
+}
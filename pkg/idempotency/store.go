@@ -0,0 +1,29 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Record is the state of an idempotency key, in flight while the first request runs and completed with its
+// response afterwards.
+type Record struct {
+	Fingerprint string `json:"fingerprint"`
+	// Token tells the locks of concurrent requests apart, only the request holding the lock completes it
+	Token string `json:"token,omitempty"`
+	Completed bool `json:"completed"`
+	Status int `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body []byte `json:"body,omitempty"`
+}
+
+// Store keeps the records of idempotency keys.
+type Store interface {
+	// Lock stores the in flight record unless the key has a record, which is returned then
+	Lock(ctx context.Context, key string, lock Record, ttl time.Duration) (*Record, error)
+	// Complete replaces the lock with the completed record, a lock that expired meanwhile is not replaced
+	Complete(ctx context.Context, key string, lock Record, record Record, ttl time.Duration) error
+	// Unlock drops the lock, so the request can be retried
+	Unlock(ctx context.Context, key string, lock Record) error
+}
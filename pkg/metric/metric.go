@@ -11,12 +11,17 @@ import (
 type Metrics interface {
 	IncreaseHits(status int, method, path string)
 	ObserveResponseTime(status int, method, path string, observeTime float64)
+	SetCircuitBreakerState(name string, state int)
+	IncreaseActiveConnections(transport string)
+	DecreaseActiveConnections(transport string)
 }
 
 type PrometheusMetrics struct {
 	HitsTotal prometheus.Counter
 	Hits *prometheus.CounterVec
 	Times *prometheus.HistogramVec
+	CircuitBreakerState *prometheus.GaugeVec
+	ActiveConnections *prometheus.GaugeVec
 }
 
 func (promMetric *PrometheusMetrics) IncreaseHits(status int, method, path string) {
@@ -28,6 +33,18 @@ func (promMetric *PrometheusMetrics) ObserveResponseTime(status int, method, pat
 	promMetric.Times.WithLabelValues(strconv.Itoa(status), method, path).Observe(observeTime)
 }
 
+func (promMetric *PrometheusMetrics) SetCircuitBreakerState(name string, state int) {
+	promMetric.CircuitBreakerState.WithLabelValues(name).Set(float64(state))
+}
+
+func (promMetric *PrometheusMetrics) IncreaseActiveConnections(transport string) {
+	promMetric.ActiveConnections.WithLabelValues(transport).Inc()
+}
+
+func (promMetric *PrometheusMetrics) DecreaseActiveConnections(transport string) {
+	promMetric.ActiveConnections.WithLabelValues(transport).Dec()
+}
+
 func CreateMetrics(address string, name string) (Metrics, error) {
 	var promMetric PrometheusMetrics
 	promMetric.HitsTotal = prometheus.NewCounter(prometheus.CounterOpts{
@@ -60,6 +77,30 @@ func CreateMetrics(address string, name string) (Metrics, error) {
 		return nil, err
 	}
 
+	promMetric.CircuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: name + "_circuit_breaker_state",
+			Help: "Circuit breaker state: 0 closed, 1 half-open, 2 open",
+		},
+		[]string{"name"},
+	)
+
+	if err := prometheus.Register(promMetric.CircuitBreakerState); err != nil {
+		return nil, err
+	}
+
+	promMetric.ActiveConnections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: name + "_active_connections",
+			Help: "Open streaming connections by transport",
+		},
+		[]string{"transport"},
+	)
+
+	if err := prometheus.Register(promMetric.ActiveConnections); err != nil {
+		return nil, err
+	}
+
 	go func() {
 		router := echo.New()
 		router.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"github.com/sony/gobreaker"
+)
+
+type fallbackLimiter struct {
+	primary Limiter
+	fallback Limiter
+	breaker *gobreaker.CircuitBreaker
+	logger logger.Logger
+}
+
+func (f fallbackLimiter) Allow(ctx context.Context, buckets ...Bucket) (Result, error) {
+	result, err := f.breaker.Execute(func() (interface{}, error) {
+		return f.primary.Allow(ctx, buckets...)
+	})
+	if err == nil {
+		return result.(Result), nil
+	}
+
+	if err = breaker.Wrap(err, "fallbackLimiter.Allow"); errors.Is(err, breaker.ErrUnavailable) {
+		f.logger.Debugf("Rate limiting falls back to memory: %s", err)
+	} else {
+		f.logger.Errorf("Rate limiting falls back to memory: %s", err)
+	}
+
+	return f.fallback.Allow(ctx, buckets...)
+}
+
+// NewFallbackLimiter asks primary through the circuit breaker and fallback while primary fails.
+func NewFallbackLimiter(primary Limiter, fallback Limiter, breaker *gobreaker.CircuitBreaker, logger logger.Logger) Limiter {
+	return &fallbackLimiter{
+		primary: primary,
+		fallback: fallback,
+		breaker: breaker,
+		logger: logger,
+	}
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/breaker"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"testing"
+	"time"
+)
+
+type failingLimiter struct {
+	calls int
+}
+
+func (f *failingLimiter) Allow(context.Context, ...Bucket) (Result, error) {
+	f.calls++
+	return Result{}, errors.New("dial tcp: connection refused")
+}
+
+func TestFallbackLimiterUsesMemoryWhileRedisIsDown(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	primary := &failingLimiter{}
+	limiter := NewFallbackLimiter(primary, NewMemoryLimiter(), breaker.NewCircuitBreaker("redis", config.CircuitBreakerConfig{MaxFailures: 2, Timeout: 60}, nil, l), l)
+	bucket := Bucket{Key: "client", Limit: Limit{Requests: 3, Window: time.Hour}}
+
+	for want := 2; want >= 0; want-- {
+		result, err := limiter.Allow(context.Background(), bucket)
+		if err != nil || !result.Allowed || result.Remaining != want {
+			t.Fatalf("result = %+v, %v, want allowed by memory with %d remaining", result, err, want)
+		}
+	}
+	if result, _ := limiter.Allow(context.Background(), bucket); result.Allowed {
+		t.Error("the memory fallback did not limit")
+	}
+	// the open breaker spares redis the calls
+	if primary.calls != 2 {
+		t.Errorf("redis calls = %d, want 2 until the breaker opened", primary.calls)
+	}
+}
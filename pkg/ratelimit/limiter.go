@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit lets Requests through per Window, refilled continuously, so a client can burst up to Requests and
+// then gets one request per Window/Requests.
+type Limit struct {
+	Requests int
+	Window time.Duration
+}
+
+func (l Limit) valid() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+// Bucket is a token bucket of a client, e.g. the bucket of its plan or of a route.
+type Bucket struct {
+	Key string
+	Limit Limit
+}
+
+// Result is the state of the most restrictive bucket of a request.
+type Result struct {
+	Allowed bool
+	Limit Limit
+	Remaining int
+	// Reset is when the bucket is full again
+	Reset time.Duration
+	// RetryAfter is when the next request would be allowed, zero for allowed requests
+	RetryAfter time.Duration
+}
+
+// Limiter takes a token from every bucket, or from none of them when one is empty.
+type Limiter interface {
+	Allow(ctx context.Context, buckets ...Bucket) (Result, error)
+}
+
+type bucketResult struct {
+	allowed bool
+	remaining int
+	reset time.Duration
+	retryAfter time.Duration
+}
+
+// merge reports the bucket that runs out first, for denied requests the one that refills last.
+func merge(buckets []Bucket, results []bucketResult) Result {
+	result := Result{Allowed: true}
+	for i, r := range results {
+		switch {
+		case !r.allowed && (result.Allowed || r.retryAfter > result.RetryAfter):
+			result = Result{Limit: buckets[i].Limit, Remaining: r.remaining, Reset: r.reset, RetryAfter: r.retryAfter}
+		case result.Allowed && (i == 0 || r.remaining < result.Remaining):
+			result = Result{Allowed: true, Limit: buckets[i].Limit, Remaining: r.remaining, Reset: r.reset}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+// newTestLimiters returns both limiters, the redis one on a miniredis with a frozen clock.
+func newTestLimiters(t *testing.T) (map[string]Limiter, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.SetTime(testNow)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() {
+		redisClient.Close()
+	})
+
+	return map[string]Limiter{"memory": NewMemoryLimiter(), "redis": NewRedisLimiter(redisClient)}, mr
+}
+
+// within tells durations apart that only differ by the time the memory limiter saw passing.
+func within(got time.Duration, want time.Duration) bool {
+	return got <= want && got > want-time.Second
+}
+
+func TestLimiterBurstsThenDenies(t *testing.T) {
+	limiters, _ := newTestLimiters(t)
+	hourly := Limit{Requests: 3, Window: time.Hour}
+
+	for name, limiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			for want := 2; want >= 0; want-- {
+				result, err := limiter.Allow(context.Background(), Bucket{Key: "client", Limit: hourly})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !result.Allowed || result.Remaining != want || result.RetryAfter != 0 || result.Limit != hourly {
+					t.Fatalf("result = %+v, want allowed with %d remaining", result, want)
+				}
+				if reset := time.Duration(3-want) * 20 * time.Minute; !within(result.Reset, reset) {
+					t.Errorf("reset = %s, want %s", result.Reset, reset)
+				}
+			}
+
+			result, err := limiter.Allow(context.Background(), Bucket{Key: "client", Limit: hourly})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// one token comes back every 20 minutes
+			if result.Allowed || result.Remaining != 0 || !within(result.RetryAfter, 20*time.Minute) || !within(result.Reset, time.Hour) {
+				t.Errorf("result = %+v, want denied, retry after 20m", result)
+			}
+
+			// other clients have buckets of their own
+			if result, _ = limiter.Allow(context.Background(), Bucket{Key: "other", Limit: hourly}); !result.Allowed {
+				t.Error("another client was denied")
+			}
+		})
+	}
+}
+
+func TestLimiterReportsTheMostRestrictiveBucket(t *testing.T) {
+	limiters, _ := newTestLimiters(t)
+	plan := Bucket{Key: "client:plan", Limit: Limit{Requests: 10, Window: time.Hour}}
+	route := Bucket{Key: "client:route", Limit: Limit{Requests: 2, Window: time.Hour}}
+	other := Bucket{Key: "client:other", Limit: Limit{Requests: 5, Window: time.Hour}}
+
+	for name, limiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				if result, err := limiter.Allow(context.Background(), plan, route); err != nil || !result.Allowed || result.Limit != route.Limit {
+					t.Fatalf("result = %+v, %v, want allowed by the route limit", result, err)
+				}
+			}
+			result, err := limiter.Allow(context.Background(), plan, route)
+			if err != nil || result.Allowed || result.Limit != route.Limit {
+				t.Fatalf("result = %+v, %v, want denied by the route limit", result, err)
+			}
+
+			// the denied request cost the plan nothing
+			if result, _ = limiter.Allow(context.Background(), plan, other); !result.Allowed || result.Remaining != 4 || result.Limit != other.Limit {
+				t.Errorf("result = %+v, want 4 left of the other bucket", result)
+			}
+			if result, _ = limiter.Allow(context.Background(), plan); result.Remaining != 6 {
+				t.Errorf("plan remaining = %d, want 6 after 4 allowed requests", result.Remaining)
+			}
+		})
+	}
+}
+
+func TestLimiterRejectsInvalidLimits(t *testing.T) {
+	limiters, _ := newTestLimiters(t)
+	for name, limiter := range limiters {
+		if _, err := limiter.Allow(context.Background(), Bucket{Key: "client", Limit: Limit{Requests: 1}}); err == nil {
+			t.Errorf("%s accepted a limit without a window", name)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"math"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type memoryBucket struct {
+	tokens float64
+	updatedAt time.Time
+	window time.Duration
+}
+
+// memoryLimiter keeps the buckets of this instance only, a client gets the limit once per replica.
+type memoryLimiter struct {
+	mu sync.Mutex
+	buckets map[string]*memoryBucket
+	sweptAt time.Time
+}
+
+func (m *memoryLimiter) Allow(ctx context.Context, buckets ...Bucket) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	tokens := make([]float64, len(buckets))
+	allowed := true
+	for i, bucket := range buckets {
+		if !bucket.Limit.valid() {
+			return Result{}, errors.Errorf("ratelimit: invalid limit of bucket %s", bucket.Key)
+		}
+		tokens[i] = float64(bucket.Limit.Requests)
+		if state, ok := m.buckets[bucket.Key]; ok {
+			tokens[i] = math.Min(tokens[i], state.tokens+float64(now.Sub(state.updatedAt))*rate(bucket.Limit))
+		}
+		if tokens[i] < 1 {
+			allowed = false
+		}
+	}
+
+	results := make([]bucketResult, len(buckets))
+	for i, bucket := range buckets {
+		var retryAfter time.Duration
+		if allowed {
+			tokens[i]--
+		} else if tokens[i] < 1 {
+			retryAfter = time.Duration(math.Ceil((1 - tokens[i]) / rate(bucket.Limit)))
+		}
+		m.buckets[bucket.Key] = &memoryBucket{tokens: tokens[i], updatedAt: now, window: bucket.Limit.Window}
+		results[i] = bucketResult{
+			allowed: allowed,
+			remaining: int(tokens[i]),
+			reset: time.Duration(math.Ceil((float64(bucket.Limit.Requests) - tokens[i]) / rate(bucket.Limit))),
+			retryAfter: retryAfter,
+		}
+	}
+
+	return merge(buckets, results), nil
+}
+
+// sweep drops the buckets that are full again, they are the same as no bucket at all.
+func (m *memoryLimiter) sweep(now time.Time) {
+	if now.Sub(m.sweptAt) < sweepInterval {
+		return
+	}
+	m.sweptAt = now
+
+	for key, bucket := range m.buckets {
+		if now.Sub(bucket.updatedAt) > bucket.window {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+// rate is the refill rate in tokens per nanosecond.
+func rate(limit Limit) float64 {
+	return float64(limit.Requests) / float64(limit.Window)
+}
+
+// NewMemoryLimiter keeps the buckets in memory, for a single instance or as the fallback of a shared limiter.
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{
+		buckets: make(map[string]*memoryBucket),
+	}
+}
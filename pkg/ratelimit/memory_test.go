@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiterRefills(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	bucket := Bucket{Key: "client", Limit: Limit{Requests: 1, Window: 50 * time.Millisecond}}
+
+	if result, _ := limiter.Allow(context.Background(), bucket); !result.Allowed {
+		t.Fatal("the first request was denied")
+	}
+	if result, _ := limiter.Allow(context.Background(), bucket); result.Allowed {
+		t.Fatal("the second request was allowed")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if result, _ := limiter.Allow(context.Background(), bucket); !result.Allowed {
+		t.Error("the bucket was not refilled")
+	}
+}
+
+func TestMemoryLimiterSweepsFullBuckets(t *testing.T) {
+	limiter := NewMemoryLimiter().(*memoryLimiter)
+	if _, err := limiter.Allow(context.Background(), Bucket{Key: "idle", Limit: Limit{Requests: 1, Window: time.Millisecond}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.Allow(context.Background(), Bucket{Key: "busy", Limit: Limit{Requests: 1, Window: time.Hour}}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	limiter.sweptAt = time.Time{}
+	if _, err := limiter.Allow(context.Background(), Bucket{Key: "busy", Limit: Limit{Requests: 1, Window: time.Hour}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := limiter.buckets["idle"]; ok || len(limiter.buckets) != 1 {
+		t.Errorf("buckets = %v, want only the busy one", limiter.buckets)
+	}
+}
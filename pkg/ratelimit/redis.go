@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// tokenBucketScript checks every bucket before taking a token from any, so a request denied by one bucket
+// costs nothing in the others. The clock is the redis clock, replicas with skewed clocks share buckets fine.
+// The reply has allowed first, then remaining, reset and retry after in milliseconds for each bucket.
+var tokenBucketScript = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local tokens = {}
+local rates = {}
+local allowed = 1
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[i * 2 - 1])
+	local rate = capacity / tonumber(ARGV[i * 2])
+	local state = redis.call('HMGET', key, 'tokens', 'ts')
+	local available = tonumber(state[1])
+	if available == nil then
+		available = capacity
+	else
+		available = math.min(capacity, available + math.max(0, now - tonumber(state[2])) * rate)
+	end
+	tokens[i] = available
+	rates[i] = rate
+	if available < 1 then
+		allowed = 0
+	end
+end
+local reply = {allowed}
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[i * 2 - 1])
+	local retry = 0
+	if allowed == 1 then
+		tokens[i] = tokens[i] - 1
+	elseif tokens[i] < 1 then
+		retry = math.ceil((1 - tokens[i]) / rates[i])
+	end
+	redis.call('HSET', key, 'tokens', tostring(tokens[i]), 'ts', now)
+	redis.call('PEXPIRE', key, tonumber(ARGV[i * 2]))
+	table.insert(reply, math.floor(tokens[i]))
+	table.insert(reply, math.ceil((capacity - tokens[i]) / rates[i]))
+	table.insert(reply, retry)
+end
+return reply
+`)
+
+type redisLimiter struct {
+	redisClient redis.UniversalClient
+}
+
+// Allow needs all keys in one hash slot on redis cluster, e.g. by a {client} hash tag in every key.
+func (r redisLimiter) Allow(ctx context.Context, buckets ...Bucket) (Result, error) {
+	keys := make([]string, 0, len(buckets))
+	args := make([]interface{}, 0, len(buckets)*2)
+	for _, bucket := range buckets {
+		if !bucket.Limit.valid() {
+			return Result{}, errors.Errorf("ratelimit: invalid limit of bucket %s", bucket.Key)
+		}
+		keys = append(keys, bucket.Key)
+		args = append(args, bucket.Limit.Requests, bucket.Limit.Window.Milliseconds())
+	}
+	if len(buckets) == 0 {
+		return Result{Allowed: true}, nil
+	}
+
+	reply, err := tokenBucketScript.Run(ctx, r.redisClient, keys, args...).Int64Slice()
+	if err != nil {
+		return Result{}, errors.Wrap(err, "redisLimiter.Allow.Script.Run")
+	}
+	if len(reply) != 1+len(buckets)*3 {
+		return Result{}, errors.Errorf("redisLimiter.Allow: unexpected reply of %d values", len(reply))
+	}
+
+	results := make([]bucketResult, len(buckets))
+	for i := range buckets {
+		values := reply[1+i*3:]
+		results[i] = bucketResult{
+			allowed: reply[0] == 1,
+			remaining: int(values[0]),
+			reset: time.Millisecond * time.Duration(values[1]),
+			retryAfter: time.Millisecond * time.Duration(values[2]),
+		}
+	}
+
+	return merge(buckets, results), nil
+}
+
+// NewRedisLimiter shares the buckets between all replicas using the redis client.
+func NewRedisLimiter(redisClient redis.UniversalClient) Limiter {
+	return &redisLimiter{
+		redisClient: redisClient,
+	}
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRedisLimiterRefillsOnTheRedisClock(t *testing.T) {
+	limiters, mr := newTestLimiters(t)
+	limiter := limiters["redis"]
+	bucket := Bucket{Key: "ratelimit:{alice}:plan", Limit: Limit{Requests: 2, Window: 10 * time.Second}}
+	allow := func() Result {
+		t.Helper()
+		result, err := limiter.Allow(context.Background(), bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	allow()
+	allow()
+	if result := allow(); result.Allowed || result.RetryAfter != 5*time.Second || result.Reset != 10*time.Second {
+		t.Fatalf("result = %+v, want denied, retry after 5s", result)
+	}
+	if ttl := mr.TTL(bucket.Key); ttl != bucket.Limit.Window {
+		t.Errorf("bucket ttl = %s, want the window", ttl)
+	}
+
+	mr.SetTime(testNow.Add(2500 * time.Millisecond))
+	if result := allow(); result.Allowed || result.RetryAfter != 2500*time.Millisecond {
+		t.Fatalf("result = %+v, want half a token, retry after 2.5s", result)
+	}
+	mr.SetTime(testNow.Add(5 * time.Second))
+	if result := allow(); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("result = %+v, want the refilled token", result)
+	}
+
+	// a full window refills the bucket, never beyond its capacity
+	mr.SetTime(testNow.Add(time.Hour))
+	if result := allow(); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("result = %+v, want 1 remaining after a full refill", result)
+	}
+}
+
+func TestRedisLimiterWithoutBuckets(t *testing.T) {
+	limiters, _ := newTestLimiters(t)
+	if result, err := limiters["redis"].Allow(context.Background()); err != nil || !result.Allowed {
+		t.Errorf("Allow() = %+v, %v, want allowed", result, err)
+	}
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"time"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Second * 30
+)
+
+type NotificationHandler func(ctx context.Context, payload string)
+
+type Listener interface {
+	// Listen blocks until ctx is done. onReconnect is called after a lost connection is
+	// re-established, since notifications sent in between are not delivered.
+	Listen(ctx context.Context, channel string, onNotification NotificationHandler, onReconnect func(ctx context.Context)) error
+}
+
+type listener struct {
+	cfg *config.Config
+	logger logger.Logger
+}
+
+func (l listener) Listen(ctx context.Context, channel string, onNotification NotificationHandler, onReconnect func(ctx context.Context)) error {
+	delay := minReconnectDelay
+	connected := false
+	for {
+		err := l.listen(ctx, channel, onNotification, func() {
+			if connected && onReconnect != nil {
+				onReconnect(ctx)
+			}
+			connected = true
+			delay = minReconnectDelay
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		l.logger.Errorf("postgres.Listener.Listen, Channel: %s, Retry in: %s, Error: %s", channel, delay, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+func (l listener) listen(ctx context.Context, channel string, onNotification NotificationHandler, onConnect func()) error {
+	conn, err := pgx.Connect(ctx, ConnectionString(l.cfg))
+	if err != nil {
+		return errors.Wrap(err, "postgres.Listener.Connect")
+	}
+	defer conn.Close(context.Background())
+
+	if _, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return errors.Wrap(err, "postgres.Listener.Exec.Listen")
+	}
+	l.logger.Infof("Listening postgres notifications, Channel: %s", channel)
+	onConnect()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return errors.Wrap(err, "postgres.Listener.WaitForNotification")
+		}
+
+		onNotification(ctx, notification.Payload)
+	}
+}
+
+func NewListener(cfg *config.Config, logger logger.Logger) Listener {
+	return &listener{
+		cfg: cfg,
+		logger: logger,
+	}
+}
@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeServer speaks enough of the postgres protocol for LISTEN, every listening connection is handed to the test.
+type fakeServer struct {
+	listener net.Listener
+	conns chan *fakeConn
+}
+
+type fakeConn struct {
+	net.Conn
+	backend *pgproto3.Backend
+	query string
+}
+
+func (c *fakeConn) notify(channel string, payload string) error {
+	return c.backend.Send(&pgproto3.NotificationResponse{PID: 1, Channel: channel, Payload: payload})
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: listener, conns: make(chan *fakeConn, 4)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	backend := pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+	for {
+		startup, err := backend.ReceiveStartupMessage()
+		if err != nil {
+			conn.Close()
+			return
+		}
+		// tls is refused, the client falls back to a plain connection
+		if _, ok := startup.(*pgproto3.SSLRequest); ok {
+			if _, err = conn.Write([]byte("N")); err != nil {
+				conn.Close()
+				return
+			}
+			continue
+		}
+		break
+	}
+
+	for _, msg := range []pgproto3.BackendMessage{&pgproto3.AuthenticationOk{}, &pgproto3.ReadyForQuery{TxStatus: 'I'}} {
+		if err := backend.Send(msg); err != nil {
+			conn.Close()
+			return
+		}
+	}
+
+	msg, err := backend.Receive()
+	query, ok := msg.(*pgproto3.Query)
+	if err != nil || !ok {
+		conn.Close()
+		return
+	}
+	for _, msg := range []pgproto3.BackendMessage{&pgproto3.CommandComplete{CommandTag: []byte("LISTEN")}, &pgproto3.ReadyForQuery{TxStatus: 'I'}} {
+		if err = backend.Send(msg); err != nil {
+			conn.Close()
+			return
+		}
+	}
+
+	s.conns <- &fakeConn{Conn: conn, backend: backend, query: query.String}
+}
+
+func (s *fakeServer) accept(t *testing.T) *fakeConn {
+	t.Helper()
+	select {
+	case conn := <-s.conns:
+		return conn
+	case <-time.After(time.Second * 5):
+		t.Fatal("the listener did not connect")
+		return nil
+	}
+}
+
+func receive(t *testing.T, values chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-values:
+		if got != want {
+			t.Errorf("received %q, want %q", got, want)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("did not receive %q", want)
+	}
+}
+
+func TestListenerDeliversNotificationsAndReconnects(t *testing.T) {
+	server := newFakeServer(t)
+	cfg := &config.Config{}
+	cfg.Logger.Level = "fatal"
+	cfg.Postgres.Host = "127.0.0.1"
+	cfg.Postgres.Port = strconv.Itoa(server.listener.Addr().(*net.TCPAddr).Port)
+	cfg.Postgres.UserName = "app"
+	cfg.Postgres.Password = "secret"
+	cfg.Postgres.DbName = "currencies"
+	l := logger.NewLogger(cfg)
+	l.InitLogger()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	payloads := make(chan string, 4)
+	reconnects := make(chan string, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- NewListener(cfg, l).Listen(ctx, "currency_changes",
+			func(_ context.Context, payload string) { payloads <- payload },
+			func(_ context.Context) { reconnects <- "reconnected" })
+	}()
+
+	conn := server.accept(t)
+	if conn.query != `LISTEN "currency_changes"` {
+		t.Errorf("query = %q, want the quoted channel", conn.query)
+	}
+	if err := conn.notify("currency_changes", "first"); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, payloads, "first")
+	select {
+	case <-reconnects:
+		t.Error("onReconnect ran for the first connection")
+	default:
+	}
+
+	// a dropped connection is re-established after the reconnect delay, onReconnect lets the caller resync
+	conn.Close()
+	conn = server.accept(t)
+	receive(t, reconnects, "reconnected")
+	if err := conn.notify("currency_changes", "second"); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, payloads, "second")
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Listen = %v, want nil once the context is done", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Listen did not return after the context was cancelled")
+	}
+}
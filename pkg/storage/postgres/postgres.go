@@ -8,15 +8,17 @@ import (
 	"time"
 )
 
-func NewPsqlDB(c *config.Config) (*gorm.DB, error) {
-	connectionString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
+func ConnectionString(c *config.Config) string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
 		c.Postgres.UserName,
 		c.Postgres.Password,
 		c.Postgres.Host,
 		c.Postgres.Port,
 		c.Postgres.DbName)
+}
 
-	db, err := gorm.Open(postgres.Open(connectionString), &gorm.Config{})
+func NewPsqlDB(c *config.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(ConnectionString(c)), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
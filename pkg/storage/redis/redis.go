@@ -1,19 +1,106 @@
 package redis
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
 	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"os"
+	"strings"
+	"time"
 )
 
-func NewRedisClient(cfg *config.Config) *redis.Client {
-	redisHost := cfg.Redis.Url
-	if redisHost == "" {
-		redisHost = ":6379"
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel = "sentinel"
+	ModeCluster = "cluster"
+)
+
+func NewRedisClient(cfg *config.Config) (redis.UniversalClient, error) {
+	opts, err := newUniversalOptions(cfg.Redis)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(cfg.Redis.Mode) {
+	case "", ModeStandalone:
+		return redis.NewClient(opts.Simple()), nil
+	case ModeSentinel:
+		if opts.MasterName == "" {
+			return nil, errors.New("redis.NewRedisClient: mastername is required in sentinel mode")
+		}
+		return redis.NewFailoverClient(opts.Failover()), nil
+	case ModeCluster:
+		return redis.NewClusterClient(opts.Cluster()), nil
+	default:
+		return nil, fmt.Errorf("redis.NewRedisClient: unknown mode %q", cfg.Redis.Mode)
+	}
+}
+
+func newUniversalOptions(c config.RedisConfig) (*redis.UniversalOptions, error) {
+	addrs := c.Addrs
+	if len(addrs) == 0 {
+		redisHost := c.Url
+		if redisHost == "" {
+			redisHost = ":6379"
+		}
+		addrs = []string{redisHost}
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs: addrs,
+		DB: c.DB,
+		Username: c.Username,
+		Password: c.Password,
+		SentinelUsername: c.SentinelUsername,
+		SentinelPassword: c.SentinelPassword,
+		MasterName: c.MasterName,
+		PoolSize: c.PoolSize,
+		MinIdleConns: c.MinIdleConns,
+		DialTimeout: time.Second * c.DialTimeout,
+		ReadTimeout: time.Second * c.ReadTimeout,
+		WriteTimeout: time.Second * c.WriteTimeout,
 	}
 
-	client := redis.NewClient(&redis.Options{
-		Addr: redisHost,
-	})
+	if c.TLS.Enabled {
+		tlsConfig, err := newTLSConfig(c.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	return opts, nil
+}
+
+func newTLSConfig(c config.RedisTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		caCert, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "redis.newTLSConfig.ReadCAFile")
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("redis.newTLSConfig: no certificates found in cafile")
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "redis.newTLSConfig.LoadX509KeyPair")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
 
-	return client
+	return tlsConfig, nil
 }
@@ -0,0 +1,177 @@
+package redis
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sefikcan/kanbersky.ca/pkg/config"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewRedisClientModes(t *testing.T) {
+	tests := []struct {
+		name string
+		redis config.RedisConfig
+		client interface{}
+		fails bool
+	}{
+		{name: "standalone by default", redis: config.RedisConfig{Url: "localhost:6379"}, client: &redis.Client{}},
+		{name: "standalone", redis: config.RedisConfig{Mode: "Standalone", Url: "localhost:6379"}, client: &redis.Client{}},
+		{name: "sentinel", redis: config.RedisConfig{Mode: ModeSentinel, Addrs: []string{"a:26379", "b:26379"}, MasterName: "mymaster"}, client: &redis.Client{}},
+		{name: "sentinel without master", redis: config.RedisConfig{Mode: ModeSentinel, Addrs: []string{"a:26379"}}, fails: true},
+		{name: "cluster", redis: config.RedisConfig{Mode: ModeCluster, Addrs: []string{"a:7000", "b:7001"}}, client: &redis.ClusterClient{}},
+		{name: "unknown mode", redis: config.RedisConfig{Mode: "ring"}, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewRedisClient(&config.Config{Redis: tt.redis})
+			if (err != nil) != tt.fails {
+				t.Fatalf("err = %v, want error %t", err, tt.fails)
+			}
+			if tt.fails {
+				return
+			}
+			defer client.Close()
+
+			switch tt.client.(type) {
+			case *redis.Client:
+				if _, ok := client.(*redis.Client); !ok {
+					t.Errorf("client = %T, want *redis.Client", client)
+				}
+			case *redis.ClusterClient:
+				if _, ok := client.(*redis.ClusterClient); !ok {
+					t.Errorf("client = %T, want *redis.ClusterClient", client)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRedisClientAuth(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.RequireUserAuth("app", "s3cret")
+
+	for password, works := range map[string]bool{"s3cret": true, "wrong": false} {
+		client, err := NewRedisClient(&config.Config{Redis: config.RedisConfig{Url: mr.Addr(), Username: "app", Password: password}})
+		if err != nil {
+			t.Fatalf("NewRedisClient: %v", err)
+		}
+		err = client.Ping(context.Background()).Err()
+		client.Close()
+		if (err == nil) != works {
+			t.Errorf("password %q: ping = %v, want it to work %t", password, err, works)
+		}
+	}
+}
+
+func TestNewRedisClientTLS(t *testing.T) {
+	dir := t.TempDir()
+	caFile, serverCert := writeCertificates(t, dir)
+
+	mr, err := miniredis.RunTLS(&tls.Config{Certificates: []tls.Certificate{serverCert}})
+	if err != nil {
+		t.Fatalf("RunTLS: %v", err)
+	}
+	defer mr.Close()
+
+	client, err := NewRedisClient(&config.Config{Redis: config.RedisConfig{
+		Url: mr.Addr(),
+		TLS: config.RedisTLSConfig{Enabled: true, CAFile: caFile, ServerName: "localhost"},
+	}})
+	if err != nil {
+		t.Fatalf("NewRedisClient: %v", err)
+	}
+	defer client.Close()
+	if err = client.Ping(context.Background()).Err(); err != nil {
+		t.Errorf("ping over tls: %v", err)
+	}
+
+	// the server certificate is not signed by the system roots
+	untrusted, err := NewRedisClient(&config.Config{Redis: config.RedisConfig{Url: mr.Addr(), TLS: config.RedisTLSConfig{Enabled: true, ServerName: "localhost"}}})
+	if err != nil {
+		t.Fatalf("NewRedisClient: %v", err)
+	}
+	defer untrusted.Close()
+	if err = untrusted.Ping(context.Background()).Err(); err == nil {
+		t.Error("ping trusted a certificate outside the ca file")
+	}
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPem := filepath.Join(dir, "ca.txt")
+	if err := os.WriteFile(notPem, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]config.RedisTLSConfig{
+		"missing ca file": {CAFile: filepath.Join(dir, "missing.pem")},
+		"ca file without certificates": {CAFile: notPem},
+		"key file without cert file": {KeyFile: filepath.Join(dir, "client.key")},
+	}
+	for name, tlsConfig := range tests {
+		if _, err := newTLSConfig(tlsConfig); err == nil {
+			t.Errorf("%s: newTLSConfig did not fail", name)
+		}
+	}
+}
+
+// writeCertificates writes a ca to dir and returns its file and a localhost certificate signed by it.
+func writeCertificates(t *testing.T, dir string) (string, tls.Certificate) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{CommonName: "test ca"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter: time.Now().Add(time.Hour),
+		IsCA: true,
+		KeyUsage: x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(dir, "ca.pem")
+	if err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject: pkix.Name{CommonName: "localhost"},
+		DNSNames: []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter: time.Now().Add(time.Hour),
+		KeyUsage: x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	serverDer, err := x509.CreateCertificate(rand.Reader, serverTemplate, caTemplate, &serverKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return caFile, tls.Certificate{Certificate: [][]byte{serverDer}, PrivateKey: serverKey}
+}
@@ -0,0 +1,38 @@
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Global is the tenant of the shared records every tenant sees, e.g. the ISO currencies.
+const Global = ""
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+type tenantKey struct{}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant of the request, Global when it has none, e.g. for platform callers and
+// background jobs. Tenants see their own records and the Global ones.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantKey{}).(string)
+	return id
+}
+
+// Visible lists the tenants whose records a request of the tenant sees.
+func Visible(id string) []string {
+	if id == Global {
+		return []string{Global}
+	}
+
+	return []string{Global, id}
+}
+
+// Valid reports whether id is a lower case tenant id of at most 63 letters, digits, dashes and underscores.
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
@@ -0,0 +1,46 @@
+package tenant
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	if id := FromContext(context.Background()); id != Global {
+		t.Errorf("FromContext without a tenant = %q, want the global tenant", id)
+	}
+	if id := FromContext(NewContext(context.Background(), "retail")); id != "retail" {
+		t.Errorf("FromContext = %q, want retail", id)
+	}
+}
+
+func TestVisible(t *testing.T) {
+	if visible := Visible(Global); !reflect.DeepEqual(visible, []string{Global}) {
+		t.Errorf("Visible(global) = %q", visible)
+	}
+	// the tenant comes last, lookups walking the list backwards find its records first
+	if visible := Visible("retail"); !reflect.DeepEqual(visible, []string{Global, "retail"}) {
+		t.Errorf("Visible(retail) = %q", visible)
+	}
+}
+
+func TestValid(t *testing.T) {
+	for id, want := range map[string]bool{
+		"retail": true,
+		"acme-eu_2": true,
+		"0day": true,
+		strings.Repeat("a", 63): true,
+		strings.Repeat("a", 64): false,
+		"": false,
+		"Retail": false,
+		"-retail": false,
+		"retail.eu": false,
+		"tenant:retail": false,
+	} {
+		if Valid(id) != want {
+			t.Errorf("Valid(%q) = %v, want %v", id, !want, want)
+		}
+	}
+}
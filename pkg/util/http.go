@@ -2,8 +2,12 @@ package util
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/sefikcan/kanbersky.ca/pkg/logger"
+	"net/http"
+	"strings"
 )
 
 func GetRequestId(c echo.Context) string {
@@ -21,3 +25,14 @@ func GetRequestCtx(c echo.Context) context.Context {
 func PrepareLogging(ctx echo.Context, logger logger.Logger, err error)  {
 	logger.Errorf("Error, RequestId: %s, IPAddress: %s, Error: %s", GetRequestId(ctx), GetIPAddress(ctx), err)
 }
+
+// ParseBindError keeps the status of echo bind errors, e.g. 415 for an unsupported body format,
+// every other bind error is a bad request.
+func ParseBindError(err error) HttpResponse {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return NewHttpResponse(httpErr.Code, strings.ToLower(fmt.Sprint(httpErr.Message)), nil)
+	}
+
+	return NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil)
+}
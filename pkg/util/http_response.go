@@ -1,8 +1,13 @@
 package util
 
 import (
+	"context"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
 )
 
 const (
@@ -24,9 +29,10 @@ type HttpResponse interface {
 }
 
 type httpResponse struct {
-	ErrStatus int `json:"status,omitempty"`
-	ErrError string `json:"error,omitempty"`
-	ErrCauses interface{} `json:"-"`
+	XMLName xml.Name `json:"-" xml:"error"`
+	ErrStatus int `json:"status,omitempty" xml:"status,omitempty"`
+	ErrError string `json:"error,omitempty" xml:"message,omitempty"`
+	ErrCauses interface{} `json:"-" xml:"-"`
 }
 
 func (h httpResponse) Status() int {
@@ -47,4 +53,28 @@ func NewHttpResponse(status int, err string, causes interface{}) HttpResponse {
 		ErrError: err,
 		ErrCauses: causes,
 	}
-}
\ No newline at end of file
+}
+
+// ParseError maps usecase and repository errors to the response returned to the client.
+func ParseError(err error) HttpResponse {
+	var httpResponse HttpResponse
+	switch {
+	case errors.As(err, &httpResponse):
+		return httpResponse
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NewHttpResponse(http.StatusNotFound, NotFound.Error(), err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewHttpResponse(http.StatusRequestTimeout, RequestTimeoutError.Error(), err)
+	default:
+		return NewHttpResponse(http.StatusInternalServerError, strings.ToLower(err.Error()), err)
+	}
+}
+
+// ResponseMessage returns the error text meant for the client, falling back to the status text.
+func ResponseMessage(resp HttpResponse) string {
+	if httpResp, ok := resp.(*httpResponse); ok && httpResp.ErrError != "" {
+		return httpResp.ErrError
+	}
+
+	return strings.ToLower(http.StatusText(resp.Status()))
+}